@@ -1,21 +1,42 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
 
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
 	"github.com/daniel-orlov/quotes-server/config"
+	"github.com/daniel-orlov/quotes-server/internal/domain/service/difficulty"
 	qsvc "github.com/daniel-orlov/quotes-server/internal/domain/service/quotes"
+	rsvc "github.com/daniel-orlov/quotes-server/internal/domain/service/reputation"
 	cstore "github.com/daniel-orlov/quotes-server/internal/storage/challenges"
 	qstore "github.com/daniel-orlov/quotes-server/internal/storage/quotes"
+	sstore "github.com/daniel-orlov/quotes-server/internal/storage/spent"
+	tstore "github.com/daniel-orlov/quotes-server/internal/storage/tokens"
 	httptransport "github.com/daniel-orlov/quotes-server/internal/transport/http"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/challenge"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/reputation"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/tokens"
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/adminauth"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/loadtracker"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/ratelimiter"
+	"github.com/daniel-orlov/quotes-server/internal/transport/realip"
 	"github.com/daniel-orlov/quotes-server/pkg/logging"
 	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/token"
+	"github.com/daniel-orlov/quotes-server/pkg/proxyproto"
 )
 
 func main() {
@@ -45,7 +66,15 @@ func main() {
 	// Initialize the quote storage.
 	quoteStorage := qstore.NewStorageInMemory(logger, qstore.GetQuotes())
 	// Initialize the challenge storage.
-	challengeStorage := cstore.NewStorageInMemory(logger)
+	challengeStorage, err := newChallengeStorage(logger, cfg)
+	if err != nil {
+		logger.Fatal("creating challenge storage failed", zap.Error(err))
+	}
+	// Initialize the spent solutions storage.
+	spentStorage, err := newSpentStorage(logger, cfg)
+	if err != nil {
+		logger.Fatal("creating spent storage failed", zap.Error(err))
+	}
 
 	// Log successful storages creation.
 	logger.Info("storages created")
@@ -55,8 +84,71 @@ func main() {
 	//--------------------------------------------------------------//
 	// Initialize the quote service.
 	quoteService := qsvc.NewService(logger, quoteStorage)
+	// Use the hostname as the node ID, if it is not set.
+	if cfg.PoW.NodeID == "" {
+		cfg.PoW.NodeID, _ = os.Hostname()
+	}
+	// Create the keyring signing the challenges, if the stateless mode is used.
+	var keyring *pow.Keyring
+	if cfg.PoW.Mode == pow.ModeStateless {
+		keyring, err = newKeyring(cfg.PoW.HMACKeys)
+		if err != nil {
+			logger.Fatal("creating keyring", zap.Error(err))
+		}
+	}
+
+	// Parse the route policies of the proof-of-work middleware.
+	policies, err := proofer.ParsePolicies(cfg.Server.Middlewares.Proofer.Policies)
+	if err != nil {
+		logger.Fatal("parsing route policies", zap.Error(err))
+	}
+
 	// Proof-of-work service.
-	powService := pow.NewService(logger, challengeStorage)
+	powService := pow.NewService(logger,
+		&pow.Config{
+			NodeID:                 cfg.PoW.NodeID,
+			Mode:                   cfg.PoW.Mode,
+			MaxChallengesPerClient: cfg.PoW.MaxChallengesPerClient,
+			Keyring:                keyring,
+			Scheme:                 cfg.PoW.Scheme,
+			Hashcash: pow.HashcashConfig{
+				Algorithm:             cfg.PoW.Algorithm,
+				LegacyAlgorithms:      cfg.PoW.LegacyAlgorithms,
+				LegacyAlgorithmsUntil: cfg.PoW.LegacyAlgorithmsUntil,
+				ExtraAlgorithms:       proofer.Algorithms(policies),
+				ValidFor:              cfg.PoW.ValidFor,
+				ClockSkew:             cfg.PoW.ClockSkew,
+			},
+			Argon2id: pow.Argon2idConfig{
+				Params: argon2id.Params{
+					Memory:      cfg.PoW.Argon2.Memory,
+					Iterations:  cfg.PoW.Argon2.Iterations,
+					Parallelism: cfg.PoW.Argon2.Parallelism,
+				},
+				ValidFor: cfg.PoW.Argon2.ValidFor,
+			},
+		},
+		challengeStorage,
+		spentStorage,
+	)
+
+	// Access token service, if the solved challenges are exchanged for the tokens.
+	var tokenService *token.Service
+	if cfg.Server.Middlewares.Proofer.Tokens.Enabled {
+		tokenService, err = newTokenService(logger, cfg)
+		if err != nil {
+			logger.Fatal("creating token service failed", zap.Error(err))
+		}
+	}
+
+	// Reputation service, if the difficulty is individual for every client.
+	var reputationService *rsvc.Service
+	if cfg.Server.Middlewares.Proofer.Reputation.Enabled {
+		reputationService, err = newReputationService(logger, cfg)
+		if err != nil {
+			logger.Fatal("creating reputation service failed", zap.Error(err))
+		}
+	}
 
 	// Log successful services creation.
 	logger.Info("services created")
@@ -66,6 +158,14 @@ func main() {
 	//--------------------------------------------------------------//
 	// Initialize the quote handler.
 	quotesHandler := quotes.NewHandler(logger, quoteService)
+	// Initialize the admin handlers of the enabled features.
+	var adminHandlers httptransport.AdminHandlers
+	if reputationService != nil {
+		adminHandlers.Reputation = reputation.NewHandler(logger, reputationService)
+	}
+	if tokenService != nil {
+		adminHandlers.Tokens = tokens.NewHandler(logger, tokenService)
+	}
 
 	// Log successful handlers creation.
 	logger.Info("handlers created")
@@ -73,23 +173,75 @@ func main() {
 	//--------------------------------------------------------------//
 	//  				    	MIDDLEWARES                     	//
 	//--------------------------------------------------------------//
+	// Global middlewares, in the order they are applied.
+	var globalMWs []gin.HandlerFunc
+
+	// Client identity, shared by the rate limiter and the proof-of-work middleware.
+	clientIdentity, err := identity.Parse(cfg.Server.Middlewares.ClientIdentity)
+	if err != nil {
+		logger.Fatal("parsing client identity", zap.Error(err))
+	}
+
+	// Rate limiter and proof-of-work middleware options.
+	var (
+		ratelimiterOpts = []ratelimiter.Option{ratelimiter.WithIdentity(clientIdentity)}
+		prooferOpts     = []proofer.Option{proofer.WithIdentity(clientIdentity)}
+	)
+
+	// Access tokens, bought with the solved challenges.
+	if tokenService != nil {
+		prooferOpts = append(prooferOpts, proofer.WithTokens(tokenService))
+	}
+
+	// Per-client difficulty, driven by the reputation of the clients.
+	if reputationService != nil {
+		ratelimiterOpts = append(ratelimiterOpts, ratelimiter.WithRejectionRecorder(reputationService))
+		prooferOpts = append(prooferOpts, proofer.WithReputation(reputationService))
+	}
+
 	// Rate limiter middleware.
 	ratelimiterMW := ratelimiter.New(logger,
 		&ratelimiter.Config{
 			Rate:  cfg.Server.Middlewares.Ratelimiter.Rate,
 			Limit: cfg.Server.Middlewares.Ratelimiter.Limit,
 			Key:   cfg.Server.Middlewares.Ratelimiter.Key,
-		})
+		},
+		ratelimiterOpts...,
+	)
+
+	// Adaptive difficulty, driven by the load tracked by the load tracker middleware.
+	if cfg.Server.Middlewares.Proofer.Difficulty.Adaptive {
+		controller, err := newDifficultyController(logger, cfg)
+		if err != nil {
+			logger.Fatal("creating difficulty controller failed", zap.Error(err))
+		}
+
+		// Evaluate the load in the background, for as long as the server runs.
+		go controller.Run(context.Background())
+
+		// Track the load of all the requests, including the rejected ones.
+		globalMWs = append(globalMWs, loadtracker.New(logger, controller).Use())
+		prooferOpts = append(prooferOpts, proofer.WithDifficulty(controller))
+	}
 
 	// Proof-of-work middleware.
 	prooferMW := proofer.New(logger,
 		&proofer.Config{
 			ChallengeDifficulty: cfg.Server.Middlewares.Proofer.ChallengeDifficulty,
 			SaltLength:          cfg.Server.Middlewares.Proofer.SaltLength,
+			Policies:            policies,
 		},
 		powService,
+		prooferOpts...,
 	)
 
+	globalMWs = append(globalMWs, ratelimiterMW.Use())
+
+	// The challenge endpoint is behind the same middlewares, except for the proof of work itself.
+	challengeMWs := append([]gin.HandlerFunc(nil), globalMWs...)
+
+	globalMWs = append(globalMWs, prooferMW.Use())
+
 	// Log successful middlewares creation.
 	logger.Info("middlewares created")
 
@@ -97,14 +249,253 @@ func main() {
 	//  				    	SERVER                        		//
 	//--------------------------------------------------------------//
 	// Initialize the Gin router.
-	router := httptransport.NewRouter(quotesHandler, ratelimiterMW.Use(), prooferMW.Use())
+	router := httptransport.NewRouter(quotesHandler, globalMWs...)
+
+	// Register the challenge endpoint, issuing the challenges the same way the proof-of-work middleware does.
+	httptransport.RegisterChallengeRoutes(router, challenge.NewHandler(logger, prooferMW, powService), challengeMWs...)
+
+	// Register the admin endpoints, if they are enabled.
+	if cfg.Server.AdminToken != "" && !adminHandlers.IsEmpty() {
+		httptransport.RegisterAdminRoutes(router, adminHandlers, adminauth.New(logger, string(cfg.Server.AdminToken)).Use())
+	}
 
 	// Log successful router creation.
 	logger.Info("router created")
 
-	// Start the server and listen on port 8080.
-	err = router.Run(fmt.Sprintf(":%d", cfg.Server.Port))
+	// Resolve the real client IP behind the trusted proxies, before any route sees the request.
+	resolver, err := realip.New(logger, &realip.Config{
+		TrustedProxies: cfg.Server.TrustedProxies,
+		Header:         cfg.Server.RemoteIPHeader,
+	})
+	if err != nil {
+		logger.Fatal("creating real ip resolver failed", zap.Error(err))
+	}
+
+	// Listen on the configured port.
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.Port))
+	if err != nil {
+		logger.Fatal("listening failed", zap.Error(err))
+	}
+
+	// Read the PROXY protocol header of the trusted proxies, if it is enabled.
+	if cfg.Server.ProxyProtocol {
+		listener = proxyproto.NewListener(listener, resolver.IsTrusted, proxyproto.DefaultHeaderTimeout)
+	}
+
+	// Start the server.
+	logger.Info("server started", zap.String("addr", listener.Addr().String()))
+
+	err = http.Serve(listener, resolver.Wrap(router))
 	if err != nil {
 		logger.Fatal("running server failed", zap.Error(err))
 	}
 }
+
+// newChallengeStorage creates the challenge storage of the configured backend.
+func newChallengeStorage(logger *zap.Logger, cfg *config.Config) (pow.ChallengeStore, error) {
+	switch cfg.PoW.ChallengeStoreBackend {
+	case cstore.BackendMemory:
+		storage := cstore.NewStorageInMemory(logger, cfg.PoW.ChallengeStoreSize)
+
+		// Sweep the expired challenges away in the background, for as long as the server runs.
+		go storage.Run(context.Background(), cfg.PoW.ChallengeStoreSweepInterval)
+
+		return storage, nil
+	case cstore.BackendBolt:
+		storage, err := cstore.NewStorageBolt(logger, cfg.PoW.ChallengeStorePath)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sweep the expired challenges away and compact the database in the background, for as long as the server runs.
+		go storage.Run(context.Background(), cfg.PoW.ChallengeStoreSweepInterval, cfg.PoW.ChallengeStoreCompactInterval)
+
+		return storage, nil
+	case cstore.BackendRedis:
+		client, err := newRedisClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return cstore.NewStorageRedis(logger, client, cfg.Redis.KeyPrefix+"challenge:"), nil
+	default:
+		return nil, fmt.Errorf("unknown challenge store backend %q", cfg.PoW.ChallengeStoreBackend)
+	}
+}
+
+// newSpentStorage creates the spent solutions storage of the configured backend.
+// In the stateless mode a solution is only rejected as spent on the nodes that remember it,
+// so a storage in memory lets it be replayed once on every other node sharing the keys.
+func newSpentStorage(logger *zap.Logger, cfg *config.Config) (pow.SpentStore, error) {
+	switch cfg.PoW.SpentStoreBackend {
+	case sstore.BackendMemory:
+		if cfg.PoW.Mode == pow.ModeStateless {
+			logger.Warn("spent solutions are kept in memory, in the stateless mode with several nodes " +
+				"a solution can be replayed once on every node, set POW_SPENT_STORE_BACKEND=redis to share them")
+		}
+
+		return sstore.NewStorageInMemory(logger, cfg.PoW.SpentStoreSize), nil
+	case sstore.BackendRedis:
+		client, err := newRedisClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return sstore.NewStorageRedis(logger, client, cfg.Redis.KeyPrefix+"spent:"), nil
+	default:
+		return nil, fmt.Errorf("unknown spent store backend %q", cfg.PoW.SpentStoreBackend)
+	}
+}
+
+// newRedisClient creates the client of the configured Redis server, failing fast, if it is not reachable.
+func newRedisClient(cfg *config.Config) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Redis.Addr,
+		Username:     cfg.Redis.Username,
+		Password:     string(cfg.Redis.Password),
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		MinIdleConns: cfg.Redis.MinIdleConns,
+		DialTimeout:  cfg.Redis.DialTimeout,
+		ReadTimeout:  cfg.Redis.ReadTimeout,
+		WriteTimeout: cfg.Redis.WriteTimeout,
+	})
+
+	// Fail fast, if Redis is not reachable
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("connecting to redis: %w", err)
+	}
+
+	return client, nil
+}
+
+// newKeyring creates the keyring from the "<id>:<secret>" keys.
+func newKeyring(rawKeys []string) (*pow.Keyring, error) {
+	// Parse the keys
+	keys, err := pow.ParseSigningKeys(rawKeys)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing keys: %w", err)
+	}
+
+	// Create the keyring
+	return pow.NewKeyring(keys...)
+}
+
+// newDifficultyController creates the difficulty controller, sampling the CPU utilisation, if the platform supports it.
+func newDifficultyController(logger *zap.Logger, cfg *config.Config) (*difficulty.Controller, error) {
+	difficultyCfg := cfg.Server.Middlewares.Proofer.Difficulty
+
+	// Sample the CPU utilisation, if the platform supports it
+	cpu, err := difficulty.NewCPUSampler()
+	if err != nil {
+		logger.Warn("cpu utilisation is not taken into account", zap.Error(err))
+	}
+
+	// Create the controller
+	return difficulty.New(logger,
+		&difficulty.Config{
+			Floor:       difficultyCfg.Floor,
+			Ceiling:     difficultyCfg.Ceiling,
+			Step:        difficultyCfg.Step,
+			Interval:    difficultyCfg.Interval,
+			MaxInFlight: difficultyCfg.MaxInFlight,
+			MaxRate:     difficultyCfg.MaxRate,
+			MaxLatency:  difficultyCfg.MaxLatency,
+			MaxCPU:      difficultyCfg.MaxCPU,
+			RaiseAt:     difficultyCfg.RaiseAt,
+			LowerAt:     difficultyCfg.LowerAt,
+		},
+		cpu,
+	)
+}
+
+// newReputationService creates the reputation service tracking the behaviour of the clients.
+func newReputationService(logger *zap.Logger, cfg *config.Config) (*rsvc.Service, error) {
+	reputationCfg := cfg.Server.Middlewares.Proofer.Reputation
+
+	return rsvc.NewService(logger,
+		&rsvc.Config{
+			HalfLife:             reputationCfg.HalfLife,
+			MaxClients:           reputationCfg.MaxClients,
+			PendingTTL:           reputationCfg.PendingTTL,
+			RequestWeight:        reputationCfg.RequestWeight,
+			FailedSolutionWeight: reputationCfg.FailedSolutionWeight,
+			RateLimitHitWeight:   reputationCfg.RateLimitHitWeight,
+			FastSolveWeight:      reputationCfg.FastSolveWeight,
+			FastSolve:            reputationCfg.FastSolve,
+			PointsPerBit:         reputationCfg.PointsPerBit,
+			MaxExtraDifficulty:   reputationCfg.MaxExtraDifficulty,
+		},
+	)
+}
+
+const (
+	// tokenKeySize is the size of the random key signing the access tokens, if none is configured.
+	tokenKeySize = 32
+	// localTokenKeyID is the ID of the random key signing the access tokens.
+	localTokenKeyID = "local"
+)
+
+// newTokenService creates the access token service, signing the tokens with the configured keys or a random one.
+func newTokenService(logger *zap.Logger, cfg *config.Config) (*token.Service, error) {
+	tokensCfg := cfg.Server.Middlewares.Proofer.Tokens
+
+	// Parse the keys
+	keys, err := pow.ParseSigningKeys(tokensCfg.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("parsing token keys: %w", err)
+	}
+
+	// Generate a random key, if none is configured
+	if len(keys) == 0 {
+		logger.Warn("no token keys configured, the tokens are only valid on this node until restart")
+
+		secret := make([]byte, tokenKeySize)
+		if _, err = rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("generating token key: %w", err)
+		}
+
+		keys = append(keys, pow.SigningKey{ID: localTokenKeyID, Secret: secret})
+	}
+
+	// Create the keyring
+	keyring, err := pow.NewKeyring(keys...)
+	if err != nil {
+		return nil, fmt.Errorf("creating token keyring: %w", err)
+	}
+
+	// Create the storage of the uses and the revocations
+	store, err := newTokenStorage(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the service
+	return token.NewService(logger,
+		&token.Config{
+			TTL:     tokensCfg.TTL,
+			MaxUses: tokensCfg.MaxUses,
+		},
+		keyring,
+		store,
+	)
+}
+
+// newTokenStorage creates the access token storage of the configured backend.
+func newTokenStorage(logger *zap.Logger, cfg *config.Config) (token.Store, error) {
+	tokensCfg := cfg.Server.Middlewares.Proofer.Tokens
+
+	switch tokensCfg.StoreBackend {
+	case tstore.BackendMemory:
+		return tstore.NewStorageInMemory(logger, tokensCfg.StoreSize), nil
+	case tstore.BackendRedis:
+		client, err := newRedisClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return tstore.NewStorageRedis(logger, client, cfg.Redis.KeyPrefix+"token:"), nil
+	default:
+		return nil, fmt.Errorf("unknown token store backend %q", tokensCfg.StoreBackend)
+	}
+}
@@ -3,10 +3,12 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/ratelimiter"
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
 )
 
 // Config is the main configuration of the application.
@@ -25,15 +27,29 @@ type Config struct {
 		GinMode string `envconfig:"GIN_MODE" default:"release"`
 		// Port is the port to listen on.
 		Port int `envconfig:"SERVER_PORT" default:"8080"`
+		// AdminToken is the bearer token protecting the admin endpoints. If it is empty, they are disabled.
+		AdminToken Secret `envconfig:"ADMIN_TOKEN"`
+		// TrustedProxies are the IP addresses and the CIDRs of the proxies, whose headers tell the real client IP.
+		// If there are none, the client IP is the peer address, and the headers are ignored.
+		TrustedProxies []string `envconfig:"SERVER_TRUSTED_PROXIES"`
+		// RemoteIPHeader is the one header the trusted proxies set to tell the real client IP, e.g. X-Forwarded-For.
+		// There is no default, as the proxies pass the other headers on from the client.
+		RemoteIPHeader string `envconfig:"SERVER_REMOTE_IP_HEADER"`
+		// ProxyProtocol enables the PROXY protocol v1 and v2 on the listener, for the trusted proxies.
+		ProxyProtocol bool `envconfig:"SERVER_PROXY_PROTOCOL" default:"false"`
 		// Meddlewares is the configuration for the middlewares.
 		Middlewares struct {
+			// ClientIdentity is the client identity spec, see the identity package for the format.
+			// The challenges, the access tokens and the reputation are bound to the client identified by it.
+			ClientIdentity string `envconfig:"CLIENT_IDENTITY" default:"ip"`
 			// Ratelimiter is the configuration for the ratelimiter middleware.
 			Ratelimiter struct {
 				// Rate is the rate at which requests are allowed.
 				Rate ratelimiter.Rate `envconfig:"RATELIMITER_RATE" default:"second"`
 				// Limit is the maximum number of requests allowed.
 				Limit uint `envconfig:"RATELIMITER_LIMIT" default:"5"`
-				// Key is the key to use for the ratelimiter.
+				// Key is the key to use for the ratelimiter: client_ip, client_id for the ClientIdentity,
+				// or any other client identity spec.
 				Key ratelimiter.Key `envconfig:"RATELIMITER_KEY" default:"client_ip"`
 			}
 			// Proofer is the configuration for the proofer middleware.
@@ -42,9 +58,152 @@ type Config struct {
 				ChallengeDifficulty int `envconfig:"CHALLENGE_DIFFICULTY" default:"20"`
 				// SaltLength is the length of the salt.
 				SaltLength int `envconfig:"SALT_LENGTH" default:"8"`
+				// Policies is the route policy table, see proofer.ParsePolicies for the format.
+				Policies string `envconfig:"PROOFER_POLICIES" default:"GET /v1/health exempt"`
+				// Difficulty is the configuration of the adaptive difficulty.
+				Difficulty struct {
+					// Adaptive enables the adaptive difficulty. If it is disabled, ChallengeDifficulty is used.
+					Adaptive bool `envconfig:"DIFFICULTY_ADAPTIVE" default:"false"`
+					// Floor is the lowest difficulty, used when the server is calm.
+					Floor int `envconfig:"DIFFICULTY_FLOOR" default:"16"`
+					// Ceiling is the highest difficulty, used during a flood.
+					Ceiling int `envconfig:"DIFFICULTY_CEILING" default:"26"`
+					// Step is by how much the difficulty is raised or lowered at once.
+					Step int `envconfig:"DIFFICULTY_STEP" default:"2"`
+					// Interval is how often the load is evaluated.
+					Interval time.Duration `envconfig:"DIFFICULTY_INTERVAL" default:"1s"`
+					// MaxInFlight is the number of in-flight requests considered a full load, 0 disables the signal.
+					MaxInFlight int `envconfig:"DIFFICULTY_MAX_IN_FLIGHT" default:"100"`
+					// MaxRate is the number of requests per second considered a full load, 0 disables the signal.
+					MaxRate float64 `envconfig:"DIFFICULTY_MAX_RATE" default:"200"`
+					// MaxLatency is the mean handler latency considered a full load, 0 disables the signal.
+					MaxLatency time.Duration `envconfig:"DIFFICULTY_MAX_LATENCY" default:"250ms"`
+					// MaxCPU is the CPU utilisation from 0 to 1 considered a full load, 0 disables the signal.
+					MaxCPU float64 `envconfig:"DIFFICULTY_MAX_CPU" default:"0.8"`
+					// RaiseAt is the load, at or above which the difficulty is raised, 1 being the full load.
+					RaiseAt float64 `envconfig:"DIFFICULTY_RAISE_AT" default:"1"`
+					// LowerAt is the load, at or below which the difficulty is lowered.
+					LowerAt float64 `envconfig:"DIFFICULTY_LOWER_AT" default:"0.5"`
+				}
+				// Tokens is the configuration of the access tokens, that the solved challenges are exchanged for.
+				Tokens struct {
+					// Enabled enables exchanging the solved challenges for the access tokens.
+					Enabled bool `envconfig:"TOKEN_ENABLED" default:"false"`
+					// TTL is for how long a token is valid.
+					TTL time.Duration `envconfig:"TOKEN_TTL" default:"30s"`
+					// MaxUses is how many requests a token allows, 0 means it is only limited by the TTL.
+					MaxUses int `envconfig:"TOKEN_MAX_USES" default:"10"`
+					// Keys are the "<id>:<secret>" keys signing the tokens, the first one being the primary.
+					// If they are empty, a random key is generated, so the tokens are only valid on this node until restart.
+					// Sharing the keys makes the tokens valid on every node, but their uses are only counted,
+					// and their revocations only honoured, on every node together with the redis store backend.
+					Keys Secrets `envconfig:"TOKEN_KEYS"`
+					// StoreBackend is where the uses and the revocations of the tokens are kept: in memory or in Redis.
+					StoreBackend string `envconfig:"TOKEN_STORE_BACKEND" default:"memory"`
+					// StoreSize is the maximum number of the tokens, whose uses and revocations are remembered in memory.
+					StoreSize int `envconfig:"TOKEN_STORE_SIZE" default:"100000"`
+				}
+				// Reputation is the configuration of the per-client reputation-based difficulty.
+				Reputation struct {
+					// Enabled enables raising the difficulty for the clients with a bad reputation.
+					Enabled bool `envconfig:"REPUTATION_ENABLED" default:"false"`
+					// HalfLife is the time, after which the score of a client is halved.
+					HalfLife time.Duration `envconfig:"REPUTATION_HALF_LIFE" default:"10m"`
+					// MaxClients is the maximum number of the clients tracked.
+					MaxClients int `envconfig:"REPUTATION_MAX_CLIENTS" default:"100000"`
+					// PendingTTL is for how long an issued challenge keeps its client tracked, unless solved.
+					PendingTTL time.Duration `envconfig:"REPUTATION_PENDING_TTL" default:"10m"`
+					// RequestWeight is added to the score for every request.
+					RequestWeight float64 `envconfig:"REPUTATION_REQUEST_WEIGHT" default:"0.01"`
+					// FailedSolutionWeight is added to the score for every invalid or reused solution.
+					FailedSolutionWeight float64 `envconfig:"REPUTATION_FAILED_SOLUTION_WEIGHT" default:"1"`
+					// RateLimitHitWeight is added to the score for every request rejected by the rate limiter.
+					RateLimitHitWeight float64 `envconfig:"REPUTATION_RATE_LIMIT_HIT_WEIGHT" default:"2"`
+					// FastSolve is the solve time, below which the solution is suspiciously fast, 0 disables the penalty.
+					FastSolve time.Duration `envconfig:"REPUTATION_FAST_SOLVE" default:"100ms"`
+					// FastSolveWeight is added to the score for every suspiciously fast solution.
+					FastSolveWeight float64 `envconfig:"REPUTATION_FAST_SOLVE_WEIGHT" default:"0.5"`
+					// PointsPerBit is the score, that adds one bit to the difficulty.
+					PointsPerBit float64 `envconfig:"REPUTATION_POINTS_PER_BIT" default:"4"`
+					// MaxExtraDifficulty is the maximum difficulty added because of the score.
+					MaxExtraDifficulty int `envconfig:"REPUTATION_MAX_EXTRA_DIFFICULTY" default:"6"`
+				}
 			}
 		}
 	}
+	// PoW is the proof of work configuration.
+	PoW struct {
+		// Scheme is the challenge scheme used for the new challenges.
+		Scheme string `envconfig:"POW_SCHEME" default:"hashcash"`
+		// NodeID is the ID of this server, carried in the challenges. If it is empty, the hostname is used.
+		NodeID string `envconfig:"POW_NODE_ID"`
+		// Mode is either stateful, keeping the challenges in the store, or stateless, signing them.
+		Mode string `envconfig:"POW_MODE" default:"stateful"`
+		// HMACKeys are the "<id>:<secret>" keys signing the stateless challenges, the first one being the primary.
+		HMACKeys Secrets `envconfig:"POW_HMAC_KEYS"`
+		// SpentStoreBackend is where the spent solutions are remembered: in memory or in Redis.
+		// In the stateless mode with several nodes, it must be shared, or a solution could be replayed on every node.
+		SpentStoreBackend string `envconfig:"POW_SPENT_STORE_BACKEND" default:"memory"`
+		// SpentStoreSize is the maximum number of the spent solutions remembered in memory until they expire.
+		SpentStoreSize int `envconfig:"POW_SPENT_STORE_SIZE" default:"100000"`
+		// MaxChallengesPerClient is the maximum number of the outstanding challenges of a client in the stateful mode.
+		MaxChallengesPerClient int `envconfig:"POW_MAX_CHALLENGES_PER_CLIENT" default:"16"`
+		// ChallengeStoreBackend is where the challenges are stored in the stateful mode: in memory, on disk or in Redis.
+		ChallengeStoreBackend string `envconfig:"POW_CHALLENGE_STORE_BACKEND" default:"memory"`
+		// ChallengeStoreSize is the maximum number of the stored challenges, the soonest expiring ones are evicted.
+		ChallengeStoreSize int `envconfig:"POW_CHALLENGE_STORE_SIZE" default:"100000"`
+		// ChallengeStoreSweepInterval is how often the expired challenges are swept away from the store.
+		ChallengeStoreSweepInterval time.Duration `envconfig:"POW_CHALLENGE_STORE_SWEEP_INTERVAL" default:"1m"`
+		// ChallengeStorePath is the path of the database file of the bolt challenge store.
+		ChallengeStorePath string `envconfig:"POW_CHALLENGE_STORE_PATH" default:"challenges.db"`
+		// ChallengeStoreCompactInterval is how often the database file of the bolt challenge store is compacted.
+		ChallengeStoreCompactInterval time.Duration `envconfig:"POW_CHALLENGE_STORE_COMPACT_INTERVAL" default:"1h"`
+		// Algorithm is the hash algorithm used for the new hashcash challenges.
+		Algorithm hashcash.Algorithm `envconfig:"HASHCASH_ALGORITHM" default:"sha256"`
+		// LegacyAlgorithms are the hash algorithms, whose solutions are still accepted until the end of the migration.
+		LegacyAlgorithms []hashcash.Algorithm `envconfig:"HASHCASH_LEGACY_ALGORITHMS" default:"sha1"`
+		// LegacyAlgorithmsUntil is the end of the migration, an RFC 3339 time shared by all the nodes.
+		// If it is not set, the legacy algorithms are not accepted.
+		LegacyAlgorithmsUntil time.Time `envconfig:"HASHCASH_LEGACY_ALGORITHMS_UNTIL"`
+		// ValidFor is for how long a hashcash challenge can be solved, 0 means until its date format expires.
+		ValidFor time.Duration `envconfig:"HASHCASH_VALID_FOR" default:"10m"`
+		// ClockSkew is the allowance for the clock skew, when checking the hashcash dates and expiry times.
+		ClockSkew time.Duration `envconfig:"HASHCASH_CLOCK_SKEW" default:"30s"`
+		// Argon2 is the configuration of the argon2id challenges.
+		Argon2 struct {
+			// Memory is the memory cost of a single argon2id hash, in KiB.
+			Memory uint32 `envconfig:"ARGON2_MEMORY" default:"16384"`
+			// Iterations is the number of passes over the memory.
+			Iterations uint32 `envconfig:"ARGON2_ITERATIONS" default:"1"`
+			// Parallelism is the number of threads used by a single argon2id hash.
+			Parallelism uint8 `envconfig:"ARGON2_PARALLELISM" default:"1"`
+			// ValidFor is for how long an argon2id challenge can be solved.
+			ValidFor time.Duration `envconfig:"ARGON2_VALID_FOR" default:"5m"`
+		}
+	}
+	// Redis is the configuration of the Redis connection, used by the Redis challenge, spent and token stores.
+	Redis struct {
+		// Addr is the "host:port" address of the Redis server.
+		Addr string `envconfig:"REDIS_ADDR" default:"localhost:6379"`
+		// Username is the ACL username, if the Redis server uses ACLs.
+		Username string `envconfig:"REDIS_USERNAME"`
+		// Password is the password of the Redis server or the ACL user.
+		Password Secret `envconfig:"REDIS_PASSWORD"`
+		// DB is the number of the Redis database.
+		DB int `envconfig:"REDIS_DB" default:"0"`
+		// KeyPrefix is prepended to all the keys, so that several applications could share the same database.
+		KeyPrefix string `envconfig:"REDIS_KEY_PREFIX" default:"quotes-server:"`
+		// PoolSize is the maximum number of the connections in the pool, 0 means 10 per CPU.
+		PoolSize int `envconfig:"REDIS_POOL_SIZE" default:"0"`
+		// MinIdleConns is the number of the idle connections kept open in the pool.
+		MinIdleConns int `envconfig:"REDIS_MIN_IDLE_CONNS" default:"0"`
+		// DialTimeout is the timeout of establishing a new connection.
+		DialTimeout time.Duration `envconfig:"REDIS_DIAL_TIMEOUT" default:"5s"`
+		// ReadTimeout is the timeout of reading a reply.
+		ReadTimeout time.Duration `envconfig:"REDIS_READ_TIMEOUT" default:"3s"`
+		// WriteTimeout is the timeout of writing a command.
+		WriteTimeout time.Duration `envconfig:"REDIS_WRITE_TIMEOUT" default:"3s"`
+	}
 }
 
 // NewConfig returns a new Config instance, populated with environment variables and defaults.
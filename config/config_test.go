@@ -1,13 +1,18 @@
 package config_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/daniel-orlov/quotes-server/config"
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
 )
 
 func TestNewConfig_UsingDefaults(t *testing.T) {
@@ -30,6 +35,71 @@ func TestNewConfig_UsingDefaults(t *testing.T) {
 	assert.Equal(t, "release", cfg.Server.GinMode)
 	assert.Equal(t, 20, cfg.Server.Middlewares.Proofer.ChallengeDifficulty)
 	assert.Equal(t, 8, cfg.Server.Middlewares.Proofer.SaltLength)
+	assert.Equal(t, "GET /v1/health exempt", cfg.Server.Middlewares.Proofer.Policies)
+	assert.False(t, cfg.Server.Middlewares.Proofer.Difficulty.Adaptive)
+	assert.Equal(t, 16, cfg.Server.Middlewares.Proofer.Difficulty.Floor)
+	assert.Equal(t, 26, cfg.Server.Middlewares.Proofer.Difficulty.Ceiling)
+	assert.Equal(t, 2, cfg.Server.Middlewares.Proofer.Difficulty.Step)
+	assert.Equal(t, time.Second, cfg.Server.Middlewares.Proofer.Difficulty.Interval)
+	assert.Equal(t, 100, cfg.Server.Middlewares.Proofer.Difficulty.MaxInFlight)
+	assert.Equal(t, 200.0, cfg.Server.Middlewares.Proofer.Difficulty.MaxRate)
+	assert.Equal(t, 250*time.Millisecond, cfg.Server.Middlewares.Proofer.Difficulty.MaxLatency)
+	assert.Equal(t, 0.8, cfg.Server.Middlewares.Proofer.Difficulty.MaxCPU)
+	assert.Equal(t, 1.0, cfg.Server.Middlewares.Proofer.Difficulty.RaiseAt)
+	assert.Equal(t, 0.5, cfg.Server.Middlewares.Proofer.Difficulty.LowerAt)
+	assert.Equal(t, config.Secret(""), cfg.Server.AdminToken)
+	assert.False(t, cfg.Server.Middlewares.Proofer.Tokens.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.Server.Middlewares.Proofer.Tokens.TTL)
+	assert.Equal(t, 10, cfg.Server.Middlewares.Proofer.Tokens.MaxUses)
+	assert.Empty(t, cfg.Server.Middlewares.Proofer.Tokens.Keys)
+	assert.Equal(t, "memory", cfg.Server.Middlewares.Proofer.Tokens.StoreBackend)
+	assert.Equal(t, 100000, cfg.Server.Middlewares.Proofer.Tokens.StoreSize)
+	assert.False(t, cfg.Server.Middlewares.Proofer.Reputation.Enabled)
+	assert.Equal(t, 10*time.Minute, cfg.Server.Middlewares.Proofer.Reputation.HalfLife)
+	assert.Equal(t, 100000, cfg.Server.Middlewares.Proofer.Reputation.MaxClients)
+	assert.Equal(t, 10*time.Minute, cfg.Server.Middlewares.Proofer.Reputation.PendingTTL)
+	assert.Equal(t, 0.01, cfg.Server.Middlewares.Proofer.Reputation.RequestWeight)
+	assert.Equal(t, 1.0, cfg.Server.Middlewares.Proofer.Reputation.FailedSolutionWeight)
+	assert.Equal(t, 2.0, cfg.Server.Middlewares.Proofer.Reputation.RateLimitHitWeight)
+	assert.Equal(t, 100*time.Millisecond, cfg.Server.Middlewares.Proofer.Reputation.FastSolve)
+	assert.Equal(t, 0.5, cfg.Server.Middlewares.Proofer.Reputation.FastSolveWeight)
+	assert.Equal(t, 4.0, cfg.Server.Middlewares.Proofer.Reputation.PointsPerBit)
+	assert.Equal(t, 6, cfg.Server.Middlewares.Proofer.Reputation.MaxExtraDifficulty)
+	assert.Equal(t, hashcash.AlgorithmSHA256, cfg.PoW.Algorithm)
+	assert.Equal(t, []hashcash.Algorithm{hashcash.AlgorithmSHA1}, cfg.PoW.LegacyAlgorithms)
+	assert.True(t, cfg.PoW.LegacyAlgorithmsUntil.IsZero())
+	assert.Equal(t, "hashcash", cfg.PoW.Scheme)
+	assert.Equal(t, "", cfg.PoW.NodeID)
+	assert.Equal(t, "stateful", cfg.PoW.Mode)
+	assert.Empty(t, cfg.PoW.HMACKeys)
+	assert.Equal(t, "memory", cfg.PoW.SpentStoreBackend)
+	assert.Equal(t, 100000, cfg.PoW.SpentStoreSize)
+	assert.Equal(t, 16, cfg.PoW.MaxChallengesPerClient)
+	assert.Equal(t, 100000, cfg.PoW.ChallengeStoreSize)
+	assert.Equal(t, time.Minute, cfg.PoW.ChallengeStoreSweepInterval)
+	assert.Equal(t, 10*time.Minute, cfg.PoW.ValidFor)
+	assert.Equal(t, 30*time.Second, cfg.PoW.ClockSkew)
+	assert.Equal(t, uint32(16384), cfg.PoW.Argon2.Memory)
+	assert.Equal(t, uint32(1), cfg.PoW.Argon2.Iterations)
+	assert.Equal(t, uint8(1), cfg.PoW.Argon2.Parallelism)
+	assert.Equal(t, 5*time.Minute, cfg.PoW.Argon2.ValidFor)
+	assert.Equal(t, "memory", cfg.PoW.ChallengeStoreBackend)
+	assert.Equal(t, "ip", cfg.Server.Middlewares.ClientIdentity)
+	assert.Empty(t, cfg.Server.TrustedProxies)
+	assert.Empty(t, cfg.Server.RemoteIPHeader)
+	assert.False(t, cfg.Server.ProxyProtocol)
+	assert.Equal(t, "challenges.db", cfg.PoW.ChallengeStorePath)
+	assert.Equal(t, time.Hour, cfg.PoW.ChallengeStoreCompactInterval)
+	assert.Equal(t, "localhost:6379", cfg.Redis.Addr)
+	assert.Equal(t, "", cfg.Redis.Username)
+	assert.Equal(t, config.Secret(""), cfg.Redis.Password)
+	assert.Equal(t, 0, cfg.Redis.DB)
+	assert.Equal(t, "quotes-server:", cfg.Redis.KeyPrefix)
+	assert.Equal(t, 0, cfg.Redis.PoolSize)
+	assert.Equal(t, 0, cfg.Redis.MinIdleConns)
+	assert.Equal(t, 5*time.Second, cfg.Redis.DialTimeout)
+	assert.Equal(t, 3*time.Second, cfg.Redis.ReadTimeout)
+	assert.Equal(t, 3*time.Second, cfg.Redis.WriteTimeout)
 }
 
 func TestNewConfig_UsingEnvironmentVariables(t *testing.T) {
@@ -44,6 +114,77 @@ func TestNewConfig_UsingEnvironmentVariables(t *testing.T) {
 		"GIN_MODE":             "debug",
 		"CHALLENGE_DIFFICULTY": "10",
 		"SALT_LENGTH":          "4",
+		"PROOFER_POLICIES":     "* /v1/quotes/* difficulty=22",
+
+		"DIFFICULTY_ADAPTIVE":      "true",
+		"DIFFICULTY_FLOOR":         "8",
+		"DIFFICULTY_CEILING":       "30",
+		"DIFFICULTY_STEP":          "1",
+		"DIFFICULTY_INTERVAL":      "5s",
+		"DIFFICULTY_MAX_IN_FLIGHT": "50",
+		"DIFFICULTY_MAX_RATE":      "1000",
+		"DIFFICULTY_MAX_LATENCY":   "1s",
+		"DIFFICULTY_MAX_CPU":       "0.9",
+		"DIFFICULTY_RAISE_AT":      "0.9",
+		"DIFFICULTY_LOWER_AT":      "0.3",
+
+		"ADMIN_TOKEN":                       "secret",
+		"TOKEN_ENABLED":                     "true",
+		"TOKEN_TTL":                         "1m",
+		"TOKEN_MAX_USES":                    "5",
+		"TOKEN_KEYS":                        "t2:new,t1:old",
+		"TOKEN_STORE_BACKEND":               "redis",
+		"TOKEN_STORE_SIZE":                  "10",
+		"REPUTATION_ENABLED":                "true",
+		"REPUTATION_HALF_LIFE":              "1m",
+		"REPUTATION_MAX_CLIENTS":            "10",
+		"REPUTATION_PENDING_TTL":            "2m",
+		"REPUTATION_REQUEST_WEIGHT":         "0.1",
+		"REPUTATION_FAILED_SOLUTION_WEIGHT": "3",
+		"REPUTATION_RATE_LIMIT_HIT_WEIGHT":  "4",
+		"REPUTATION_FAST_SOLVE":             "50ms",
+		"REPUTATION_FAST_SOLVE_WEIGHT":      "1.5",
+		"REPUTATION_POINTS_PER_BIT":         "2",
+		"REPUTATION_MAX_EXTRA_DIFFICULTY":   "8",
+
+		"HASHCASH_ALGORITHM":               "blake2b256",
+		"HASHCASH_LEGACY_ALGORITHMS":       "sha1,sha256",
+		"HASHCASH_LEGACY_ALGORITHMS_UNTIL": "2023-06-01T00:00:00Z",
+
+		"POW_SCHEME":                         "argon2id",
+		"POW_NODE_ID":                        "node-1",
+		"POW_MODE":                           "stateless",
+		"POW_HMAC_KEYS":                      "k2:new,k1:old",
+		"POW_SPENT_STORE_BACKEND":            "redis",
+		"POW_SPENT_STORE_SIZE":               "10",
+		"POW_MAX_CHALLENGES_PER_CLIENT":      "4",
+		"POW_CHALLENGE_STORE_SIZE":           "20",
+		"POW_CHALLENGE_STORE_SWEEP_INTERVAL": "5s",
+		"HASHCASH_VALID_FOR":                 "1m",
+		"HASHCASH_CLOCK_SKEW":                "5s",
+		"ARGON2_MEMORY":                      "8192",
+		"ARGON2_ITERATIONS":                  "2",
+		"ARGON2_PARALLELISM":                 "4",
+		"ARGON2_VALID_FOR":                   "1m",
+
+		"POW_CHALLENGE_STORE_BACKEND":          "redis",
+		"CLIENT_IDENTITY":                      "header:X-API-Key|ip",
+		"POW_CHALLENGE_STORE_PATH":             "/var/lib/quotes/challenges.db",
+		"POW_CHALLENGE_STORE_COMPACT_INTERVAL": "10m",
+		"REDIS_ADDR":                           "redis:6380",
+		"REDIS_USERNAME":                       "user",
+		"REDIS_PASSWORD":                       "pass",
+		"REDIS_DB":                             "2",
+		"REDIS_KEY_PREFIX":                     "quotes:",
+		"REDIS_POOL_SIZE":                      "20",
+		"REDIS_MIN_IDLE_CONNS":                 "2",
+		"REDIS_DIAL_TIMEOUT":                   "1s",
+		"REDIS_READ_TIMEOUT":                   "2s",
+		"REDIS_WRITE_TIMEOUT":                  "4s",
+
+		"SERVER_TRUSTED_PROXIES":  "10.0.0.0/8,192.0.2.1",
+		"SERVER_REMOTE_IP_HEADER": "X-Real-IP",
+		"SERVER_PROXY_PROTOCOL":   "true",
 	})
 	// Assert that no error was returned
 	assert.NoError(t, err)
@@ -67,6 +208,105 @@ func TestNewConfig_UsingEnvironmentVariables(t *testing.T) {
 	assert.Equal(t, "debug", cfg.Server.GinMode)
 	assert.Equal(t, 10, cfg.Server.Middlewares.Proofer.ChallengeDifficulty)
 	assert.Equal(t, 4, cfg.Server.Middlewares.Proofer.SaltLength)
+	assert.Equal(t, "* /v1/quotes/* difficulty=22", cfg.Server.Middlewares.Proofer.Policies)
+	assert.True(t, cfg.Server.Middlewares.Proofer.Difficulty.Adaptive)
+	assert.Equal(t, 8, cfg.Server.Middlewares.Proofer.Difficulty.Floor)
+	assert.Equal(t, 30, cfg.Server.Middlewares.Proofer.Difficulty.Ceiling)
+	assert.Equal(t, 1, cfg.Server.Middlewares.Proofer.Difficulty.Step)
+	assert.Equal(t, 5*time.Second, cfg.Server.Middlewares.Proofer.Difficulty.Interval)
+	assert.Equal(t, 50, cfg.Server.Middlewares.Proofer.Difficulty.MaxInFlight)
+	assert.Equal(t, 1000.0, cfg.Server.Middlewares.Proofer.Difficulty.MaxRate)
+	assert.Equal(t, time.Second, cfg.Server.Middlewares.Proofer.Difficulty.MaxLatency)
+	assert.Equal(t, 0.9, cfg.Server.Middlewares.Proofer.Difficulty.MaxCPU)
+	assert.Equal(t, 0.9, cfg.Server.Middlewares.Proofer.Difficulty.RaiseAt)
+	assert.Equal(t, 0.3, cfg.Server.Middlewares.Proofer.Difficulty.LowerAt)
+	assert.Equal(t, config.Secret("secret"), cfg.Server.AdminToken)
+	assert.True(t, cfg.Server.Middlewares.Proofer.Tokens.Enabled)
+	assert.Equal(t, time.Minute, cfg.Server.Middlewares.Proofer.Tokens.TTL)
+	assert.Equal(t, 5, cfg.Server.Middlewares.Proofer.Tokens.MaxUses)
+	assert.Equal(t, config.Secrets{"t2:new", "t1:old"}, cfg.Server.Middlewares.Proofer.Tokens.Keys)
+	assert.Equal(t, "redis", cfg.Server.Middlewares.Proofer.Tokens.StoreBackend)
+	assert.Equal(t, 10, cfg.Server.Middlewares.Proofer.Tokens.StoreSize)
+	assert.True(t, cfg.Server.Middlewares.Proofer.Reputation.Enabled)
+	assert.Equal(t, time.Minute, cfg.Server.Middlewares.Proofer.Reputation.HalfLife)
+	assert.Equal(t, 10, cfg.Server.Middlewares.Proofer.Reputation.MaxClients)
+	assert.Equal(t, 2*time.Minute, cfg.Server.Middlewares.Proofer.Reputation.PendingTTL)
+	assert.Equal(t, 0.1, cfg.Server.Middlewares.Proofer.Reputation.RequestWeight)
+	assert.Equal(t, 3.0, cfg.Server.Middlewares.Proofer.Reputation.FailedSolutionWeight)
+	assert.Equal(t, 4.0, cfg.Server.Middlewares.Proofer.Reputation.RateLimitHitWeight)
+	assert.Equal(t, 50*time.Millisecond, cfg.Server.Middlewares.Proofer.Reputation.FastSolve)
+	assert.Equal(t, 1.5, cfg.Server.Middlewares.Proofer.Reputation.FastSolveWeight)
+	assert.Equal(t, 2.0, cfg.Server.Middlewares.Proofer.Reputation.PointsPerBit)
+	assert.Equal(t, 8, cfg.Server.Middlewares.Proofer.Reputation.MaxExtraDifficulty)
+	assert.Equal(t, hashcash.AlgorithmBLAKE2b256, cfg.PoW.Algorithm)
+	assert.Equal(t, []hashcash.Algorithm{hashcash.AlgorithmSHA1, hashcash.AlgorithmSHA256}, cfg.PoW.LegacyAlgorithms)
+	assert.Equal(t, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), cfg.PoW.LegacyAlgorithmsUntil)
+	assert.Equal(t, "argon2id", cfg.PoW.Scheme)
+	assert.Equal(t, "node-1", cfg.PoW.NodeID)
+	assert.Equal(t, "stateless", cfg.PoW.Mode)
+	assert.Equal(t, config.Secrets{"k2:new", "k1:old"}, cfg.PoW.HMACKeys)
+	assert.Equal(t, "redis", cfg.PoW.SpentStoreBackend)
+	assert.Equal(t, 10, cfg.PoW.SpentStoreSize)
+	assert.Equal(t, 4, cfg.PoW.MaxChallengesPerClient)
+	assert.Equal(t, 20, cfg.PoW.ChallengeStoreSize)
+	assert.Equal(t, 5*time.Second, cfg.PoW.ChallengeStoreSweepInterval)
+	assert.Equal(t, time.Minute, cfg.PoW.ValidFor)
+	assert.Equal(t, 5*time.Second, cfg.PoW.ClockSkew)
+	assert.Equal(t, uint32(8192), cfg.PoW.Argon2.Memory)
+	assert.Equal(t, uint32(2), cfg.PoW.Argon2.Iterations)
+	assert.Equal(t, uint8(4), cfg.PoW.Argon2.Parallelism)
+	assert.Equal(t, time.Minute, cfg.PoW.Argon2.ValidFor)
+	assert.Equal(t, "redis", cfg.PoW.ChallengeStoreBackend)
+	assert.Equal(t, "header:X-API-Key|ip", cfg.Server.Middlewares.ClientIdentity)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, cfg.Server.TrustedProxies)
+	assert.Equal(t, "X-Real-IP", cfg.Server.RemoteIPHeader)
+	assert.True(t, cfg.Server.ProxyProtocol)
+	assert.Equal(t, "/var/lib/quotes/challenges.db", cfg.PoW.ChallengeStorePath)
+	assert.Equal(t, 10*time.Minute, cfg.PoW.ChallengeStoreCompactInterval)
+	assert.Equal(t, "redis:6380", cfg.Redis.Addr)
+	assert.Equal(t, "user", cfg.Redis.Username)
+	assert.Equal(t, config.Secret("pass"), cfg.Redis.Password)
+	assert.Equal(t, 2, cfg.Redis.DB)
+	assert.Equal(t, "quotes:", cfg.Redis.KeyPrefix)
+	assert.Equal(t, 20, cfg.Redis.PoolSize)
+	assert.Equal(t, 2, cfg.Redis.MinIdleConns)
+	assert.Equal(t, time.Second, cfg.Redis.DialTimeout)
+	assert.Equal(t, 2*time.Second, cfg.Redis.ReadTimeout)
+	assert.Equal(t, 4*time.Second, cfg.Redis.WriteTimeout)
+}
+
+func TestConfig_Secrets(t *testing.T) {
+	// Create a config with the secrets
+	cfg := &config.Config{}
+	cfg.PoW.HMACKeys = config.Secrets{"k2:hmac-secret", "k1:old-hmac-secret"}
+	cfg.Server.AdminToken = "admin-secret"
+	cfg.Server.Middlewares.Proofer.Tokens.Keys = config.Secrets{"t1:token-secret"}
+	cfg.Redis.Password = "redis-secret"
+
+	// Log the config, the way the server does
+	var buf bytes.Buffer
+
+	logger := zap.New(zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zapcore.DebugLevel,
+	))
+	logger.Debug("config", zap.Any("config", cfg))
+
+	// Assert the secrets are redacted, but their number is still shown
+	assert.NotContains(t, buf.String(), "hmac-secret")
+	assert.Contains(t, buf.String(), `"HMACKeys":["[REDACTED]","[REDACTED]"]`)
+	assert.NotContains(t, buf.String(), "admin-secret")
+	assert.Contains(t, buf.String(), `"AdminToken":"[REDACTED]"`)
+	assert.NotContains(t, buf.String(), "token-secret")
+	assert.Contains(t, buf.String(), `"Keys":["[REDACTED]"]`)
+	assert.NotContains(t, buf.String(), "redis-secret")
+	assert.Contains(t, buf.String(), `"Password":"[REDACTED]"`)
+
+	// Assert the secrets are redacted when formatted as well
+	assert.NotContains(t, fmt.Sprintf("%v", cfg.PoW.HMACKeys), "hmac-secret")
+
+	// Assert the empty secrets are shown as such
+	assert.Equal(t, "", config.Secret("").String())
+	assert.Equal(t, "[REDACTED]", config.Secret("secret").String())
 }
 
 // setEnvVars sets the given environment variables.
@@ -0,0 +1,45 @@
+package config
+
+import "encoding/json"
+
+// redacted replaces the secrets, when the configuration is logged.
+const redacted = "[REDACTED]"
+
+// Secret is a configuration value, that is never logged, e.g. a password.
+// Its String and JSON forms are redacted, unless it is empty, so that a missing secret can still be told apart.
+type Secret string
+
+// String returns the redacted secret.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// MarshalJSON returns the redacted secret, as the loggers encode the configuration to JSON.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Secrets is a list of the configuration values, that are never logged, e.g. signing keys.
+// Only their number is shown.
+type Secrets []string
+
+// String returns the redacted secrets.
+func (s Secrets) String() string {
+	b, _ := s.MarshalJSON()
+
+	return string(b)
+}
+
+// MarshalJSON returns the redacted secrets, as the loggers encode the configuration to JSON.
+func (s Secrets) MarshalJSON() ([]byte, error) {
+	out := make([]Secret, len(s))
+	for i, secret := range s {
+		out[i] = Secret(secret)
+	}
+
+	return json.Marshal(out)
+}
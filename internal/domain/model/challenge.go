@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Challenge is a proof-of-work challenge issued for a route, along with what the client needs to solve it.
+type Challenge struct {
+	// Challenge is the challenge string to solve.
+	Challenge string `json:"challenge"`
+	// ID is the ID of the challenge, that tells it apart from the other outstanding challenges of the client.
+	ID string `json:"id,omitempty"`
+	// Scheme is the name of the challenge scheme, e.g. hashcash or argon2id.
+	Scheme string `json:"scheme"`
+	// Algorithm is the hash algorithm of the challenge, it is empty for the schemes, that have no choice of the algorithm.
+	Algorithm string `json:"algorithm,omitempty"`
+	// Difficulty is the difficulty of the challenge, its meaning depends on the scheme.
+	Difficulty int `json:"difficulty"`
+	// Expires is the expiry time of the challenge, it is not set if the challenge never expires.
+	Expires *time.Time `json:"expires,omitempty"`
+	// Method is the HTTP method of the route the challenge is issued for.
+	Method string `json:"method"`
+	// Resource is the path of the route the challenge is issued for.
+	Resource string `json:"resource"`
+	// Header is the name of the request header, that the solution is sent in.
+	Header string `json:"header"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Reputation is the reputation of a client, the higher the score, the worse the client behaved.
+type Reputation struct {
+	// ClientID is the ID of the client, e.g. its IP address.
+	ClientID string `json:"client_id"`
+	// Score is the current score, decayed over time.
+	Score float64 `json:"score"`
+	// ExtraDifficulty is the difficulty added to the challenges of the client because of the score.
+	ExtraDifficulty int `json:"extra_difficulty"`
+	// Requests is the number of the requests made by the client.
+	Requests uint64 `json:"requests"`
+	// FailedSolutions is the number of the invalid or reused solutions sent by the client.
+	FailedSolutions uint64 `json:"failed_solutions"`
+	// RateLimitHits is the number of the requests of the client rejected by the rate limiter.
+	RateLimitHits uint64 `json:"rate_limit_hits"`
+	// Solved is the number of the challenges solved by the client.
+	Solved uint64 `json:"solved"`
+	// MeanSolveTime is the mean time between issuing a challenge to the client and receiving its solution.
+	MeanSolveTime time.Duration `json:"mean_solve_time"`
+	// UpdatedAt is the time of the last event of the client.
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package difficulty
+
+import "time"
+
+// Config is the configuration for the difficulty controller.
+type Config struct {
+	// Floor is the lowest difficulty, used when the server is calm. It is at least 1.
+	Floor int
+	// Ceiling is the highest difficulty, used during a flood.
+	Ceiling int
+	// Step is by how much the difficulty is raised or lowered at once.
+	Step int
+	// Interval is how often the load is evaluated.
+	Interval time.Duration
+
+	// MaxInFlight is the number of in-flight requests, that is considered a full load. Zero disables the signal.
+	MaxInFlight int
+	// MaxRate is the number of requests per second, that is considered a full load. Zero disables the signal.
+	MaxRate float64
+	// MaxLatency is the mean handler latency, that is considered a full load. Zero disables the signal.
+	MaxLatency time.Duration
+	// MaxCPU is the CPU utilisation from 0 to 1, that is considered a full load. Zero disables the signal.
+	MaxCPU float64
+
+	// RaiseAt is the load, at or above which the difficulty is raised, 1 being the full load.
+	RaiseAt float64
+	// LowerAt is the load, at or below which the difficulty is lowered.
+	// It must be below RaiseAt: the gap between the two is the hysteresis, that keeps the difficulty from oscillating.
+	LowerAt float64
+}
@@ -0,0 +1,213 @@
+// Package difficulty contains the difficulty controller, that adapts the proof-of-work difficulty to the server load.
+// When the server is calm, the legitimate clients pay almost nothing, while during a flood the attackers pay a lot.
+package difficulty
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CPUSampler is a port for sampling the CPU utilisation.
+type CPUSampler interface {
+	// Sample returns the CPU utilisation from 0 to 1 since the previous sample.
+	Sample() (float64, error)
+}
+
+// Load is the load of the server over the last interval, each signal being normalized, so that 1 is the full load.
+type Load struct {
+	// InFlight is the load by the number of the in-flight requests.
+	InFlight float64
+	// Rate is the load by the number of the requests per second.
+	Rate float64
+	// Latency is the load by the mean handler latency.
+	Latency float64
+	// CPU is the load by the CPU utilisation.
+	CPU float64
+}
+
+// Max returns the highest of the signals, i.e. the server is as loaded as its most loaded resource.
+func (l Load) Max() float64 {
+	return math.Max(math.Max(l.InFlight, l.Rate), math.Max(l.Latency, l.CPU))
+}
+
+// Controller raises the difficulty, when the server load is high, and lowers it, when the load is low.
+// It is safe for concurrent use.
+type Controller struct {
+	logger *zap.Logger
+	cfg    *Config
+	cpu    CPUSampler
+
+	// current is the current difficulty.
+	current atomic.Int64
+	// inFlight is the number of the requests being handled.
+	inFlight atomic.Int64
+
+	// mu guards the counters of the current interval.
+	mu sync.Mutex
+	// finished is the number of the requests finished during the current interval.
+	finished int64
+	// latency is the total latency of the requests finished during the current interval.
+	latency time.Duration
+	// since is the start of the current interval.
+	since time.Time
+}
+
+// New creates a new difficulty controller, starting at the floor.
+// The CPU sampler is optional: if it is nil, the CPU utilisation is not taken into account.
+func New(logger *zap.Logger, cfg *Config, cpu CPUSampler) (*Controller, error) {
+	// Logging the call
+	logger.Debug("creating a new difficulty controller", zap.Int("floor", cfg.Floor), zap.Int("ceiling", cfg.Ceiling))
+
+	// Validate the config, the challenges can not be issued with no difficulty at all
+	if cfg.Floor < 1 || cfg.Floor > cfg.Ceiling {
+		return nil, fmt.Errorf("%w: floor %d, ceiling %d", ErrInvalidBounds, cfg.Floor, cfg.Ceiling)
+	}
+
+	if cfg.Step <= 0 {
+		return nil, ErrInvalidStep
+	}
+
+	if cfg.Interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	if cfg.LowerAt >= cfg.RaiseAt {
+		return nil, fmt.Errorf("%w: lower at %v, raise at %v", ErrInvalidThresholds, cfg.LowerAt, cfg.RaiseAt)
+	}
+
+	c := &Controller{logger: logger, cfg: cfg, cpu: cpu, since: time.Now()}
+	c.current.Store(int64(cfg.Floor))
+
+	return c, nil
+}
+
+// Current returns the current difficulty.
+func (c *Controller) Current() int {
+	return int(c.current.Load())
+}
+
+// RequestStarted records that a request has started.
+func (c *Controller) RequestStarted() {
+	c.inFlight.Add(1)
+}
+
+// RequestFinished records that a request has finished after the given latency.
+func (c *Controller) RequestFinished(latency time.Duration) {
+	c.inFlight.Add(-1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.finished++
+	c.latency += latency
+}
+
+// Run evaluates the load every interval, until the context is canceled.
+func (c *Controller) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Evaluate()
+		}
+	}
+}
+
+// Evaluate measures the load over the interval since the previous evaluation and adjusts the difficulty.
+// It returns the measured load.
+func (c *Controller) Evaluate() Load {
+	// Measure the load
+	load := c.measure()
+
+	// Adjust the difficulty within the bounds, keeping it, while the load is between the thresholds
+	current := c.Current()
+	next := current
+
+	switch pressure := load.Max(); {
+	case pressure >= c.cfg.RaiseAt:
+		next = minInt(current+c.cfg.Step, c.cfg.Ceiling)
+	case pressure <= c.cfg.LowerAt:
+		next = maxInt(current-c.cfg.Step, c.cfg.Floor)
+	}
+
+	if next != current {
+		c.current.Store(int64(next))
+
+		c.logger.Info("difficulty changed",
+			zap.Int("from", current),
+			zap.Int("to", next),
+			zap.Float64("load_in_flight", load.InFlight),
+			zap.Float64("load_rate", load.Rate),
+			zap.Float64("load_latency", load.Latency),
+			zap.Float64("load_cpu", load.CPU),
+		)
+	}
+
+	return load
+}
+
+// measure returns the load over the interval since the previous measurement and starts a new interval.
+func (c *Controller) measure() Load {
+	// Take the counters of the interval and reset them
+	c.mu.Lock()
+	finished, latency, since := c.finished, c.latency, c.since
+	c.finished, c.latency, c.since = 0, 0, time.Now()
+	c.mu.Unlock()
+
+	var load Load
+
+	// In-flight requests
+	if c.cfg.MaxInFlight > 0 {
+		load.InFlight = float64(c.inFlight.Load()) / float64(c.cfg.MaxInFlight)
+	}
+
+	// Request rate
+	if elapsed := time.Since(since).Seconds(); c.cfg.MaxRate > 0 && elapsed > 0 {
+		load.Rate = float64(finished) / elapsed / c.cfg.MaxRate
+	}
+
+	// Mean handler latency
+	if c.cfg.MaxLatency > 0 && finished > 0 {
+		load.Latency = float64(latency) / float64(finished) / float64(c.cfg.MaxLatency)
+	}
+
+	// CPU utilisation
+	if c.cfg.MaxCPU > 0 && c.cpu != nil {
+		utilisation, err := c.cpu.Sample()
+		if err != nil {
+			c.logger.Warn("sampling cpu utilisation failed", zap.Error(err))
+		} else {
+			load.CPU = utilisation / c.cfg.MaxCPU
+		}
+	}
+
+	return load
+}
+
+// minInt returns the smaller of the two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// maxInt returns the bigger of the two integers.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
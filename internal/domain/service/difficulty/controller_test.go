@@ -0,0 +1,195 @@
+package difficulty_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/service/difficulty"
+)
+
+// fakeCPU is a CPU sampler returning a fixed utilisation.
+type fakeCPU struct {
+	utilisation float64
+	err         error
+}
+
+// Sample returns the fixed utilisation.
+func (f *fakeCPU) Sample() (float64, error) {
+	return f.utilisation, f.err
+}
+
+// testConfig returns a config, where 10 in-flight requests or a full CPU is the full load.
+func testConfig() *difficulty.Config {
+	return &difficulty.Config{
+		Floor:       10,
+		Ceiling:     13,
+		Step:        1,
+		Interval:    time.Second,
+		MaxInFlight: 10,
+		MaxCPU:      1,
+		RaiseAt:     1,
+		LowerAt:     0.5,
+	}
+}
+
+// startRequests starts n requests on the controller.
+func startRequests(c *difficulty.Controller, n int) {
+	for i := 0; i < n; i++ {
+		c.RequestStarted()
+	}
+}
+
+// finishRequests finishes n requests on the controller, each taking the given latency.
+func finishRequests(c *difficulty.Controller, n int, latency time.Duration) {
+	for i := 0; i < n; i++ {
+		c.RequestFinished(latency)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *difficulty.Config)
+		wantErr error
+	}{
+		{name: "valid config", modify: func(cfg *difficulty.Config) {}, wantErr: nil},
+		{name: "negative floor", modify: func(cfg *difficulty.Config) { cfg.Floor = -1 }, wantErr: difficulty.ErrInvalidBounds},
+		{name: "zero floor", modify: func(cfg *difficulty.Config) { cfg.Floor = 0 }, wantErr: difficulty.ErrInvalidBounds},
+		{name: "floor above ceiling", modify: func(cfg *difficulty.Config) { cfg.Floor = 20 }, wantErr: difficulty.ErrInvalidBounds},
+		{name: "zero step", modify: func(cfg *difficulty.Config) { cfg.Step = 0 }, wantErr: difficulty.ErrInvalidStep},
+		{name: "zero interval", modify: func(cfg *difficulty.Config) { cfg.Interval = 0 }, wantErr: difficulty.ErrInvalidInterval},
+		{name: "no hysteresis", modify: func(cfg *difficulty.Config) { cfg.LowerAt = cfg.RaiseAt }, wantErr: difficulty.ErrInvalidThresholds},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.modify(cfg)
+
+			_, err := difficulty.New(zap.NewNop(), cfg, nil)
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestController_Evaluate(t *testing.T) {
+	t.Run("starts at the floor", func(t *testing.T) {
+		c, err := difficulty.New(zap.NewNop(), testConfig(), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, 10, c.Current())
+	})
+
+	t.Run("raises the difficulty under load up to the ceiling", func(t *testing.T) {
+		c, err := difficulty.New(zap.NewNop(), testConfig(), nil)
+		require.NoError(t, err)
+
+		// Full load by the in-flight requests
+		startRequests(c, 10)
+
+		for _, want := range []int{11, 12, 13, 13} {
+			load := c.Evaluate()
+			assert.Equal(t, 1.0, load.InFlight)
+			assert.Equal(t, want, c.Current())
+		}
+	})
+
+	t.Run("keeps the difficulty between the thresholds", func(t *testing.T) {
+		c, err := difficulty.New(zap.NewNop(), testConfig(), nil)
+		require.NoError(t, err)
+
+		// Raise the difficulty once
+		startRequests(c, 10)
+		c.Evaluate()
+		require.Equal(t, 11, c.Current())
+
+		// The load drops, but not below the lowering threshold
+		finishRequests(c, 3, time.Millisecond)
+
+		for i := 0; i < 3; i++ {
+			c.Evaluate()
+			assert.Equal(t, 11, c.Current(), "difficulty should not oscillate")
+		}
+	})
+
+	t.Run("lowers the difficulty when calm down to the floor", func(t *testing.T) {
+		c, err := difficulty.New(zap.NewNop(), testConfig(), nil)
+		require.NoError(t, err)
+
+		// Raise the difficulty twice
+		startRequests(c, 10)
+		c.Evaluate()
+		c.Evaluate()
+		require.Equal(t, 12, c.Current())
+
+		// The flood is over
+		finishRequests(c, 10, time.Millisecond)
+
+		for _, want := range []int{11, 10, 10} {
+			c.Evaluate()
+			assert.Equal(t, want, c.Current())
+		}
+	})
+
+	t.Run("takes the most loaded signal", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.MaxLatency = 100 * time.Millisecond
+
+		c, err := difficulty.New(zap.NewNop(), cfg, &fakeCPU{utilisation: 0.2})
+		require.NoError(t, err)
+
+		// The handlers are slow, while nothing else is loaded
+		startRequests(c, 2)
+		finishRequests(c, 2, 200*time.Millisecond)
+
+		load := c.Evaluate()
+		assert.Equal(t, 2.0, load.Latency)
+		assert.Equal(t, 0.2, load.CPU)
+		assert.Equal(t, 2.0, load.Max())
+		assert.Equal(t, 11, c.Current())
+	})
+
+	t.Run("raises the difficulty on high cpu utilisation", func(t *testing.T) {
+		c, err := difficulty.New(zap.NewNop(), testConfig(), &fakeCPU{utilisation: 1})
+		require.NoError(t, err)
+
+		c.Evaluate()
+		assert.Equal(t, 11, c.Current())
+	})
+
+	t.Run("ignores cpu sampling errors", func(t *testing.T) {
+		c, err := difficulty.New(zap.NewNop(), testConfig(), &fakeCPU{err: errors.New("sampling failed")})
+		require.NoError(t, err)
+
+		load := c.Evaluate()
+		assert.Zero(t, load.CPU)
+		assert.Equal(t, 10, c.Current())
+	})
+
+	t.Run("measures the request rate", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.MaxRate = 1
+
+		c, err := difficulty.New(zap.NewNop(), cfg, nil)
+		require.NoError(t, err)
+
+		// Many requests within a very short interval
+		startRequests(c, 100)
+		finishRequests(c, 100, time.Millisecond)
+
+		load := c.Evaluate()
+		assert.Greater(t, load.Rate, 1.0)
+		assert.Equal(t, 11, c.Current())
+	})
+}
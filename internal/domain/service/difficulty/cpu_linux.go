@@ -0,0 +1,104 @@
+//go:build linux
+
+package difficulty
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// procStatPath is the path to the kernel statistics, that hold the CPU times.
+	procStatPath = "/proc/stat"
+
+	// cpuTimesCounted is the number of the CPU times counted, from user to steal.
+	cpuTimesCounted = 8
+)
+
+// ProcStatSampler samples the CPU utilisation from /proc/stat.
+type ProcStatSampler struct {
+	mu sync.Mutex
+	// idle and total are the CPU times of the previous sample.
+	idle, total uint64
+}
+
+// NewCPUSampler creates a new CPU sampler for the platform.
+func NewCPUSampler() (CPUSampler, error) {
+	sampler := &ProcStatSampler{}
+
+	// Take the first sample, so that the next one measures the utilisation since now
+	if _, err := sampler.Sample(); err != nil {
+		return nil, err
+	}
+
+	return sampler, nil
+}
+
+// Sample returns the CPU utilisation from 0 to 1 since the previous sample.
+func (s *ProcStatSampler) Sample() (float64, error) {
+	// Read the CPU times
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Compute the utilisation since the previous sample
+	deltaIdle, deltaTotal := idle-s.idle, total-s.total
+	s.idle, s.total = idle, total
+
+	if deltaTotal == 0 {
+		return 0, nil
+	}
+
+	return 1 - float64(deltaIdle)/float64(deltaTotal), nil
+}
+
+// readCPUTimes reads the idle and the total CPU times from the aggregate "cpu" line of /proc/stat.
+func readCPUTimes() (idle, total uint64, err error) {
+	file, err := os.Open(procStatPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("opening %s: %w", procStatPath, err)
+	}
+	defer file.Close()
+
+	// The first line is the aggregate of all the CPUs
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return 0, 0, fmt.Errorf("reading %s: %w", procStatPath, scanner.Err())
+	}
+
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected %s format: %q", procStatPath, scanner.Text())
+	}
+
+	// The fields are user, nice, system, idle, iowait, irq, softirq, steal, guest and guest_nice.
+	// The guest times are already counted in the user times, so they are skipped.
+	times := fields[1:]
+	if len(times) > cpuTimesCounted {
+		times = times[:cpuTimesCounted]
+	}
+
+	for i, field := range times {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parsing %s: %w", procStatPath, err)
+		}
+
+		total += value
+
+		// Count both idle and iowait as idle
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+
+	return idle, total, nil
+}
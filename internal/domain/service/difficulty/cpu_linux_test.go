@@ -0,0 +1,24 @@
+//go:build linux
+
+package difficulty_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/service/difficulty"
+)
+
+func TestNewCPUSampler(t *testing.T) {
+	// Create a new sampler
+	sampler, err := difficulty.NewCPUSampler()
+	require.NoError(t, err)
+
+	// Sample the utilisation
+	utilisation, err := sampler.Sample()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, utilisation, 0.0)
+	assert.LessOrEqual(t, utilisation, 1.0)
+}
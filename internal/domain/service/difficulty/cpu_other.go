@@ -0,0 +1,9 @@
+//go:build !linux
+
+package difficulty
+
+// NewCPUSampler creates a new CPU sampler for the platform.
+// The CPU utilisation is only sampled on Linux, so it returns ErrCPUSamplingUnsupported.
+func NewCPUSampler() (CPUSampler, error) {
+	return nil, ErrCPUSamplingUnsupported
+}
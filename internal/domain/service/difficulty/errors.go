@@ -0,0 +1,20 @@
+package difficulty
+
+import "errors"
+
+var (
+	// ErrInvalidBounds is returned when the floor is below 1 or above the ceiling.
+	ErrInvalidBounds = errors.New("difficulty floor must be between 1 and the ceiling")
+
+	// ErrInvalidStep is returned when the step is not positive.
+	ErrInvalidStep = errors.New("difficulty step must be positive")
+
+	// ErrInvalidInterval is returned when the evaluation interval is not positive.
+	ErrInvalidInterval = errors.New("difficulty evaluation interval must be positive")
+
+	// ErrInvalidThresholds is returned when the lowering threshold is not below the raising one.
+	ErrInvalidThresholds = errors.New("difficulty lowering threshold must be below the raising one")
+
+	// ErrCPUSamplingUnsupported is returned when the CPU utilisation can not be sampled on the platform.
+	ErrCPUSamplingUnsupported = errors.New("cpu sampling is not supported on this platform")
+)
@@ -0,0 +1,31 @@
+package reputation
+
+import "time"
+
+// Config is the configuration for the reputation service.
+type Config struct {
+	// HalfLife is the time, after which the score of a client is halved.
+	HalfLife time.Duration
+	// MaxClients is the maximum number of the clients tracked.
+	MaxClients int
+	// PendingTTL is for how long a challenge issued to a client is pending, e.g. the validity of the challenges.
+	// A client with a pending challenge is not forgotten, and the challenges solved later have no solve time.
+	PendingTTL time.Duration
+
+	// RequestWeight is added to the score for every request.
+	RequestWeight float64
+	// FailedSolutionWeight is added to the score for every invalid or reused solution.
+	FailedSolutionWeight float64
+	// RateLimitHitWeight is added to the score for every request rejected by the rate limiter.
+	RateLimitHitWeight float64
+	// FastSolveWeight is added to the score for every challenge solved faster than FastSolve.
+	FastSolveWeight float64
+	// FastSolve is the solve time, below which the solution is suspiciously fast, e.g. solved on dedicated hardware.
+	// Zero disables the penalty.
+	FastSolve time.Duration
+
+	// PointsPerBit is the score, that adds one bit to the difficulty of the challenges of the client.
+	PointsPerBit float64
+	// MaxExtraDifficulty is the maximum difficulty added because of the score.
+	MaxExtraDifficulty int
+}
@@ -0,0 +1,17 @@
+package reputation
+
+import "errors"
+
+var (
+	// ErrInvalidHalfLife is returned when the half-life is not positive.
+	ErrInvalidHalfLife = errors.New("reputation half-life must be positive")
+
+	// ErrInvalidMaxClients is returned when the maximum number of the clients is not positive.
+	ErrInvalidMaxClients = errors.New("reputation maximum number of clients must be positive")
+
+	// ErrInvalidPendingTTL is returned when the time a challenge is pending for is not positive.
+	ErrInvalidPendingTTL = errors.New("reputation pending challenge TTL must be positive")
+
+	// ErrInvalidPointsPerBit is returned when the score per difficulty bit is not positive.
+	ErrInvalidPointsPerBit = errors.New("reputation points per difficulty bit must be positive")
+)
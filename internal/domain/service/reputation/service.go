@@ -0,0 +1,364 @@
+// Package reputation contains the reputation service, that tracks how every client behaves
+// and raises the difficulty of the challenges for the clients, that misbehave.
+// The scores decay over time, so that a client is forgiven, once it behaves again.
+package reputation
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+)
+
+// minScore is the score, below which a client is forgotten, when there is no room for the new clients.
+const minScore = 0.01
+
+// dropWarnInterval is how often at most the dropped events are warned about, as they come in floods.
+const dropWarnInterval = time.Minute
+
+// client is the reputation of a single client.
+type client struct {
+	// id is the ID of the client.
+	id string
+	// index is the index of the client in the heap of the forgettable clients.
+	index int
+	// forgettableAt is the time, when the score has decayed to nothing and no challenge is pending.
+	forgettableAt time.Time
+
+	// score is the score at the time of the last update.
+	score float64
+	// updatedAt is the time of the last update.
+	updatedAt time.Time
+	// issuedAt is the time the last challenge was issued to the client, it is zero if there is none pending.
+	issuedAt time.Time
+
+	requests        uint64
+	failedSolutions uint64
+	rateLimitHits   uint64
+	solved          uint64
+	totalSolveTime  time.Duration
+}
+
+// decay decays the score up to the given time.
+func (c *client) decay(now time.Time, halfLife time.Duration) {
+	if elapsed := now.Sub(c.updatedAt); elapsed > 0 {
+		c.score *= math.Exp2(-float64(elapsed) / float64(halfLife))
+	}
+
+	c.updatedAt = now
+}
+
+// forgettable returns the time, when the client can be forgotten: its score has decayed below minScore
+// and its last challenge is no longer pending. The decay does not change it, only the new events do.
+func (c *client) forgettable(halfLife, pendingTTL time.Duration) time.Time {
+	at := c.updatedAt
+
+	// The score is halved every half-life
+	if c.score >= minScore {
+		at = at.Add(time.Duration(float64(halfLife) * math.Log2(c.score/minScore)))
+	}
+
+	// The pending challenge keeps the client, until it expires
+	if pending := c.issuedAt.Add(pendingTTL); !c.issuedAt.IsZero() && pending.After(at) {
+		at = pending
+	}
+
+	return at
+}
+
+// Service is a reputation service.
+// It is safe for concurrent use.
+type Service struct {
+	logger *zap.Logger
+	cfg    *Config
+
+	mu      sync.Mutex
+	clients map[string]*client
+	// byForgettable is a min-heap of the clients ordered by the time they can be forgotten,
+	// so that making room for a new client does not scan all of them.
+	byForgettable clientHeap
+	// dropped is the number of the events dropped since the last warning, that was at droppedWarnedAt.
+	dropped         uint64
+	droppedWarnedAt time.Time
+}
+
+// NewService creates a new reputation service.
+func NewService(logger *zap.Logger, cfg *Config) (*Service, error) {
+	// Logging the call
+	logger.Debug("creating a new reputation service")
+
+	// Validate the config
+	if cfg.HalfLife <= 0 {
+		return nil, ErrInvalidHalfLife
+	}
+
+	if cfg.MaxClients <= 0 {
+		return nil, ErrInvalidMaxClients
+	}
+
+	if cfg.PendingTTL <= 0 {
+		return nil, ErrInvalidPendingTTL
+	}
+
+	if cfg.PointsPerBit <= 0 {
+		return nil, ErrInvalidPointsPerBit
+	}
+
+	return &Service{logger: logger, cfg: cfg, clients: make(map[string]*client)}, nil
+}
+
+// RecordRequest records a request of the client.
+func (s *Service) RecordRequest(clientID string) {
+	s.update(clientID, func(c *client) {
+		c.requests++
+		c.score += s.cfg.RequestWeight
+	})
+}
+
+// RecordChallengeIssued records that a challenge was issued to the client, to measure its solve time.
+func (s *Service) RecordChallengeIssued(clientID string) {
+	s.update(clientID, func(c *client) {
+		c.issuedAt = time.Now()
+	})
+}
+
+// RecordFailedSolution records an invalid or reused solution of the client.
+func (s *Service) RecordFailedSolution(clientID string) {
+	s.update(clientID, func(c *client) {
+		c.failedSolutions++
+		c.score += s.cfg.FailedSolutionWeight
+	})
+}
+
+// RecordRateLimitHit records a request of the client rejected by the rate limiter.
+func (s *Service) RecordRateLimitHit(clientID string) {
+	s.update(clientID, func(c *client) {
+		c.rateLimitHits++
+		c.score += s.cfg.RateLimitHitWeight
+	})
+}
+
+// RecordSolved records a solved challenge of the client, along with its solve time, if a challenge is pending.
+func (s *Service) RecordSolved(clientID string) {
+	s.update(clientID, func(c *client) {
+		c.solved++
+
+		// Without a pending challenge, the solve time is unknown
+		if c.issuedAt.IsZero() {
+			return
+		}
+
+		solveTime := time.Since(c.issuedAt)
+		c.issuedAt = time.Time{}
+
+		// The challenge is no longer pending, so it is not the one solved
+		if solveTime > s.cfg.PendingTTL {
+			return
+		}
+
+		c.totalSolveTime += solveTime
+
+		// Penalize the suspiciously fast solutions
+		if s.cfg.FastSolve > 0 && solveTime < s.cfg.FastSolve {
+			c.score += s.cfg.FastSolveWeight
+		}
+	})
+}
+
+// Difficulty returns the difficulty of the challenges of the client, i.e. the base difficulty,
+// raised by one bit for every PointsPerBit of the score, up to MaxExtraDifficulty.
+func (s *Service) Difficulty(clientID string, base int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.clients[clientID]
+	if !ok {
+		return base
+	}
+
+	c.decay(time.Now(), s.cfg.HalfLife)
+
+	return base + s.extraDifficulty(c.score)
+}
+
+// Get returns the reputation of the client and reports whether the client is tracked.
+func (s *Service) Get(clientID string) (model.Reputation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.clients[clientID]
+	if !ok {
+		return model.Reputation{}, false
+	}
+
+	c.decay(time.Now(), s.cfg.HalfLife)
+
+	return s.reputation(clientID, c), true
+}
+
+// List returns the reputations of all the tracked clients, the worst first.
+func (s *Service) List() []model.Reputation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	reputations := make([]model.Reputation, 0, len(s.clients))
+
+	for clientID, c := range s.clients {
+		c.decay(now, s.cfg.HalfLife)
+		reputations = append(reputations, s.reputation(clientID, c))
+	}
+
+	sort.Slice(reputations, func(i, j int) bool {
+		return reputations[i].Score > reputations[j].Score
+	})
+
+	return reputations
+}
+
+// Reset forgets the client and reports whether it was tracked.
+func (s *Service) Reset(clientID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.clients[clientID]
+	if !ok {
+		return false
+	}
+
+	s.forget(c)
+	s.logger.Info("reputation reset", zap.String("client_id", clientID))
+
+	return true
+}
+
+// update applies the change to the client, decaying its score first.
+// A new client is only tracked, if there is room for it, otherwise the event is dropped:
+// it is better to under-penalize a client, than to let the tracking exhaust the memory.
+func (s *Service) update(clientID string, change func(c *client)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	c, ok := s.clients[clientID]
+	if !ok {
+		// Make room for the new client, if needed
+		if len(s.clients) >= s.cfg.MaxClients && !s.forgetForgiven(now) {
+			s.drop(now)
+			return
+		}
+
+		c = &client{id: clientID, updatedAt: now}
+		s.clients[clientID] = c
+		heap.Push(&s.byForgettable, c)
+	}
+
+	c.decay(now, s.cfg.HalfLife)
+	change(c)
+
+	// Reorder the client, as the event postpones its forgetting
+	c.forgettableAt = c.forgettable(s.cfg.HalfLife, s.cfg.PendingTTL)
+	heap.Fix(&s.byForgettable, c.index)
+}
+
+// forgetForgiven forgets the client, that can be forgotten the soonest, if its score has decayed to nothing
+// and no challenge is pending, and reports whether the room was made.
+func (s *Service) forgetForgiven(now time.Time) bool {
+	if s.byForgettable.Len() == 0 || now.Before(s.byForgettable[0].forgettableAt) {
+		return false
+	}
+
+	s.forget(s.byForgettable[0])
+
+	return true
+}
+
+// forget stops tracking the client.
+func (s *Service) forget(c *client) {
+	heap.Remove(&s.byForgettable, c.index)
+	delete(s.clients, c.id)
+}
+
+// drop counts the event of a new client dropped for the lack of room, warning about the dropped events
+// at most once per dropWarnInterval.
+func (s *Service) drop(now time.Time) {
+	s.dropped++
+
+	if now.Sub(s.droppedWarnedAt) < dropWarnInterval {
+		return
+	}
+
+	s.logger.Warn("reputation is not tracked, too many clients", zap.Uint64("dropped_events", s.dropped))
+	s.dropped = 0
+	s.droppedWarnedAt = now
+}
+
+// extraDifficulty returns the difficulty added because of the score.
+func (s *Service) extraDifficulty(score float64) int {
+	extra := int(score / s.cfg.PointsPerBit)
+	if extra > s.cfg.MaxExtraDifficulty {
+		return s.cfg.MaxExtraDifficulty
+	}
+
+	return extra
+}
+
+// reputation returns the reputation model of the client.
+func (s *Service) reputation(clientID string, c *client) model.Reputation {
+	var meanSolveTime time.Duration
+	if c.solved > 0 {
+		meanSolveTime = c.totalSolveTime / time.Duration(c.solved)
+	}
+
+	return model.Reputation{
+		ClientID:        clientID,
+		Score:           c.score,
+		ExtraDifficulty: s.extraDifficulty(c.score),
+		Requests:        c.requests,
+		FailedSolutions: c.failedSolutions,
+		RateLimitHits:   c.rateLimitHits,
+		Solved:          c.solved,
+		MeanSolveTime:   meanSolveTime,
+		UpdatedAt:       c.updatedAt,
+	}
+}
+
+// clientHeap is a min-heap of the clients ordered by the time they can be forgotten, it implements heap.Interface.
+type clientHeap []*client
+
+// Len returns the number of the clients in the heap.
+func (h clientHeap) Len() int { return len(h) }
+
+// Less reports whether the client i can be forgotten before the client j.
+func (h clientHeap) Less(i, j int) bool { return h[i].forgettableAt.Before(h[j].forgettableAt) }
+
+// Swap swaps the clients i and j.
+func (h clientHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+// Push adds the client to the heap.
+func (h *clientHeap) Push(x any) {
+	c := x.(*client)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+// Pop removes the last client from the heap.
+func (h *clientHeap) Pop() any {
+	old := *h
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+
+	return c
+}
@@ -0,0 +1,260 @@
+package reputation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/service/reputation"
+)
+
+const testClient = "192.0.2.1"
+
+// testConfig returns a config, where every failed solution adds a bit of difficulty,
+// with some room for the decay between the events.
+func testConfig() *reputation.Config {
+	return &reputation.Config{
+		HalfLife:             time.Hour,
+		MaxClients:           10,
+		PendingTTL:           time.Hour,
+		RequestWeight:        0.1,
+		FailedSolutionWeight: 1,
+		RateLimitHitWeight:   2,
+		FastSolveWeight:      1,
+		FastSolve:            time.Hour,
+		PointsPerBit:         0.9,
+		MaxExtraDifficulty:   4,
+	}
+}
+
+// newService creates a new reputation service with the modified test config.
+func newService(t *testing.T, modify func(cfg *reputation.Config)) *reputation.Service {
+	t.Helper()
+
+	cfg := testConfig()
+	modify(cfg)
+
+	svc, err := reputation.NewService(zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	return svc
+}
+
+func TestNewService(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *reputation.Config)
+		wantErr error
+	}{
+		{name: "valid config", modify: func(cfg *reputation.Config) {}, wantErr: nil},
+		{name: "zero half-life", modify: func(cfg *reputation.Config) { cfg.HalfLife = 0 }, wantErr: reputation.ErrInvalidHalfLife},
+		{name: "zero max clients", modify: func(cfg *reputation.Config) { cfg.MaxClients = 0 }, wantErr: reputation.ErrInvalidMaxClients},
+		{name: "zero pending TTL", modify: func(cfg *reputation.Config) { cfg.PendingTTL = 0 }, wantErr: reputation.ErrInvalidPendingTTL},
+		{name: "zero points per bit", modify: func(cfg *reputation.Config) { cfg.PointsPerBit = 0 }, wantErr: reputation.ErrInvalidPointsPerBit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.modify(cfg)
+
+			_, err := reputation.NewService(zap.NewNop(), cfg)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_Difficulty(t *testing.T) {
+	t.Run("Unknown client gets the base difficulty", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {})
+
+		assert.Equal(t, 20, svc.Difficulty(testClient, 20))
+	})
+
+	t.Run("Failed solutions raise the difficulty", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {})
+
+		svc.RecordFailedSolution(testClient)
+		svc.RecordFailedSolution(testClient)
+
+		assert.Equal(t, 22, svc.Difficulty(testClient, 20))
+		assert.Equal(t, 20, svc.Difficulty("192.0.2.2", 20), "other clients should not be affected")
+	})
+
+	t.Run("Rate limit hits raise the difficulty", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {})
+
+		svc.RecordRateLimitHit(testClient)
+
+		assert.Equal(t, 22, svc.Difficulty(testClient, 20))
+	})
+
+	t.Run("Difficulty is capped", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {})
+
+		for i := 0; i < 10; i++ {
+			svc.RecordRateLimitHit(testClient)
+		}
+
+		assert.Equal(t, 24, svc.Difficulty(testClient, 20))
+	})
+
+	t.Run("Fast solves raise the difficulty", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {})
+
+		svc.RecordChallengeIssued(testClient)
+		svc.RecordSolved(testClient)
+
+		assert.Equal(t, 21, svc.Difficulty(testClient, 20))
+
+		// Without a pending challenge the solve time is unknown, so there is no penalty
+		svc.RecordSolved(testClient)
+
+		assert.Equal(t, 21, svc.Difficulty(testClient, 20))
+	})
+
+	t.Run("Score decays", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) { cfg.HalfLife = 10 * time.Millisecond })
+
+		svc.RecordRateLimitHit(testClient)
+		time.Sleep(50 * time.Millisecond)
+
+		assert.Equal(t, 20, svc.Difficulty(testClient, 20))
+	})
+}
+
+func TestService_Get(t *testing.T) {
+	svc := newService(t, func(cfg *reputation.Config) {})
+
+	// Unknown client
+	_, ok := svc.Get(testClient)
+	assert.False(t, ok)
+
+	// Record some events
+	svc.RecordRequest(testClient)
+	svc.RecordRequest(testClient)
+	svc.RecordFailedSolution(testClient)
+	svc.RecordRateLimitHit(testClient)
+	svc.RecordChallengeIssued(testClient)
+	svc.RecordSolved(testClient)
+
+	rep, ok := svc.Get(testClient)
+	require.True(t, ok)
+
+	assert.Equal(t, testClient, rep.ClientID)
+	assert.InDelta(t, 4.2, rep.Score, 0.01)
+	assert.Equal(t, 4, rep.ExtraDifficulty)
+	assert.Equal(t, uint64(2), rep.Requests)
+	assert.Equal(t, uint64(1), rep.FailedSolutions)
+	assert.Equal(t, uint64(1), rep.RateLimitHits)
+	assert.Equal(t, uint64(1), rep.Solved)
+	assert.Less(t, rep.MeanSolveTime, time.Second)
+	assert.WithinDuration(t, time.Now(), rep.UpdatedAt, time.Second)
+}
+
+func TestService_List(t *testing.T) {
+	svc := newService(t, func(cfg *reputation.Config) {})
+
+	svc.RecordRequest("192.0.2.1")
+	svc.RecordRateLimitHit("192.0.2.2")
+	svc.RecordFailedSolution("192.0.2.3")
+
+	reputations := svc.List()
+	require.Len(t, reputations, 3)
+
+	// The worst client goes first
+	assert.Equal(t, "192.0.2.2", reputations[0].ClientID)
+	assert.Equal(t, "192.0.2.3", reputations[1].ClientID)
+	assert.Equal(t, "192.0.2.1", reputations[2].ClientID)
+}
+
+func TestService_Reset(t *testing.T) {
+	svc := newService(t, func(cfg *reputation.Config) {})
+
+	svc.RecordRateLimitHit(testClient)
+
+	assert.True(t, svc.Reset(testClient))
+	assert.False(t, svc.Reset(testClient), "client should be forgotten")
+	assert.Equal(t, 20, svc.Difficulty(testClient, 20))
+}
+
+func TestService_MaxClients(t *testing.T) {
+	t.Run("New clients are not tracked when full", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) { cfg.MaxClients = 1 })
+
+		svc.RecordRateLimitHit("192.0.2.1")
+		svc.RecordRateLimitHit("192.0.2.2")
+
+		assert.Len(t, svc.List(), 1)
+		assert.Equal(t, 20, svc.Difficulty("192.0.2.2", 20))
+	})
+
+	t.Run("Forgiven clients make room", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {
+			cfg.MaxClients = 1
+			cfg.HalfLife = time.Millisecond
+		})
+
+		svc.RecordRateLimitHit("192.0.2.1")
+		time.Sleep(50 * time.Millisecond)
+		svc.RecordRateLimitHit("192.0.2.2")
+
+		reputations := svc.List()
+		require.Len(t, reputations, 1)
+		assert.Equal(t, "192.0.2.2", reputations[0].ClientID)
+	})
+
+	t.Run("Clients with a pending challenge are kept", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {
+			cfg.MaxClients = 1
+			cfg.HalfLife = time.Millisecond
+		})
+
+		svc.RecordChallengeIssued("192.0.2.1")
+		time.Sleep(50 * time.Millisecond)
+		svc.RecordRateLimitHit("192.0.2.2")
+
+		reputations := svc.List()
+		require.Len(t, reputations, 1)
+		assert.Equal(t, "192.0.2.1", reputations[0].ClientID)
+	})
+
+	t.Run("Stale pending challenges make room", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {
+			cfg.MaxClients = 1
+			cfg.HalfLife = time.Millisecond
+			cfg.PendingTTL = 10 * time.Millisecond
+		})
+
+		svc.RecordChallengeIssued("192.0.2.1")
+		time.Sleep(50 * time.Millisecond)
+		svc.RecordRateLimitHit("192.0.2.2")
+
+		reputations := svc.List()
+		require.Len(t, reputations, 1)
+		assert.Equal(t, "192.0.2.2", reputations[0].ClientID)
+	})
+
+	t.Run("The soonest forgiven client makes room", func(t *testing.T) {
+		svc := newService(t, func(cfg *reputation.Config) {
+			cfg.MaxClients = 2
+			cfg.HalfLife = 10 * time.Millisecond
+		})
+
+		// The first client misbehaves a lot more, so it is forgiven much later
+		for i := 0; i < 100; i++ {
+			svc.RecordRateLimitHit("192.0.2.1")
+		}
+
+		svc.RecordRequest("192.0.2.2")
+		time.Sleep(50 * time.Millisecond)
+		svc.RecordRequest("192.0.2.3")
+
+		reputations := svc.List()
+		require.Len(t, reputations, 2)
+		assert.Equal(t, "192.0.2.1", reputations[0].ClientID)
+		assert.Equal(t, "192.0.2.3", reputations[1].ClientID)
+	})
+}
@@ -0,0 +1,12 @@
+// Package challenges contains the challenge storage implementations: in memory or on disk for a single node,
+// and in Redis for several nodes sharing the challenges.
+package challenges
+
+const (
+	// BackendMemory keeps the challenges in memory, they are lost on restart and not shared between the nodes.
+	BackendMemory = "memory"
+	// BackendBolt keeps the challenges in an embedded bbolt database file, they survive a restart.
+	BackendBolt = "bolt"
+	// BackendRedis keeps the challenges in Redis, shared between all the nodes using it.
+	BackendRedis = "redis"
+)
@@ -0,0 +1,7 @@
+package challenges
+
+import "errors"
+
+// ErrStorageClosed is returned when the storage is used after it was closed,
+// or after its database could not be reopened during compaction.
+var ErrStorageClosed = errors.New("challenge storage is closed")
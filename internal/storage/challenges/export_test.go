@@ -0,0 +1,8 @@
+package challenges
+
+import bolt "go.etcd.io/bbolt"
+
+// FailReopen makes the storage fail to open its database file with the error from now on.
+func FailReopen(s *StorageBolt, err error) {
+	s.open = func(string) (*bolt.DB, error) { return nil, err }
+}
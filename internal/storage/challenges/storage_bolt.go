@@ -0,0 +1,562 @@
+package challenges
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+const (
+	// boltOpenTimeout is for how long to wait for the lock of the database file, held by another process.
+	boltOpenTimeout = time.Second
+	// boltCompactTxMaxSize is the maximum size of a single transaction, when copying the database during compaction.
+	boltCompactTxMaxSize = 64 << 10
+	// boltFileMode is the mode of the database file.
+	boltFileMode = 0o600
+	// boltExpiresSize is the size of the expiry time, encoded as big-endian Unix nanoseconds.
+	boltExpiresSize = 8
+)
+
+var (
+	// boltChallenges is the bucket of the challenges, keyed by the challenge key.
+	// Every value is the expiry time followed by the challenge.
+	boltChallenges = []byte("challenges")
+	// boltExpiries is the bucket indexing the challenges by the expiry time, so that the sweep does not scan them all.
+	// Every key is the expiry time followed by the challenge key, the values are empty.
+	boltExpiries = []byte("expiries")
+	// boltGroups is the bucket of the group members, keyed by the group, a zero byte and the challenge key.
+	// Every value is the expiry time of the challenge, so that the member expiring the soonest could be evicted.
+	boltGroups = []byte("groups")
+	// boltMembers is the bucket of the groups of the challenges, that are members of one, keyed by the challenge key.
+	boltMembers = []byte("members")
+)
+
+// StorageBolt is a challenge storage in an embedded bbolt database, so that the challenges survive a restart.
+// Every challenge is kept for its time to live, after which it is no longer returned and is swept away.
+// The database file does not shrink on its own, so it is compacted from time to time by copying it.
+// The groups are indexed in their own buckets, and are trimmed in the same transaction, that adds a challenge.
+type StorageBolt struct {
+	logger *zap.Logger
+	path   string
+	// mu guards the db, which is replaced during compaction, all the other operations only read-lock it.
+	// The db is nil once the storage is closed, or could not be reopened after compaction.
+	mu sync.RWMutex
+	db *bolt.DB
+	// open opens the database file, it is replaced in the tests to make reopening fail.
+	open func(path string) (*bolt.DB, error)
+}
+
+// NewStorageBolt opens the challenge storage in the bbolt database file at the path, creating it if needed.
+func NewStorageBolt(logger *zap.Logger, path string) (*StorageBolt, error) {
+	// Logging the call
+	logger.Debug("creating a new challenge storage in bolt", zap.String("path", path))
+
+	// Opening the database
+	db, err := openBolt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StorageBolt{logger: logger, path: path, db: db, open: openBolt}, nil
+}
+
+// openBolt opens the database file at the path and creates the buckets, if they do not exist yet.
+func openBolt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, boltFileMode, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltChallenges, boltExpiries, boltGroups, boltMembers} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("creating bolt buckets: %w", err)
+	}
+
+	return db, nil
+}
+
+// Add adds a challenge to the store for the ttl, replacing the challenge stored under the same key, if any.
+func (s *StorageBolt) Add(ctx context.Context, key, value string, ttl time.Duration) error {
+	// Logging the call
+	s.logger.Debug("adding challenge to the store",
+		zap.String("key", key), zap.String("value", value), zap.Duration("ttl", ttl),
+	)
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	expires := encodeExpires(time.Now().Add(ttl))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return ErrStorageClosed
+	}
+
+	// Storing the challenge along with its expiry time
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putChallenge(tx, key, value, expires)
+	})
+	if err != nil {
+		return fmt.Errorf("adding challenge to bolt: %w", err)
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// AddToGroup adds a challenge to the store for the ttl, as Add does, and makes it a member of the group.
+// If the group then has more than limit members, the other ones expiring the soonest are deleted,
+// the new challenge is never deleted, as it is about to be given to the client.
+func (s *StorageBolt) AddToGroup(ctx context.Context, group, key, value string, ttl time.Duration, limit int) error {
+	// Logging the call
+	s.logger.Debug("adding challenge to the group in the store",
+		zap.String("group", group), zap.String("key", key), zap.String("value", value), zap.Duration("ttl", ttl),
+	)
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	expires := encodeExpires(time.Now().Add(ttl))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return ErrStorageClosed
+	}
+
+	// Storing the challenge and its membership, and evicting the other members over the limit, all at once
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := putChallenge(tx, key, value, expires); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(boltMembers).Put([]byte(key), []byte(group)); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(boltGroups).Put(groupKey(group, key), expires); err != nil {
+			return err
+		}
+
+		return trimGroup(tx, group, key, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("adding challenge to the group in bolt: %w", err)
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// Get returns the challenge stored under the key and reports whether it is in the store and has not expired yet.
+func (s *StorageBolt) Get(ctx context.Context, key string) (string, bool, error) {
+	// Logging the call
+	s.logger.Debug("getting challenge from the store", zap.String("key", key))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return "", false, ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return "", false, ErrStorageClosed
+	}
+
+	// Reading the challenge, the expired ones are left to the sweeper
+	var value string
+	var ok bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value, ok = liveChallenge(tx.Bucket(boltChallenges).Get([]byte(key)))
+
+		return nil
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("getting challenge from bolt: %w", err)
+	}
+
+	// Returning the challenge, the result and nil as the error
+	return value, ok, nil
+}
+
+// Delete deletes a challenge from the store.
+func (s *StorageBolt) Delete(ctx context.Context, key string) error {
+	// Logging the call
+	s.logger.Debug("deleting challenge from the store", zap.String("key", key))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return ErrStorageClosed
+	}
+
+	// Deleting the challenge along with its index
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		_, err := deleteChallenge(tx, key)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("deleting challenge from bolt: %w", err)
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// Consume returns the challenge stored under the key and deletes it in one read-write transaction,
+// which bbolt runs one at a time, so that of the concurrent callers only one gets the challenge.
+// It reports whether the challenge was in the store and had not expired yet.
+func (s *StorageBolt) Consume(ctx context.Context, key string) (string, bool, error) {
+	// Logging the call
+	s.logger.Debug("consuming challenge from the store", zap.String("key", key))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return "", false, ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return "", false, ErrStorageClosed
+	}
+
+	// Reading and deleting the challenge at once
+	var value string
+	var ok bool
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		stored, err := deleteChallenge(tx, key)
+		value, ok = liveChallenge(stored)
+
+		return err
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("consuming challenge from bolt: %w", err)
+	}
+
+	// Returning the challenge, the result and nil as the error
+	return value, ok, nil
+}
+
+// Run sweeps the expired challenges away every sweep interval, and compacts the database every compact interval,
+// until the context is canceled.
+func (s *StorageBolt) Run(ctx context.Context, sweepInterval, compactInterval time.Duration) {
+	sweepTicker := time.NewTicker(sweepInterval)
+	defer sweepTicker.Stop()
+
+	compactTicker := time.NewTicker(compactInterval)
+	defer compactTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sweepTicker.C:
+			swept, err := s.Sweep()
+			if err != nil {
+				s.logger.Error("sweeping challenge storage failed", zap.Error(err))
+
+				continue
+			}
+
+			s.logger.Debug("challenge storage swept", zap.Int("swept", swept))
+		case <-compactTicker.C:
+			if err := s.Compact(); err != nil {
+				s.logger.Error("compacting challenge storage failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Sweep deletes the expired challenges and returns their number.
+func (s *StorageBolt) Sweep() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return 0, ErrStorageClosed
+	}
+
+	now := encodeExpires(time.Now())
+	swept := 0
+
+	// The index is ordered by the expiry time, so the expired challenges come first
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		challenges, expiries := tx.Bucket(boltChallenges), tx.Bucket(boltExpiries)
+
+		cursor := expiries.Cursor()
+		for k, _ := cursor.First(); k != nil && bytes.Compare(k[:boltExpiresSize], now) <= 0; k, _ = cursor.Next() {
+			if err := challenges.Delete(k[boltExpiresSize:]); err != nil {
+				return err
+			}
+
+			if err := leaveGroup(tx, string(k[boltExpiresSize:])); err != nil {
+				return err
+			}
+
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+
+			swept++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("sweeping challenges from bolt: %w", err)
+	}
+
+	return swept, nil
+}
+
+// Compact rewrites the database into a new file without the free pages, left by the deleted challenges,
+// and replaces the old file with it. The storage is blocked meanwhile.
+// If the database could not be reopened afterwards, the storage fails every later call with ErrStorageClosed.
+func (s *StorageBolt) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return ErrStorageClosed
+	}
+
+	before := s.fileSize()
+	compactPath := s.path + ".compact"
+
+	// Copying the challenges into a new database
+	dst, err := bolt.Open(compactPath, boltFileMode, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return fmt.Errorf("opening compacted bolt database: %w", err)
+	}
+
+	if err = bolt.Compact(dst, s.db, boltCompactTxMaxSize); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(compactPath)
+
+		return fmt.Errorf("compacting bolt database: %w", err)
+	}
+
+	if err = dst.Close(); err != nil {
+		_ = os.Remove(compactPath)
+
+		return fmt.Errorf("closing compacted bolt database: %w", err)
+	}
+
+	// Replacing the old database with the new one
+	if err = s.db.Close(); err != nil {
+		_ = os.Remove(compactPath)
+
+		return fmt.Errorf("closing bolt database: %w", err)
+	}
+
+	renameErr := os.Rename(compactPath, s.path)
+	if renameErr != nil {
+		_ = os.Remove(compactPath)
+	}
+
+	// Reopening the database, the old one if the new one could not replace it.
+	// The closed database is never used again, so that the later calls fail instead of panicking.
+	db, err := s.open(s.path)
+	if err != nil {
+		s.db = nil
+
+		return fmt.Errorf("reopening bolt database: %w", err)
+	}
+
+	s.db = db
+
+	if renameErr != nil {
+		return fmt.Errorf("replacing bolt database: %w", renameErr)
+	}
+
+	s.logger.Debug("challenge storage compacted", zap.Int64("before", before), zap.Int64("after", s.fileSize()))
+
+	return nil
+}
+
+// Close closes the database, the later calls fail with ErrStorageClosed.
+func (s *StorageBolt) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	db := s.db
+	s.db = nil
+
+	return db.Close()
+}
+
+// fileSize returns the size of the database file, or 0 if it is unknown.
+func (s *StorageBolt) fileSize() int64 {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
+
+// putChallenge stores the challenge along with its expiry time, and indexes it by the expiry time.
+// The challenge stored under the same key, if any, is replaced and leaves its group.
+func putChallenge(tx *bolt.Tx, key, value string, expires []byte) error {
+	// Deleting the replaced challenge along with its index and membership, if any
+	if _, err := deleteChallenge(tx, key); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(boltChallenges).Put([]byte(key), append(append([]byte(nil), expires...), value...)); err != nil {
+		return err
+	}
+
+	return tx.Bucket(boltExpiries).Put(expiryKey(expires, key), nil)
+}
+
+// trimGroup deletes the members of the group expiring the soonest, except for the given one,
+// until the group has at most limit members. The groups are small, so it simply scans them.
+func trimGroup(tx *bolt.Tx, group, except string, limit int) error {
+	type member struct {
+		key     string
+		expires []byte
+	}
+
+	// Collecting the members of the group
+	prefix := groupKey(group, "")
+	members := make([]member, 0, limit+1)
+
+	cursor := tx.Bucket(boltGroups).Cursor()
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		members = append(members, member{key: string(k[len(prefix):]), expires: append([]byte(nil), v...)})
+	}
+
+	// Deleting the other members expiring the soonest
+	for len(members) > limit && len(members) > 1 {
+		first := -1
+
+		for i, m := range members {
+			if m.key != except && (first < 0 || bytes.Compare(m.expires, members[first].expires) < 0) {
+				first = i
+			}
+		}
+
+		if _, err := deleteChallenge(tx, members[first].key); err != nil {
+			return err
+		}
+
+		members = append(members[:first], members[first+1:]...)
+	}
+
+	return nil
+}
+
+// leaveGroup removes the challenge stored under the key from its group, if any.
+func leaveGroup(tx *bolt.Tx, key string) error {
+	members := tx.Bucket(boltMembers)
+
+	group := members.Get([]byte(key))
+	if group == nil {
+		return nil
+	}
+
+	if err := tx.Bucket(boltGroups).Delete(groupKey(string(group), key)); err != nil {
+		return err
+	}
+
+	return members.Delete([]byte(key))
+}
+
+// deleteChallenge deletes the challenge stored under the key along with its index and membership,
+// and returns the stored value.
+func deleteChallenge(tx *bolt.Tx, key string) ([]byte, error) {
+	challenges, expiries := tx.Bucket(boltChallenges), tx.Bucket(boltExpiries)
+
+	// Copying the value, as it is only valid until the challenge is deleted
+	stored := challenges.Get([]byte(key))
+	if stored == nil {
+		return nil, nil
+	}
+
+	stored = append([]byte(nil), stored...)
+
+	if err := expiries.Delete(expiryKey(stored[:boltExpiresSize], key)); err != nil {
+		return nil, err
+	}
+
+	if err := leaveGroup(tx, key); err != nil {
+		return nil, err
+	}
+
+	return stored, challenges.Delete([]byte(key))
+}
+
+// liveChallenge decodes the stored value and reports whether the challenge is there and has not expired yet.
+func liveChallenge(stored []byte) (string, bool) {
+	if len(stored) < boltExpiresSize {
+		return "", false
+	}
+
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(stored[:boltExpiresSize])))
+	if !time.Now().Before(expires) {
+		return "", false
+	}
+
+	return string(stored[boltExpiresSize:]), true
+}
+
+// encodeExpires encodes the expiry time as big-endian Unix nanoseconds, so that the encoded times sort as the times.
+func encodeExpires(expires time.Time) []byte {
+	encoded := make([]byte, boltExpiresSize)
+	binary.BigEndian.PutUint64(encoded, uint64(expires.UnixNano()))
+
+	return encoded
+}
+
+// groupKey returns the key of the challenge in the group index.
+// The group and the key are separated by a zero byte, so that the members of the group could be found by the prefix.
+func groupKey(group, key string) []byte {
+	return append(append(append(make([]byte, 0, len(group)+1+len(key)), group...), 0), key...)
+}
+
+// expiryKey returns the key of the challenge in the expiry index.
+func expiryKey(expires []byte, key string) []byte {
+	return append(append(make([]byte, 0, len(expires)+len(key)), expires...), key...)
+}
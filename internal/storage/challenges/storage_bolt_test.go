@@ -0,0 +1,369 @@
+package challenges_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/storage/challenges"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/storetest"
+)
+
+// newBoltStorage creates a challenge storage in a new database file, that is closed when the test ends.
+func newBoltStorage(t *testing.T) (*challenges.StorageBolt, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "challenges.db")
+
+	store, err := challenges.NewStorageBolt(zap.NewNop(), path)
+	require.NoError(t, err, "the storage should be opened")
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store, path
+}
+
+func TestStorageBolt_Add(t *testing.T) {
+	t.Run("add challenge to the store", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+
+		// add a challenge to the store
+		err := store.Add(context.Background(), "key", "value", time.Minute)
+		assert.NoError(t, err, "there should be no error")
+
+		// check that the challenge is in the store
+		value, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be in the store")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+	})
+
+	t.Run("add challenge to the store with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// add a challenge to the store
+		err := store.Add(ctx, "key", "value", time.Minute)
+		assert.Error(t, err, "there should be an error")
+
+		// check that the challenge is not in the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should not be in the store")
+	})
+
+	t.Run("adding the same key again renews the challenge", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+
+		// add a challenge, which expires right away, and then replace it with a long-living one
+		require.NoError(t, store.Add(context.Background(), "key", "old", time.Nanosecond))
+		require.NoError(t, store.Add(context.Background(), "key", "new", time.Minute))
+		time.Sleep(time.Millisecond)
+
+		// check that the sweep leaves the renewed challenge alone
+		swept, err := store.Sweep()
+		assert.NoError(t, err, "there should be no error")
+		assert.Equal(t, 0, swept, "the renewed challenge should not be swept")
+
+		// check that the new challenge is returned
+		value, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be in the store")
+		assert.Equal(t, "new", value, "the new challenge should be returned")
+	})
+}
+
+func TestStorageBolt_Get(t *testing.T) {
+	t.Run("get challenge from the store with a non-existent key", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+
+		// get the challenge from the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should not be in the store")
+	})
+
+	t.Run("expired challenge is not returned", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+
+		// add a challenge, which expires right away
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Nanosecond))
+		time.Sleep(time.Millisecond)
+
+		// check that the challenge is not returned
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the expired challenge should not be returned")
+	})
+
+	t.Run("get challenge from the store with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// get the challenge from the store
+		_, ok, err := store.Get(ctx, "key")
+		assert.Error(t, err, "there should be an error")
+		assert.False(t, ok, "the challenge should not be returned")
+	})
+}
+
+func TestStorageBolt_Delete(t *testing.T) {
+	t.Run("delete challenge from the store", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// delete the challenge from the store
+		err := store.Delete(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+
+		// check that the challenge is not in the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should not be in the store")
+	})
+
+	t.Run("delete challenge from the store with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// delete the challenge from the store
+		err := store.Delete(ctx, "key")
+		assert.Error(t, err, "there should be an error")
+
+		// check that the challenge is in the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be in the store")
+	})
+}
+
+func TestStorageBolt_Consume(t *testing.T) {
+	t.Run("consume challenge from the store", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// consume the challenge
+		value, ok, err := store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be consumed")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+
+		// check that the challenge is gone
+		_, ok, err = store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should be consumed only once")
+	})
+
+	t.Run("expired challenge is not consumed", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Nanosecond))
+		time.Sleep(time.Millisecond)
+
+		// consume the challenge
+		_, ok, err := store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the expired challenge should not be consumed")
+	})
+
+	t.Run("challenge is consumed only once by the concurrent callers", func(t *testing.T) {
+		// create a new storage
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// consume the challenge concurrently
+		var consumed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, ok, _ := store.Consume(context.Background(), "key"); ok {
+					atomic.AddInt32(&consumed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// check that only one caller got the challenge
+		assert.Equal(t, int32(1), consumed, "the challenge should be consumed only once")
+	})
+}
+
+func TestStorageBolt_Sweep(t *testing.T) {
+	// create a new storage
+	store, _ := newBoltStorage(t)
+
+	// add two expired challenges and a live one
+	require.NoError(t, store.Add(context.Background(), "expired-1", "value", time.Nanosecond))
+	require.NoError(t, store.Add(context.Background(), "live", "value", time.Minute))
+	require.NoError(t, store.Add(context.Background(), "expired-2", "value", time.Nanosecond))
+	time.Sleep(time.Millisecond)
+
+	// sweep the storage
+	swept, err := store.Sweep()
+
+	// check that only the expired challenges are swept
+	assert.NoError(t, err, "there should be no error")
+	assert.Equal(t, 2, swept, "the expired challenges should be swept")
+
+	_, ok, err := store.Get(context.Background(), "live")
+	assert.NoError(t, err, "there should be no error")
+	assert.True(t, ok, "the live challenge should be in the store")
+}
+
+func TestStorageBolt_Persistence(t *testing.T) {
+	t.Run("challenges survive a restart", func(t *testing.T) {
+		// create a new storage and add a challenge
+		store, path := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// restart the storage
+		require.NoError(t, store.Close())
+		store, err := challenges.NewStorageBolt(zap.NewNop(), path)
+		require.NoError(t, err, "the storage should be reopened")
+		t.Cleanup(func() { _ = store.Close() })
+
+		// check that the challenge is still there
+		value, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should survive the restart")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+	})
+
+	t.Run("compaction keeps the live challenges", func(t *testing.T) {
+		// create a new storage and fill it with the challenges, most of which are deleted afterwards
+		store, _ := newBoltStorage(t)
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, store.Add(context.Background(), fmt.Sprintf("key-%d", i), "value", time.Minute))
+		}
+		for i := 1; i < 1000; i++ {
+			require.NoError(t, store.Delete(context.Background(), fmt.Sprintf("key-%d", i)))
+		}
+
+		// compact the storage
+		err := store.Compact()
+		assert.NoError(t, err, "there should be no error")
+
+		// check that the live challenge is kept and the storage still works
+		value, ok, err := store.Get(context.Background(), "key-0")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the live challenge should be kept")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+		assert.NoError(t, store.Add(context.Background(), "key-1", "value", time.Minute))
+
+		// check that the swept index is compacted along
+		swept, err := store.Sweep()
+		assert.NoError(t, err, "there should be no error")
+		assert.Equal(t, 0, swept, "no challenge should be expired")
+	})
+
+	t.Run("storage fails without panicking when the database could not be reopened", func(t *testing.T) {
+		// create a new storage, which could not open its database file again
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+		challenges.FailReopen(store, errors.New("disk is gone"))
+
+		// compact the storage
+		err := store.Compact()
+		assert.Error(t, err, "there should be an error")
+
+		// check that every later call fails with ErrStorageClosed
+		assert.ErrorIs(t, store.Add(context.Background(), "key", "value", time.Minute), challenges.ErrStorageClosed)
+		assert.ErrorIs(t, store.AddToGroup(context.Background(), "group", "key", "value", time.Minute, 1), challenges.ErrStorageClosed)
+		_, _, err = store.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, challenges.ErrStorageClosed)
+		_, _, err = store.Consume(context.Background(), "key")
+		assert.ErrorIs(t, err, challenges.ErrStorageClosed)
+		assert.ErrorIs(t, store.Delete(context.Background(), "key"), challenges.ErrStorageClosed)
+		_, err = store.Sweep()
+		assert.ErrorIs(t, err, challenges.ErrStorageClosed)
+		assert.ErrorIs(t, store.Compact(), challenges.ErrStorageClosed)
+	})
+
+	t.Run("closed storage fails with ErrStorageClosed", func(t *testing.T) {
+		// create a new storage and close it
+		store, _ := newBoltStorage(t)
+		require.NoError(t, store.Close())
+
+		// check that the later calls fail
+		_, _, err := store.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, challenges.ErrStorageClosed)
+		assert.NoError(t, store.Close(), "closing again should do nothing")
+	})
+
+	t.Run("database file is locked by the open storage", func(t *testing.T) {
+		// create a new storage
+		_, path := newBoltStorage(t)
+
+		// open the same file again
+		_, err := challenges.NewStorageBolt(zap.NewNop(), path)
+		assert.Error(t, err, "the locked file should not be opened")
+	})
+}
+
+func TestStorageBolt_Concurrency(t *testing.T) {
+	// create a new storage
+	store, _ := newBoltStorage(t)
+
+	// add, get, delete, sweep and compact the challenges concurrently, the race detector catches unguarded access
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				key := fmt.Sprintf("key-%d-%d", i, j)
+				assert.NoError(t, store.Add(context.Background(), key, "value", time.Duration(j%3)*time.Millisecond))
+				_, _, err := store.Get(context.Background(), key)
+				assert.NoError(t, err, "there should be no error")
+				if j%2 == 0 {
+					assert.NoError(t, store.Delete(context.Background(), key))
+				}
+				_, err = store.Sweep()
+				assert.NoError(t, err, "there should be no error")
+				if j%10 == 0 {
+					assert.NoError(t, store.Compact())
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestStorageBolt_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (pow.ChallengeStore, func(time.Duration)) {
+		store, _ := newBoltStorage(t)
+
+		return store, time.Sleep
+	})
+}
@@ -1,58 +1,152 @@
-// Package challenges contains the challenges storage in memory implementation.
 package challenges
 
 import (
+	"container/heap"
 	"context"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 )
 
-// StorageInMemory is a quote storage in memory.
+// StorageInMemory is a challenge storage in memory, safe for concurrent use.
+// Every challenge is kept for its time to live, after which it is no longer returned and is swept away.
+// The storage is bounded by size: when it is full, the challenge expiring the soonest is evicted to make room.
+// The challenges are also kept in a heap ordered by the expiry time, so that neither the sweep nor the eviction
+// scans the whole storage. A group is bounded as well, by evicting its own challenge expiring the soonest.
 type StorageInMemory struct {
 	logger *zap.Logger
-	db     map[string]string
+	mu     sync.Mutex
+	db     map[string]*entry
+	byExp  expiryHeap
+	groups map[string][]*entry
+	size   int
+	// expired is the number of the challenges swept away after they expired.
+	expired uint64
+	// evicted is the number of the challenges evicted before they expired, to make room for the new ones.
+	evicted uint64
 }
 
-// NewStorageInMemory creates a new quote storage in memory.
-func NewStorageInMemory(logger *zap.Logger) *StorageInMemory {
+// Stats are the statistics of the storage.
+type Stats struct {
+	// Size is the number of the challenges in the storage, including the expired ones, that were not swept yet.
+	Size int
+	// Capacity is the maximum number of the challenges in the storage.
+	Capacity int
+	// Expired is the number of the challenges swept away after they expired.
+	Expired uint64
+	// Evicted is the number of the challenges evicted before they expired, because the storage was full.
+	Evicted uint64
+}
+
+// NewStorageInMemory creates a new challenge storage in memory, holding at most size challenges.
+func NewStorageInMemory(logger *zap.Logger, size int) *StorageInMemory {
 	// Logging the call
-	logger.Debug("creating a new challenge storage in memory")
+	logger.Debug("creating a new challenge storage in memory", zap.Int("size", size))
 
-	return &StorageInMemory{logger: logger, db: make(map[string]string)}
+	return &StorageInMemory{logger: logger, db: make(map[string]*entry), groups: make(map[string][]*entry), size: size}
 }
 
-// Add adds a challenge to the store.
-func (s *StorageInMemory) Add(ctx context.Context, key, value string) error {
+// Add adds a challenge to the store for the ttl, replacing the challenge stored under the same key, if any.
+// If the storage is full, the challenge expiring the soonest is evicted, so that the new challenge always fits.
+func (s *StorageInMemory) Add(ctx context.Context, key, value string, ttl time.Duration) error {
 	// Logging the call
-	s.logger.Debug("adding challenge to the store", zap.String("key", key), zap.String("value", value))
+	s.logger.Debug("adding challenge to the store",
+		zap.String("key", key), zap.String("value", value), zap.Duration("ttl", ttl),
+	)
 
 	// Checking if the context is canceled
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 
-	// Adding the challenge to the db
-	s.db[key] = value
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Replacing the challenge takes it out of its group, if any
+	s.leave(s.add(key, value, time.Now().Add(ttl)))
 
 	// Returning nil as the error
 	return nil
 }
 
-// Get checks if a challenge is in the store.
-func (s *StorageInMemory) Get(ctx context.Context, key string) (bool, error) {
+// AddToGroup adds a challenge to the store for the ttl, as Add does, and makes it a member of the group.
+// If the group then has more than limit members, the other ones expiring the soonest are evicted,
+// the new challenge is never evicted, as it is about to be given to the client.
+func (s *StorageInMemory) AddToGroup(ctx context.Context, group, key, value string, ttl time.Duration, limit int) error {
+	// Logging the call
+	s.logger.Debug("adding challenge to the group in the store",
+		zap.String("group", group), zap.String("key", key), zap.String("value", value), zap.Duration("ttl", ttl),
+	)
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.add(key, value, time.Now().Add(ttl))
+
+	// Moving the challenge to the group, it could have been a member of another one under the same key
+	s.leave(e)
+	e.group = group
+	s.groups[group] = append(s.groups[group], e)
+
+	// Evicting the other members expiring the soonest
+	for len(s.groups[group]) > limit && len(s.groups[group]) > 1 {
+		s.evict(soonest(s.groups[group], e))
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// add adds the challenge to the db and returns its entry. It must be called with the lock held.
+func (s *StorageInMemory) add(key, value string, expires time.Time) *entry {
+	// Replacing the challenge stored under the same key, it does not take more room
+	if e, ok := s.db[key]; ok {
+		e.value, e.expires = value, expires
+		heap.Fix(&s.byExp, e.index)
+
+		return e
+	}
+
+	// Making room for the challenge, the expired ones go first, as they expire the soonest
+	for len(s.db) >= s.size && s.byExp.Len() > 0 {
+		s.evict(s.byExp[0])
+	}
+
+	// Adding the challenge to the db
+	e := &entry{key: key, value: value, expires: expires}
+	s.db[key] = e
+	heap.Push(&s.byExp, e)
+
+	return e
+}
+
+// Get returns the challenge stored under the key and reports whether it is in the store and has not expired yet.
+func (s *StorageInMemory) Get(ctx context.Context, key string) (string, bool, error) {
 	// Logging the call
 	s.logger.Debug("getting challenge from the store", zap.String("key", key))
 
 	// Checking if the context is canceled
 	if ctx.Err() != nil {
-		return false, ctx.Err()
+		return "", false, ctx.Err()
 	}
 
-	// Checking if the challenge is in the db
-	_, ok := s.db[key]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Checking if the challenge is in the db and has not expired, the expired ones are left to the sweeper
+	e, ok := s.db[key]
+	if !ok || !time.Now().Before(e.expires) {
+		return "", false, nil
+	}
 
-	// Returning the result and nil as the error
-	return ok, nil
+	// Returning the challenge, the result and nil as the error
+	return e.value, true, nil
 }
 
 // Delete deletes a challenge from the store.
@@ -65,9 +159,194 @@ func (s *StorageInMemory) Delete(ctx context.Context, key string) error {
 		return ctx.Err()
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Deleting the challenge from the db
-	delete(s.db, key)
+	if e, ok := s.db[key]; ok {
+		s.remove(e)
+	}
 
 	// Returning nil as the error
 	return nil
 }
+
+// Consume returns the challenge stored under the key and deletes it in one step, under the lock,
+// so that of the concurrent callers only one gets the challenge.
+// It reports whether the challenge was in the store and had not expired yet.
+func (s *StorageInMemory) Consume(ctx context.Context, key string) (string, bool, error) {
+	// Logging the call
+	s.logger.Debug("consuming challenge from the store", zap.String("key", key))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return "", false, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Checking if the challenge is in the db, the expired ones are left to the sweeper
+	e, ok := s.db[key]
+	if !ok || !time.Now().Before(e.expires) {
+		return "", false, nil
+	}
+
+	// Deleting the challenge from the db
+	s.remove(e)
+
+	// Returning the challenge, the result and nil as the error
+	return e.value, true, nil
+}
+
+// Run sweeps the expired challenges away every interval, until the context is canceled.
+func (s *StorageInMemory) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			swept := s.Sweep()
+
+			// Logging the stats of the storage
+			stats := s.Stats()
+			s.logger.Debug("challenge storage swept",
+				zap.Int("swept", swept),
+				zap.Int("size", stats.Size),
+				zap.Uint64("expired", stats.Expired),
+				zap.Uint64("evicted", stats.Evicted),
+			)
+		}
+	}
+}
+
+// Sweep deletes the expired challenges and returns their number.
+func (s *StorageInMemory) Sweep() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	swept := 0
+
+	// The earliest expiring challenge is always on top of the heap
+	for s.byExp.Len() > 0 && !now.Before(s.byExp[0].expires) {
+		s.remove(s.byExp[0])
+		swept++
+	}
+
+	s.expired += uint64(swept)
+
+	return swept
+}
+
+// Stats returns the statistics of the storage.
+func (s *StorageInMemory) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Stats{Size: len(s.db), Capacity: s.size, Expired: s.expired, Evicted: s.evicted}
+}
+
+// evict deletes the challenge to make room for the new ones, counting it as expired, if it has already expired.
+// It must be called with the lock held.
+func (s *StorageInMemory) evict(e *entry) {
+	if time.Now().Before(e.expires) {
+		s.evicted++
+	} else {
+		s.expired++
+	}
+
+	s.remove(e)
+}
+
+// remove deletes the challenge from the db, the heap and its group. It must be called with the lock held.
+func (s *StorageInMemory) remove(e *entry) {
+	heap.Remove(&s.byExp, e.index)
+	delete(s.db, e.key)
+	s.leave(e)
+}
+
+// leave removes the challenge from its group, if any, and forgets the group, once it is empty.
+// It must be called with the lock held.
+func (s *StorageInMemory) leave(e *entry) {
+	if e.group == "" {
+		return
+	}
+
+	members := s.groups[e.group]
+	for i, member := range members {
+		if member == e {
+			members = append(members[:i], members[i+1:]...)
+
+			break
+		}
+	}
+
+	if len(members) == 0 {
+		delete(s.groups, e.group)
+	} else {
+		s.groups[e.group] = members
+	}
+
+	e.group = ""
+}
+
+// soonest returns the entry expiring the soonest, except for the given one, or nil if there is no other entry.
+// The groups are small, so it simply scans them.
+func soonest(entries []*entry, except *entry) *entry {
+	var first *entry
+
+	for _, e := range entries {
+		if e != except && (first == nil || e.expires.Before(first.expires)) {
+			first = e
+		}
+	}
+
+	return first
+}
+
+// entry is a challenge along with its expiry time, its position in the heap and its group, if any.
+type entry struct {
+	key     string
+	value   string
+	expires time.Time
+	index   int
+	group   string
+}
+
+// expiryHeap is a min-heap of the challenges ordered by the expiry time, it implements heap.Interface.
+// The entries know their index, so that they could be fixed or removed when replaced or deleted.
+type expiryHeap []*entry
+
+// Len returns the number of the entries in the heap.
+func (h expiryHeap) Len() int { return len(h) }
+
+// Less reports whether the entry i expires before the entry j.
+func (h expiryHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+
+// Swap swaps the entries i and j.
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+// Push adds the entry to the heap.
+func (h *expiryHeap) Push(x any) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+// Pop removes the last entry from the heap.
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+
+	return e
+}
@@ -2,27 +2,33 @@ package challenges_test
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/daniel-orlov/quotes-server/internal/storage/challenges"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/storetest"
 )
 
 func TestStorageInMemory_Add(t *testing.T) {
 	t.Run("add challenge to the store", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// add a challenge to the store
-		err := store.Add(context.Background(), "key", "value")
+		err := store.Add(context.Background(), "key", "value", time.Minute)
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
 
 		// check that the challenge is in the store
-		ok, err := store.Get(context.Background(), "key")
+		_, ok, err := store.Get(context.Background(), "key")
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -33,20 +39,20 @@ func TestStorageInMemory_Add(t *testing.T) {
 
 	t.Run("add challenge to the store with a canceled context", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// create a canceled context
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
 
 		// add a challenge to the store
-		err := store.Add(ctx, "key", "value")
+		err := store.Add(ctx, "key", "value", time.Minute)
 
 		// check that there is an error
 		assert.Error(t, err, "there should be an error")
 
 		// check that the challenge is not in the store
-		ok, err := store.Get(context.Background(), "key")
+		_, ok, err := store.Get(context.Background(), "key")
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -59,10 +65,10 @@ func TestStorageInMemory_Add(t *testing.T) {
 func TestStorageInMemory_Delete(t *testing.T) {
 	t.Run("delete challenge from the store", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// add a challenge to the store
-		err := store.Add(context.Background(), "key", "value")
+		err := store.Add(context.Background(), "key", "value", time.Minute)
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -74,7 +80,7 @@ func TestStorageInMemory_Delete(t *testing.T) {
 		assert.NoError(t, err, "there should be no error")
 
 		// check that the challenge is not in the store
-		ok, err := store.Get(context.Background(), "key")
+		_, ok, err := store.Get(context.Background(), "key")
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -85,10 +91,10 @@ func TestStorageInMemory_Delete(t *testing.T) {
 
 	t.Run("delete challenge from the store with a canceled context", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// add a challenge to the store
-		err := store.Add(context.Background(), "key", "value")
+		err := store.Add(context.Background(), "key", "value", time.Minute)
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -104,7 +110,7 @@ func TestStorageInMemory_Delete(t *testing.T) {
 		assert.Error(t, err, "there should be an error")
 
 		// check that the challenge is in the store
-		ok, err := store.Get(context.Background(), "key")
+		_, ok, err := store.Get(context.Background(), "key")
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -117,30 +123,33 @@ func TestStorageInMemory_Delete(t *testing.T) {
 func TestStorageInMemory_Get(t *testing.T) {
 	t.Run("get challenge from the store", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// add a challenge to the store
-		err := store.Add(context.Background(), "key", "value")
+		err := store.Add(context.Background(), "key", "value", time.Minute)
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
 
 		// get the challenge from the store
-		ok, err := store.Get(context.Background(), "key")
+		value, ok, err := store.Get(context.Background(), "key")
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
 
 		// check that the challenge is in the store
 		assert.True(t, ok, "the challenge should be in the store")
+
+		// check that the stored challenge is returned
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
 	})
 
 	t.Run("get challenge from the store with a non-existent key", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// get the challenge from the store
-		ok, err := store.Get(context.Background(), "key")
+		_, ok, err := store.Get(context.Background(), "key")
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -151,10 +160,10 @@ func TestStorageInMemory_Get(t *testing.T) {
 
 	t.Run("get challenge from the store with a canceled context", func(t *testing.T) {
 		// create a new storage
-		store := challenges.NewStorageInMemory(zap.NewNop())
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
 
 		// add a challenge to the store
-		err := store.Add(context.Background(), "key", "value")
+		err := store.Add(context.Background(), "key", "value", time.Minute)
 
 		// check that there is no error
 		assert.NoError(t, err, "there should be no error")
@@ -164,7 +173,7 @@ func TestStorageInMemory_Get(t *testing.T) {
 		cancel()
 
 		// get the challenge from the store
-		ok, err := store.Get(ctx, "key")
+		_, ok, err := store.Get(ctx, "key")
 
 		// check that there is an error
 		assert.Error(t, err, "there should be an error")
@@ -173,3 +182,256 @@ func TestStorageInMemory_Get(t *testing.T) {
 		assert.False(t, ok, "the challenge should not be in the store")
 	})
 }
+
+func TestStorageInMemory_TTL(t *testing.T) {
+	t.Run("expired challenge is not returned", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+
+		// add a challenge, which expires right away
+		err := store.Add(context.Background(), "key", "value", time.Nanosecond)
+		assert.NoError(t, err, "there should be no error")
+		time.Sleep(time.Millisecond)
+
+		// check that the challenge is not returned
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the expired challenge should not be returned")
+	})
+
+	t.Run("adding the same key again renews the challenge", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+
+		// add a challenge, which expires right away, and then replace it with a long-living one
+		assert.NoError(t, store.Add(context.Background(), "key", "old", time.Nanosecond))
+		assert.NoError(t, store.Add(context.Background(), "key", "new", time.Minute))
+		time.Sleep(time.Millisecond)
+
+		// check that the new challenge is returned and the sweep leaves it alone
+		assert.Equal(t, 0, store.Sweep(), "the renewed challenge should not be swept")
+		value, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be in the store")
+		assert.Equal(t, "new", value, "the new challenge should be returned")
+		assert.Equal(t, 1, store.Stats().Size, "the replaced challenge should not take more room")
+	})
+}
+
+func TestStorageInMemory_Sweep(t *testing.T) {
+	t.Run("sweep deletes only the expired challenges", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+
+		// add two expired challenges and a live one
+		assert.NoError(t, store.Add(context.Background(), "expired-1", "value", time.Nanosecond))
+		assert.NoError(t, store.Add(context.Background(), "live", "value", time.Minute))
+		assert.NoError(t, store.Add(context.Background(), "expired-2", "value", time.Nanosecond))
+		time.Sleep(time.Millisecond)
+
+		// sweep the storage
+		swept := store.Sweep()
+
+		// check that only the expired challenges are swept
+		assert.Equal(t, 2, swept, "the expired challenges should be swept")
+		assert.Equal(t, challenges.Stats{Size: 1, Capacity: 10, Expired: 2}, store.Stats())
+
+		_, ok, err := store.Get(context.Background(), "live")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the live challenge should be in the store")
+	})
+
+	t.Run("run sweeps in the background until the context is canceled", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+		assert.NoError(t, store.Add(context.Background(), "key", "value", time.Nanosecond))
+
+		// run the sweeper
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			store.Run(ctx, time.Millisecond)
+			close(done)
+		}()
+
+		// check that the expired challenge is swept away
+		assert.Eventually(t, func() bool { return store.Stats().Size == 0 }, time.Second, time.Millisecond)
+
+		// check that the sweeper stops
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("the sweeper should stop when the context is canceled")
+		}
+	})
+}
+
+func TestStorageInMemory_Eviction(t *testing.T) {
+	t.Run("full storage evicts the challenge expiring the soonest", func(t *testing.T) {
+		// create a new storage holding two challenges
+		store := challenges.NewStorageInMemory(zap.NewNop(), 2)
+
+		// fill the storage
+		assert.NoError(t, store.Add(context.Background(), "late", "value", time.Hour))
+		assert.NoError(t, store.Add(context.Background(), "soon", "value", time.Minute))
+
+		// add one more challenge
+		assert.NoError(t, store.Add(context.Background(), "new", "value", time.Minute))
+
+		// check that the challenge expiring the soonest is evicted
+		_, ok, _ := store.Get(context.Background(), "soon")
+		assert.False(t, ok, "the challenge expiring the soonest should be evicted")
+		_, ok, _ = store.Get(context.Background(), "late")
+		assert.True(t, ok, "the challenge expiring later should be kept")
+		_, ok, _ = store.Get(context.Background(), "new")
+		assert.True(t, ok, "the new challenge should be in the store")
+		assert.Equal(t, challenges.Stats{Size: 2, Capacity: 2, Evicted: 1}, store.Stats())
+	})
+
+	t.Run("full storage drops the expired challenges first", func(t *testing.T) {
+		// create a new storage holding two challenges
+		store := challenges.NewStorageInMemory(zap.NewNop(), 2)
+
+		// fill the storage with an expired challenge and a live one
+		assert.NoError(t, store.Add(context.Background(), "expired", "value", time.Nanosecond))
+		assert.NoError(t, store.Add(context.Background(), "live", "value", time.Minute))
+		time.Sleep(time.Millisecond)
+
+		// add one more challenge
+		assert.NoError(t, store.Add(context.Background(), "new", "value", time.Minute))
+
+		// check that the expired challenge made room, and it is not counted as evicted
+		_, ok, _ := store.Get(context.Background(), "live")
+		assert.True(t, ok, "the live challenge should be kept")
+		assert.Equal(t, challenges.Stats{Size: 2, Capacity: 2, Expired: 1}, store.Stats())
+	})
+	t.Run("full group evicts its challenge expiring the soonest", func(t *testing.T) {
+		// create a new storage with room to spare
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+
+		// fill the group of two challenges, next to a challenge of another group expiring even sooner
+		assert.NoError(t, store.AddToGroup(context.Background(), "other", "other", "value", time.Second, 2))
+		assert.NoError(t, store.AddToGroup(context.Background(), "group", "late", "value", time.Hour, 2))
+		assert.NoError(t, store.AddToGroup(context.Background(), "group", "soon", "value", time.Minute, 2))
+
+		// add one more challenge to the group
+		assert.NoError(t, store.AddToGroup(context.Background(), "group", "new", "value", time.Minute, 2))
+
+		// check that only the challenge of the group expiring the soonest is evicted
+		_, ok, _ := store.Get(context.Background(), "soon")
+		assert.False(t, ok, "the challenge of the group expiring the soonest should be evicted")
+		_, ok, _ = store.Get(context.Background(), "other")
+		assert.True(t, ok, "the challenge of another group should be kept")
+		assert.Equal(t, challenges.Stats{Size: 3, Capacity: 10, Evicted: 1}, store.Stats())
+	})
+}
+
+func TestStorageInMemory_Concurrency(t *testing.T) {
+	// create a new storage, smaller than the number of the challenges, so that they are evicted as well
+	store := challenges.NewStorageInMemory(zap.NewNop(), 50)
+
+	// add, get, delete and sweep the challenges concurrently, the race detector catches unguarded access
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d-%d", i, j)
+				assert.NoError(t, store.Add(context.Background(), key, "value", time.Duration(j%3)*time.Millisecond))
+				_, _, err := store.Get(context.Background(), key)
+				assert.NoError(t, err, "there should be no error")
+				if j%2 == 0 {
+					assert.NoError(t, store.Delete(context.Background(), key))
+				}
+				store.Sweep()
+				store.Stats()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// check that the storage stays within its bounds
+	assert.LessOrEqual(t, store.Stats().Size, 50, "the storage should not grow over its size")
+}
+
+func TestStorageInMemory_Consume(t *testing.T) {
+	t.Run("consume challenge from the store", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+		assert.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// consume the challenge
+		value, ok, err := store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be consumed")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+
+		// check that the challenge is gone
+		_, ok, err = store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should be consumed only once")
+		assert.Equal(t, 0, store.Stats().Size, "the consumed challenge should not take room")
+	})
+
+	t.Run("expired challenge is not consumed", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+		assert.NoError(t, store.Add(context.Background(), "key", "value", time.Nanosecond))
+		time.Sleep(time.Millisecond)
+
+		// consume the challenge
+		_, ok, err := store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the expired challenge should not be consumed")
+	})
+
+	t.Run("consume challenge with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+		assert.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// consume the challenge
+		_, ok, err := store.Consume(ctx, "key")
+		assert.Error(t, err, "there should be an error")
+		assert.False(t, ok, "the challenge should not be consumed")
+
+		// check that the challenge is still in the store
+		_, ok, _ = store.Get(context.Background(), "key")
+		assert.True(t, ok, "the challenge should be in the store")
+	})
+
+	t.Run("challenge is consumed only once by the concurrent callers", func(t *testing.T) {
+		// create a new storage
+		store := challenges.NewStorageInMemory(zap.NewNop(), 10)
+		assert.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// consume the challenge concurrently
+		var consumed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, ok, _ := store.Consume(context.Background(), "key"); ok {
+					atomic.AddInt32(&consumed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// check that only one caller got the challenge
+		assert.Equal(t, int32(1), consumed, "the challenge should be consumed only once")
+	})
+}
+
+func TestStorageInMemory_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (pow.ChallengeStore, func(time.Duration)) {
+		return challenges.NewStorageInMemory(zap.NewNop(), 1000), time.Sleep
+	})
+}
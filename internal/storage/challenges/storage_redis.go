@@ -0,0 +1,181 @@
+package challenges
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisGroupPrefix is the prefix of the groups, following the prefix of the storage.
+const redisGroupPrefix = "groups:"
+
+// redisAddToGroup is the script adding a challenge to a group, so that the group is trimmed atomically.
+// Every group is a sorted set of the challenge keys, scored by their expiry time in milliseconds.
+// The members, whose challenges are gone, are forgotten before the group is trimmed.
+// KEYS[1] is the challenge key, KEYS[2] is the group key,
+// ARGV[1] is the challenge, ARGV[2] is the ttl and ARGV[3] is the expiry time, both in milliseconds,
+// ARGV[4] is the limit.
+var redisAddToGroup = redis.NewScript(`
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+
+local ttl = redis.call('PTTL', KEYS[2])
+redis.call('ZADD', KEYS[2], ARGV[3], KEYS[1])
+
+for _, member in ipairs(redis.call('ZRANGE', KEYS[2], 0, -1)) do
+	if redis.call('EXISTS', member) == 0 then
+		redis.call('ZREM', KEYS[2], member)
+	end
+end
+
+local over = redis.call('ZCARD', KEYS[2]) - tonumber(ARGV[4])
+for _, member in ipairs(redis.call('ZRANGE', KEYS[2], 0, -1)) do
+	if over <= 0 then
+		break
+	end
+
+	if member ~= KEYS[1] then
+		redis.call('DEL', member)
+		redis.call('ZREM', KEYS[2], member)
+		over = over - 1
+	end
+end
+
+if ttl < tonumber(ARGV[2]) then
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+end
+
+return redis.status_reply('OK')
+`)
+
+// StorageRedis is a challenge storage in Redis, so that a challenge issued by one node can be solved on any other.
+// Every challenge is stored under the prefixed key with the Redis TTL, so Redis expires it on its own.
+// The groups are stored as sorted sets, that live as long as their last member, and are trimmed by a script.
+// The script touches every challenge of the group, so the storage needs a single Redis server, not a Redis Cluster.
+type StorageRedis struct {
+	logger *zap.Logger
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewStorageRedis creates a new challenge storage in Redis, storing the challenges under the prefixed keys.
+func NewStorageRedis(logger *zap.Logger, client redis.UniversalClient, prefix string) *StorageRedis {
+	// Logging the call
+	logger.Debug("creating a new challenge storage in redis", zap.String("prefix", prefix))
+
+	return &StorageRedis{logger: logger, client: client, prefix: prefix}
+}
+
+// Add adds a challenge to the store for the ttl, replacing the challenge stored under the same key, if any.
+// The challenges, that have already expired, are not stored at all, as Redis would keep them forever without a TTL.
+func (s *StorageRedis) Add(ctx context.Context, key, value string, ttl time.Duration) error {
+	// Logging the call
+	s.logger.Debug("adding challenge to the store",
+		zap.String("key", key), zap.String("value", value), zap.Duration("ttl", ttl),
+	)
+
+	// Checking if the challenge has already expired
+	if ttl <= 0 {
+		return nil
+	}
+
+	// Setting the challenge with the TTL
+	err := s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("setting challenge in redis: %w", err)
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// AddToGroup adds a challenge to the store for the ttl, as Add does, and makes it a member of the group.
+// If the group then has more than limit members, the other ones expiring the soonest are deleted,
+// the new challenge is never deleted, as it is about to be given to the client.
+func (s *StorageRedis) AddToGroup(ctx context.Context, group, key, value string, ttl time.Duration, limit int) error {
+	// Logging the call
+	s.logger.Debug("adding challenge to the group in the store",
+		zap.String("group", group), zap.String("key", key), zap.String("value", value), zap.Duration("ttl", ttl),
+	)
+
+	// Checking if the challenge has already expired
+	if ttl <= 0 {
+		return nil
+	}
+
+	// Setting the challenge with the TTL and trimming the group at once
+	keys := []string{s.prefix + key, s.prefix + redisGroupPrefix + group}
+	expires := time.Now().Add(ttl).UnixMilli()
+
+	// Rounding the ttl up to a millisecond, as Redis does not accept a zero one
+	ttlMillis := ttl.Milliseconds()
+	if ttlMillis == 0 {
+		ttlMillis = 1
+	}
+
+	err := redisAddToGroup.Run(ctx, s.client, keys, value, ttlMillis, expires, limit).Err()
+	if err != nil {
+		return fmt.Errorf("adding challenge to the group in redis: %w", err)
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// Get returns the challenge stored under the key and reports whether it is in the store and has not expired yet.
+func (s *StorageRedis) Get(ctx context.Context, key string) (string, bool, error) {
+	// Logging the call
+	s.logger.Debug("getting challenge from the store", zap.String("key", key))
+
+	// Getting the challenge, a missing key is not an error
+	value, err := s.client.Get(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, fmt.Errorf("getting challenge from redis: %w", err)
+	}
+
+	// Returning the challenge, the result and nil as the error
+	return value, true, nil
+}
+
+// Delete deletes a challenge from the store.
+func (s *StorageRedis) Delete(ctx context.Context, key string) error {
+	// Logging the call
+	s.logger.Debug("deleting challenge from the store", zap.String("key", key))
+
+	// Deleting the challenge, a missing key is not an error
+	err := s.client.Del(ctx, s.prefix+key).Err()
+	if err != nil {
+		return fmt.Errorf("deleting challenge from redis: %w", err)
+	}
+
+	// Returning nil as the error
+	return nil
+}
+
+// Consume returns the challenge stored under the key and deletes it in one atomic step,
+// so that of the several nodes consuming the same challenge at once only one gets it.
+// It reports whether the challenge was in the store. It requires Redis 6.2 or newer.
+func (s *StorageRedis) Consume(ctx context.Context, key string) (string, bool, error) {
+	// Logging the call
+	s.logger.Debug("consuming challenge from the store", zap.String("key", key))
+
+	// Getting and deleting the challenge at once, a missing key is not an error
+	value, err := s.client.GetDel(ctx, s.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, fmt.Errorf("consuming challenge from redis: %w", err)
+	}
+
+	// Returning the challenge, the result and nil as the error
+	return value, true, nil
+}
@@ -0,0 +1,207 @@
+package challenges_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/storage/challenges"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/storetest"
+)
+
+// redisAddrEnv is the environment variable with the address of a local Redis to test against.
+// If it is not set, the tests run against an in-process Redis stand-in.
+const redisAddrEnv = "TEST_REDIS_ADDR"
+
+// newRedisStorage creates a challenge storage in Redis, isolated from the other tests by the key prefix,
+// and returns it along with the function moving the Redis clock forward.
+func newRedisStorage(t *testing.T) (*challenges.StorageRedis, func(time.Duration)) {
+	t.Helper()
+
+	// Use the local Redis, if there is one
+	if addr := os.Getenv(redisAddrEnv); addr != "" {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		t.Cleanup(func() { _ = client.Close() })
+		require.NoError(t, client.Ping(context.Background()).Err(), "redis should be reachable")
+
+		return challenges.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":"), time.Sleep
+	}
+
+	// Otherwise use the in-process stand-in, whose clock can be moved forward
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return challenges.NewStorageRedis(zap.NewNop(), client, "test:"), server.FastForward
+}
+
+func TestStorageRedis_Add(t *testing.T) {
+	t.Run("add challenge to the store", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// add a challenge to the store
+		err := store.Add(context.Background(), "key", "value", time.Minute)
+		assert.NoError(t, err, "there should be no error")
+
+		// check that the challenge is in the store
+		value, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be in the store")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+	})
+
+	t.Run("add challenge, that has already expired", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// add an expired challenge to the store
+		err := store.Add(context.Background(), "key", "value", 0)
+		assert.NoError(t, err, "there should be no error")
+
+		// check that the challenge is not in the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the expired challenge should not be stored")
+	})
+
+	t.Run("challenge expires with its ttl", func(t *testing.T) {
+		// create a new storage
+		store, fastForward := newRedisStorage(t)
+
+		// add a challenge to the store and let it expire
+		err := store.Add(context.Background(), "key", "value", 100*time.Millisecond)
+		assert.NoError(t, err, "there should be no error")
+		fastForward(200 * time.Millisecond)
+
+		// check that the challenge is not in the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the expired challenge should not be in the store")
+	})
+
+	t.Run("add challenge with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// add a challenge to the store
+		err := store.Add(ctx, "key", "value", time.Minute)
+		assert.Error(t, err, "there should be an error")
+	})
+}
+
+func TestStorageRedis_Get(t *testing.T) {
+	t.Run("get challenge with a non-existent key", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// get the challenge from the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should not be in the store")
+	})
+
+	t.Run("get challenge, when redis is unreachable", func(t *testing.T) {
+		// create a new storage, whose Redis is gone
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		t.Cleanup(func() { _ = client.Close() })
+		store := challenges.NewStorageRedis(zap.NewNop(), client, "test:")
+		server.Close()
+
+		// get the challenge from the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.Error(t, err, "there should be an error")
+		assert.False(t, ok, "the challenge should not be in the store")
+	})
+}
+
+func TestStorageRedis_Delete(t *testing.T) {
+	t.Run("delete challenge from the store", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// delete the challenge from the store
+		err := store.Delete(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+
+		// check that the challenge is not in the store
+		_, ok, err := store.Get(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should not be in the store")
+	})
+
+	t.Run("delete challenge with a non-existent key", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// delete the challenge from the store
+		err := store.Delete(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+	})
+}
+
+func TestStorageRedis_Consume(t *testing.T) {
+	t.Run("consume challenge from the store", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// consume the challenge
+		value, ok, err := store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, ok, "the challenge should be consumed")
+		assert.Equal(t, "value", value, "the stored challenge should be returned")
+
+		// check that the challenge is gone
+		_, ok, err = store.Consume(context.Background(), "key")
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, ok, "the challenge should be consumed only once")
+	})
+
+	t.Run("challenge is consumed only once by the concurrent callers", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+		require.NoError(t, store.Add(context.Background(), "key", "value", time.Minute))
+
+		// consume the challenge concurrently
+		var consumed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, ok, err := store.Consume(context.Background(), "key")
+				assert.NoError(t, err, "there should be no error")
+				if ok {
+					atomic.AddInt32(&consumed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// check that only one caller got the challenge
+		assert.Equal(t, int32(1), consumed, "the challenge should be consumed only once")
+	})
+}
+
+func TestStorageRedis_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (pow.ChallengeStore, func(time.Duration)) {
+		return newRedisStorage(t)
+	})
+}
@@ -0,0 +1,6 @@
+package spent
+
+import "errors"
+
+// ErrStorageFull is returned when there is no room for one more key, even after evicting the expired ones.
+var ErrStorageFull = errors.New("spent storage is full")
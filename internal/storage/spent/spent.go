@@ -0,0 +1,10 @@
+// Package spent contains the spent solutions storage implementations: in memory for a single node,
+// and in Redis for several nodes sharing the spent solutions.
+package spent
+
+const (
+	// BackendMemory keeps the spent solutions in memory, they are not shared between the nodes.
+	BackendMemory = "memory"
+	// BackendRedis keeps the spent solutions in Redis, shared between all the nodes using it.
+	BackendRedis = "redis"
+)
@@ -0,0 +1,134 @@
+package spent
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// StorageInMemory is a spent solutions storage in memory.
+// It is bounded by size and evicts the keys once they expire.
+// The keys are also kept in a heap ordered by the expiry time, so that the eviction does not scan the whole storage.
+type StorageInMemory struct {
+	logger *zap.Logger
+	mu     sync.Mutex
+	db     map[string]time.Time
+	byExp  expiryHeap
+	size   int
+}
+
+// NewStorageInMemory creates a new spent solutions storage in memory, holding at most size keys.
+func NewStorageInMemory(logger *zap.Logger, size int) *StorageInMemory {
+	// Logging the call
+	logger.Debug("creating a new spent storage in memory", zap.Int("size", size))
+
+	return &StorageInMemory{logger: logger, db: make(map[string]time.Time), size: size}
+}
+
+// Spend marks the key as spent until the expiry time and reports whether it was not spent before.
+// It fails closed with ErrStorageFull, if there is no room for the key, as accepting it would allow reusing it.
+func (s *StorageInMemory) Spend(ctx context.Context, key string, expires time.Time) (bool, error) {
+	// Logging the call
+	s.logger.Debug("spending key", zap.String("key", key), zap.Time("expires", expires))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Evicting the expired keys
+	s.evict()
+
+	// Checking if the key is already spent
+	if _, ok := s.db[key]; ok {
+		return false, nil
+	}
+
+	// Checking if there is room for the key
+	if len(s.db) >= s.size {
+		return false, ErrStorageFull
+	}
+
+	// Marking the key as spent
+	s.db[key] = expires
+	heap.Push(&s.byExp, expiryEntry{key: key, expires: expires})
+
+	// Returning the result and nil as the error
+	return true, nil
+}
+
+// IsSpent reports whether the key is spent and has not expired yet.
+func (s *StorageInMemory) IsSpent(ctx context.Context, key string) (bool, error) {
+	// Logging the call
+	s.logger.Debug("checking if key is spent", zap.String("key", key))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Checking if the key is in the db and has not expired
+	expires, ok := s.db[key]
+
+	// Returning the result and nil as the error
+	return ok && time.Now().Before(expires), nil
+}
+
+// Len returns the number of the keys in the storage, including the expired ones, that were not evicted yet.
+func (s *StorageInMemory) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.db)
+}
+
+// evict deletes the expired keys. It must be called with the lock held.
+func (s *StorageInMemory) evict() {
+	now := time.Now()
+
+	// The earliest expiring key is always on top of the heap
+	for s.byExp.Len() > 0 && !now.Before(s.byExp[0].expires) {
+		entry := heap.Pop(&s.byExp).(expiryEntry)
+		delete(s.db, entry.key)
+	}
+}
+
+// expiryEntry is a key along with its expiry time.
+type expiryEntry struct {
+	key     string
+	expires time.Time
+}
+
+// expiryHeap is a min-heap of the keys ordered by the expiry time, it implements heap.Interface.
+type expiryHeap []expiryEntry
+
+// Len returns the number of the entries in the heap.
+func (h expiryHeap) Len() int { return len(h) }
+
+// Less reports whether the entry i expires before the entry j.
+func (h expiryHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+
+// Swap swaps the entries i and j.
+func (h expiryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Push adds the entry to the heap.
+func (h *expiryHeap) Push(x any) { *h = append(*h, x.(expiryEntry)) }
+
+// Pop removes the last entry from the heap.
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+
+	return entry
+}
@@ -0,0 +1,161 @@
+package spent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/storage/spent"
+)
+
+func TestStorageInMemory_Spend(t *testing.T) {
+	t.Run("spend key once", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// spend the key
+		fresh, err := store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the key was not spent before
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, fresh, "the key should not be spent before")
+
+		// spend the key again
+		fresh, err = store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the key was spent before
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, fresh, "the key should be spent before")
+	})
+
+	t.Run("spend key again after it expires", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// spend the key, that has already expired
+		_, err := store.Spend(context.Background(), "key", time.Now().Add(-time.Second))
+		assert.NoError(t, err, "there should be no error")
+
+		// spend the key again
+		fresh, err := store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the expired key was evicted
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, fresh, "the expired key should be evicted")
+	})
+
+	t.Run("storage is full", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 1)
+
+		// spend the key, filling the storage
+		_, err := store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// spend another key
+		fresh, err := store.Spend(context.Background(), "another-key", time.Now().Add(time.Minute))
+
+		// check that the storage fails closed
+		assert.ErrorIs(t, err, spent.ErrStorageFull, "there should be ErrStorageFull")
+		assert.False(t, fresh, "the key should not be accepted")
+	})
+
+	t.Run("storage is full of expired keys", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 2)
+
+		// fill the storage with the keys, that have already expired
+		_, err := store.Spend(context.Background(), "key", time.Now().Add(-time.Second))
+		assert.NoError(t, err, "there should be no error")
+		_, err = store.Spend(context.Background(), "another-key", time.Now().Add(-time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// spend one more key
+		fresh, err := store.Spend(context.Background(), "one-more-key", time.Now().Add(time.Minute))
+
+		// check that the expired keys were evicted
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, fresh, "the key should be accepted")
+		assert.Equal(t, 1, store.Len(), "only the unexpired key should be left")
+	})
+
+	t.Run("spend key with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// spend the key
+		_, err := store.Spend(ctx, "key", time.Now().Add(time.Minute))
+
+		// check that there is an error
+		assert.Error(t, err, "there should be an error")
+		assert.Zero(t, store.Len(), "the key should not be spent")
+	})
+}
+
+func TestStorageInMemory_IsSpent(t *testing.T) {
+	t.Run("key is not spent", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// check the key
+		isSpent, err := store.IsSpent(context.Background(), "key")
+
+		// check that the key is not spent
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, isSpent, "the key should not be spent")
+	})
+
+	t.Run("key is spent", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// spend the key
+		_, err := store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// check the key
+		isSpent, err := store.IsSpent(context.Background(), "key")
+
+		// check that the key is spent
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, isSpent, "the key should be spent")
+	})
+
+	t.Run("key has expired", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// spend the key, that has already expired
+		_, err := store.Spend(context.Background(), "key", time.Now().Add(-time.Second))
+		assert.NoError(t, err, "there should be no error")
+
+		// check the key
+		isSpent, err := store.IsSpent(context.Background(), "key")
+
+		// check that the expired key is not spent
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, isSpent, "the expired key should not be spent")
+	})
+
+	t.Run("check key with a canceled context", func(t *testing.T) {
+		// create a new storage
+		store := spent.NewStorageInMemory(zap.NewNop(), 10)
+
+		// create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// check the key
+		_, err := store.IsSpent(ctx, "key")
+
+		// check that there is an error
+		assert.Error(t, err, "there should be an error")
+	})
+}
@@ -0,0 +1,68 @@
+package spent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// StorageRedis is a spent solutions storage in Redis, so that a solution spent on one node is spent on every other.
+// Every key is stored under the prefixed key with the Redis TTL, so Redis forgets it on its own, once it expires.
+type StorageRedis struct {
+	logger *zap.Logger
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewStorageRedis creates a new spent solutions storage in Redis, storing the keys under the prefixed ones.
+func NewStorageRedis(logger *zap.Logger, client redis.UniversalClient, prefix string) *StorageRedis {
+	// Logging the call
+	logger.Debug("creating a new spent storage in redis", zap.String("prefix", prefix))
+
+	return &StorageRedis{logger: logger, client: client, prefix: prefix}
+}
+
+// Spend marks the key as spent until the expiry time and reports whether it was not spent before.
+// The keys, that have already expired, are not stored at all, as Redis would keep them forever without a TTL.
+func (s *StorageRedis) Spend(ctx context.Context, key string, expires time.Time) (bool, error) {
+	// Logging the call
+	s.logger.Debug("spending key", zap.String("key", key), zap.Time("expires", expires))
+
+	// Checking if the key has already expired
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return true, nil
+	}
+
+	// Rounding the ttl up to a millisecond, as Redis does not accept a zero one
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+
+	// Marking the key as spent, unless it already is, at once
+	fresh, err := s.client.SetNX(ctx, s.prefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("spending key in redis: %w", err)
+	}
+
+	// Returning the result and nil as the error
+	return fresh, nil
+}
+
+// IsSpent reports whether the key is spent and has not expired yet.
+func (s *StorageRedis) IsSpent(ctx context.Context, key string) (bool, error) {
+	// Logging the call
+	s.logger.Debug("checking if key is spent", zap.String("key", key))
+
+	// Checking if the key exists, Redis has already forgotten the expired ones
+	n, err := s.client.Exists(ctx, s.prefix+key).Result()
+	if err != nil {
+		return false, fmt.Errorf("checking if key is spent in redis: %w", err)
+	}
+
+	// Returning the result and nil as the error
+	return n > 0, nil
+}
@@ -0,0 +1,123 @@
+package spent_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/storage/spent"
+)
+
+// redisAddrEnv is the environment variable with the address of a local Redis to test against.
+// If it is not set, the tests run against an in-process Redis stand-in.
+const redisAddrEnv = "TEST_REDIS_ADDR"
+
+// newRedisClient returns a client of the local Redis, if there is one, or of an in-process stand-in,
+// along with the function moving the Redis clock forward.
+func newRedisClient(t *testing.T) (redis.UniversalClient, func(time.Duration)) {
+	t.Helper()
+
+	// Use the local Redis, if there is one
+	if addr := os.Getenv(redisAddrEnv); addr != "" {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		t.Cleanup(func() { _ = client.Close() })
+		require.NoError(t, client.Ping(context.Background()).Err(), "redis should be reachable")
+
+		return client, time.Sleep
+	}
+
+	// Otherwise use the in-process stand-in, whose clock can be moved forward
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client, server.FastForward
+}
+
+func TestStorageRedis_Spend(t *testing.T) {
+	t.Run("spend key once", func(t *testing.T) {
+		// create a new storage
+		client, _ := newRedisClient(t)
+		store := spent.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+
+		// spend the key
+		fresh, err := store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the key was not spent before
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, fresh, "the key should not be spent before")
+
+		// spend the key again
+		fresh, err = store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the key was spent before
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, fresh, "the key should be spent before")
+	})
+
+	t.Run("key spent on one node is spent on every node", func(t *testing.T) {
+		// create two storages of the same Redis, as two nodes would
+		client, _ := newRedisClient(t)
+		first := spent.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+		second := spent.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+
+		// spend the key on one node
+		fresh, err := first.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, fresh, "the key should not be spent before")
+
+		// spend the key on the other one
+		fresh, err = second.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the replay is caught
+		assert.NoError(t, err, "there should be no error")
+		assert.False(t, fresh, "the key should be spent on every node")
+	})
+
+	t.Run("spend key again after it expires", func(t *testing.T) {
+		// create a new storage
+		client, fastForward := newRedisClient(t)
+		store := spent.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+
+		// spend the key, that expires soon
+		_, err := store.Spend(context.Background(), "key", time.Now().Add(50*time.Millisecond))
+		assert.NoError(t, err, "there should be no error")
+
+		// wait for the key to expire
+		fastForward(100 * time.Millisecond)
+
+		// spend the key again
+		fresh, err := store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+
+		// check that the expired key was forgotten
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, fresh, "the expired key should be forgotten")
+	})
+}
+
+func TestStorageRedis_IsSpent(t *testing.T) {
+	// create a new storage
+	client, _ := newRedisClient(t)
+	store := spent.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+
+	// check that the key is not spent yet
+	isSpent, err := store.IsSpent(context.Background(), "key")
+	assert.NoError(t, err, "there should be no error")
+	assert.False(t, isSpent, "the key should not be spent yet")
+
+	// spend the key
+	_, err = store.Spend(context.Background(), "key", time.Now().Add(time.Minute))
+	assert.NoError(t, err, "there should be no error")
+
+	// check that the key is spent
+	isSpent, err = store.IsSpent(context.Background(), "key")
+	assert.NoError(t, err, "there should be no error")
+	assert.True(t, isSpent, "the key should be spent")
+}
@@ -0,0 +1,6 @@
+package tokens
+
+import "errors"
+
+// ErrStorageFull is returned when there is no room for one more token, even after evicting the expired ones.
+var ErrStorageFull = errors.New("token storage is full")
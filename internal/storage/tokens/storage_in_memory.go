@@ -0,0 +1,185 @@
+package tokens
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// token is the state of a token: the number of its uses and whether it was revoked,
+// along with its expiry time and its position in the heap.
+type token struct {
+	id      string
+	uses    int
+	revoked bool
+	expires time.Time
+	index   int
+}
+
+// StorageInMemory is an access token storage in memory.
+// It is bounded by size and only evicts the expired tokens once it is full,
+// as the tokens are short-lived and the eviction is rarely needed.
+// The tokens are also kept in a heap ordered by the expiry time, so that the eviction does not scan the whole storage.
+type StorageInMemory struct {
+	logger *zap.Logger
+	mu     sync.Mutex
+	db     map[string]*token
+	byExp  expiryHeap
+	size   int
+}
+
+// NewStorageInMemory creates a new access token storage in memory, holding at most size tokens.
+func NewStorageInMemory(logger *zap.Logger, size int) *StorageInMemory {
+	// Logging the call
+	logger.Debug("creating a new token storage in memory", zap.Int("size", size))
+
+	return &StorageInMemory{logger: logger, db: make(map[string]*token), size: size}
+}
+
+// Use counts a use of the token and returns the number of its uses so far, including this one,
+// and whether the token was revoked.
+// It fails closed with ErrStorageFull, if there is no room for the token, as its uses could not be counted.
+func (s *StorageInMemory) Use(ctx context.Context, id string, expires time.Time) (int, bool, error) {
+	// Logging the call
+	s.logger.Debug("using token", zap.String("id", id))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return 0, false, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Getting the token, or adding it on its first use
+	t, err := s.get(id, expires)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// Counting the use, the revoked tokens are not used anymore
+	if !t.revoked {
+		t.uses++
+	}
+
+	// Returning the result and nil as the error
+	return t.uses, t.revoked, nil
+}
+
+// Revoke revokes the token until the expiry time.
+func (s *StorageInMemory) Revoke(ctx context.Context, id string, expires time.Time) error {
+	// Logging the call
+	s.logger.Debug("revoking token", zap.String("id", id), zap.Time("expires", expires))
+
+	// Checking if the context is canceled
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Getting the token, or adding it, if it was not used yet
+	t, err := s.get(id, expires)
+	if err != nil {
+		return err
+	}
+
+	// Revoking the token, for as long as it could be valid
+	t.revoked = true
+	if expires.After(t.expires) {
+		t.expires = expires
+		heap.Fix(&s.byExp, t.index)
+	}
+
+	return nil
+}
+
+// Len returns the number of the tokens in the storage, including the expired ones, that were not evicted yet.
+func (s *StorageInMemory) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.db)
+}
+
+// get returns the token, adding it, if it is not in the storage or has expired. It must be called with the lock held.
+func (s *StorageInMemory) get(id string, expires time.Time) (*token, error) {
+	// Checking if the token is in the db and has not expired
+	t, ok := s.db[id]
+	if ok && time.Now().Before(t.expires) {
+		return t, nil
+	}
+
+	// Starting the expired token over
+	if ok {
+		*t = token{id: id, expires: expires, index: t.index}
+		heap.Fix(&s.byExp, t.index)
+
+		return t, nil
+	}
+
+	// Evicting the expired tokens, if there is no room for the token
+	if len(s.db) >= s.size {
+		s.evict()
+
+		if len(s.db) >= s.size {
+			return nil, ErrStorageFull
+		}
+	}
+
+	// Adding the token
+	t = &token{id: id, expires: expires}
+	s.db[id] = t
+	heap.Push(&s.byExp, t)
+
+	return t, nil
+}
+
+// evict deletes the expired tokens. It must be called with the lock held.
+func (s *StorageInMemory) evict() {
+	now := time.Now()
+
+	// The earliest expiring token is always on top of the heap
+	for s.byExp.Len() > 0 && !now.Before(s.byExp[0].expires) {
+		t := heap.Pop(&s.byExp).(*token)
+		delete(s.db, t.id)
+	}
+}
+
+// expiryHeap is a min-heap of the tokens ordered by the expiry time, it implements heap.Interface.
+// The tokens know their index, so that they could be fixed when revoked or started over.
+type expiryHeap []*token
+
+// Len returns the number of the tokens in the heap.
+func (h expiryHeap) Len() int { return len(h) }
+
+// Less reports whether the token i expires before the token j.
+func (h expiryHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+
+// Swap swaps the tokens i and j.
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+// Push adds the token to the heap.
+func (h *expiryHeap) Push(x any) {
+	t := x.(*token)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+// Pop removes the last token from the heap.
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+
+	return t
+}
@@ -0,0 +1,162 @@
+package tokens_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/storage/tokens"
+)
+
+func TestStorageInMemory_Use(t *testing.T) {
+	t.Run("count uses", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 10)
+
+		// use the token twice
+		for want := 1; want <= 2; want++ {
+			uses, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+			// check the number of uses
+			assert.NoError(t, err, "there should be no error")
+			assert.False(t, revoked, "the token should not be revoked")
+			assert.Equal(t, want, uses, "the uses should be counted")
+		}
+	})
+
+	t.Run("count uses again after the token expires", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 10)
+
+		// use the token, that has already expired
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(-time.Second))
+		assert.NoError(t, err, "there should be no error")
+
+		// use the token again
+		uses, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the expired token was forgotten
+		assert.NoError(t, err, "there should be no error")
+		assert.Equal(t, 1, uses, "the expired token should be forgotten")
+	})
+
+	t.Run("storage is full", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 1)
+
+		// use the token, filling the storage
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// use another token
+		_, _, err = store.Use(context.Background(), "another-id", time.Now().Add(time.Minute))
+
+		// check that the storage fails closed
+		assert.ErrorIs(t, err, tokens.ErrStorageFull, "the storage should be full")
+	})
+
+	t.Run("expired tokens make room", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 1)
+
+		// use the token, that expires right away
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Millisecond))
+		assert.NoError(t, err, "there should be no error")
+
+		// wait for the token to expire
+		time.Sleep(5 * time.Millisecond)
+
+		// use another token
+		_, _, err = store.Use(context.Background(), "another-id", time.Now().Add(time.Minute))
+
+		// check that the expired token was evicted
+		assert.NoError(t, err, "there should be no error")
+		assert.Equal(t, 1, store.Len(), "the expired token should be evicted")
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 10)
+
+		// cancel the context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// use the token
+		_, _, err := store.Use(ctx, "id", time.Now().Add(time.Minute))
+
+		// check the error
+		assert.ErrorIs(t, err, context.Canceled, "the context should be canceled")
+	})
+}
+
+func TestStorageInMemory_Revoke(t *testing.T) {
+	t.Run("revoke used token", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 10)
+
+		// use the token
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// revoke the token
+		err = store.Revoke(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// use the token again
+		uses, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the token is revoked and the use is not counted
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, revoked, "the token should be revoked")
+		assert.Equal(t, 1, uses, "the use of a revoked token should not be counted")
+	})
+
+	t.Run("revoke unused token", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 10)
+
+		// revoke the token before it is used
+		err := store.Revoke(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// use the token
+		_, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the token is revoked
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, revoked, "the token should be revoked")
+	})
+
+	t.Run("revoked token is not evicted before the revocation expires", func(t *testing.T) {
+		// create a new storage
+		store := tokens.NewStorageInMemory(zap.NewNop(), 2)
+
+		// use the tokens, the first one expiring right away
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Millisecond))
+		assert.NoError(t, err, "there should be no error")
+		_, _, err = store.Use(context.Background(), "another-id", time.Now().Add(time.Millisecond))
+		assert.NoError(t, err, "there should be no error")
+
+		// revoke the first token for longer
+		err = store.Revoke(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// wait for the other token to expire
+		time.Sleep(5 * time.Millisecond)
+
+		// use a new token, making room for it
+		_, _, err = store.Use(context.Background(), "new-id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// check that only the expired token was evicted, and the revoked one is still revoked
+		assert.Equal(t, 2, store.Len(), "only the expired token should be evicted")
+
+		_, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, revoked, "the token should still be revoked")
+	})
+}
@@ -0,0 +1,121 @@
+package tokens
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisUse is the script counting a use of a token, so that the count and the revocation check are atomic.
+// Every token is a hash of its uses and the revocation flag, that lives as long as the token.
+// KEYS[1] is the token key, ARGV[1] is the ttl of the token in milliseconds.
+// It returns the number of the uses and 1, if the token was revoked, or 0 otherwise.
+var redisUse = redis.NewScript(`
+local revoked = redis.call('HEXISTS', KEYS[1], 'revoked')
+
+local uses
+if revoked == 1 then
+	uses = tonumber(redis.call('HGET', KEYS[1], 'uses') or 0)
+else
+	uses = redis.call('HINCRBY', KEYS[1], 'uses', 1)
+end
+
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+
+return {uses, revoked}
+`)
+
+// redisRevoke is the script revoking a token until it expires.
+// KEYS[1] is the token key, ARGV[1] is the ttl of the revocation in milliseconds.
+var redisRevoke = redis.NewScript(`
+redis.call('HSET', KEYS[1], 'revoked', 1)
+
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+
+return redis.status_reply('OK')
+`)
+
+// StorageRedis is an access token storage in Redis, so that the uses of a token are counted, and its revocation
+// is honoured, on all the nodes together. Every token is stored under the prefixed token ID with the Redis TTL,
+// so Redis forgets it on its own, once it expires.
+type StorageRedis struct {
+	logger *zap.Logger
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewStorageRedis creates a new access token storage in Redis, storing the tokens under the prefixed IDs.
+func NewStorageRedis(logger *zap.Logger, client redis.UniversalClient, prefix string) *StorageRedis {
+	// Logging the call
+	logger.Debug("creating a new token storage in redis", zap.String("prefix", prefix))
+
+	return &StorageRedis{logger: logger, client: client, prefix: prefix}
+}
+
+// Use counts a use of the token and returns the number of its uses so far, including this one,
+// and whether the token was revoked.
+// The tokens, that have already expired, are not stored at all, as Redis would keep them forever without a TTL.
+func (s *StorageRedis) Use(ctx context.Context, id string, expires time.Time) (int, bool, error) {
+	// Logging the call
+	s.logger.Debug("using token", zap.String("id", id))
+
+	// Checking if the token has already expired
+	ttl, ok := redisTTL(expires)
+	if !ok {
+		return 1, false, nil
+	}
+
+	// Counting the use and checking the revocation at once
+	result, err := redisUse.Run(ctx, s.client, []string{s.prefix + id}, ttl).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("using token in redis: %w", err)
+	}
+
+	if len(result) != 2 {
+		return 0, false, fmt.Errorf("using token in redis: unexpected reply %v", result)
+	}
+
+	// Returning the result and nil as the error
+	return int(result[0]), result[1] == 1, nil
+}
+
+// Revoke revokes the token until the expiry time.
+func (s *StorageRedis) Revoke(ctx context.Context, id string, expires time.Time) error {
+	// Logging the call
+	s.logger.Debug("revoking token", zap.String("id", id), zap.Time("expires", expires))
+
+	// Checking if the token has already expired, it could not be used anyway
+	ttl, ok := redisTTL(expires)
+	if !ok {
+		return nil
+	}
+
+	// Revoking the token, for as long as it could be valid
+	if err := redisRevoke.Run(ctx, s.client, []string{s.prefix + id}, ttl).Err(); err != nil {
+		return fmt.Errorf("revoking token in redis: %w", err)
+	}
+
+	return nil
+}
+
+// redisTTL returns the time left until the expiry time in milliseconds, at least one, as Redis does not accept
+// a zero one. It reports false, if the time has already passed.
+func redisTTL(expires time.Time) (int64, bool) {
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return 0, false
+	}
+
+	if millis := ttl.Milliseconds(); millis > 0 {
+		return millis, true
+	}
+
+	return 1, true
+}
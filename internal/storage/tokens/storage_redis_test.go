@@ -0,0 +1,196 @@
+package tokens_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/storage/tokens"
+)
+
+// redisAddrEnv is the environment variable with the address of a local Redis to test against.
+// If it is not set, the tests run against an in-process Redis stand-in.
+const redisAddrEnv = "TEST_REDIS_ADDR"
+
+// newRedisClient returns a client of the local Redis, if there is one, or of an in-process stand-in,
+// along with the function moving the Redis clock forward.
+func newRedisClient(t *testing.T) (redis.UniversalClient, func(time.Duration)) {
+	t.Helper()
+
+	// Use the local Redis, if there is one
+	if addr := os.Getenv(redisAddrEnv); addr != "" {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		t.Cleanup(func() { _ = client.Close() })
+		require.NoError(t, client.Ping(context.Background()).Err(), "redis should be reachable")
+
+		return client, time.Sleep
+	}
+
+	// Otherwise use the in-process stand-in, whose clock can be moved forward
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client, server.FastForward
+}
+
+// newRedisStorage creates a token storage in Redis, isolated from the other tests by the key prefix,
+// and returns it along with the function moving the Redis clock forward.
+func newRedisStorage(t *testing.T) (*tokens.StorageRedis, func(time.Duration)) {
+	t.Helper()
+
+	client, fastForward := newRedisClient(t)
+
+	return tokens.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":"), fastForward
+}
+
+func TestStorageRedis_Use(t *testing.T) {
+	t.Run("count uses", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// use the token twice
+		for want := 1; want <= 2; want++ {
+			uses, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+			// check the number of uses
+			assert.NoError(t, err, "there should be no error")
+			assert.False(t, revoked, "the token should not be revoked")
+			assert.Equal(t, want, uses, "the uses should be counted")
+		}
+	})
+
+	t.Run("uses are shared between the nodes", func(t *testing.T) {
+		// create two storages of the same Redis, as two nodes would
+		client, _ := newRedisClient(t)
+		first := tokens.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+		second := tokens.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+
+		// use the token on each of them
+		_, _, err := first.Use(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		uses, _, err := second.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the uses are counted together
+		assert.NoError(t, err, "there should be no error")
+		assert.Equal(t, 2, uses, "the uses on both nodes should be counted")
+	})
+
+	t.Run("count uses again after the token expires", func(t *testing.T) {
+		// create a new storage
+		store, fastForward := newRedisStorage(t)
+
+		// use the token, that expires soon
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(50*time.Millisecond))
+		assert.NoError(t, err, "there should be no error")
+
+		// wait for the token to expire
+		fastForward(100 * time.Millisecond)
+
+		// use the token again
+		uses, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the expired token was forgotten
+		assert.NoError(t, err, "there should be no error")
+		assert.Equal(t, 1, uses, "the expired token should be forgotten")
+	})
+
+	t.Run("expired token is not stored", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// use the token, that has already expired, twice
+		for i := 0; i < 2; i++ {
+			uses, revoked, err := store.Use(context.Background(), "id", time.Now().Add(-time.Second))
+
+			// check that the use is not remembered
+			assert.NoError(t, err, "there should be no error")
+			assert.False(t, revoked, "the token should not be revoked")
+			assert.Equal(t, 1, uses, "the expired token should not be stored")
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// cancel the context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// use the token
+		_, _, err := store.Use(ctx, "id", time.Now().Add(time.Minute))
+
+		// check the error
+		assert.ErrorIs(t, err, context.Canceled, "the context should be canceled")
+	})
+}
+
+func TestStorageRedis_Revoke(t *testing.T) {
+	t.Run("revoke used token", func(t *testing.T) {
+		// create a new storage
+		store, _ := newRedisStorage(t)
+
+		// use the token
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// revoke the token
+		err = store.Revoke(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// use the token again
+		uses, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the token is revoked and the use is not counted
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, revoked, "the token should be revoked")
+		assert.Equal(t, 1, uses, "the use of a revoked token should not be counted")
+	})
+
+	t.Run("revocation is shared between the nodes", func(t *testing.T) {
+		// create two storages of the same Redis, as two nodes would
+		client, _ := newRedisClient(t)
+		first := tokens.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+		second := tokens.NewStorageRedis(zap.NewNop(), client, "test:"+t.Name()+":")
+
+		// revoke the token on one node
+		err := first.Revoke(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// use the token on the other one
+		_, revoked, err := second.Use(context.Background(), "id", time.Now().Add(time.Minute))
+
+		// check that the token is revoked
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, revoked, "the token should be revoked on every node")
+	})
+
+	t.Run("revoked token is kept until the revocation expires", func(t *testing.T) {
+		// create a new storage
+		store, fastForward := newRedisStorage(t)
+
+		// use the token, that expires soon, and revoke it for longer
+		_, _, err := store.Use(context.Background(), "id", time.Now().Add(50*time.Millisecond))
+		assert.NoError(t, err, "there should be no error")
+
+		err = store.Revoke(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+
+		// wait for the token to expire
+		fastForward(100 * time.Millisecond)
+
+		// check that the token is still revoked
+		_, revoked, err := store.Use(context.Background(), "id", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "there should be no error")
+		assert.True(t, revoked, "the token should still be revoked")
+	})
+}
@@ -0,0 +1,10 @@
+// Package tokens contains the access token storage implementations: in memory for a single node,
+// and in Redis for several nodes sharing the uses and the revocations of the tokens.
+package tokens
+
+const (
+	// BackendMemory keeps the tokens in memory, their uses and revocations are not shared between the nodes.
+	BackendMemory = "memory"
+	// BackendRedis keeps the tokens in Redis, shared between all the nodes using it.
+	BackendRedis = "redis"
+)
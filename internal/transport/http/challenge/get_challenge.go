@@ -0,0 +1,93 @@
+package challenge
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+// GetChallenge handles the request for a challenge for a route, given by the resource and method query parameters.
+// The challenge is the same the route would return in the X-Hashcash header, so its solution is sent the same way.
+func (h *Handler) GetChallenge(c *gin.Context) {
+	// Logging the call
+	h.logger.Debug("handling the request for getting a challenge")
+
+	// Get the route from the query parameters.
+	resource := c.Query(ResourceParam)
+	if !strings.HasPrefix(resource, "/") {
+		// The route is missing or is not a path.
+		problem.Abort(c, problem.New(problem.TypeBadRequest, "resource must be a path, starting with /"))
+
+		// Exit the function.
+		return
+	}
+
+	method := strings.ToUpper(c.DefaultQuery(MethodParam, http.MethodGet))
+
+	// Call the issuer.
+	challenge, err := h.issuer.IssueChallenge(c.Request.Context(), h.issuer.ClientID(c), method, resource)
+	if err != nil {
+		// The route does not require a proof of work.
+		if errors.Is(err, proofer.ErrRouteExempt) {
+			problem.Abort(c, problem.New(problem.TypeBadRequest, "resource does not require proof of work"))
+
+			// Exit the function.
+			return
+		}
+
+		// Log the actual error.
+		h.logger.Error("failed to issue challenge", zap.Error(err))
+
+		// Return a generic error to the client.
+		problem.Abort(c, problem.New(problem.TypeInternal, "failed to issue challenge"))
+
+		// Exit the function.
+		return
+	}
+
+	// Read the parameters of the challenge.
+	metadata, err := h.describer.Metadata(challenge)
+	if err != nil {
+		// Log the actual error.
+		h.logger.Error("failed to read challenge metadata", zap.Error(err))
+
+		// Return a generic error to the client.
+		problem.Abort(c, problem.New(problem.TypeInternal, "failed to issue challenge"))
+
+		// Exit the function.
+		return
+	}
+
+	// Return the challenge to the client.
+	c.JSON(http.StatusOK, newChallenge(challenge, metadata, method, resource))
+}
+
+// newChallenge builds the challenge document from the challenge string and its metadata.
+func newChallenge(challenge string, metadata pow.Metadata, method, resource string) model.Challenge {
+	doc := model.Challenge{
+		Challenge:  challenge,
+		ID:         metadata.ID,
+		Scheme:     metadata.Scheme,
+		Algorithm:  string(metadata.Algorithm),
+		Difficulty: metadata.Difficulty,
+		Method:     method,
+		Resource:   resource,
+		Header:     proofer.ChallengeHeader,
+	}
+
+	// Tell the expiry time, if the challenge expires
+	if !metadata.Expires.IsZero() {
+		expires := metadata.Expires.UTC()
+		doc.Expires = &expires
+	}
+
+	return doc
+}
@@ -0,0 +1,130 @@
+package challenge_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/challenge"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+// fakeIssuer issues a fixed challenge and remembers the client and the route it was asked for.
+type fakeIssuer struct {
+	challenge string
+	err       error
+	clientID  string
+	method    string
+	path      string
+}
+
+// IssueChallenge returns the fixed challenge and remembers the client and the route.
+func (i *fakeIssuer) IssueChallenge(_ context.Context, clientID, method, path string) (string, error) {
+	i.clientID, i.method, i.path = clientID, method, path
+
+	return i.challenge, i.err
+}
+
+// ClientID identifies the client by the API key header.
+func (i *fakeIssuer) ClientID(c *gin.Context) string {
+	return c.GetHeader("X-API-Key")
+}
+
+// fakeDescriber returns fixed metadata.
+type fakeDescriber struct {
+	metadata pow.Metadata
+	err      error
+}
+
+// Metadata returns the fixed metadata.
+func (d fakeDescriber) Metadata(string) (pow.Metadata, error) {
+	return d.metadata, d.err
+}
+
+func TestHandler_GetChallenge(t *testing.T) {
+	expires := time.Date(2023, 5, 20, 12, 0, 0, 0, time.UTC)
+	metadata := pow.Metadata{ID: "salt", Scheme: pow.SchemeHashcash, Difficulty: 20, Algorithm: "sha1", Expires: expires}
+
+	testCases := []struct {
+		name           string
+		query          string
+		issuerError    error
+		describerError error
+		expectedCode   int
+		expectedMethod string
+	}{
+		{name: "Success", query: "?resource=/v1/quotes/random", expectedCode: http.StatusOK, expectedMethod: http.MethodGet},
+		{name: "Custom method", query: "?resource=/v1/quotes/random&method=post", expectedCode: http.StatusOK, expectedMethod: http.MethodPost},
+		{name: "Missing resource", query: "", expectedCode: http.StatusBadRequest},
+		{name: "Resource is not a path", query: "?resource=quotes", expectedCode: http.StatusBadRequest},
+		{name: "Exempt resource", query: "?resource=/v1/health", issuerError: proofer.ErrRouteExempt, expectedCode: http.StatusBadRequest},
+		{name: "Issuer error", query: "?resource=/v1/quotes/random", issuerError: errors.New("issuer error"), expectedCode: http.StatusInternalServerError},
+		{name: "Describer error", query: "?resource=/v1/quotes/random", describerError: errors.New("describer error"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Creating a handler
+			issuer := &fakeIssuer{challenge: "1:20:230520120000:client::salt:0", err: tc.issuerError}
+			handler := challenge.NewHandler(zap.NewNop(), issuer, fakeDescriber{metadata: metadata, err: tc.describerError})
+
+			// Setting the gin to test mode
+			gin.SetMode(gin.TestMode)
+
+			// Registering the endpoint handler to the router
+			r := gin.New()
+			r.GET(challenge.ResourceEndpoint, handler.GetChallenge)
+
+			// Serving the request
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, challenge.ResourceEndpoint+tc.query, nil)
+			req.Header.Set("X-API-Key", "key-1")
+			r.ServeHTTP(w, req)
+
+			// Assertions
+			assert.Equal(t, tc.expectedCode, w.Code)
+
+			// Expect the errors to be problems of the same status
+			if tc.expectedCode != http.StatusOK {
+				var p problem.Problem
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+				assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+				assert.Equal(t, tc.expectedCode, p.Status)
+
+				return
+			}
+
+			// Expect the challenge to be issued for the client, as the issuer identifies it, and the route
+			assert.Equal(t, "key-1", issuer.clientID)
+			assert.Equal(t, tc.expectedMethod, issuer.method)
+			assert.Equal(t, "/v1/quotes/random", issuer.path)
+
+			// Expect the challenge document
+			var doc model.Challenge
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+
+			assert.Equal(t, "1:20:230520120000:client::salt:0", doc.Challenge)
+			assert.Equal(t, "salt", doc.ID)
+			assert.Equal(t, pow.SchemeHashcash, doc.Scheme)
+			assert.Equal(t, "sha1", doc.Algorithm)
+			assert.Equal(t, 20, doc.Difficulty)
+			assert.Equal(t, tc.expectedMethod, doc.Method)
+			assert.Equal(t, "/v1/quotes/random", doc.Resource)
+			assert.Equal(t, proofer.ChallengeHeader, doc.Header)
+			require.NotNil(t, doc.Expires)
+			assert.True(t, expires.Equal(*doc.Expires))
+		})
+	}
+}
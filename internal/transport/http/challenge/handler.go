@@ -0,0 +1,52 @@
+// Package challenge contains the http transport for issuing the proof-of-work challenges
+// separately from the protected routes, so that the clients could solve them before their first request.
+package challenge
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+// Issuer is the port for issuing the challenges for the routes, e.g. the proofer middleware.
+// It also identifies the client of the request the same way, as when it checks the solutions.
+type Issuer interface {
+	IssueChallenge(ctx context.Context, clientID, method, path string) (string, error)
+	ClientID(c *gin.Context) string
+}
+
+// Describer is the port for reading the parameters of the challenges, e.g. the PoW service.
+type Describer interface {
+	Metadata(challenge string) (pow.Metadata, error)
+}
+
+// Handler is the HTTP handler for the /challenge resource.
+type Handler struct {
+	logger    *zap.Logger
+	issuer    Issuer
+	describer Describer
+}
+
+const (
+	// ResourceEndpoint is the endpoint for the /challenge resource.
+	ResourceEndpoint = "/challenge"
+	// ResourceParam is the name of the query parameter holding the path of the route to get a challenge for.
+	ResourceParam = "resource"
+	// MethodParam is the name of the query parameter holding the method of the route, GET by default.
+	MethodParam = "method"
+)
+
+// NewHandler creates a new challenge handler.
+func NewHandler(logger *zap.Logger, issuer Issuer, describer Describer) *Handler {
+	// Logging the call
+	logger.Debug("creating a new challenge handler")
+
+	return &Handler{
+		logger:    logger,
+		issuer:    issuer,
+		describer: describer,
+	}
+}
@@ -0,0 +1,17 @@
+// Package health contains the http transport for the health checks.
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResourceEndpoint is the endpoint for the /health resource.
+const ResourceEndpoint = "/health"
+
+// GetHealth handles the health check request.
+// The server is healthy as long as it serves the requests, so it always returns 200.
+func GetHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
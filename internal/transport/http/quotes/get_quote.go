@@ -6,7 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
-	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
 )
 
 // GetQuote handles the request for getting a quote.
@@ -19,10 +20,10 @@ func (h *Handler) GetQuote(c *gin.Context) {
 	// Handle the error.
 	if err != nil {
 		// Log the actual error.
-		h.logger.Error("failed to get quote", zap.Error(err))
+		h.logger.Error("failed to get quote", zap.Error(err), zap.String("request_id", requestid.Get(c)))
 
 		// Return a generic error to the client.
-		c.AbortWithStatusJSON(http.StatusInternalServerError, model.Quote{})
+		problem.Abort(c, problem.New(problem.TypeInternal, "failed to get quote"))
 
 		// Exit the function.
 		return
@@ -30,7 +31,7 @@ func (h *Handler) GetQuote(c *gin.Context) {
 
 	if quote == nil {
 		// Return a generic error to the client.
-		c.AbortWithStatusJSON(http.StatusNotFound, model.Quote{})
+		problem.Abort(c, problem.New(problem.TypeNotFound, "no quotes found"))
 
 		// Exit the function.
 		return
@@ -17,6 +17,7 @@ import (
 	"github.com/daniel-orlov/quotes-server/internal/domain/model"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes/mocks"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
 )
 
 func TestHandler_GetQuote_Success(t *testing.T) {
@@ -125,26 +126,26 @@ func TestHandler_GetQuote_Internal_Error(t *testing.T) {
 	// Asserting the response code
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 
+	// Asserting the response is a problem
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
 	// Creating a variable to store the response body
-	var quote model.Quote
+	var p problem.Problem
 
-	// Unmarshalling the response body into the quote variable
-	err = json.Unmarshal(w.Body.Bytes(), &quote)
+	// Unmarshalling the response body into the problem variable
+	err = json.Unmarshal(w.Body.Bytes(), &p)
 
 	// Asserting the error is nil
 	assert.NoError(t, err)
 
-	// Asserting the quote is not nil
-	assert.NotNil(t, quote)
-
-	// Asserting the quote text is empty
-	assert.Empty(t, quote.Text)
+	// Asserting the problem type
+	assert.Equal(t, problem.TypeInternal.URI(), p.Type)
 
-	// Asserting the quote author is empty
-	assert.Empty(t, quote.Author)
+	// Asserting the problem status matches the response code
+	assert.Equal(t, w.Code, p.Status)
 
-	// Asserting the quote id is empty
-	assert.Empty(t, quote.ID)
+	// Asserting the problem points at the request
+	assert.Equal(t, endpoint, p.Instance)
 }
 
 func TestHandler_GetQuote_Not_Found_Error(t *testing.T) {
@@ -179,24 +180,24 @@ func TestHandler_GetQuote_Not_Found_Error(t *testing.T) {
 	// Asserting the response code
 	assert.Equal(t, http.StatusNotFound, w.Code)
 
+	// Asserting the response is a problem
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
 	// Creating a variable to store the response body
-	var quote model.Quote
+	var p problem.Problem
 
-	// Unmarshalling the response body into the quote variable
-	err = json.Unmarshal(w.Body.Bytes(), &quote)
+	// Unmarshalling the response body into the problem variable
+	err = json.Unmarshal(w.Body.Bytes(), &p)
 
 	// Asserting the error is nil
 	assert.NoError(t, err)
 
-	// Asserting the quote is not nil
-	assert.NotNil(t, quote)
-
-	// Asserting the quote text is empty
-	assert.Empty(t, quote.Text)
+	// Asserting the problem type
+	assert.Equal(t, problem.TypeNotFound.URI(), p.Type)
 
-	// Asserting the quote author is empty
-	assert.Empty(t, quote.Author)
+	// Asserting the problem status matches the response code
+	assert.Equal(t, w.Code, p.Status)
 
-	// Asserting the quote id is empty
-	assert.Empty(t, quote.ID)
+	// Asserting the problem points at the request
+	assert.Equal(t, endpoint, p.Instance)
 }
@@ -0,0 +1,39 @@
+// Package reputation contains the http transport for inspecting and resetting the reputation of the clients.
+package reputation
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+)
+
+// Service is the port for the reputation use cases.
+type Service interface {
+	Get(clientID string) (model.Reputation, bool)
+	List() []model.Reputation
+	Reset(clientID string) bool
+}
+
+// Handler is the HTTP handler for the /reputation resource.
+type Handler struct {
+	logger  *zap.Logger
+	service Service
+}
+
+const (
+	// ResourceEndpoint is the endpoint for the /reputation resource.
+	ResourceEndpoint = "/reputation"
+	// ClientParam is the name of the path parameter holding the client ID.
+	ClientParam = "client"
+)
+
+// NewHandler creates a new reputation handler.
+func NewHandler(logger *zap.Logger, service Service) *Handler {
+	// Logging the call
+	logger.Debug("creating a new reputation handler")
+
+	return &Handler{
+		logger:  logger,
+		service: service,
+	}
+}
@@ -0,0 +1,55 @@
+package reputation
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+// ListReputations handles the request for listing the reputations of all the tracked clients, the worst first.
+func (h *Handler) ListReputations(c *gin.Context) {
+	// Logging the call
+	h.logger.Debug("handling the request for listing reputations")
+
+	// Return the reputations to the client.
+	c.JSON(http.StatusOK, h.service.List())
+}
+
+// GetReputation handles the request for getting the reputation of a client.
+func (h *Handler) GetReputation(c *gin.Context) {
+	// Logging the call
+	h.logger.Debug("handling the request for getting a reputation")
+
+	// Call the service.
+	reputation, ok := h.service.Get(c.Param(ClientParam))
+	if !ok {
+		// The client is not tracked.
+		problem.Abort(c, problem.New(problem.TypeNotFound, "client is not tracked"))
+
+		// Exit the function.
+		return
+	}
+
+	// Return the reputation to the client.
+	c.JSON(http.StatusOK, reputation)
+}
+
+// ResetReputation handles the request for resetting the reputation of a client.
+func (h *Handler) ResetReputation(c *gin.Context) {
+	// Logging the call
+	h.logger.Debug("handling the request for resetting a reputation")
+
+	// Call the service.
+	if !h.service.Reset(c.Param(ClientParam)) {
+		// The client is not tracked.
+		problem.Abort(c, problem.New(problem.TypeNotFound, "client is not tracked"))
+
+		// Exit the function.
+		return
+	}
+
+	// The reputation is reset.
+	c.Status(http.StatusNoContent)
+}
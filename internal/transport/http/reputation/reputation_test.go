@@ -0,0 +1,112 @@
+package reputation_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+	rsvc "github.com/daniel-orlov/quotes-server/internal/domain/service/reputation"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/reputation"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+// newRouter creates a router serving the reputation endpoints of a service tracking a single client.
+func newRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	// Create a reputation service
+	service, err := rsvc.NewService(zap.NewNop(), &rsvc.Config{
+		HalfLife:             time.Hour,
+		MaxClients:           10,
+		PendingTTL:           time.Minute,
+		FailedSolutionWeight: 1,
+		PointsPerBit:         1,
+		MaxExtraDifficulty:   4,
+	})
+	require.NoError(t, err)
+
+	// Track a client
+	service.RecordFailedSolution("192.0.2.1")
+
+	// Creating a handler
+	handler := reputation.NewHandler(zap.NewNop(), service)
+
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Registering the endpoint handlers to the router
+	r := gin.New()
+	r.GET("/reputation", handler.ListReputations)
+	r.GET("/reputation/:client", handler.GetReputation)
+	r.DELETE("/reputation/:client", handler.ResetReputation)
+
+	return r
+}
+
+// serve serves the request and returns the response.
+func serve(r *gin.Engine, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+
+	return w
+}
+
+func TestHandler_ListReputations(t *testing.T) {
+	r := newRouter(t)
+
+	w := serve(r, http.MethodGet, "/reputation")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var reputations []model.Reputation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reputations))
+	require.Len(t, reputations, 1)
+	assert.Equal(t, "192.0.2.1", reputations[0].ClientID)
+	assert.Equal(t, uint64(1), reputations[0].FailedSolutions)
+}
+
+func TestHandler_GetReputation(t *testing.T) {
+	t.Run("Tracked client", func(t *testing.T) {
+		r := newRouter(t)
+
+		w := serve(r, http.MethodGet, "/reputation/192.0.2.1")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var rep model.Reputation
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rep))
+		assert.Equal(t, "192.0.2.1", rep.ClientID)
+		assert.Greater(t, rep.Score, 0.0)
+	})
+
+	t.Run("Unknown client", func(t *testing.T) {
+		r := newRouter(t)
+
+		w := serve(r, http.MethodGet, "/reputation/192.0.2.2")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), problem.TypeNotFound.URI())
+	})
+}
+
+func TestHandler_ResetReputation(t *testing.T) {
+	r := newRouter(t)
+
+	// Reset the tracked client
+	w := serve(r, http.MethodDelete, "/reputation/192.0.2.1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// The client is forgotten
+	w = serve(r, http.MethodGet, "/reputation/192.0.2.1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(r, http.MethodDelete, "/reputation/192.0.2.1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), problem.TypeNotFound.URI())
+}
@@ -4,21 +4,50 @@ package http
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/challenge"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/health"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/reputation"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/tokens"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+const (
+	// V1Endpoint is the endpoint of the first version of the API.
+	V1Endpoint = "/v1"
+	// AdminEndpoint is the endpoint of the admin API.
+	AdminEndpoint = "/admin"
 )
 
 // NewRouter creates a new HTTP router.
 // It accepts a quotes handler and a list of global middlewares, which will be applied to all routes.
+// The router trusts no proxy, so the client IP is always the remote address of the request,
+// which is resolved from the headers of the trusted proxies beforehand, see realip.Resolver.
 // TODO: refactor to accept a map of handlers to middleware lists for extensibility.
 func NewRouter(quotesHandler *quotes.Handler, globalMWs ...gin.HandlerFunc) *gin.Engine {
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Trust no proxy, as any client can send the forwarding headers, the real client IP is resolved before the router
+	_ = r.SetTrustedProxies(nil)
+
+	// Tag every request with an ID, that the error responses refer to
+	r.Use(requestid.Use())
+
+	// Answer the unknown routes and methods with the problem details, like the rest of the errors
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(problem.NoRoute)
+	r.NoMethod(problem.NoMethod)
+
 	// Initialize an API version group
 	// Add global middlewares
-	v1 := r.Group("/v1", globalMWs...)
+	v1 := r.Group(V1Endpoint, globalMWs...)
 
 	{
+		// Initialize the health check endpoint, it is exempt from the proof of work by the default route policies
+		v1.GET(health.ResourceEndpoint, health.GetHealth)
+
 		// Initialize quotes group
 		quoteGroup := v1.Group(quotes.ResourceEndpoint)
 		{
@@ -30,3 +59,56 @@ func NewRouter(quotesHandler *quotes.Handler, globalMWs ...gin.HandlerFunc) *gin
 	// Return router
 	return r
 }
+
+// RegisterChallengeRoutes registers the challenge endpoint on the router, next to the API endpoints.
+// The challenge endpoint is not behind the proof-of-work middleware, as it is what the clients call to get a challenge,
+// so it gets its own middlewares, e.g. the rate limiter.
+func RegisterChallengeRoutes(r *gin.Engine, handler *challenge.Handler, mws ...gin.HandlerFunc) {
+	// Initialize an API version group
+	// Add the challenge middlewares
+	v1 := r.Group(V1Endpoint, mws...)
+
+	// Initialize the challenge endpoint
+	v1.GET(challenge.ResourceEndpoint, handler.GetChallenge)
+}
+
+// AdminHandlers are the handlers of the admin endpoints, the nil ones are not registered.
+type AdminHandlers struct {
+	// Reputation is the handler for inspecting and resetting the reputation of the clients.
+	Reputation *reputation.Handler
+	// Tokens is the handler for revoking the access tokens.
+	Tokens *tokens.Handler
+}
+
+// IsEmpty reports whether there are no admin handlers to register.
+func (h AdminHandlers) IsEmpty() bool {
+	return h.Reputation == nil && h.Tokens == nil
+}
+
+// RegisterAdminRoutes registers the admin endpoints on the router.
+// The admin endpoints are not behind the global middlewares, but behind the admin middlewares, e.g. the admin auth.
+func RegisterAdminRoutes(r *gin.Engine, handlers AdminHandlers, adminMWs ...gin.HandlerFunc) {
+	// Initialize an admin group
+	// Add admin middlewares
+	admin := r.Group(AdminEndpoint, adminMWs...)
+
+	// Initialize reputation group
+	if handlers.Reputation != nil {
+		reputationGroup := admin.Group(reputation.ResourceEndpoint)
+		{
+			// Initialize reputation endpoints
+			reputationGroup.GET("", handlers.Reputation.ListReputations)
+			reputationGroup.GET("/:"+reputation.ClientParam, handlers.Reputation.GetReputation)
+			reputationGroup.DELETE("/:"+reputation.ClientParam, handlers.Reputation.ResetReputation)
+		}
+	}
+
+	// Initialize tokens group
+	if handlers.Tokens != nil {
+		tokensGroup := admin.Group(tokens.ResourceEndpoint)
+		{
+			// Initialize tokens endpoints
+			tokensGroup.DELETE("/:"+tokens.IDParam, handlers.Tokens.RevokeToken)
+		}
+	}
+}
@@ -1,21 +1,33 @@
 package http_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/daniel-orlov/quotes-server/internal/domain/model"
+	rsvc "github.com/daniel-orlov/quotes-server/internal/domain/service/reputation"
 	httptransport "github.com/daniel-orlov/quotes-server/internal/transport/http" //
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/challenge"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/health"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes/mocks"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/reputation"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/adminauth"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
+	proofermocks "github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer/mocks"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
 )
 
 func TestNewRouter_GET_quote(t *testing.T) {
@@ -49,3 +61,127 @@ func TestNewRouter_GET_quote(t *testing.T) {
 	// Assert the response code
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestRegisterAdminRoutes(t *testing.T) {
+	// Create a reputation service
+	reputationService, err := rsvc.NewService(zap.NewNop(), &rsvc.Config{HalfLife: time.Hour, MaxClients: 1, PendingTTL: time.Minute, PointsPerBit: 1})
+	require.NoError(t, err)
+
+	// Create a router with the admin endpoints behind the admin auth
+	r := httptransport.NewRouter(quotes.NewHandler(zap.NewNop(), mocks.NewMockQuoteService(nil, nil)))
+	httptransport.RegisterAdminRoutes(r,
+		httptransport.AdminHandlers{Reputation: reputation.NewHandler(zap.NewNop(), reputationService)},
+		adminauth.New(zap.NewNop(), "secret").Use(),
+	)
+
+	t.Run("Authorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin"+reputation.ResourceEndpoint, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin"+reputation.ResourceEndpoint, nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Handler is not registered", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/admin/tokens/abc", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestNewRouter_GET_health(t *testing.T) {
+	// Create a router
+	r := httptransport.NewRouter(quotes.NewHandler(zap.NewNop(), mocks.NewMockQuoteService(nil, nil)))
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1"+health.ResourceEndpoint, nil))
+
+	// Assert the response
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestRegisterChallengeRoutes(t *testing.T) {
+	// Create a proofer middleware issuing a fixed hashcash challenge
+	mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20, SaltLength: 8},
+		proofermocks.NewMockPoWService("1:20:230520:client::salt:0", false, nil),
+	)
+
+	// Create a router with the API behind the proofer, and the challenge endpoint next to it
+	r := httptransport.NewRouter(quotes.NewHandler(zap.NewNop(), mocks.NewMockQuoteService(nil, nil)), mw.Use())
+	httptransport.RegisterChallengeRoutes(r, challenge.NewHandler(zap.NewNop(), mw,
+		pow.NewService(zap.NewNop(), &pow.Config{}, nil, nil),
+	))
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1"+challenge.ResourceEndpoint+"?resource=/v1/quotes/random", nil))
+
+	// Assert the response, the challenge endpoint is not behind the proofer
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"challenge":"1:20:230520:client::salt:0"`)
+}
+
+func TestNewRouter_Problems(t *testing.T) {
+	// Create a router
+	r := httptransport.NewRouter(quotes.NewHandler(zap.NewNop(), mocks.NewMockQuoteService(nil, nil)))
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		expectedType problem.Type
+	}{
+		{name: "Unknown route", method: http.MethodGet, path: "/v1/unknown", expectedType: problem.TypeNotFound},
+		{name: "Unsupported method", method: http.MethodPost, path: "/v1" + health.ResourceEndpoint, expectedType: problem.TypeMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Serve the request
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			// Assert the response is a problem, referring to the request ID
+			assert.Equal(t, tc.expectedType.Status, w.Code)
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, tc.expectedType.URI(), p.Type)
+			assert.NotEmpty(t, p.RequestID)
+			assert.Equal(t, w.Header().Get(requestid.Header), p.RequestID)
+		})
+	}
+}
+
+func TestNewRouter_ClientIP(t *testing.T) {
+	// Create a router recording the client IP, as the proofer and the rate limiter see it
+	var clientIP string
+
+	r := httptransport.NewRouter(quotes.NewHandler(zap.NewNop(), mocks.NewMockQuoteService(nil, nil)),
+		func(c *gin.Context) { clientIP = c.ClientIP() },
+	)
+
+	// Serve the request with the spoofed headers
+	req := httptest.NewRequest(http.MethodGet, "/v1"+health.ResourceEndpoint, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	req.Header.Set("X-Real-IP", "198.51.100.7")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert the spoofed headers are ignored
+	assert.Equal(t, "192.0.2.1", clientIP)
+}
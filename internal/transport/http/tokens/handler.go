@@ -0,0 +1,37 @@
+// Package tokens contains the http transport for revoking the access tokens.
+package tokens
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Service is the port for the access token use cases.
+type Service interface {
+	Revoke(ctx context.Context, id string) error
+}
+
+// Handler is the HTTP handler for the /tokens resource.
+type Handler struct {
+	logger  *zap.Logger
+	service Service
+}
+
+const (
+	// ResourceEndpoint is the endpoint for the /tokens resource.
+	ResourceEndpoint = "/tokens"
+	// IDParam is the name of the path parameter holding the token ID.
+	IDParam = "id"
+)
+
+// NewHandler creates a new tokens handler.
+func NewHandler(logger *zap.Logger, service Service) *Handler {
+	// Logging the call
+	logger.Debug("creating a new tokens handler")
+
+	return &Handler{
+		logger:  logger,
+		service: service,
+	}
+}
@@ -0,0 +1,32 @@
+package tokens
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+// RevokeToken handles the request for revoking an access token.
+// The token does not have to be known, so that a token could be revoked before it is used.
+func (h *Handler) RevokeToken(c *gin.Context) {
+	// Logging the call
+	h.logger.Debug("handling the request for revoking a token")
+
+	// Call the service.
+	if err := h.service.Revoke(c.Request.Context(), c.Param(IDParam)); err != nil {
+		// Log the actual error.
+		h.logger.Error("failed to revoke token", zap.Error(err))
+
+		// Return a generic error to the client.
+		problem.Abort(c, problem.New(problem.TypeInternal, "failed to revoke token"))
+
+		// Exit the function.
+		return
+	}
+
+	// The token is revoked.
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package tokens_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/tokens"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+// fakeService remembers the revoked token IDs.
+type fakeService struct {
+	revoked []string
+	err     error
+}
+
+// Revoke remembers the revoked token ID.
+func (s *fakeService) Revoke(_ context.Context, id string) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.revoked = append(s.revoked, id)
+
+	return nil
+}
+
+func TestHandler_RevokeToken(t *testing.T) {
+	testCases := []struct {
+		name         string
+		serviceError error
+		expectedCode int
+	}{
+		{name: "Success", expectedCode: http.StatusNoContent},
+		{name: "Service error", serviceError: errors.New("service error"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Creating a handler
+			service := &fakeService{err: tc.serviceError}
+			handler := tokens.NewHandler(zap.NewNop(), service)
+
+			// Setting the gin to test mode
+			gin.SetMode(gin.TestMode)
+
+			// Registering the endpoint handler to the router
+			r := gin.New()
+			r.DELETE("/tokens/:id", handler.RevokeToken)
+
+			// Serving the request
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/tokens/abc", nil))
+
+			// Assertions
+			assert.Equal(t, tc.expectedCode, w.Code)
+
+			if tc.serviceError == nil {
+				assert.Equal(t, []string{"abc"}, service.revoked)
+			} else {
+				assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Body.String(), problem.TypeInternal.URI())
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package identity
+
+import "errors"
+
+// ErrInvalidSpec is returned when the client identity spec cannot be parsed.
+var ErrInvalidSpec = errors.New("invalid client identity spec")
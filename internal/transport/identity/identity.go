@@ -0,0 +1,179 @@
+// Package identity identifies the clients of the requests, so that the challenges, the access tokens, the reputation
+// and the rate limits could be bound to a client rather than to a whole network behind a shared IP address.
+//
+// The client identity is configured with a spec: the alternatives separated by "|", the first one available in
+// the request identifying the client, each of them being one or more parts combined with "+". The parts are:
+//
+//   - "ip" is the client IP address;
+//   - "header:<name>" is the value of the header, e.g. an API key;
+//   - "cookie:<name>" is the value of the cookie, e.g. a session ID;
+//   - "tls" is the SHA-256 fingerprint of the TLS client certificate.
+//
+// For example, "header:X-API-Key|cookie:session+ip" identifies the clients by the API key, if they send one,
+// otherwise by the session together with the IP address. If no alternative is available, the IP address is used.
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// KindIP is the kind of the part identifying the client by its IP address.
+	KindIP = "ip"
+	// KindHeader is the kind of the part identifying the client by a header, followed by ":<name>".
+	KindHeader = "header"
+	// KindCookie is the kind of the part identifying the client by a cookie, followed by ":<name>".
+	KindCookie = "cookie"
+	// KindTLS is the kind of the part identifying the client by the fingerprint of its TLS client certificate.
+	KindTLS = "tls"
+
+	// alternativeSeparator separates the alternatives in the spec.
+	alternativeSeparator = "|"
+	// partSeparator separates the combined parts in the spec and in the client IDs.
+	partSeparator = "+"
+	// secretHashSize is the number of the bytes of the hash, that the secret parts, e.g. API keys, are replaced with.
+	secretHashSize = 16
+)
+
+// Extractor extracts a part of the client identity from the request and reports whether the request has it.
+type Extractor func(c *gin.Context) (string, bool)
+
+// Identifier identifies the clients of the requests by the configured alternatives.
+type Identifier struct {
+	spec         string
+	alternatives [][]Extractor
+}
+
+// ClientIP returns the identifier of the clients by their IP address, which is the default.
+func ClientIP() *Identifier {
+	return &Identifier{spec: KindIP, alternatives: [][]Extractor{{fromIP}}}
+}
+
+// Parse parses the client identity spec, see the package documentation for the format.
+func Parse(spec string) (*Identifier, error) {
+	id := &Identifier{spec: spec}
+
+	// Parse the alternatives, each of them being the combined parts
+	for _, rawAlternative := range strings.Split(spec, alternativeSeparator) {
+		var alternative []Extractor
+
+		for _, rawPart := range strings.Split(rawAlternative, partSeparator) {
+			extractor, err := parsePart(strings.TrimSpace(rawPart))
+			if err != nil {
+				return nil, err
+			}
+
+			alternative = append(alternative, extractor)
+		}
+
+		id.alternatives = append(id.alternatives, alternative)
+	}
+
+	return id, nil
+}
+
+// parsePart parses a single part of the spec into its extractor.
+func parsePart(part string) (Extractor, error) {
+	kind, name, hasName := strings.Cut(part, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	name = strings.TrimSpace(name)
+
+	switch {
+	case kind == KindIP && !hasName:
+		return fromIP, nil
+	case kind == KindTLS && !hasName:
+		return fromTLS, nil
+	case kind == KindHeader && name != "":
+		return fromHeader(name), nil
+	case kind == KindCookie && name != "":
+		return fromCookie(name), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown part %q", ErrInvalidSpec, part)
+	}
+}
+
+// ID returns the ID of the client of the request, the parts of the first available alternative combined.
+// If no alternative is available in the request, the client is identified by its IP address.
+func (id *Identifier) ID(c *gin.Context) string {
+	for _, alternative := range id.alternatives {
+		if clientID, ok := extract(c, alternative); ok {
+			return clientID
+		}
+	}
+
+	return c.ClientIP()
+}
+
+// String returns the spec of the identifier.
+func (id *Identifier) String() string {
+	return id.spec
+}
+
+// extract returns the parts of the alternative combined, and reports whether the request has them all.
+func extract(c *gin.Context, alternative []Extractor) (string, bool) {
+	parts := make([]string, 0, len(alternative))
+
+	for _, extractor := range alternative {
+		part, ok := extractor(c)
+		if !ok {
+			return "", false
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, partSeparator), true
+}
+
+// fromIP extracts the IP address of the client, which is always available.
+func fromIP(c *gin.Context) (string, bool) {
+	return c.ClientIP(), true
+}
+
+// fromHeader returns the extractor of the header value, that is hashed, as it is usually a secret, like an API key.
+func fromHeader(name string) Extractor {
+	return func(c *gin.Context) (string, bool) {
+		value := c.GetHeader(name)
+		if value == "" {
+			return "", false
+		}
+
+		return KindHeader + ":" + hashSecret(value), true
+	}
+}
+
+// fromCookie returns the extractor of the cookie value, that is hashed, as it is usually a secret, like a session ID.
+func fromCookie(name string) Extractor {
+	return func(c *gin.Context) (string, bool) {
+		value, err := c.Cookie(name)
+		if err != nil || value == "" {
+			return "", false
+		}
+
+		return KindCookie + ":" + hashSecret(value), true
+	}
+}
+
+// fromTLS extracts the SHA-256 fingerprint of the TLS client certificate,
+// which is only available, if the server terminates TLS and the client presents a certificate.
+func fromTLS(c *gin.Context) (string, bool) {
+	if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		return "", false
+	}
+
+	fingerprint := sha256.Sum256(c.Request.TLS.PeerCertificates[0].Raw)
+
+	return KindTLS + ":" + hex.EncodeToString(fingerprint[:]), true
+}
+
+// hashSecret hashes the secret part of the identity, so that it is neither stored nor logged as is.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:secretHashSize])
+}
@@ -0,0 +1,146 @@
+package identity_test
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
+)
+
+// newContext creates a gin context of a request from the IP address, modified by the function.
+func newContext(modify func(r *http.Request)) *gin.Context {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/quotes/random", nil)
+	c.Request.RemoteAddr = "192.0.2.1:1234"
+
+	if modify != nil {
+		modify(c.Request)
+	}
+
+	return c
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "IP", spec: "ip"},
+		{name: "Header", spec: "header:X-API-Key"},
+		{name: "Cookie", spec: "cookie:session"},
+		{name: "TLS", spec: "tls"},
+		{name: "Combination", spec: "cookie:session+ip"},
+		{name: "Alternatives", spec: "header:X-API-Key | cookie:session + ip | tls"},
+		{name: "Case-insensitive kind", spec: "IP+Header:X-API-Key"},
+		{name: "Empty spec", spec: "", wantErr: true},
+		{name: "Empty alternative", spec: "ip|", wantErr: true},
+		{name: "Unknown kind", spec: "query:key", wantErr: true},
+		{name: "Header without a name", spec: "header", wantErr: true},
+		{name: "Cookie without a name", spec: "cookie:", wantErr: true},
+		{name: "IP with a name", spec: "ip:v4", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := identity.Parse(tc.spec)
+
+			if tc.wantErr {
+				assert.ErrorIs(t, err, identity.ErrInvalidSpec)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.spec, id.String())
+		})
+	}
+}
+
+func TestIdentifier_ID(t *testing.T) {
+	withHeader := func(r *http.Request) { r.Header.Set("X-API-Key", "secret-key") }
+	withCookie := func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "session", Value: "secret-session"}) }
+
+	t.Run("IP", func(t *testing.T) {
+		assert.Equal(t, "192.0.2.1", identity.ClientIP().ID(newContext(nil)))
+	})
+
+	t.Run("Header is hashed", func(t *testing.T) {
+		id, err := identity.Parse("header:X-API-Key")
+		require.NoError(t, err)
+
+		clientID := id.ID(newContext(withHeader))
+
+		assert.True(t, strings.HasPrefix(clientID, "header:"), "expected the header part")
+		assert.NotContains(t, clientID, "secret-key", "expected the secret to be hashed")
+		assert.Equal(t, clientID, id.ID(newContext(withHeader)), "expected the same client ID for the same key")
+	})
+
+	t.Run("Different keys behind the same IP", func(t *testing.T) {
+		id, err := identity.Parse("header:X-API-Key")
+		require.NoError(t, err)
+
+		other := id.ID(newContext(func(r *http.Request) { r.Header.Set("X-API-Key", "other-key") }))
+
+		assert.NotEqual(t, id.ID(newContext(withHeader)), other, "expected different clients")
+	})
+
+	t.Run("Cookie is hashed", func(t *testing.T) {
+		id, err := identity.Parse("cookie:session")
+		require.NoError(t, err)
+
+		clientID := id.ID(newContext(withCookie))
+
+		assert.True(t, strings.HasPrefix(clientID, "cookie:"), "expected the cookie part")
+		assert.NotContains(t, clientID, "secret-session", "expected the secret to be hashed")
+	})
+
+	t.Run("TLS client certificate", func(t *testing.T) {
+		id, err := identity.Parse("tls")
+		require.NoError(t, err)
+
+		clientID := id.ID(newContext(func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("certificate")}}}
+		}))
+
+		fingerprint := sha256.Sum256([]byte("certificate"))
+		assert.Equal(t, "tls:"+hex.EncodeToString(fingerprint[:]), clientID, "expected the SHA-256 fingerprint")
+	})
+
+	t.Run("Combination", func(t *testing.T) {
+		id, err := identity.Parse("cookie:session+ip")
+		require.NoError(t, err)
+
+		clientID := id.ID(newContext(withCookie))
+
+		assert.True(t, strings.HasPrefix(clientID, "cookie:"), "expected the cookie part first")
+		assert.True(t, strings.HasSuffix(clientID, "+192.0.2.1"), "expected the IP part last")
+	})
+
+	t.Run("Alternatives", func(t *testing.T) {
+		id, err := identity.Parse("header:X-API-Key|cookie:session+ip")
+		require.NoError(t, err)
+
+		// The first available alternative identifies the client
+		assert.True(t, strings.HasPrefix(id.ID(newContext(withHeader)), "header:"), "expected the header")
+		assert.True(t, strings.HasPrefix(id.ID(newContext(withCookie)), "cookie:"), "expected the cookie")
+	})
+
+	t.Run("No alternative available", func(t *testing.T) {
+		id, err := identity.Parse("header:X-API-Key|tls")
+		require.NoError(t, err)
+
+		assert.Equal(t, "192.0.2.1", id.ID(newContext(nil)), "expected the IP address")
+	})
+}
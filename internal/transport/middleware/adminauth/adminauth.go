@@ -0,0 +1,53 @@
+// Package adminauth provides a middleware that protects the admin endpoints with a static bearer token.
+package adminauth
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+// bearerPrefix is the prefix of the Authorization header value carrying a bearer token.
+const bearerPrefix = "Bearer "
+
+// AdminAuth is a middleware that only lets through the requests carrying the admin token.
+type AdminAuth struct {
+	logger *zap.Logger
+	token  string
+}
+
+// New creates a new admin auth middleware instance.
+// The token must not be empty, otherwise every request is rejected.
+func New(logger *zap.Logger, token string) *AdminAuth {
+	// Logging the call
+	logger.Debug("creating a new admin auth middleware")
+
+	return &AdminAuth{logger: logger, token: token}
+}
+
+// Use uses the admin auth middleware.
+func (mw *AdminAuth) Use() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the token from the request
+		header := c.GetHeader("Authorization")
+		token := strings.TrimPrefix(header, bearerPrefix)
+
+		// Compare the tokens in constant time, so that the token can't be guessed by timing
+		if mw.token == "" || token == header ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(mw.token)) != 1 {
+			mw.logger.Warn("unauthorized admin request", zap.String("client_ip", c.ClientIP()))
+
+			c.Header("WWW-Authenticate", "Bearer")
+			problem.Abort(c, problem.New(problem.TypeUnauthorized, "admin token is missing or invalid"))
+
+			return
+		}
+
+		// Continue processing the request
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package adminauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/adminauth"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+func TestAdminAuth_Use(t *testing.T) {
+	testCases := []struct {
+		name          string
+		token         string
+		authorization string
+		expectedCode  int
+	}{
+		{name: "Valid token", token: "secret", authorization: "Bearer secret", expectedCode: http.StatusOK},
+		{name: "Missing token", token: "secret", authorization: "", expectedCode: http.StatusUnauthorized},
+		{name: "Wrong token", token: "secret", authorization: "Bearer guess", expectedCode: http.StatusUnauthorized},
+		{name: "Not a bearer token", token: "secret", authorization: "secret", expectedCode: http.StatusUnauthorized},
+		{name: "Admin token is not set", token: "", authorization: "Bearer ", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setting the gin to test mode
+			gin.SetMode(gin.TestMode)
+			// Creating a router using the admin auth middleware
+			r := gin.New()
+			r.GET("/admin", adminauth.New(zap.NewNop(), tc.token).Use(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			// Creating a request
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			// Serving the request
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			// Assertions
+			assert.Equal(t, tc.expectedCode, w.Code)
+
+			// Expect the rejections to be problems
+			if tc.expectedCode == http.StatusUnauthorized {
+				assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Body.String(), problem.TypeUnauthorized.URI())
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package loadtracker provides a middleware that tracks the server load.
+// It reports every request, along with its latency, to the recorder, e.g. the difficulty controller.
+package loadtracker
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LoadRecorder is a port to the recorder of the server load.
+type LoadRecorder interface {
+	RequestStarted()
+	RequestFinished(latency time.Duration)
+}
+
+// LoadTracker is a middleware that tracks the in-flight requests, the request rate and the latency.
+type LoadTracker struct {
+	logger   *zap.Logger
+	recorder LoadRecorder
+}
+
+// New creates new LoadTracker middleware.
+func New(logger *zap.Logger, recorder LoadRecorder) *LoadTracker {
+	// Logging the call
+	logger.Debug("creating a new load tracker middleware")
+
+	return &LoadTracker{logger: logger, recorder: recorder}
+}
+
+// Use uses the load tracker middleware.
+// It should go first, so that the rejected requests are counted as well, as they load the server too.
+func (mw *LoadTracker) Use() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Record the start of the request
+		start := time.Now()
+		mw.recorder.RequestStarted()
+
+		// Record the end of the request, even if a handler panics
+		defer func() {
+			mw.recorder.RequestFinished(time.Since(start))
+		}()
+
+		// Continue processing the request
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package loadtracker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/loadtracker"
+)
+
+const testEndpoint = "/test"
+
+// recorder is a load recorder, that counts the requests.
+type recorder struct {
+	started  int
+	finished int
+	latency  time.Duration
+	// inFlight is the number of the in-flight requests, seen by the handler.
+	inFlight int
+}
+
+// RequestStarted records that a request has started.
+func (r *recorder) RequestStarted() {
+	r.started++
+}
+
+// RequestFinished records that a request has finished.
+func (r *recorder) RequestFinished(latency time.Duration) {
+	r.finished++
+	r.latency += latency
+}
+
+func TestLoadTracker_Use(t *testing.T) {
+	t.Run("Request is tracked", func(t *testing.T) {
+		// Create a recorder
+		rec := &recorder{}
+
+		// Create a LoadTracker instance
+		mw := loadtracker.New(zap.NewNop(), rec)
+
+		// Setting the gin to test mode
+		gin.SetMode(gin.TestMode)
+		// Creating a recorder to record the response
+		w := httptest.NewRecorder()
+		// Creating a context to use in the request
+		c, r := gin.CreateTestContext(w)
+
+		// Create a Gin handler using the LoadTracker middleware
+		r.GET(testEndpoint, mw.Use(), func(c *gin.Context) {
+			// The request is in flight
+			rec.inFlight = rec.started - rec.finished
+
+			time.Sleep(time.Millisecond)
+			c.Status(http.StatusOK)
+		})
+
+		// Serving the request
+		r.ServeHTTP(c.Writer, httptest.NewRequest(http.MethodGet, testEndpoint, nil))
+
+		// Assertions
+		assert.Equal(t, http.StatusOK, w.Code, "status code should be 200")
+		assert.Equal(t, 1, rec.inFlight, "request should be in flight in the handler")
+		assert.Equal(t, 1, rec.started, "request should be started")
+		assert.Equal(t, 1, rec.finished, "request should be finished")
+		assert.GreaterOrEqual(t, rec.latency, time.Millisecond, "latency should be recorded")
+	})
+
+	t.Run("Aborted request is tracked", func(t *testing.T) {
+		// Create a recorder
+		rec := &recorder{}
+
+		// Create a LoadTracker instance
+		mw := loadtracker.New(zap.NewNop(), rec)
+
+		// Setting the gin to test mode
+		gin.SetMode(gin.TestMode)
+		// Creating a recorder to record the response
+		w := httptest.NewRecorder()
+		// Creating a context to use in the request
+		c, r := gin.CreateTestContext(w)
+
+		// Create a Gin handler using the LoadTracker middleware, followed by a middleware rejecting the request
+		r.GET(testEndpoint, mw.Use(), func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{"error": "rejected"})
+		})
+
+		// Serving the request
+		r.ServeHTTP(c.Writer, httptest.NewRequest(http.MethodGet, testEndpoint, nil))
+
+		// Assertions
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code, "status code should be 428")
+		assert.Equal(t, 1, rec.started, "request should be started")
+		assert.Equal(t, 1, rec.finished, "request should be finished")
+	})
+}
@@ -0,0 +1,80 @@
+package proofer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
+)
+
+// ErrorChallengeRequired is the code of the response to a request without a solution.
+// It is only sent in the response body, as the WWW-Authenticate error is reserved for the rejected solutions.
+const ErrorChallengeRequired httpauth.ErrorCode = "challenge_required"
+
+// solutionFrom returns the solution sent with the request, either in the Authorization header under the PoW scheme,
+// or in the legacy X-Hashcash header, or an empty string, if there is none.
+// It returns an error wrapping pow.ErrMalformedSolution, if the Authorization header is malformed.
+func solutionFrom(c *gin.Context) (string, error) {
+	// Prefer the Authorization header, ignoring the other schemes
+	if header := c.GetHeader(httpauth.AuthorizationHeader); header != "" {
+		solution, err := httpauth.ParseAuthorization(header)
+
+		switch {
+		case err == nil:
+			return solution, nil
+		case !errors.Is(err, httpauth.ErrNotPoWScheme):
+			return "", fmt.Errorf("%w: %s", pow.ErrMalformedSolution, err)
+		}
+	}
+
+	// Fall back to the legacy header
+	return c.GetHeader(ChallengeHeader), nil
+}
+
+// setChallenge sets the challenge in the response, both in the WWW-Authenticate header and in the legacy X-Hashcash one.
+// The code tells the client, why its solution was rejected, it is empty if no solution was sent.
+func (mw *Proofer) setChallenge(c *gin.Context, challenge string, code httpauth.ErrorCode) {
+	// Set the challenge in the legacy header
+	c.Header(ChallengeHeader, challenge)
+
+	// Describe the challenge, so that the client does not have to parse it
+	auth := httpauth.Challenge{Challenge: challenge, Error: code}
+
+	metadata, err := mw.svc.Metadata(challenge)
+	if err != nil {
+		// The challenge is still solvable without its description
+		mw.logger.Warn("failed to get challenge metadata", zap.Error(err))
+	} else {
+		auth.Algorithm, auth.Difficulty, auth.Expires = string(metadata.Algorithm), metadata.Difficulty, metadata.Expires
+
+		// The schemes without a choice of the algorithm are named after it, e.g. argon2id
+		if auth.Algorithm == "" {
+			auth.Algorithm = metadata.Scheme
+		}
+	}
+
+	// Set the challenge in the WWW-Authenticate header
+	c.Header(httpauth.ChallengeHeader, auth.String())
+}
+
+// errorCode returns the code of the reason, why the solution was rejected.
+func errorCode(err error) httpauth.ErrorCode {
+	switch {
+	case errors.Is(err, pow.ErrMalformedSolution):
+		return httpauth.ErrorMalformedSolution
+	case errors.Is(err, pow.ErrStampAlreadySpent):
+		return httpauth.ErrorReplayedSolution
+	case errors.Is(err, hashcash.ErrExpiredHashcash), errors.Is(err, argon2id.ErrExpiredPuzzle):
+		return httpauth.ErrorExpiredSolution
+	case errors.Is(err, pow.ErrDifficultyMismatch):
+		return httpauth.ErrorWrongDifficulty
+	default:
+		return httpauth.ErrorInvalidSolution
+	}
+}
@@ -0,0 +1,155 @@
+package proofer_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer/mocks"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
+)
+
+// serveAuth serves the request through the proofer middleware and returns the response.
+func serveAuth(t *testing.T, svc *mocks.MockPoWService, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	// Create a Proofer instance with mock dependencies
+	mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20, SaltLength: 8}, svc)
+
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+	// Creating a recorder to record the response
+	w := httptest.NewRecorder()
+	// Creating a context to use in the request
+	c, r := gin.CreateTestContext(w)
+
+	// Create a Gin handler using the Proofer middleware
+	r.GET(testEndpoint, mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Serving the request
+	r.ServeHTTP(c.Writer, req)
+
+	return w
+}
+
+func TestProofer_AuthScheme(t *testing.T) {
+	t.Run("Challenge in the WWW-Authenticate header", func(t *testing.T) {
+		// Serve a request without a solution
+		w := serveAuth(t, mocks.NewMockPoWService("challenge", false, nil), httptest.NewRequest(http.MethodGet, testEndpoint, nil))
+
+		// Expect the challenge in both headers
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		assert.Equal(t, "challenge", w.Header().Get(proofer.ChallengeHeader))
+
+		challenge, err := httpauth.ParseChallenge(w.Header().Get(httpauth.ChallengeHeader))
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, httpauth.Challenge{Challenge: "challenge", Difficulty: 20}, challenge)
+
+		// Expect the code in the body
+		assertProblem(t, w, problem.TypeChallengeRequired, "proof-of-work requirements not met", "challenge_required")
+	})
+
+	t.Run("Solution in the Authorization header", func(t *testing.T) {
+		// Serve a request with the solution under the PoW scheme
+		req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+		req.Header.Set(httpauth.AuthorizationHeader, httpauth.Authorization("solution"))
+
+		w := serveAuth(t, mocks.NewMockPoWService("challenge", true, nil), req)
+
+		// Expect the request to pass
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Authorization header of another scheme", func(t *testing.T) {
+		// Serve a request with a bearer token and the solution in the legacy header
+		req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+		req.Header.Set(httpauth.AuthorizationHeader, "Bearer token")
+		req.Header.Set(proofer.ChallengeHeader, "solution")
+
+		w := serveAuth(t, mocks.NewMockPoWService("challenge", true, nil), req)
+
+		// Expect the legacy header to be used
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	testCases := []struct {
+		name          string
+		authorization string
+		checkError    error
+		expectedCode  int
+		expectedError httpauth.ErrorCode
+	}{
+		{
+			name:          "Malformed Authorization header",
+			authorization: `PoW solution="unterminated`,
+			expectedCode:  http.StatusPreconditionRequired,
+			expectedError: httpauth.ErrorMalformedSolution,
+		},
+		{
+			name:          "Malformed stamp",
+			checkError:    fmt.Errorf("%w: %s", pow.ErrMalformedSolution, "incorrect number of parts"),
+			expectedCode:  http.StatusPreconditionRequired,
+			expectedError: httpauth.ErrorMalformedSolution,
+		},
+		{
+			name:          "Expired stamp",
+			checkError:    fmt.Errorf("checking solution: %w", hashcash.ErrExpiredHashcash),
+			expectedCode:  http.StatusPreconditionRequired,
+			expectedError: httpauth.ErrorExpiredSolution,
+		},
+		{
+			name:          "Wrong difficulty",
+			checkError:    fmt.Errorf("checking solution: %w", pow.ErrDifficultyMismatch),
+			expectedCode:  http.StatusPreconditionRequired,
+			expectedError: httpauth.ErrorWrongDifficulty,
+		},
+		{
+			name:          "Replayed stamp",
+			checkError:    pow.ErrStampAlreadySpent,
+			expectedCode:  http.StatusConflict,
+			expectedError: httpauth.ErrorReplayedSolution,
+		},
+		{
+			name:          "Invalid stamp",
+			checkError:    errors.New("incorrect solution"),
+			expectedCode:  http.StatusPreconditionRequired,
+			expectedError: httpauth.ErrorInvalidSolution,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Serve a request with the solution
+			req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+			if tc.authorization != "" {
+				req.Header.Set(httpauth.AuthorizationHeader, tc.authorization)
+			} else {
+				req.Header.Set(httpauth.AuthorizationHeader, httpauth.Authorization("solution"))
+			}
+
+			w := serveAuth(t, mocks.NewMockPoWService("challenge", false, nil).WithCheckError(tc.checkError), req)
+
+			// Expect the solution to be rejected with a new challenge
+			assert.Equal(t, tc.expectedCode, w.Code)
+
+			challenge, err := httpauth.ParseChallenge(w.Header().Get(httpauth.ChallengeHeader))
+			require.NoError(t, err, "expected no error")
+
+			// Expect the reason in the header and in the body
+			assert.Equal(t, "challenge", challenge.Challenge)
+			assert.Equal(t, tc.expectedError, challenge.Error)
+			assert.Contains(t, w.Body.String(), fmt.Sprintf(`"code":%q`, tc.expectedError))
+		})
+	}
+}
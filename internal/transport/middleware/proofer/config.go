@@ -6,4 +6,7 @@ type Config struct {
 	ChallengeDifficulty int
 	// SaltLength is the length of the salt.
 	SaltLength int
+	// Policies are the route policies, the first one matching a request applies to it.
+	// The requests not matching any policy require a proof of work with the default parameters.
+	Policies []Policy
 }
@@ -0,0 +1,10 @@
+package proofer
+
+import "errors"
+
+var (
+	// ErrInvalidPolicy is returned when a route policy could not be parsed.
+	ErrInvalidPolicy = errors.New("invalid route policy")
+	// ErrRouteExempt is returned when a challenge is asked for a route, that does not require a proof of work.
+	ErrRouteExempt = errors.New("route is exempt from proof of work")
+)
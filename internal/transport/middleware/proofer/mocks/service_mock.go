@@ -11,6 +11,11 @@ type MockPoWService struct {
 	challenge       string
 	challengeSolved bool
 	serviceError    error
+	checkError      error
+	// lastParams are the parameters of the last challenge generated.
+	lastParams pow.ChallengeParams
+	// lastKey is the key of the last challenge generated.
+	lastKey pow.Key
 }
 
 // NewMockPoWService creates a new mock PoW service.
@@ -18,24 +23,65 @@ func NewMockPoWService(challenge string, challengeSolved bool, serviceError erro
 	return &MockPoWService{challenge: challenge, challengeSolved: challengeSolved, serviceError: serviceError}
 }
 
+// WithCheckError makes the mock return the error from CheckSolution only, while NewChallenge still succeeds.
+func (m *MockPoWService) WithCheckError(checkError error) *MockPoWService {
+	m.checkError = checkError
+
+	return m
+}
+
+// LastDifficulty returns the difficulty of the last challenge generated.
+func (m *MockPoWService) LastDifficulty() int {
+	return m.lastParams.Difficulty
+}
+
+// LastParams returns the parameters of the last challenge generated, including the ones set by the options.
+func (m *MockPoWService) LastParams() pow.ChallengeParams {
+	return m.lastParams
+}
+
+// LastKey returns the key of the last challenge generated.
+func (m *MockPoWService) LastKey() pow.Key {
+	return m.lastKey
+}
+
 // NewChallenge generates a new challenge.
-func (m MockPoWService) NewChallenge(_ context.Context, _ pow.Key, _, _ int) (string, error) {
+func (m *MockPoWService) NewChallenge(
+	_ context.Context, key pow.Key, difficulty, saltLength int, opts ...pow.ChallengeOption,
+) (string, error) {
 	// If the service error is not nil, return it
 	if m.serviceError != nil {
 		return "", m.serviceError
 	}
 
+	// Remember the key and the parameters
+	m.lastKey = key
+	m.lastParams = pow.ChallengeParams{Difficulty: difficulty, SaltLength: saltLength}
+	for _, opt := range opts {
+		opt(&m.lastParams)
+	}
+
 	// Return the challenge
 	return m.challenge, nil
 }
 
+// Metadata describes the last challenge generated by its parameters, as the mock challenges are not parsable.
+func (m *MockPoWService) Metadata(_ string) (pow.Metadata, error) {
+	return pow.Metadata{Difficulty: m.lastParams.Difficulty, Algorithm: m.lastParams.Algorithm}, nil
+}
+
 // CheckSolution checks if the solution is valid.
-func (m MockPoWService) CheckSolution(_ context.Context, _ string, _ pow.Key) (bool, error) {
+func (m *MockPoWService) CheckSolution(_ context.Context, _ string, _ pow.Key) (bool, error) {
 	// If the service error is not nil, return it
 	if m.serviceError != nil {
 		return false, m.serviceError
 	}
 
+	// If the check error is not nil, return it
+	if m.checkError != nil {
+		return false, m.checkError
+	}
+
 	// Return the result
 	return m.challengeSolved, nil
 }
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer/mocks"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
 )
 
 func TestMockPoWService_CheckSolution(t *testing.T) {
@@ -25,6 +27,25 @@ func TestMockPoWService_CheckSolution(t *testing.T) {
 		assert.False(t, isCorrect, "expected false")
 	})
 
+	t.Run("Check error", func(t *testing.T) {
+		// Create a new service
+		service := mocks.NewMockPoWService("challenge", true, nil).WithCheckError(errors.New("check error"))
+
+		// Check the solution
+		isCorrect, err := service.CheckSolution(context.TODO(), "", nil)
+
+		// Expect an error
+		assert.Error(t, err, "expected an error")
+
+		// Expect the solution to be incorrect
+		assert.False(t, isCorrect, "expected false")
+
+		// Expect a new challenge to be generated
+		challenge, err := service.NewChallenge(context.TODO(), nil, 0, 0)
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, "challenge", challenge, "expected challenge")
+	})
+
 	t.Run("Solution is incorrect", func(t *testing.T) {
 		// Create a new service
 		service := mocks.NewMockPoWService("", false, nil)
@@ -77,12 +98,29 @@ func TestMockPoWService_NewChallenge(t *testing.T) {
 		service := mocks.NewMockPoWService("new-challenge", false, nil)
 
 		// Create a new challenge
-		newChallenge, err := service.NewChallenge(context.Background(), nil, 0, 0)
+		newChallenge, err := service.NewChallenge(context.Background(), nil, 12, 0)
 
 		// Expect no error
 		assert.NoError(t, err, "expected no error")
 
 		// Expect a new challenge to be equal to the prepared one
 		assert.Equal(t, challenge, newChallenge, "expected equal")
+
+		// Expect the difficulty to be remembered
+		assert.Equal(t, 12, service.LastDifficulty(), "expected difficulty to be remembered")
 	})
 }
+
+func TestMockPoWService_NewChallenge_Options(t *testing.T) {
+	// Create a new service
+	service := mocks.NewMockPoWService("challenge", false, nil)
+
+	// Generate a new challenge with the options
+	challenge, err := service.NewChallenge(context.TODO(), nil, 20, 8, pow.WithValidFor(time.Minute))
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, "challenge", challenge, "expected challenge")
+
+	// Expect the parameters to be remembered
+	assert.Equal(t, 20, service.LastDifficulty())
+	assert.Equal(t, pow.ChallengeParams{Difficulty: 20, SaltLength: 8, ValidFor: time.Minute}, service.LastParams())
+}
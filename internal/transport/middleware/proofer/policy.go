@@ -0,0 +1,187 @@
+package proofer
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+// AnyMethod is the method of the policies, that apply to all the methods.
+const AnyMethod = "*"
+
+// Policy is the proof-of-work policy of the routes matching the method and the path pattern.
+// The zero values of the challenge parameters fall back to the middleware and the PoW service configuration.
+type Policy struct {
+	// Method is the HTTP method of the route, or AnyMethod.
+	Method string
+	// Path is the path pattern of the route, as understood by path.Match, e.g. "/v1/quotes/*".
+	Path string
+	// Exempt reports whether the route does not require a proof of work, e.g. a health check.
+	Exempt bool
+	// Difficulty is the difficulty of the challenges, it takes precedence over the adaptive difficulty.
+	Difficulty int
+	// ValidFor is the validity period of the challenges.
+	ValidFor time.Duration
+	// Algorithm is the hash algorithm of the hashcash challenges.
+	Algorithm hashcash.Algorithm
+}
+
+// Matches reports whether the policy applies to the request with the given method and path.
+func (p Policy) Matches(method, requestPath string) bool {
+	// Check the method
+	if p.Method != AnyMethod && !strings.EqualFold(p.Method, method) {
+		return false
+	}
+
+	// Check the path, the pattern is validated on parsing, so the error is not possible
+	matched, _ := path.Match(p.Path, requestPath)
+
+	return matched
+}
+
+// challengeOptions returns the options of the challenges issued under the policy.
+func (p Policy) challengeOptions() []pow.ChallengeOption {
+	var opts []pow.ChallengeOption
+
+	if p.ValidFor > 0 {
+		opts = append(opts, pow.WithValidFor(p.ValidFor))
+	}
+
+	if p.Algorithm != "" {
+		opts = append(opts, pow.WithAlgorithm(p.Algorithm))
+	}
+
+	return opts
+}
+
+// Policy options, as they are written in the policy table.
+const (
+	policyOptionExempt     = "exempt"
+	policyOptionDifficulty = "difficulty"
+	policyOptionValidFor   = "valid_for"
+	policyOptionAlgorithm  = "algorithm"
+)
+
+// ParsePolicies parses the route policy table.
+// The policies are separated by semicolons, each of them is a method, a path pattern and the options,
+// separated by spaces, e.g. "GET /v1/health exempt; * /v1/quotes/* difficulty=22 valid_for=1m algorithm=sha256".
+// The first policy matching a request applies to it.
+func ParsePolicies(raw string) ([]Policy, error) {
+	var policies []Policy
+
+	for _, rawPolicy := range strings.Split(raw, ";") {
+		// Skip the empty policies, e.g. after a trailing semicolon
+		fields := strings.Fields(rawPolicy)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Parse the policy
+		policy, err := parsePolicy(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// parsePolicy parses a single policy from its fields.
+func parsePolicy(fields []string) (Policy, error) {
+	// Both the method and the path are required
+	if len(fields) < 2 {
+		return Policy{}, fmt.Errorf("%w: expected <method> <path> [options], got %q", ErrInvalidPolicy, strings.Join(fields, " "))
+	}
+
+	policy := Policy{Method: strings.ToUpper(fields[0]), Path: fields[1]}
+
+	// Check the path pattern, so that matching could not fail
+	if _, err := path.Match(policy.Path, "/"); err != nil || !strings.HasPrefix(policy.Path, "/") {
+		return Policy{}, fmt.Errorf("%w: invalid path pattern %q", ErrInvalidPolicy, policy.Path)
+	}
+
+	// Parse the options
+	for _, option := range fields[2:] {
+		if err := policy.parseOption(option); err != nil {
+			return Policy{}, err
+		}
+	}
+
+	return policy, nil
+}
+
+// parseOption parses a single option of the policy.
+func (p *Policy) parseOption(option string) error {
+	name, value, _ := strings.Cut(option, "=")
+
+	switch name {
+	case policyOptionExempt:
+		p.Exempt = true
+	case policyOptionDifficulty:
+		difficulty, err := strconv.Atoi(value)
+		if err != nil || difficulty <= 0 {
+			return fmt.Errorf("%w: invalid difficulty %q", ErrInvalidPolicy, value)
+		}
+
+		p.Difficulty = difficulty
+	case policyOptionValidFor:
+		validFor, err := time.ParseDuration(value)
+		if err != nil || validFor <= 0 {
+			return fmt.Errorf("%w: invalid validity period %q", ErrInvalidPolicy, value)
+		}
+
+		p.ValidFor = validFor
+	case policyOptionAlgorithm:
+		algorithm := hashcash.Algorithm(value)
+		if !algorithm.IsValid() {
+			return fmt.Errorf("%w: invalid algorithm %q", ErrInvalidPolicy, value)
+		}
+
+		p.Algorithm = algorithm
+	default:
+		return fmt.Errorf("%w: unknown option %q", ErrInvalidPolicy, option)
+	}
+
+	return nil
+}
+
+// Algorithms returns the hash algorithms set by the policies, so that the PoW service could offer them.
+func Algorithms(policies []Policy) []hashcash.Algorithm {
+	var algorithms []hashcash.Algorithm
+
+	seen := make(map[hashcash.Algorithm]bool)
+
+	for _, policy := range policies {
+		if policy.Algorithm != "" && !seen[policy.Algorithm] {
+			seen[policy.Algorithm] = true
+			algorithms = append(algorithms, policy.Algorithm)
+		}
+	}
+
+	return algorithms
+}
+
+// policyFor returns the policy of the request, i.e. the first matching one,
+// or the zero policy, requiring a proof of work with the default parameters, if none matches.
+func (mw *Proofer) policyFor(r *http.Request) Policy {
+	return mw.policyForRoute(r.Method, r.URL.Path)
+}
+
+// policyForRoute returns the policy of the route, given by its method and path, like policyFor does for a request.
+func (mw *Proofer) policyForRoute(method, path string) Policy {
+	for _, policy := range mw.cfg.Policies {
+		if policy.Matches(method, path) {
+			return policy
+		}
+	}
+
+	return Policy{}
+}
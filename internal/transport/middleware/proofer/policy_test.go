@@ -0,0 +1,168 @@
+package proofer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer/mocks"
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+func TestParsePolicies(t *testing.T) {
+	t.Run("Valid policies", func(t *testing.T) {
+		policies, err := proofer.ParsePolicies(
+			"get /v1/health exempt; * /v1/quotes/* difficulty=22 valid_for=1m algorithm=blake2b256;",
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, []proofer.Policy{
+			{Method: http.MethodGet, Path: "/v1/health", Exempt: true},
+			{
+				Method:     proofer.AnyMethod,
+				Path:       "/v1/quotes/*",
+				Difficulty: 22,
+				ValidFor:   time.Minute,
+				Algorithm:  hashcash.AlgorithmBLAKE2b256,
+			},
+		}, policies)
+	})
+
+	t.Run("Empty table", func(t *testing.T) {
+		policies, err := proofer.ParsePolicies("")
+		require.NoError(t, err)
+		assert.Empty(t, policies)
+	})
+
+	t.Run("Invalid policies", func(t *testing.T) {
+		tests := []struct {
+			name string
+			raw  string
+		}{
+			{name: "Missing path", raw: "GET"},
+			{name: "Relative path", raw: "GET v1/health"},
+			{name: "Malformed path pattern", raw: "GET /v1/[health"},
+			{name: "Unknown option", raw: "GET /v1/health free"},
+			{name: "Invalid difficulty", raw: "GET /v1/health difficulty=hard"},
+			{name: "Negative difficulty", raw: "GET /v1/health difficulty=-1"},
+			{name: "Invalid validity period", raw: "GET /v1/health valid_for=forever"},
+			{name: "Unknown algorithm", raw: "GET /v1/health algorithm=md5"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := proofer.ParsePolicies(tt.raw)
+				assert.ErrorIs(t, err, proofer.ErrInvalidPolicy)
+			})
+		}
+	})
+}
+
+func TestPolicy_Matches(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy proofer.Policy
+		method string
+		path   string
+		want   bool
+	}{
+		{name: "Exact match", policy: proofer.Policy{Method: http.MethodGet, Path: "/v1/health"}, method: http.MethodGet, path: "/v1/health", want: true},
+		{name: "Other method", policy: proofer.Policy{Method: http.MethodGet, Path: "/v1/health"}, method: http.MethodPost, path: "/v1/health", want: false},
+		{name: "Any method", policy: proofer.Policy{Method: proofer.AnyMethod, Path: "/v1/health"}, method: http.MethodPost, path: "/v1/health", want: true},
+		{name: "Pattern match", policy: proofer.Policy{Method: http.MethodGet, Path: "/v1/quotes/*"}, method: http.MethodGet, path: "/v1/quotes/random", want: true},
+		{name: "Pattern does not cross segments", policy: proofer.Policy{Method: http.MethodGet, Path: "/v1/*"}, method: http.MethodGet, path: "/v1/quotes/random", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Matches(tt.method, tt.path))
+		})
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	algorithms := proofer.Algorithms([]proofer.Policy{
+		{Algorithm: hashcash.AlgorithmBLAKE2b256},
+		{},
+		{Algorithm: hashcash.AlgorithmSHA3256},
+		{Algorithm: hashcash.AlgorithmBLAKE2b256},
+	})
+
+	assert.Equal(t, []hashcash.Algorithm{hashcash.AlgorithmBLAKE2b256, hashcash.AlgorithmSHA3256}, algorithms)
+}
+
+func TestProofer_Policies(t *testing.T) {
+	// Route policies
+	policies := []proofer.Policy{
+		{Method: http.MethodGet, Path: "/v1/health", Exempt: true},
+		{Method: proofer.AnyMethod, Path: "/v1/expensive", Difficulty: 24, ValidFor: time.Minute, Algorithm: hashcash.AlgorithmBLAKE2b256},
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedCode   int
+		expectedParams pow.ChallengeParams
+	}{
+		{
+			name:         "Exempt route",
+			path:         "/v1/health",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Route with a policy",
+			path:         "/v1/expensive",
+			expectedCode: http.StatusPreconditionRequired,
+			expectedParams: pow.ChallengeParams{
+				Difficulty: 24,
+				SaltLength: 8,
+				ValidFor:   time.Minute,
+				Algorithm:  hashcash.AlgorithmBLAKE2b256,
+			},
+		},
+		{
+			name:         "Route without a policy",
+			path:         "/v1/other",
+			expectedCode: http.StatusPreconditionRequired,
+			// The difficulty comes from the source, as the route has no policy
+			expectedParams: pow.ChallengeParams{Difficulty: 22, SaltLength: 8},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a mock PoW service
+			svc := mocks.NewMockPoWService("challenge", false, nil)
+
+			// Create a Proofer instance with the policies
+			mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20, SaltLength: 8, Policies: policies}, svc,
+				proofer.WithDifficulty(staticDifficulty(22)),
+			)
+
+			// Setting the gin to test mode
+			gin.SetMode(gin.TestMode)
+			// Creating a recorder to record the response
+			w := httptest.NewRecorder()
+			// Creating a context to use in the request
+			c, r := gin.CreateTestContext(w)
+
+			// Create a Gin handler using the Proofer middleware
+			r.Use(mw.Use())
+			r.GET(tc.path, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			// Serving the request
+			r.ServeHTTP(c.Writer, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			// Assertions
+			assert.Equal(t, tc.expectedCode, w.Code)
+
+			assert.Equal(t, tc.expectedParams, svc.LastParams())
+		})
+	}
+}
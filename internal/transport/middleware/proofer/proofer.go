@@ -6,18 +6,46 @@ package proofer
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
 	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/token"
 )
 
 // PoWService is a port to the PoW service.
 type PoWService interface {
-	NewChallenge(ctx context.Context, challengeKey pow.Key, difficulty, saltLength int) (string, error)
+	NewChallenge(
+		ctx context.Context, challengeKey pow.Key, difficulty, saltLength int, opts ...pow.ChallengeOption,
+	) (string, error)
 	CheckSolution(ctx context.Context, solution string, challengeKey pow.Key) (bool, error)
+	Metadata(challenge string) (pow.Metadata, error)
+}
+
+// DifficultySource is a port to the source of the current challenge difficulty, e.g. the difficulty controller.
+type DifficultySource interface {
+	Current() int
+}
+
+// Reputation is a port to the reputation service, that raises the difficulty for the misbehaving clients.
+type Reputation interface {
+	RecordRequest(clientID string)
+	RecordChallengeIssued(clientID string)
+	RecordFailedSolution(clientID string)
+	RecordSolved(clientID string)
+	Difficulty(clientID string, base int) int
+}
+
+// TokenService is a port to the access token service, that a solved challenge is exchanged for a token with.
+type TokenService interface {
+	Issue(ctx context.Context, key pow.Key) (string, token.Claims, error)
+	Redeem(ctx context.Context, token string, key pow.Key) (token.Claims, error)
 }
 
 // Proofer is a middleware that checks Proof-of-Work in request and thus prevents DoS-attacks.
@@ -25,46 +53,125 @@ type Proofer struct {
 	logger *zap.Logger
 	cfg    *Config
 	svc    PoWService
+	// difficulty is the source of the challenge difficulty, if it is not static.
+	difficulty DifficultySource
+	// reputation is the reputation service, if the difficulty is individual for every client.
+	reputation Reputation
+	// tokens is the access token service, if a solved challenge buys more than a single request.
+	tokens TokenService
+	// identity identifies the clients, that the challenges, the tokens and the reputation are bound to.
+	identity *identity.Identifier
+}
+
+// Option is an optional parameter of the Proofer middleware.
+type Option func(mw *Proofer)
+
+// WithDifficulty makes the middleware ask the source for the difficulty every time it issues a challenge,
+// instead of using the static Config.ChallengeDifficulty.
+func WithDifficulty(source DifficultySource) Option {
+	return func(mw *Proofer) {
+		mw.difficulty = source
+	}
+}
+
+// WithReputation makes the middleware report the behaviour of the clients to the reputation service
+// and raise the difficulty of the challenges for the clients with a bad reputation.
+func WithReputation(reputation Reputation) Option {
+	return func(mw *Proofer) {
+		mw.reputation = reputation
+	}
+}
+
+// WithTokens makes the middleware exchange every solved challenge for an access token,
+// that lets the client make more requests to the same route without solving a new challenge.
+func WithTokens(tokens TokenService) Option {
+	return func(mw *Proofer) {
+		mw.tokens = tokens
+	}
+}
+
+// WithIdentity makes the middleware identify the clients by the identifier, instead of their IP address.
+func WithIdentity(identifier *identity.Identifier) Option {
+	return func(mw *Proofer) {
+		mw.identity = identifier
+	}
 }
 
 // New creates new Proofer middleware.
-func New(logger *zap.Logger, cfg *Config, svc PoWService) *Proofer {
+func New(logger *zap.Logger, cfg *Config, svc PoWService, opts ...Option) *Proofer {
 	// Logging the call
 	logger.Debug("creating a new proofer middleware")
 
-	return &Proofer{logger: logger, cfg: cfg, svc: svc}
+	mw := &Proofer{logger: logger, cfg: cfg, svc: svc, identity: identity.ClientIP()}
+
+	// Apply the optional parameters
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return mw
 }
 
 const (
 	// ChallengeHeader is the name of the header that contains the challenge solution, like a hashcash challenge.
 	ChallengeHeader = "X-Hashcash"
+	// TokenHeader is the name of the header that contains the access token.
+	TokenHeader = "X-PoW-Token"
+	// TokenCookie is the name of the cookie that contains the access token, for the clients that do not set headers.
+	TokenCookie = "pow_token"
 )
 
 // Use uses the proofer middleware.
 func (mw *Proofer) Use() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Get the policy of the route
+		policy := mw.policyFor(c.Request)
+
+		// Let the exempt routes through, e.g. the health checks
+		if policy.Exempt {
+			c.Next()
+			return
+		}
+
+		// Record the request of the client
+		if mw.reputation != nil {
+			mw.reputation.RecordRequest(mw.ClientID(c))
+		}
+
+		// Let the requests with a valid access token through, without a new challenge
+		if mw.redeemToken(c) {
+			c.Next()
+			return
+		}
+
 		// Get the solution from the request
-		solution := c.GetHeader(ChallengeHeader)
+		solution, err := solutionFrom(c)
+		if err != nil {
+			// Reject the malformed solution, return a new challenge
+			mw.logger.Debug("malformed solution", zap.Error(err))
+			_ = mw.rejectSolution(c, policy, err)
+
+			return
+		}
 
 		// if hashcash is not present, return challenge
 		if solution == "" {
 			// Try to get a new challenge
-			if err := mw.handleNewChallengeRequest(c); err != nil {
+			if err := mw.handleNewChallengeRequest(c, policy, ""); err != nil {
 				// Return error
-				mw.handleError(c, http.StatusInternalServerError, "failed to get new challenge", err)
+				mw.handleError(c, "failed to get new challenge", err)
 				// Abort request
 				return
 			}
 
 			// Abort request, return challenge
-			mw.abortRequest(c, http.StatusPreconditionRequired, "proof-of-work requirements not met")
+			mw.abortRequest(c, problem.TypeChallengeRequired, "proof-of-work requirements not met", ErrorChallengeRequired)
 			return
 		}
 
 		// Try to check the solution
-		if err := mw.handleSolutionCheck(c, solution); err != nil {
-			// Return error
-			mw.handleError(c, http.StatusInternalServerError, "failed to check solution", err)
+		if err := mw.handleSolutionCheck(c, solution, policy); err != nil {
+			// The request is already aborted with the error
 			return
 		}
 
@@ -73,35 +180,95 @@ func (mw *Proofer) Use() gin.HandlerFunc {
 	}
 }
 
-// handleNewChallengeRequest handles a request for a new challenge.
-func (mw *Proofer) handleNewChallengeRequest(c *gin.Context) error {
-	// Get a new challenge from the service
-	challenge, err := mw.svc.NewChallenge(
-		c.Request.Context(),
-		pow.NewChallengeKey(c.ClientIP(), fmt.Sprintf("%s:%s", c.Request.Method, c.Request.URL.Path)),
-		mw.cfg.ChallengeDifficulty,
-		mw.cfg.SaltLength,
-	)
+// handleNewChallengeRequest handles a request for a new challenge under the route policy.
+// The code tells the client, why its solution was rejected, it is empty if no solution was sent.
+func (mw *Proofer) handleNewChallengeRequest(c *gin.Context, policy Policy, code httpauth.ErrorCode) error {
+	// Get a new challenge for the client and the route of the request
+	challenge, err := mw.newChallenge(c.Request.Context(), mw.ClientID(c), mw.challengeKey(c), policy)
 	// Handle error
 	if err != nil {
 		mw.logger.Error("failed to get new challenge", zap.Error(err))
 		return err
 	}
 
-	// Set the challenge in the response header
-	c.Header(ChallengeHeader, challenge)
+	// Set the challenge in the response headers
+	mw.setChallenge(c, challenge, code)
 
 	// Challenge is set, return nil
 	return nil
 }
 
-// handleSolutionCheck handles a request for a solution check.
-func (mw *Proofer) handleSolutionCheck(c *gin.Context, solution string) error {
+// IssueChallenge issues a new challenge for the client and the route, given by its method and path,
+// the same way the middleware does, when a request to the route comes without a solution.
+// It lets the clients get a challenge before their first request to the route.
+// It returns ErrRouteExempt, if the route does not require a proof of work.
+func (mw *Proofer) IssueChallenge(ctx context.Context, clientID, method, path string) (string, error) {
+	// Get the policy of the route
+	policy := mw.policyForRoute(method, path)
+
+	// There is nothing to solve for the exempt routes
+	if policy.Exempt {
+		return "", ErrRouteExempt
+	}
+
+	// Record the request of the client, asking for a challenge is a request too
+	if mw.reputation != nil {
+		mw.reputation.RecordRequest(clientID)
+	}
+
+	// Get a new challenge for the client and the route
+	return mw.newChallenge(ctx, clientID, routeKey(clientID, method, path), policy)
+}
+
+// newChallenge gets a new challenge for the client and the challenge key under the route policy.
+func (mw *Proofer) newChallenge(ctx context.Context, clientID string, key pow.Key, policy Policy) (string, error) {
+	// Get a new challenge from the service
+	challenge, err := mw.svc.NewChallenge(
+		ctx,
+		key,
+		mw.challengeDifficulty(clientID, policy),
+		mw.cfg.SaltLength,
+		policy.challengeOptions()...,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// Start measuring the solve time of the client
+	if mw.reputation != nil {
+		mw.reputation.RecordChallengeIssued(clientID)
+	}
+
+	return challenge, nil
+}
+
+// challengeDifficulty returns the difficulty of the new challenge for the client under the route policy.
+func (mw *Proofer) challengeDifficulty(clientID string, policy Policy) int {
+	// Use the difficulty of the route, if it is set, otherwise the one from the source or the static one
+	difficulty := mw.cfg.ChallengeDifficulty
+
+	switch {
+	case policy.Difficulty > 0:
+		difficulty = policy.Difficulty
+	case mw.difficulty != nil:
+		difficulty = mw.difficulty.Current()
+	}
+
+	// Raise the difficulty for the clients with a bad reputation
+	if mw.reputation != nil {
+		difficulty = mw.reputation.Difficulty(clientID, difficulty)
+	}
+
+	return difficulty
+}
+
+// handleSolutionCheck handles a request for a solution check under the route policy.
+func (mw *Proofer) handleSolutionCheck(c *gin.Context, solution string, policy Policy) error {
 	// Check the solution with the service
 	solved, err := mw.svc.CheckSolution(
 		c.Request.Context(),
 		solution,
-		pow.NewChallengeKey(c.ClientIP(), fmt.Sprintf("%s:%s", c.Request.Method, c.Request.URL.Path)),
+		mw.challengeKey(c),
 	)
 	// Handle error
 	if err != nil {
@@ -113,36 +280,126 @@ func (mw *Proofer) handleSolutionCheck(c *gin.Context, solution string) error {
 
 	// If solution is not valid, return error and a new challenge, abort request
 	if !solved {
-		// Try to get a new challenge
-		if err = mw.handleNewChallengeRequest(c); err != nil {
-			// Return error
-			mw.handleError(c, http.StatusInternalServerError, "failed to get new challenge", err)
-			return err
-		}
+		return mw.rejectSolution(c, policy, err)
+	}
 
-		// Abort request, return challenge
-		mw.abortRequest(c, http.StatusPreconditionRequired, "proof-of-work requirements not met: solution is invalid")
+	// Report the outcome to the reputation service
+	if mw.reputation != nil {
+		mw.reputation.RecordSolved(mw.ClientID(c))
 	}
 
+	// Exchange the solution for an access token
+	mw.issueToken(c)
+
 	// Solution is valid, return nil
 	return nil
 }
 
-// handleError handles an error.
-func (mw *Proofer) handleError(c *gin.Context, statusCode int, errorMessage string, err error) {
+// rejectSolution rejects the solution, that failed the check with the error, and returns a new challenge,
+// telling the client the reason in a machine-readable code.
+// It returns an error, if a new challenge could not be issued, the response is already aborted then.
+func (mw *Proofer) rejectSolution(c *gin.Context, policy Policy, checkErr error) error {
+	// Report the failed solution to the reputation service
+	if mw.reputation != nil {
+		mw.reputation.RecordFailedSolution(mw.ClientID(c))
+	}
+
+	// Get the code of the reason
+	code := errorCode(checkErr)
+
+	// Try to get a new challenge
+	if err := mw.handleNewChallengeRequest(c, policy, code); err != nil {
+		// Return error
+		mw.handleError(c, "failed to get new challenge", err)
+		return err
+	}
+
+	// If the solution was already spent, tell the client it is reused, rather than invalid
+	if code == httpauth.ErrorReplayedSolution {
+		// Abort request, return challenge
+		mw.abortRequest(c, problem.TypeSolutionReplayed, "proof-of-work requirements not met: solution was already spent", code)
+		return nil
+	}
+
+	// Abort request, return challenge
+	mw.abortRequest(c, problem.TypeSolutionRejected, "proof-of-work requirements not met: solution is invalid", code)
+
+	return nil
+}
+
+// redeemToken reports whether the request carries a valid access token for its client and route, and counts its use.
+// An invalid token is not an error: the request falls back to the proof of work.
+func (mw *Proofer) redeemToken(c *gin.Context) bool {
+	// Tokens are not used
+	if mw.tokens == nil {
+		return false
+	}
+
+	// Get the token from the header, falling back to the cookie
+	raw := c.GetHeader(TokenHeader)
+	if raw == "" {
+		raw, _ = c.Cookie(TokenCookie)
+	}
+
+	if raw == "" {
+		return false
+	}
+
+	// Redeem the token
+	if _, err := mw.tokens.Redeem(c.Request.Context(), raw, mw.challengeKey(c)); err != nil {
+		mw.logger.Debug("access token rejected", zap.Error(err))
+		return false
+	}
+
+	return true
+}
+
+// issueToken issues an access token for the client and the route of the request and sets it in the response.
+// Failing to issue a token is not fatal: the request is already paid for, and the client will solve a new challenge.
+func (mw *Proofer) issueToken(c *gin.Context) {
+	// Tokens are not used
+	if mw.tokens == nil {
+		return
+	}
+
+	// Issue the token
+	issued, claims, err := mw.tokens.Issue(c.Request.Context(), mw.challengeKey(c))
+	if err != nil {
+		mw.logger.Error("failed to issue access token", zap.Error(err))
+		return
+	}
+
+	// Set the token in the response header and cookie, the cookie expires along with the token
+	c.Header(TokenHeader, issued)
+	c.SetCookie(TokenCookie, issued, int(time.Until(claims.Expires).Seconds()), c.Request.URL.Path, "", false, true)
+}
+
+// ClientID returns the ID of the client of the request, that the challenges, the tokens and the reputation are bound to.
+func (mw *Proofer) ClientID(c *gin.Context) string {
+	return mw.identity.ID(c)
+}
+
+// challengeKey returns the key of the challenges and the tokens of the request, i.e. its client and route.
+func (mw *Proofer) challengeKey(c *gin.Context) pow.Key {
+	return routeKey(mw.ClientID(c), c.Request.Method, c.Request.URL.Path)
+}
+
+// routeKey returns the key of the challenges and the tokens of the client and the route, given by its method and path.
+func routeKey(clientID, method, path string) pow.Key {
+	return pow.NewChallengeKey(clientID, fmt.Sprintf("%s:%s", method, path))
+}
+
+// handleError handles an unexpected error.
+func (mw *Proofer) handleError(c *gin.Context, errorMessage string, err error) {
 	// Log the actual error
-	mw.logger.Error(errorMessage, zap.Error(err))
+	mw.logger.Error(errorMessage, zap.Error(err), zap.String("request_id", requestid.Get(c)))
 
 	// Abort with a generic error message
-	c.AbortWithStatusJSON(statusCode, gin.H{
-		"error": errorMessage,
-	})
+	problem.Abort(c, problem.New(problem.TypeInternal, errorMessage))
 }
 
-// abortRequest aborts the request with a generic error message.
+// abortRequest aborts the request with a problem of the type, a generic error message and a machine-readable code.
 // Don't use this method to abort the request with an actual error message.
-func (mw *Proofer) abortRequest(c *gin.Context, statusCode int, errorMessage string) {
-	c.AbortWithStatusJSON(statusCode, gin.H{
-		"error": errorMessage,
-	})
+func (mw *Proofer) abortRequest(c *gin.Context, t problem.Type, errorMessage string, code httpauth.ErrorCode) {
+	problem.Abort(c, problem.New(t, errorMessage).WithCode(string(code)))
 }
@@ -1,21 +1,49 @@
 package proofer_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	cstore "github.com/daniel-orlov/quotes-server/internal/storage/challenges"
+	sstore "github.com/daniel-orlov/quotes-server/internal/storage/spent"
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer/mocks"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
 )
 
 const testEndpoint = "/test"
 
+// assertProblem asserts that the response is a problem of the type with the detail and the code.
+func assertProblem(t *testing.T, w *httptest.ResponseRecorder, typ problem.Type, detail, code string) {
+	t.Helper()
+
+	// Expect the problem content type
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	// Expect a single problem in the body
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p), "expected a single problem")
+
+	assert.Equal(t, typ.URI(), p.Type)
+	assert.Equal(t, typ.Status, p.Status)
+	assert.Equal(t, detail, p.Detail)
+	assert.Equal(t, code, p.Code)
+	assert.Equal(t, testEndpoint, p.Instance)
+}
+
 func TestProofer_Use(t *testing.T) {
 	t.Run("Challenge Request", func(t *testing.T) {
 		t.Run("Service failure", func(t *testing.T) {
@@ -43,7 +71,7 @@ func TestProofer_Use(t *testing.T) {
 
 			// Assertions
 			assert.Equal(t, http.StatusInternalServerError, w.Code, "status code should be 500")
-			assert.Equal(t, "{\"error\":\"failed to get new challenge\"}", w.Body.String())
+			assertProblem(t, w, problem.TypeInternal, "failed to get new challenge", "")
 		})
 
 		t.Run("Get back a challenge", func(t *testing.T) {
@@ -101,7 +129,7 @@ func TestProofer_Use(t *testing.T) {
 
 				// Assertions
 				assert.Equal(t, http.StatusInternalServerError, w.Code, "status code should be 500")
-				assert.Equal(t, "{\"error\":\"failed to get new challenge\"}{\"error\":\"failed to check solution\"}", w.Body.String())
+				assertProblem(t, w, problem.TypeInternal, "failed to get new challenge", "")
 			})
 
 			t.Run("Incorrect solution, get back with a new one", func(t *testing.T) {
@@ -133,6 +161,36 @@ func TestProofer_Use(t *testing.T) {
 				assert.Equal(t, "new_challenge", w.Header().Get(proofer.ChallengeHeader))
 			})
 
+			t.Run("Spent solution, get back with a new one", func(t *testing.T) {
+				// Create a mock PoW service
+				svc := mocks.NewMockPoWService("new_challenge", false, nil).WithCheckError(pow.ErrStampAlreadySpent)
+
+				// Create a Proofer instance with mock dependencies
+				mw := proofer.New(zap.NewNop(), &proofer.Config{}, svc)
+
+				// Setting the gin to test mode
+				gin.SetMode(gin.TestMode)
+				// Creating a recorder to record the response
+				w := httptest.NewRecorder()
+				// Creating a context to use in the request
+				c, r := gin.CreateTestContext(w)
+
+				// Create a Gin handler using the Proofer middleware
+				r.GET(testEndpoint, mw.Use())
+
+				// Creating a request
+				req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+				req.Header.Set(proofer.ChallengeHeader, "solution")
+
+				// Serving the request
+				r.ServeHTTP(c.Writer, req)
+
+				// Assertions
+				assert.Equal(t, http.StatusConflict, w.Code, "status code should be 409")
+				assert.Equal(t, "new_challenge", w.Header().Get(proofer.ChallengeHeader))
+				assertProblem(t, w, problem.TypeSolutionReplayed, "proof-of-work requirements not met: solution was already spent", "replayed_solution")
+			})
+
 			t.Run("Correct solution", func(t *testing.T) {
 				// Create a mock PoW service
 				svc := mocks.NewMockPoWService("", true, nil)
@@ -163,3 +221,343 @@ func TestProofer_Use(t *testing.T) {
 		})
 	})
 }
+
+// staticDifficulty is a difficulty source returning a fixed difficulty.
+type staticDifficulty int
+
+// Current returns the fixed difficulty.
+func (d staticDifficulty) Current() int {
+	return int(d)
+}
+
+func TestProofer_Difficulty(t *testing.T) {
+	t.Run("Static difficulty", func(t *testing.T) {
+		// Create a mock PoW service
+		svc := mocks.NewMockPoWService("challenge", false, nil)
+
+		// Create a Proofer instance with the static difficulty
+		mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20}, svc)
+
+		// Setting the gin to test mode
+		gin.SetMode(gin.TestMode)
+		// Creating a recorder to record the response
+		w := httptest.NewRecorder()
+		// Creating a context to use in the request
+		c, r := gin.CreateTestContext(w)
+
+		// Create a Gin handler using the Proofer middleware
+		r.GET(testEndpoint, mw.Use())
+
+		// Serving the request
+		r.ServeHTTP(c.Writer, httptest.NewRequest(http.MethodGet, testEndpoint, nil))
+
+		// Assertions
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code, "status code should be 428")
+		assert.Equal(t, 20, svc.LastDifficulty(), "challenge should have the static difficulty")
+	})
+
+	t.Run("Difficulty from the source", func(t *testing.T) {
+		// Create a mock PoW service
+		svc := mocks.NewMockPoWService("challenge", false, nil)
+
+		// Create a Proofer instance asking the source for the difficulty
+		mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20}, svc, proofer.WithDifficulty(staticDifficulty(24)))
+
+		// Setting the gin to test mode
+		gin.SetMode(gin.TestMode)
+		// Creating a recorder to record the response
+		w := httptest.NewRecorder()
+		// Creating a context to use in the request
+		c, r := gin.CreateTestContext(w)
+
+		// Create a Gin handler using the Proofer middleware
+		r.GET(testEndpoint, mw.Use())
+
+		// Serving the request
+		r.ServeHTTP(c.Writer, httptest.NewRequest(http.MethodGet, testEndpoint, nil))
+
+		// Assertions
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code, "status code should be 428")
+		assert.Equal(t, 24, svc.LastDifficulty(), "challenge should have the difficulty from the source")
+	})
+}
+
+// recordingReputation is a reputation service recording the events and adding a fixed extra difficulty.
+type recordingReputation struct {
+	extra  int
+	events []string
+}
+
+// RecordRequest records the request.
+func (r *recordingReputation) RecordRequest(string) { r.events = append(r.events, "request") }
+
+// RecordChallengeIssued records the issued challenge.
+func (r *recordingReputation) RecordChallengeIssued(string) { r.events = append(r.events, "issued") }
+
+// RecordFailedSolution records the failed solution.
+func (r *recordingReputation) RecordFailedSolution(string) { r.events = append(r.events, "failed") }
+
+// RecordSolved records the solved challenge.
+func (r *recordingReputation) RecordSolved(string) { r.events = append(r.events, "solved") }
+
+// Difficulty returns the base difficulty raised by the fixed extra difficulty.
+func (r *recordingReputation) Difficulty(_ string, base int) int { return base + r.extra }
+
+func TestProofer_Reputation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		solved         bool
+		solution       string
+		expectedCode   int
+		expectedEvents []string
+	}{
+		{
+			name:           "Challenge issued",
+			expectedCode:   http.StatusPreconditionRequired,
+			expectedEvents: []string{"request", "issued"},
+		},
+		{
+			name:           "Solution is invalid",
+			solution:       "invalid",
+			expectedCode:   http.StatusPreconditionRequired,
+			expectedEvents: []string{"request", "failed", "issued"},
+		},
+		{
+			name:           "Solution is valid",
+			solved:         true,
+			solution:       "valid",
+			expectedCode:   http.StatusOK,
+			expectedEvents: []string{"request", "solved"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a mock PoW service
+			svc := mocks.NewMockPoWService("challenge", tc.solved, nil)
+			// Create a reputation service raising the difficulty by 3
+			reputation := &recordingReputation{extra: 3}
+
+			// Create a Proofer instance with the difficulty raised by the reputation
+			mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20}, svc,
+				proofer.WithDifficulty(staticDifficulty(22)),
+				proofer.WithReputation(reputation),
+			)
+
+			// Setting the gin to test mode
+			gin.SetMode(gin.TestMode)
+			// Creating a recorder to record the response
+			w := httptest.NewRecorder()
+			// Creating a context to use in the request
+			c, r := gin.CreateTestContext(w)
+
+			// Create a Gin handler using the Proofer middleware
+			r.GET(testEndpoint, mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			// Creating a request with the solution, if any
+			req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+			if tc.solution != "" {
+				req.Header.Set(proofer.ChallengeHeader, tc.solution)
+			}
+
+			// Serving the request
+			r.ServeHTTP(c.Writer, req)
+
+			// Assertions
+			assert.Equal(t, tc.expectedCode, w.Code)
+			assert.Equal(t, tc.expectedEvents, reputation.events)
+
+			if tc.expectedCode != http.StatusOK {
+				assert.Equal(t, 25, svc.LastDifficulty(), "difficulty should be raised on top of the source")
+			}
+		})
+	}
+}
+
+func TestProofer_IssueChallenge(t *testing.T) {
+	// Parse the route policies
+	policies, err := proofer.ParsePolicies("GET /v1/health exempt; GET /v1/quotes/* difficulty=22")
+	require.NoError(t, err, "expected no error")
+
+	t.Run("Challenge for the route", func(t *testing.T) {
+		// Create a mock PoW service
+		svc := mocks.NewMockPoWService("challenge", false, nil)
+		// Create a reputation service raising the difficulty by 3
+		reputation := &recordingReputation{extra: 3}
+
+		// Create a Proofer instance with the route policies
+		mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20, SaltLength: 8, Policies: policies}, svc,
+			proofer.WithReputation(reputation),
+		)
+
+		// Issue a challenge for the route
+		challenge, err := mw.IssueChallenge(context.TODO(), "client", http.MethodGet, "/v1/quotes/random")
+		require.NoError(t, err, "expected no error")
+
+		// Expect the challenge to be issued for the client and the route under the route policy
+		assert.Equal(t, "challenge", challenge)
+		assert.Equal(t, "client", svc.LastKey().ClientID())
+		assert.Equal(t, "GET:/v1/quotes/random", svc.LastKey().ResourceID())
+		assert.Equal(t, 25, svc.LastDifficulty(), "difficulty should be raised on top of the route policy")
+		assert.Equal(t, []string{"request", "issued"}, reputation.events)
+	})
+
+	t.Run("Exempt route", func(t *testing.T) {
+		// Create a Proofer instance with the route policies
+		mw := proofer.New(zap.NewNop(), &proofer.Config{Policies: policies}, mocks.NewMockPoWService("challenge", false, nil))
+
+		// Issue a challenge for the exempt route
+		_, err := mw.IssueChallenge(context.TODO(), "client", http.MethodGet, "/v1/health")
+
+		// Expect an error - ErrRouteExempt
+		assert.ErrorIs(t, err, proofer.ErrRouteExempt, "expected ErrRouteExempt")
+	})
+
+	t.Run("Service failure", func(t *testing.T) {
+		// Create a Proofer instance with a failing service
+		mw := proofer.New(zap.NewNop(), &proofer.Config{}, mocks.NewMockPoWService("", false, errors.New("service failure")))
+
+		// Issue a challenge for the route
+		_, err := mw.IssueChallenge(context.TODO(), "client", http.MethodGet, "/v1/quotes/random")
+
+		// Expect an error
+		assert.Error(t, err, "expected error")
+	})
+}
+
+func TestProofer_Identity(t *testing.T) {
+	// requestChallenge requests a challenge from behind the shared IP address with the API key
+	// and returns the client ID, that the challenge is issued for.
+	requestChallenge := func(t *testing.T, mw *proofer.Proofer, svc *mocks.MockPoWService, apiKey string) string {
+		t.Helper()
+
+		// Setting the gin to test mode
+		gin.SetMode(gin.TestMode)
+		// Creating a recorder to record the response
+		w := httptest.NewRecorder()
+		// Creating a context to use in the request
+		c, r := gin.CreateTestContext(w)
+
+		// Create a Gin handler using the Proofer middleware
+		r.GET(testEndpoint, mw.Use())
+
+		// Serving the request
+		req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-API-Key", apiKey)
+		r.ServeHTTP(c.Writer, req)
+
+		require.Equal(t, http.StatusPreconditionRequired, w.Code, "status code should be 428")
+
+		return svc.LastKey().ClientID()
+	}
+
+	t.Run("Clients are identified by the IP address by default", func(t *testing.T) {
+		// Create a Proofer instance
+		svc := mocks.NewMockPoWService("challenge", false, nil)
+		mw := proofer.New(zap.NewNop(), &proofer.Config{}, svc)
+
+		// Expect the clients behind the same IP address to share the challenge
+		assert.Equal(t, "192.0.2.1", requestChallenge(t, mw, svc, "key-1"))
+		assert.Equal(t, "192.0.2.1", requestChallenge(t, mw, svc, "key-2"))
+	})
+
+	t.Run("Clients are identified by the identifier", func(t *testing.T) {
+		// Create a Proofer instance identifying the clients by the API key
+		identifier, err := identity.Parse("header:X-API-Key|ip")
+		require.NoError(t, err)
+
+		svc := mocks.NewMockPoWService("challenge", false, nil)
+		mw := proofer.New(zap.NewNop(), &proofer.Config{}, svc, proofer.WithIdentity(identifier))
+
+		// Expect the clients behind the same IP address to get their own challenges
+		first, second := requestChallenge(t, mw, svc, "key-1"), requestChallenge(t, mw, svc, "key-2")
+		assert.NotEqual(t, first, second, "clients should not share the challenge")
+		assert.Equal(t, first, requestChallenge(t, mw, svc, "key-1"), "client should keep its identity")
+
+		// Expect the clients without the API key to fall back to the IP address
+		assert.Equal(t, "192.0.2.1", requestChallenge(t, mw, svc, ""))
+	})
+}
+
+func TestProofer_RealService(t *testing.T) {
+	// newService creates a real PoW service in the mode, or of the argon2id scheme, with the stores in memory
+	newService := func(t *testing.T, mode string) *pow.Service {
+		t.Helper()
+
+		cfg := &pow.Config{Mode: mode}
+
+		switch mode {
+		case pow.ModeStateless:
+			keyring, err := pow.NewKeyring(pow.SigningKey{ID: "k1", Secret: []byte("secret")})
+			require.NoError(t, err)
+
+			cfg.Keyring = keyring
+		case pow.SchemeArgon2id:
+			cfg.Mode = pow.ModeStateful
+			cfg.Scheme = pow.SchemeArgon2id
+			cfg.Argon2id = pow.Argon2idConfig{
+				Params:   argon2id.Params{Memory: 64, Iterations: 1, Parallelism: 1},
+				ValidFor: time.Minute,
+			}
+		}
+
+		return pow.NewService(zap.NewNop(), cfg,
+			cstore.NewStorageInMemory(zap.NewNop(), 10), sstore.NewStorageInMemory(zap.NewNop(), 10),
+		)
+	}
+
+	testCases := []struct {
+		name       string
+		spec       string
+		remoteAddr string
+	}{
+		{name: "IPv4 peer", spec: "ip", remoteAddr: "192.0.2.1:1234"},
+		{name: "IPv6 peer", spec: "ip", remoteAddr: "[2001:db8::1]:1234"},
+		{name: "Header identity", spec: "header:X-API-Key", remoteAddr: "192.0.2.1:1234"},
+		{name: "Combined identity", spec: "header:X-API-Key+ip", remoteAddr: "[2001:db8::1]:1234"},
+	}
+
+	for _, mode := range []string{pow.ModeStateful, pow.ModeStateless, pow.SchemeArgon2id} {
+		for _, tc := range testCases {
+			t.Run(mode+"/"+tc.name, func(t *testing.T) {
+				// Create a Proofer instance with the real service
+				identifier, err := identity.Parse(tc.spec)
+				require.NoError(t, err)
+
+				mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 4, SaltLength: 8},
+					newService(t, mode), proofer.WithIdentity(identifier),
+				)
+
+				// Create a Gin router using the Proofer middleware
+				gin.SetMode(gin.TestMode)
+
+				r := gin.New()
+				r.GET(testEndpoint, mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+				// serve serves the request of the client with the solution
+				serve := func(solution string) *httptest.ResponseRecorder {
+					w := httptest.NewRecorder()
+					req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+					req.RemoteAddr = tc.remoteAddr
+					req.Header.Set("X-API-Key", "key-1")
+					req.Header.Set(proofer.ChallengeHeader, solution)
+					r.ServeHTTP(w, req)
+
+					return w
+				}
+
+				// Expect a challenge to be issued
+				w := serve("")
+				require.Equal(t, http.StatusPreconditionRequired, w.Code, "status code should be 428")
+
+				// Solve the challenge
+				result, err := pow.Solve(context.TODO(), w.Header().Get(proofer.ChallengeHeader), 1)
+				require.NoError(t, err)
+
+				// Expect the solution to be accepted
+				assert.Equal(t, http.StatusOK, serve(result.Solution).Code, "status code should be 200")
+			})
+		}
+	}
+}
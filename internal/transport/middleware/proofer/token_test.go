@@ -0,0 +1,119 @@
+package proofer_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	tstore "github.com/daniel-orlov/quotes-server/internal/storage/tokens"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer/mocks"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/token"
+)
+
+// newTokenService creates a token service, whose tokens allow two requests.
+func newTokenService(t *testing.T) *token.Service {
+	t.Helper()
+
+	keyring, err := pow.NewKeyring(pow.SigningKey{ID: "k1", Secret: []byte("secret")})
+	require.NoError(t, err)
+
+	tokens, err := token.NewService(zap.NewNop(), &token.Config{TTL: time.Minute, MaxUses: 2}, keyring,
+		tstore.NewStorageInMemory(zap.NewNop(), 10))
+	require.NoError(t, err)
+
+	return tokens
+}
+
+// newTokenRouter creates a router protected by the proofer, exchanging the solutions for the tokens.
+func newTokenRouter(t *testing.T, svc proofer.PoWService) *gin.Engine {
+	t.Helper()
+
+	// Create a Proofer instance with the tokens
+	mw := proofer.New(zap.NewNop(), &proofer.Config{ChallengeDifficulty: 20, SaltLength: 8}, svc,
+		proofer.WithTokens(newTokenService(t)),
+	)
+
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Create a Gin handler using the Proofer middleware
+	r := gin.New()
+	r.GET(testEndpoint, mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	return r
+}
+
+// serveWith serves a request to the test endpoint with the header set, if it is not empty.
+func serveWith(r *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, testEndpoint, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestProofer_Tokens(t *testing.T) {
+	t.Run("Solution is exchanged for a token", func(t *testing.T) {
+		// The solution is valid
+		r := newTokenRouter(t, mocks.NewMockPoWService("challenge", true, nil))
+
+		// Send the solution
+		w := serveWith(r, proofer.ChallengeHeader, "solution")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Expect the token in the header and the cookie
+		issued := w.Header().Get(proofer.TokenHeader)
+		assert.NotEmpty(t, issued, "token should be issued")
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, proofer.TokenCookie, cookies[0].Name)
+		assert.Equal(t, issued, cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+	})
+
+	t.Run("Token buys the requests", func(t *testing.T) {
+		// Issue a token with a valid solution
+		svc := mocks.NewMockPoWService("challenge", true, nil)
+		r := newTokenRouter(t, svc)
+		issued := serveWith(r, proofer.ChallengeHeader, "solution").Header().Get(proofer.TokenHeader)
+		require.NotEmpty(t, issued)
+
+		// The token is used up by two requests
+		assert.Equal(t, http.StatusOK, serveWith(r, proofer.TokenHeader, issued).Code)
+		assert.Equal(t, http.StatusOK, serveWith(r, "Cookie", proofer.TokenCookie+"="+issued).Code)
+
+		// Then the client has to solve a new challenge
+		w := serveWith(r, proofer.TokenHeader, issued)
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		assert.Equal(t, "challenge", w.Header().Get(proofer.ChallengeHeader))
+	})
+
+	t.Run("Invalid token falls back to the proof of work", func(t *testing.T) {
+		r := newTokenRouter(t, mocks.NewMockPoWService("challenge", false, nil))
+
+		w := serveWith(r, proofer.TokenHeader, "v1.k1.forged.token")
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		assert.Equal(t, "challenge", w.Header().Get(proofer.ChallengeHeader))
+	})
+
+	t.Run("Invalid solution is not exchanged for a token", func(t *testing.T) {
+		r := newTokenRouter(t, mocks.NewMockPoWService("challenge", false, nil))
+
+		w := serveWith(r, proofer.ChallengeHeader, "invalid")
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		assert.Empty(t, w.Header().Get(proofer.TokenHeader))
+	})
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
 )
 
 // Config is the configuration for the rate limiter middleware.
@@ -19,11 +21,14 @@ type Config struct {
 }
 
 // Key is the type used to identify keys in the store.
+// Besides the constants below, it can be any client identity spec, see the identity package, e.g. "header:X-API-Key".
 type Key string
 
 const (
 	// ClientIP is the key used in the store to identify the client.
 	ClientIP Key = "client_ip"
+	// ClientID identifies the client the same way as the proofer does, by the identifier given with WithIdentity.
+	ClientID Key = "client_id"
 )
 
 // String returns a string representation of the key.
@@ -68,24 +73,31 @@ func (mw *RateLimiter) parseRate(rate Rate) time.Duration {
 	}
 }
 
+// parseKey parses the key and returns the function identifying the client of the request.
 func (mw *RateLimiter) parseKey(key Key) func(c *gin.Context) string {
-	// to lower case
-	key = Key(strings.ToLower(string(key)))
-
-	switch key {
+	switch Key(strings.ToLower(string(key))) {
 	case ClientIP:
 		return func(c *gin.Context) string {
 			return c.ClientIP()
 		}
-	default:
+	case ClientID:
+		return mw.clientID
+	}
+
+	// Parse the key as a client identity spec, the header and cookie names are case-sensitive
+	identifier, err := identity.Parse(string(key))
+	if err != nil {
 		// log warning if unknown key is used
 		mw.logger.Warn("unknown key, using default",
 			zap.String("key", string(key)),
 			zap.String("default", string(ClientIP)),
+			zap.Error(err),
 		)
 
 		return func(c *gin.Context) string {
 			return c.ClientIP()
 		}
 	}
+
+	return identifier.ID
 }
@@ -17,6 +17,11 @@ func TestKey_String(t *testing.T) {
 			k:    ratelimiter.ClientIP,
 			want: "client_ip",
 		},
+		{
+			name: "ClientID",
+			k:    ratelimiter.ClientID,
+			want: "client_id",
+		},
 		{
 			name: "invalid",
 			k:    ratelimiter.Key("invalid"),
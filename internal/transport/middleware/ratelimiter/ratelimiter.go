@@ -2,12 +2,14 @@
 package ratelimiter
 
 import (
-	"net/http"
 	"time"
 
 	ratelimit "github.com/JGLTechnologies/gin-rate-limit"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
 )
 
 // RateLimiter is a middleware that limits the number of requests a client can make.
@@ -15,17 +17,52 @@ type RateLimiter struct {
 	logger *zap.Logger
 	cfg    *Config
 	store  ratelimit.Store
+	// rejections is notified of every rejected request, if set.
+	rejections RejectionRecorder
+	// identity identifies the clients for the ClientID key and the rejection recorder.
+	identity *identity.Identifier
+}
+
+// RejectionRecorder is a port to the recorder of the rejected requests, e.g. the reputation service.
+type RejectionRecorder interface {
+	RecordRateLimitHit(clientID string)
+}
+
+// Option is an optional parameter of the RateLimiter middleware.
+type Option func(mw *RateLimiter)
+
+// WithRejectionRecorder makes the middleware report every rejected request to the recorder.
+func WithRejectionRecorder(recorder RejectionRecorder) Option {
+	return func(mw *RateLimiter) {
+		mw.rejections = recorder
+	}
+}
+
+// WithIdentity makes the middleware identify the clients by the identifier, when reporting the rejected requests
+// and for the ClientID key, instead of their IP address. It should be the same identifier, as the proofer uses.
+func WithIdentity(identifier *identity.Identifier) Option {
+	return func(mw *RateLimiter) {
+		mw.identity = identifier
+	}
 }
 
 // New creates a new rate limiter middleware instance.
-func New(logger *zap.Logger, cfg *Config) *RateLimiter {
+func New(logger *zap.Logger, cfg *Config, opts ...Option) *RateLimiter {
 	// Logging the call
 	logger.Debug("creating a new rate limiter middleware")
 
-	return &RateLimiter{
-		logger: logger,
-		cfg:    cfg,
+	mw := &RateLimiter{
+		logger:   logger,
+		cfg:      cfg,
+		identity: identity.ClientIP(),
+	}
+
+	// Apply the optional parameters
+	for _, opt := range opts {
+		opt(mw)
 	}
+
+	return mw
 }
 
 // Use uses the rate limiter middleware.
@@ -38,7 +75,7 @@ func (mw *RateLimiter) Use() gin.HandlerFunc {
 
 	// Create a new rate limiter middleware instance.
 	rateLimiter := ratelimit.RateLimiter(mw.store, &ratelimit.Options{
-		ErrorHandler: errorHandler,
+		ErrorHandler: mw.errorHandler,
 		KeyFunc:      mw.parseKey(mw.cfg.Key),
 	})
 
@@ -47,7 +84,21 @@ func (mw *RateLimiter) Use() gin.HandlerFunc {
 }
 
 // errorHandler is the function that is called when a request is rejected.
-// It returns a 429 status code with a message indicating when the client can retry.
-func errorHandler(c *gin.Context, info ratelimit.Info) {
-	c.String(http.StatusTooManyRequests, "Too many requests. Try again in "+time.Until(info.ResetTime).String())
+// It returns a 429 problem with a hint, when the client can retry.
+func (mw *RateLimiter) errorHandler(c *gin.Context, info ratelimit.Info) {
+	// Report the rejected request
+	if mw.rejections != nil {
+		mw.rejections.RecordRateLimitHit(mw.clientID(c))
+	}
+
+	// Tell the client when the limit resets
+	retryAfter := time.Until(info.ResetTime)
+
+	problem.Abort(c, problem.New(problem.TypeRateLimited, "too many requests, try again in "+retryAfter.Round(time.Second).String()).
+		WithRetryAfter(retryAfter))
+}
+
+// clientID returns the ID of the client of the request, as the identifier identifies it.
+func (mw *RateLimiter) clientID(c *gin.Context) string {
+	return mw.identity.ID(c)
 }
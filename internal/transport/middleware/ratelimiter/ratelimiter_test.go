@@ -0,0 +1,155 @@
+package ratelimiter_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/identity"
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/ratelimiter"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+// countingRecorder counts the rejected requests of every client.
+type countingRecorder map[string]int
+
+// RecordRateLimitHit counts the rejected request.
+func (r countingRecorder) RecordRateLimitHit(clientID string) {
+	r[clientID]++
+}
+
+func TestRateLimiter_WithRejectionRecorder(t *testing.T) {
+	// Create a recorder
+	recorder := countingRecorder{}
+
+	// Create a rate limiter allowing a single request per minute
+	mw := ratelimiter.New(zap.NewNop(),
+		&ratelimiter.Config{Rate: ratelimiter.Minute, Limit: 1, Key: ratelimiter.ClientIP},
+		ratelimiter.WithRejectionRecorder(recorder),
+	)
+
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+	// Creating a router using the rate limiter middleware
+	r := gin.New()
+	r.GET("/test", mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Sending three requests, the last two of them are rejected
+	codes := make([]int, 0, 3)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		r.ServeHTTP(w, req)
+
+		codes = append(codes, w.Code)
+	}
+
+	// Assertions
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+	assert.Equal(t, countingRecorder{"192.0.2.1": 2}, recorder)
+}
+
+func TestRateLimiter_Problem(t *testing.T) {
+	// Create a rate limiter allowing a single request per minute
+	mw := ratelimiter.New(zap.NewNop(), &ratelimiter.Config{Rate: ratelimiter.Minute, Limit: 1, Key: ratelimiter.ClientIP})
+
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+	// Creating a router using the rate limiter middleware
+	r := gin.New()
+	r.GET("/test", mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// Sending two requests, the last of them is rejected
+	var w *httptest.ResponseRecorder
+
+	for i := 0; i < 2; i++ {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	}
+
+	// Assertions, the rejection is a problem with a retry hint
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"), "expected a retry hint")
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.TypeRateLimited.URI(), p.Type)
+	assert.Positive(t, p.RetryAfter)
+	assert.LessOrEqual(t, p.RetryAfter, 60)
+}
+
+func TestRateLimiter_Key(t *testing.T) {
+	// serve sends the requests from behind the shared IP address with the API keys and returns the status codes
+	serve := func(mw *ratelimiter.RateLimiter, apiKeys ...string) []int {
+		// Setting the gin to test mode
+		gin.SetMode(gin.TestMode)
+		// Creating a router using the rate limiter middleware
+		r := gin.New()
+		r.GET("/test", mw.Use(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		codes := make([]int, 0, len(apiKeys))
+
+		for _, apiKey := range apiKeys {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("X-API-Key", apiKey)
+			r.ServeHTTP(w, req)
+
+			codes = append(codes, w.Code)
+		}
+
+		return codes
+	}
+
+	t.Run("Client IP", func(t *testing.T) {
+		// Create a rate limiter allowing a single request per minute for every IP address
+		mw := ratelimiter.New(zap.NewNop(), &ratelimiter.Config{Rate: ratelimiter.Minute, Limit: 1, Key: ratelimiter.ClientIP})
+
+		// Expect the clients behind the same IP address to share the limit
+		assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, serve(mw, "key-1", "key-2"))
+	})
+
+	t.Run("Client identity spec", func(t *testing.T) {
+		// Create a rate limiter allowing a single request per minute for every API key
+		mw := ratelimiter.New(zap.NewNop(), &ratelimiter.Config{Rate: ratelimiter.Minute, Limit: 1, Key: "header:X-API-Key|ip"})
+
+		// Expect every client to have its own limit
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, serve(mw, "key-1", "key-2", "key-1"))
+	})
+
+	t.Run("Client ID of the identifier", func(t *testing.T) {
+		// Create a rate limiter identifying the clients the same way as the proofer, and recording the rejections
+		identifier, err := identity.Parse("header:X-API-Key")
+		require.NoError(t, err)
+
+		recorder := countingRecorder{}
+		mw := ratelimiter.New(zap.NewNop(), &ratelimiter.Config{Rate: ratelimiter.Minute, Limit: 1, Key: ratelimiter.ClientID},
+			ratelimiter.WithIdentity(identifier), ratelimiter.WithRejectionRecorder(recorder),
+		)
+
+		// Expect every client to have its own limit, and the rejections to be recorded under its ID
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, serve(mw, "key-1", "key-2", "key-2"))
+		require.Len(t, recorder, 1)
+		for clientID := range recorder {
+			assert.NotEqual(t, "192.0.2.1", clientID, "rejection should be recorded under the client ID")
+		}
+	})
+
+	t.Run("Invalid key falls back to the client IP", func(t *testing.T) {
+		// Create a rate limiter with an invalid key
+		mw := ratelimiter.New(zap.NewNop(), &ratelimiter.Config{Rate: ratelimiter.Minute, Limit: 1, Key: "query:key"})
+
+		// Expect the clients behind the same IP address to share the limit
+		assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, serve(mw, "key-1", "key-2"))
+	})
+}
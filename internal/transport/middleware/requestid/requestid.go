@@ -0,0 +1,56 @@
+// Package requestid provides a middleware that tags every request with an ID,
+// so that the error responses could be correlated with the logs.
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	// Header is the name of the header carrying the request ID, both in the request and in the response.
+	Header = "X-Request-ID"
+	// contextKey is the key of the request ID in the gin context.
+	contextKey = "request_id"
+	// maxLength is the maximum length of the request ID sent by the client, the longer ones are replaced.
+	maxLength = 128
+)
+
+// Use returns the request ID middleware.
+// It keeps the request ID sent by the client, e.g. by a proxy, if it is valid, otherwise it generates a new one.
+func Use() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Take the request ID from the request, or generate a new one
+		id := c.GetHeader(Header)
+		if !isValid(id) {
+			id = ulid.Make().String()
+		}
+
+		// Remember the request ID and return it to the client
+		c.Set(contextKey, id)
+		c.Header(Header, id)
+
+		// Continue processing the request
+		c.Next()
+	}
+}
+
+// Get returns the ID of the request, or an empty string, if the middleware is not used.
+func Get(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+// isValid reports whether the request ID sent by the client is safe to log and to return, i.e. it is short and printable.
+func isValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,58 @@
+package requestid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+)
+
+func TestUse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sent     string
+		expected string
+	}{
+		{name: "Generated", sent: ""},
+		{name: "Kept", sent: "abc-123", expected: "abc-123"},
+		{name: "Too long", sent: strings.Repeat("a", 129)},
+		{name: "Not printable", sent: "abc 123"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Setting the gin to test mode
+			gin.SetMode(gin.TestMode)
+
+			// Registering the middleware and a handler returning the request ID
+			r := gin.New()
+			r.Use(requestid.Use())
+			r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, requestid.Get(c)) })
+
+			// Serving the request
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.sent != "" {
+				req.Header.Set(requestid.Header, tc.sent)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			// Assertions, the handler and the client see the same ID
+			id := w.Header().Get(requestid.Header)
+			assert.NotEmpty(t, id)
+			assert.Equal(t, id, w.Body.String())
+
+			if tc.expected != "" {
+				assert.Equal(t, tc.expected, id)
+			} else {
+				assert.NotEqual(t, tc.sent, id)
+			}
+		})
+	}
+}
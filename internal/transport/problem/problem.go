@@ -0,0 +1,90 @@
+// Package problem renders the error responses of all the handlers and middlewares
+// as RFC 7807 problem details, with the application/problem+json content type.
+package problem
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+)
+
+// ContentType is the content type of the problem details.
+const ContentType = "application/problem+json"
+
+// Problem is an error response in the RFC 7807 format, extended with the request ID, the error code and the retry hint.
+type Problem struct {
+	// Type is the URI of the problem type.
+	Type string `json:"type"`
+	// Title is the short summary of the problem type.
+	Title string `json:"title"`
+	// Status is the HTTP status code.
+	Status int `json:"status"`
+	// Detail is the explanation of this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request, that the problem occurred at.
+	Instance string `json:"instance,omitempty"`
+	// RequestID is the ID of the request, that the problem can be found in the logs by.
+	RequestID string `json:"request_id,omitempty"`
+	// Code is the machine-readable reason of the problem, if the type has several, e.g. the proof-of-work error codes.
+	Code string `json:"code,omitempty"`
+	// RetryAfter is the number of seconds to wait before retrying the request, if it is worth retrying.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// New creates a new problem of the type with the detail.
+func New(t Type, detail string) *Problem {
+	return &Problem{
+		Type:   t.URI(),
+		Title:  t.Title,
+		Status: t.Status,
+		Detail: detail,
+	}
+}
+
+// WithCode sets the machine-readable reason of the problem.
+func (p *Problem) WithCode(code string) *Problem {
+	p.Code = code
+
+	return p
+}
+
+// WithRetryAfter sets the time to wait before retrying the request, rounded up to whole seconds.
+// Retrying right away is not worth a hint, so the non-positive times are ignored.
+func (p *Problem) WithRetryAfter(d time.Duration) *Problem {
+	if d > 0 {
+		p.RetryAfter = int(math.Ceil(d.Seconds()))
+	}
+
+	return p
+}
+
+// Abort aborts the request with the problem, filling in the path and the ID of the request.
+// The retry hint, if any, is also sent in the Retry-After header.
+func Abort(c *gin.Context, p *Problem) {
+	// Fill in the occurrence of the problem
+	p.Instance = c.Request.URL.Path
+	p.RequestID = requestid.Get(c)
+
+	// Tell the client when to retry
+	if p.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(p.RetryAfter))
+	}
+
+	// Render the problem, the JSON renderer keeps the content type, if it is already set
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// NoRoute is the handler of the requests for the routes, that do not exist.
+func NoRoute(c *gin.Context) {
+	Abort(c, New(TypeNotFound, "route does not exist"))
+}
+
+// NoMethod is the handler of the requests with a method, that the route does not support.
+func NoMethod(c *gin.Context) {
+	Abort(c, New(TypeMethodNotAllowed, "method is not supported by the route"))
+}
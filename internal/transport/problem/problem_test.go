@@ -0,0 +1,105 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/requestid"
+	"github.com/daniel-orlov/quotes-server/internal/transport/problem"
+)
+
+func TestAbort(t *testing.T) {
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Registering a handler aborting with a problem behind the request ID middleware
+	r := gin.New()
+	r.Use(requestid.Use())
+	r.GET("/limited", func(c *gin.Context) {
+		problem.Abort(c, problem.New(problem.TypeRateLimited, "try again later").
+			WithCode("limit").
+			WithRetryAfter(1500*time.Millisecond))
+	})
+
+	// Serving the request
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.Header.Set(requestid.Header, "request-1")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "retry hint should be rounded up")
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.Problem{
+		Type:       problem.TypeRateLimited.URI(),
+		Title:      "Too Many Requests",
+		Status:     http.StatusTooManyRequests,
+		Detail:     "try again later",
+		Instance:   "/limited",
+		RequestID:  "request-1",
+		Code:       "limit",
+		RetryAfter: 2,
+	}, p)
+}
+
+func TestProblem_WithRetryAfter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		wait     time.Duration
+		expected int
+	}{
+		{name: "No wait", wait: 0, expected: 0},
+		{name: "Less than a second", wait: 10 * time.Millisecond, expected: 1},
+		{name: "Whole seconds", wait: 3 * time.Second, expected: 3},
+		{name: "Past reset time", wait: -time.Second, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := problem.New(problem.TypeRateLimited, "").WithRetryAfter(tc.wait)
+
+			assert.Equal(t, tc.expected, p.RetryAfter)
+		})
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	// Setting the gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Registering the fallback handlers
+	r := gin.New()
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(problem.NoRoute)
+	r.NoMethod(problem.NoMethod)
+	r.GET("/exists", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	t.Run("Route does not exist", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), problem.TypeNotFound.URI())
+	})
+
+	t.Run("Method is not supported", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/exists", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Contains(t, w.Body.String(), problem.TypeMethodNotAllowed.URI())
+	})
+}
@@ -0,0 +1,42 @@
+package problem
+
+import "net/http"
+
+// TypeBaseURI is the base of the problem type URIs, every type is documented under its slug.
+const TypeBaseURI = "https://github.com/daniel-orlov/quotes-server/blob/main/docs/004_Error_responses.md#"
+
+// Type is a problem type, i.e. a class of errors sharing the title and the status code.
+type Type struct {
+	// Slug is the identifier of the type, appended to TypeBaseURI.
+	Slug string
+	// Title is the short summary of the type, that does not change from occurrence to occurrence.
+	Title string
+	// Status is the HTTP status code of the type.
+	Status int
+}
+
+// URI returns the URI of the problem type.
+func (t Type) URI() string {
+	return TypeBaseURI + t.Slug
+}
+
+var (
+	// TypeBadRequest is the type of the requests with invalid parameters.
+	TypeBadRequest = Type{Slug: "bad-request", Title: "Bad Request", Status: http.StatusBadRequest}
+	// TypeUnauthorized is the type of the requests without valid credentials.
+	TypeUnauthorized = Type{Slug: "unauthorized", Title: "Unauthorized", Status: http.StatusUnauthorized}
+	// TypeNotFound is the type of the requests for the resources, that do not exist.
+	TypeNotFound = Type{Slug: "not-found", Title: "Not Found", Status: http.StatusNotFound}
+	// TypeMethodNotAllowed is the type of the requests with a method, that the resource does not support.
+	TypeMethodNotAllowed = Type{Slug: "method-not-allowed", Title: "Method Not Allowed", Status: http.StatusMethodNotAllowed}
+	// TypeSolutionReplayed is the type of the requests with a proof-of-work solution, that was already spent.
+	TypeSolutionReplayed = Type{Slug: "solution-replayed", Title: "Proof of Work Already Spent", Status: http.StatusConflict}
+	// TypeChallengeRequired is the type of the requests without a proof-of-work solution.
+	TypeChallengeRequired = Type{Slug: "challenge-required", Title: "Proof of Work Required", Status: http.StatusPreconditionRequired}
+	// TypeSolutionRejected is the type of the requests with an invalid proof-of-work solution.
+	TypeSolutionRejected = Type{Slug: "solution-rejected", Title: "Proof of Work Rejected", Status: http.StatusPreconditionRequired}
+	// TypeRateLimited is the type of the requests rejected by the rate limiter.
+	TypeRateLimited = Type{Slug: "rate-limited", Title: "Too Many Requests", Status: http.StatusTooManyRequests}
+	// TypeInternal is the type of the unexpected server errors, their details are only logged.
+	TypeInternal = Type{Slug: "internal-error", Title: "Internal Server Error", Status: http.StatusInternalServerError}
+)
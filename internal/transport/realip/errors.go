@@ -0,0 +1,9 @@
+package realip
+
+import "errors"
+
+// ErrInvalidProxy is returned when a trusted proxy is neither an IP address nor a CIDR.
+var ErrInvalidProxy = errors.New("invalid trusted proxy")
+
+// ErrInvalidHeader is returned when the header telling the client address is not a single header name.
+var ErrInvalidHeader = errors.New("invalid remote ip header")
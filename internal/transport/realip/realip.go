@@ -0,0 +1,241 @@
+// Package realip resolves the real IP address of the client, when the server is behind the trusted proxies,
+// e.g. the load balancers, that tell it in the Forwarded, X-Forwarded-For or X-Real-IP header.
+// The header is only honoured, when the request comes from a trusted proxy, as any client can send it,
+// so that the clients could not spoof their address to evade the rate limiter or the proof of work.
+// Only the one header the proxies are known to set is honoured, as they pass the other ones on from the client.
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderForwarded is the standard header of the proxies, e.g. `Forwarded: for=192.0.2.60;proto=https`.
+	// Read more:
+	// - https://datatracker.ietf.org/doc/html/rfc7239
+	HeaderForwarded = "Forwarded"
+	// HeaderXForwardedFor is the de facto standard header of the proxies, listing the client and the proxies.
+	HeaderXForwardedFor = "X-Forwarded-For"
+	// HeaderXRealIP is the header of the proxies, e.g. nginx, carrying the client address alone.
+	HeaderXRealIP = "X-Real-IP"
+)
+
+// Config is the configuration of the real IP resolver.
+type Config struct {
+	// TrustedProxies are the IP addresses and the CIDRs of the trusted proxies.
+	// If there are none, the header is never honoured, and the client is the peer of the connection.
+	TrustedProxies []string
+	// Header is the header telling the client address, the one the trusted proxies set or append to.
+	// Besides the Forwarded header, it is a comma-separated list of the addresses, e.g. X-Forwarded-For.
+	// There is no default, as honouring a header the proxies pass on from the client lets it spoof its address.
+	// If it is empty, no header is honoured, e.g. when the proxies use the PROXY protocol instead.
+	Header string
+}
+
+// Resolver resolves the real IP address of the client from the headers of the trusted proxies.
+type Resolver struct {
+	logger  *zap.Logger
+	trusted []*net.IPNet
+	header  string
+}
+
+// New creates a new real IP resolver.
+// It returns ErrInvalidProxy, if any of the trusted proxies is neither an IP address nor a CIDR,
+// and ErrInvalidHeader, if the header is not a single header name, e.g. a comma-separated list of them.
+func New(logger *zap.Logger, cfg *Config) (*Resolver, error) {
+	// Logging the call
+	logger.Debug("creating a new real ip resolver",
+		zap.Strings("trusted_proxies", cfg.TrustedProxies), zap.String("header", cfg.Header),
+	)
+
+	// Check that there is exactly one header, if any
+	header := strings.TrimSpace(cfg.Header)
+	if strings.ContainsAny(header, ", \t") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, cfg.Header)
+	}
+
+	// Parse the trusted proxies
+	trusted := make([]*net.IPNet, 0, len(cfg.TrustedProxies))
+
+	for _, proxy := range cfg.TrustedProxies {
+		network, err := parseProxy(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, err
+		}
+
+		trusted = append(trusted, network)
+	}
+
+	return &Resolver{logger: logger, trusted: trusted, header: header}, nil
+}
+
+// parseProxy parses the trusted proxy, either an IP address or a CIDR, into a network.
+func parseProxy(proxy string) (*net.IPNet, error) {
+	// A CIDR, e.g. 10.0.0.0/8
+	if strings.Contains(proxy, "/") {
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, proxy)
+		}
+
+		return network, nil
+	}
+
+	// A single IP address, e.g. 10.0.0.1
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, proxy)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)}, nil
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)}, nil
+}
+
+// IsTrusted reports whether the IP address belongs to a trusted proxy.
+func (r *Resolver) IsTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP address of the client of the request.
+// If the request comes from a trusted proxy, the address is taken from the configured header, going through
+// the proxies from the nearest one, until the first address, that is not a trusted proxy.
+// Otherwise, or if the header is missing or not usable, it is the address of the peer.
+func (r *Resolver) ClientIP(req *http.Request) net.IP {
+	// Get the address of the peer
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	peer := net.ParseIP(host)
+
+	// The headers of the other peers are not trusted
+	if r.header == "" || !r.IsTrusted(peer) {
+		return peer
+	}
+
+	// Take the address from the configured header only, the other ones are not set by the proxies
+	values := req.Header.Values(r.header)
+	if len(values) == 0 {
+		return peer
+	}
+
+	// Collect the addresses from the client to the nearest proxy, the repeated headers are one list
+	var chain []string
+	if strings.EqualFold(r.header, HeaderForwarded) {
+		chain = forwardedFor(values)
+	} else {
+		chain = strings.Split(strings.Join(values, ","), ",")
+	}
+
+	if ip, ok := r.fromChain(chain); ok {
+		return ip
+	}
+
+	return peer
+}
+
+// fromChain returns the address of the client from the chain of the addresses, which is the first one,
+// counting from the nearest proxy, that is not a trusted proxy. If all of them are trusted, it is the farthest one.
+// It reports false, if the chain has an invalid address before the client is found, as it can not be relied on.
+func (r *Resolver) fromChain(chain []string) (net.IP, bool) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseNode(chain[i])
+		if ip == nil {
+			return nil, false
+		}
+
+		if i == 0 || !r.IsTrusted(ip) {
+			return ip, true
+		}
+	}
+
+	return nil, false
+}
+
+// Wrap returns the handler serving the requests with the address of the client as their remote address,
+// so that the router and all the middlewares see the same real address.
+// The port of the client is not known, so it is 0 unless the address is of the peer itself.
+func (r *Resolver) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Replace the address of the proxy with the address of the client
+		if ip := r.ClientIP(req); ip != nil {
+			if host, _, err := net.SplitHostPort(req.RemoteAddr); err != nil || !net.ParseIP(host).Equal(ip) {
+				r.logger.Debug("resolved client ip behind the proxy",
+					zap.String("proxy", req.RemoteAddr), zap.String("client_ip", ip.String()),
+				)
+
+				req.RemoteAddr = net.JoinHostPort(ip.String(), "0")
+			}
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// forwardedFor returns the "for" parameters of the Forwarded headers, in order.
+// An element without the parameter is returned as an empty string, which is an invalid address.
+func forwardedFor(values []string) []string {
+	var chain []string
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			node := ""
+
+			for _, pair := range strings.Split(element, ";") {
+				name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(name, "for") {
+					node = strings.Trim(value, `"`)
+
+					break
+				}
+			}
+
+			chain = append(chain, node)
+		}
+	}
+
+	return chain
+}
+
+// parseNode parses the address of a node, optionally with the port, e.g. 192.0.2.1, 192.0.2.1:80 or [2001:db8::1]:80.
+// It returns nil for the invalid, unknown and obfuscated addresses.
+func parseNode(node string) net.IP {
+	node = strings.TrimSpace(node)
+
+	// A bare address, including IPv6 without the brackets, as sent by some proxies
+	if ip := net.ParseIP(node); ip != nil {
+		return ip
+	}
+
+	// An IPv6 address in the brackets without the port
+	if strings.HasPrefix(node, "[") && strings.HasSuffix(node, "]") {
+		return net.ParseIP(node[1 : len(node)-1])
+	}
+
+	// An address with the port
+	host, _, err := net.SplitHostPort(node)
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
@@ -0,0 +1,263 @@
+package realip_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/internal/transport/realip"
+)
+
+// newResolver creates a resolver trusting the proxies in 10.0.0.0/8 and 2001:db8::1, that set the header.
+func newResolver(t *testing.T, header string) *realip.Resolver {
+	t.Helper()
+
+	resolver, err := realip.New(zap.NewNop(), &realip.Config{
+		TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"},
+		Header:         header,
+	})
+	require.NoError(t, err)
+
+	return resolver
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name    string
+		proxies []string
+		header  string
+		wantErr error
+	}{
+		{name: "No proxies"},
+		{name: "IPv4 address", proxies: []string{"10.0.0.1"}},
+		{name: "IPv6 address", proxies: []string{"2001:db8::1"}},
+		{name: "CIDRs", proxies: []string{"10.0.0.0/8", " 2001:db8::/32 "}},
+		{name: "Hostname", proxies: []string{"proxy.local"}, wantErr: realip.ErrInvalidProxy},
+		{name: "Invalid CIDR", proxies: []string{"10.0.0.0/33"}, wantErr: realip.ErrInvalidProxy},
+		{name: "Header", proxies: []string{"10.0.0.1"}, header: " X-Forwarded-For "},
+		{name: "List of headers", proxies: []string{"10.0.0.1"}, header: "Forwarded,X-Forwarded-For", wantErr: realip.ErrInvalidHeader},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := realip.New(zap.NewNop(), &realip.Config{TrustedProxies: tc.proxies, Header: tc.header})
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResolver_IsTrusted(t *testing.T) {
+	resolver := newResolver(t, realip.HeaderXForwardedFor)
+
+	assert.True(t, resolver.IsTrusted(net.ParseIP("10.1.2.3")))
+	assert.True(t, resolver.IsTrusted(net.ParseIP("::ffff:10.1.2.3")))
+	assert.True(t, resolver.IsTrusted(net.ParseIP("2001:db8::1")))
+	assert.False(t, resolver.IsTrusted(net.ParseIP("2001:db8::2")))
+	assert.False(t, resolver.IsTrusted(net.ParseIP("192.0.2.1")))
+	assert.False(t, resolver.IsTrusted(nil))
+}
+
+func TestResolver_ClientIP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{
+			name:       "Untrusted peer without headers",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "192.0.2.1:1234",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "Untrusted peer spoofing X-Forwarded-For",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "192.0.2.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "Untrusted peer spoofing Forwarded",
+			configured: realip.HeaderForwarded,
+			remoteAddr: "192.0.2.1:1234",
+			header:     http.Header{"Forwarded": {"for=198.51.100.7"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "Untrusted peer spoofing X-Real-IP",
+			configured: realip.HeaderXRealIP,
+			remoteAddr: "192.0.2.1:1234",
+			header:     http.Header{"X-Real-Ip": {"198.51.100.7"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "Trusted proxy without headers",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "Trusted proxy with X-Forwarded-For",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Client spoofing X-Forwarded-For through the trusted proxy",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.9, 198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Chain of the trusted proxies",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7, 10.0.0.2", "10.0.0.3"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Chain of only the trusted proxies",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "Trusted IPv6 proxy",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "[2001:db8::1]:1234",
+			header:     http.Header{"X-Forwarded-For": {"2001:db8::7"}},
+			want:       "2001:db8::7",
+		},
+		{
+			name:       "Trusted proxy with Forwarded",
+			configured: realip.HeaderForwarded,
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{"Forwarded": {
+				`for=203.0.113.9;proto=http, for="[2001:db8::7]:4711";proto=https`,
+			}},
+			want: "2001:db8::7",
+		},
+		{
+			name:       "Forwarded with the port",
+			configured: realip.HeaderForwarded,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"Forwarded": {`For="198.51.100.7:4711", for=10.0.0.2`}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Obfuscated Forwarded does not fall back to X-Forwarded-For",
+			configured: realip.HeaderForwarded,
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {"for=_hidden"},
+				"X-Forwarded-For": {"198.51.100.7"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name:       "Spoofed Forwarded is ignored when X-Forwarded-For is configured",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {"for=203.0.113.9"},
+				"X-Forwarded-For": {"198.51.100.7"},
+			},
+			want: "198.51.100.7",
+		},
+		{
+			name:       "Trusted proxy with X-Real-IP",
+			configured: realip.HeaderXRealIP,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Real-Ip": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "Invalid address",
+			configured: realip.HeaderXForwardedFor,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"unknown"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "Configured header",
+			configured: "CF-Connecting-IP",
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"Cf-Connecting-Ip": {"198.51.100.7"},
+				"X-Forwarded-For":  {"203.0.113.9"},
+			},
+			want: "198.51.100.7",
+		},
+		{
+			name:       "Header not configured",
+			configured: realip.HeaderXRealIP,
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "No header configured",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			want:       "10.0.0.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver := newResolver(t, tc.configured)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/quotes/random", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header = tc.header
+
+			assert.Equal(t, tc.want, resolver.ClientIP(req).String())
+		})
+	}
+}
+
+func TestResolver_Wrap(t *testing.T) {
+	resolver := newResolver(t, realip.HeaderXForwardedFor)
+
+	var remoteAddr string
+
+	handler := resolver.Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		remoteAddr = req.RemoteAddr
+	}))
+
+	t.Run("Trusted proxy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/quotes/random", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(realip.HeaderXForwardedFor, "198.51.100.7")
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "198.51.100.7:0", remoteAddr)
+	})
+
+	t.Run("Untrusted peer", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/quotes/random", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set(realip.HeaderXForwardedFor, "198.51.100.7")
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "192.0.2.1:1234", remoteAddr)
+	})
+}
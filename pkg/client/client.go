@@ -3,6 +3,7 @@ package client
 
 import (
 	"net/http"
+	"sync"
 
 	"go.uber.org/zap"
 )
@@ -12,9 +13,30 @@ type Client struct {
 	logger *zap.Logger
 	cfg    *Config
 	client *http.Client
+
+	// mu guards the token.
+	mu sync.Mutex
+	// token is the access token, that the last solved challenge was exchanged for, if the server issues them.
+	token string
 }
 
 // NewClient creates a new quotes server client.
 func NewClient(logger *zap.Logger, cfg *Config, client *http.Client) *Client {
 	return &Client{logger: logger, cfg: cfg, client: client}
 }
+
+// accessToken returns the access token to send with the next request, if any.
+func (c *Client) accessToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// setAccessToken remembers the access token to send with the next requests, an empty one forgets it.
+func (c *Client) setAccessToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -30,6 +31,15 @@ type Config struct {
 		// If RequestCount is 0, client will send requests infinitely
 		RequestCount int `envconfig:"REQUEST_COUNT" default:"0"`
 	}
+
+	// Solver is the proof-of-work solver configuration.
+	Solver struct {
+		// Workers is the number of goroutines solving a challenge.
+		// If Workers is 0, client will use as many workers as there are CPUs
+		Workers int `envconfig:"SOLVER_WORKERS" default:"0"`
+		// Timeout is the maximum time client will spend solving a single challenge
+		Timeout time.Duration `envconfig:"SOLVER_TIMEOUT" default:"1m"`
+	}
 }
 
 // NewConfig returns a new Config instance, populated with environment variables and defaults.
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,7 +12,8 @@ import (
 
 	"github.com/daniel-orlov/quotes-server/internal/domain/model"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
-	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
 )
 
 // Run runs the client.
@@ -61,6 +63,11 @@ func (c *Client) SendRequest(url string) error {
 		return fmt.Errorf("creating request: %w", err)
 	}
 
+	// Set the access token, if the server issued one, so that no challenge has to be solved
+	if token := c.accessToken(); token != "" {
+		req.Header.Set(proofer.TokenHeader, token)
+	}
+
 	// Send the request
 	res, err := c.client.Do(req) //nolint:bodyclose // The response body is closed in defer func and linter can't see it
 	// Return any error
@@ -83,12 +90,14 @@ func (c *Client) SendRequest(url string) error {
 		return fmt.Errorf("logging response: %w", err)
 	}
 
-	// Check if the response is a hashcash challenge
-	hashcashChallenge := res.Header.Get(proofer.ChallengeHeader)
+	// Check if the response is a proof-of-work challenge
+	challenge, authScheme := c.challengeFrom(res)
+
+	// If it does, the access token is no longer accepted, solve it and retry
+	if challenge != "" {
+		c.setAccessToken("")
 
-	// If it does, solve it and retry
-	if hashcashChallenge != "" {
-		return c.solveHashcashChallengeAndRetry(url, hashcashChallenge)
+		return c.solveChallengeAndRetry(url, challenge, authScheme)
 	}
 
 	return nil
@@ -104,22 +113,50 @@ func (c *Client) buildEndpointURL() string {
 	)
 }
 
-// solveHashcashChallengeAndRetry solves the hashcash challenge and retries the request.
-func (c *Client) solveHashcashChallengeAndRetry(url, hashcashChallenge string) error {
-	// Parse the hashcash challenge
-	hc, err := hashcash.ParseStr(hashcashChallenge)
-	// Return any error
-	if err != nil {
-		return fmt.Errorf("parsing hashcash challenge: %w", err)
+// challengeFrom returns the proof-of-work challenge of the response, if any,
+// and whether it came under the PoW authentication scheme, rather than in the legacy X-Hashcash header.
+func (c *Client) challengeFrom(res *http.Response) (string, bool) {
+	// Prefer the WWW-Authenticate header, there may be one per authentication scheme
+	for _, header := range res.Header.Values(httpauth.ChallengeHeader) {
+		challenge, err := httpauth.ParseChallenge(header)
+		if err != nil {
+			continue
+		}
+
+		// Log the reason, why the previous solution was rejected, if it was
+		if challenge.Error != "" {
+			c.logger.Warn("solution rejected", zap.String("error", string(challenge.Error)))
+		}
+
+		return challenge.Challenge, true
 	}
 
-	// Solve the hashcash challenge
-	solution, err := hc.Solve()
+	// Fall back to the legacy header
+	return res.Header.Get(proofer.ChallengeHeader), false
+}
+
+// solveChallengeAndRetry solves the proof-of-work challenge and retries the request.
+// The challenge scheme, e.g. hashcash or argon2id, is detected from the challenge itself.
+// The solution is sent the same way the challenge came, under the PoW authentication scheme or in the legacy header.
+func (c *Client) solveChallengeAndRetry(url, challenge string, authScheme bool) error {
+	// Limit the time spent solving the challenge
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Solver.Timeout)
+	defer cancel()
+
+	// Solve the challenge
+	result, err := pow.Solve(ctx, challenge, c.cfg.Solver.Workers)
 	// Return any error
 	if err != nil {
-		return fmt.Errorf("solving hashcash challenge: %w", err)
+		return fmt.Errorf("solving challenge: %w", err)
 	}
 
+	// Log the solving stats
+	c.logger.Debug("challenge solved",
+		zap.Uint64("attempts", result.Attempts),
+		zap.Duration("duration", result.Duration),
+		zap.Float64("hash_rate", result.HashRate()),
+	)
+
 	// Retry the request with the solution
 	// Create a new request
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -129,7 +166,11 @@ func (c *Client) solveHashcashChallengeAndRetry(url, hashcashChallenge string) e
 	}
 
 	// Set the solution as a header
-	req.Header.Set(proofer.ChallengeHeader, solution)
+	if authScheme {
+		req.Header.Set(httpauth.AuthorizationHeader, httpauth.Authorization(result.Solution))
+	} else {
+		req.Header.Set(proofer.ChallengeHeader, result.Solution)
+	}
 
 	// Send the request
 	res, err := c.client.Do(req) //nolint:bodyclose // The response body is closed in defer func and linter can't see it
@@ -147,6 +188,11 @@ func (c *Client) solveHashcashChallengeAndRetry(url, hashcashChallenge string) e
 		}
 	}(res.Body, c.logger)
 
+	// Remember the access token, that the solution was exchanged for, if the server issued one
+	if token := res.Header.Get(proofer.TokenHeader); token != "" {
+		c.setAccessToken(token)
+	}
+
 	// Log the response
 	err = c.logResponse(res)
 	// Return any error
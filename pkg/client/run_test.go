@@ -12,6 +12,8 @@ import (
 
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
 	"github.com/daniel-orlov/quotes-server/pkg/client"
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
 )
 
 func TestClient_Run(t *testing.T) {
@@ -173,6 +175,96 @@ func TestClient_SendRequest(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("Solving Hashcash Challenge Times Out", func(t *testing.T) {
+		// Create a client, that gives up solving quickly
+		timeoutCfg := *cfg
+		timeoutCfg.Solver.Timeout = 50 * time.Millisecond
+		quotesClient := client.NewClient(logger, &timeoutCfg, &http.Client{})
+
+		// Start a mock server
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Respond with a hashcash challenge header, that can not be solved in time
+			w.Header().Set(proofer.ChallengeHeader, "1:60:23:some-resource::salt:0")
+			// Respond with a precondition required status code
+			w.WriteHeader(http.StatusPreconditionRequired)
+			w.Write([]byte(`{"quote": "Test quote"}`))
+		}))
+		defer server.Close()
+
+		// Invoke the SendRequest method
+		err = quotesClient.SendRequest(server.URL)
+
+		// Assertions
+		assert.ErrorIs(t, err, hashcash.ErrSolvingCanceled)
+	})
+
+	t.Run("Access Token", func(t *testing.T) {
+		// Create a new client
+		quotesClient := client.NewClient(logger, cfg, &http.Client{})
+
+		// Count the requests paid with a solution and with the token
+		var solved, withToken int
+
+		// Start a mock server, that exchanges the solutions for a token
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Header.Get(proofer.TokenHeader) == "token":
+				withToken++
+			case r.Header.Get(proofer.ChallengeHeader) != "":
+				solved++
+				w.Header().Set(proofer.TokenHeader, "token")
+			default:
+				w.Header().Set(proofer.ChallengeHeader, "1:4:23:some-resource::salt:0")
+				w.WriteHeader(http.StatusPreconditionRequired)
+			}
+
+			_, _ = w.Write([]byte(`{"quote": "Test quote"}`))
+		}))
+		defer server.Close()
+
+		// Send three requests
+		for i := 0; i < 3; i++ {
+			require.NoError(t, quotesClient.SendRequest(server.URL))
+		}
+
+		// Assertions, only the first request needs a solution
+		assert.Equal(t, 1, solved, "expected a single solution")
+		assert.Equal(t, 2, withToken, "expected the token to be reused")
+	})
+
+	t.Run("PoW Authentication Scheme", func(t *testing.T) {
+		// Create a new client
+		quotesClient := client.NewClient(logger, cfg, &http.Client{})
+
+		// Remember the solution sent back
+		var solution string
+
+		// Start a mock server, that sends the challenge under the PoW scheme only
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if header := r.Header.Get(httpauth.AuthorizationHeader); header != "" {
+				solution, _ = httpauth.ParseAuthorization(header)
+			} else {
+				w.Header().Add(httpauth.ChallengeHeader, `Bearer realm="api"`)
+				w.Header().Add(httpauth.ChallengeHeader, httpauth.Challenge{Challenge: "1:4:23:some-resource::salt:0"}.String())
+				w.WriteHeader(http.StatusPreconditionRequired)
+			}
+
+			_, _ = w.Write([]byte(`{"quote": "Test quote"}`))
+		}))
+		defer server.Close()
+
+		// Invoke the SendRequest method
+		err = quotesClient.SendRequest(server.URL)
+
+		// Assertions, the solution is sent back under the PoW scheme
+		require.NoError(t, err)
+		require.NotEmpty(t, solution, "expected the solution in the Authorization header")
+
+		stamp, err := hashcash.ParseStamp(solution)
+		require.NoError(t, err)
+		assert.NoError(t, stamp.Verify(), "expected a correct solution")
+	})
+
 	t.Run("Invalid Response Body", func(t *testing.T) {
 		// Create a new client
 		quotesClient := client.NewClient(logger, cfg, &http.Client{})
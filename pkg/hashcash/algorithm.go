@@ -0,0 +1,170 @@
+package hashcash
+
+import (
+	"crypto/sha1" //nolint:gosec // sha1 is used for hashcash by design
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// Algorithm is an identifier of the hash algorithm used to compute the hashcash.
+// It is carried in the stamp extension field, so that both the issuer and the solver use the same hash function.
+type Algorithm string
+
+const (
+	// AlgorithmSHA1 is the SHA-1 hash algorithm, as defined by the hashcash v1 specification.
+	AlgorithmSHA1 Algorithm = "sha1"
+
+	// AlgorithmSHA256 is the SHA-256 hash algorithm.
+	AlgorithmSHA256 Algorithm = "sha256"
+
+	// AlgorithmBLAKE2b256 is the BLAKE2b hash algorithm with 256-bit output.
+	AlgorithmBLAKE2b256 Algorithm = "blake2b256"
+
+	// AlgorithmSHA3256 is the SHA3 hash algorithm with 256-bit output.
+	AlgorithmSHA3256 Algorithm = "sha3-256"
+
+	// DefaultAlgorithm is the algorithm used when the stamp does not specify one.
+	// It is SHA-1 to stay compatible with the stamps minted by other hashcash v1 implementations.
+	DefaultAlgorithm = AlgorithmSHA1
+
+	// AlgorithmExtensionKey is the name of the extension field that carries the algorithm identifier.
+	AlgorithmExtensionKey = "alg"
+)
+
+// algorithms is the registry of the known hash algorithms.
+var algorithms = struct {
+	sync.RWMutex
+	registry map[Algorithm]*algorithmEntry
+}{
+	registry: map[Algorithm]*algorithmEntry{
+		AlgorithmSHA1:       newAlgorithmEntry(sha1.New), //nolint:gosec // sha1 is used for hashcash by design
+		AlgorithmSHA256:     newAlgorithmEntry(sha256.New),
+		AlgorithmBLAKE2b256: newAlgorithmEntry(newBLAKE2b256),
+		AlgorithmSHA3256:    newAlgorithmEntry(sha3.New256),
+	},
+}
+
+// algorithmEntry is a registered hash algorithm.
+type algorithmEntry struct {
+	// newHash creates a new hash function of the algorithm.
+	newHash func() hash.Hash
+	// hashers is a pool of hashers, so that verifying a stamp does not allocate a new hash function every time.
+	hashers sync.Pool
+}
+
+// newAlgorithmEntry creates a registry entry for the hash function constructor.
+func newAlgorithmEntry(newHash func() hash.Hash) *algorithmEntry {
+	entry := &algorithmEntry{newHash: newHash}
+
+	// Hashers are created on demand
+	entry.hashers.New = func() any {
+		return &hasher{hash: newHash()}
+	}
+
+	return entry
+}
+
+// hasher is a hash function along with the buffers it writes to and reads from.
+// The buffers are reused between the calls, so that hashing does not allocate.
+type hasher struct {
+	hash hash.Hash
+	buf  []byte
+	sum  []byte
+}
+
+// RegisterAlgorithm registers a new hash algorithm, so that it could be used in hashcash stamps.
+// It returns an error if the algorithm is already registered.
+func RegisterAlgorithm(algorithm Algorithm, newHash func() hash.Hash) error {
+	// Check that the algorithm and the hash constructor are not empty
+	if algorithm == "" || newHash == nil {
+		return ErrUnknownAlgorithm
+	}
+
+	algorithms.Lock()
+	defer algorithms.Unlock()
+
+	// Check that the algorithm is not registered yet
+	if _, ok := algorithms.registry[algorithm]; ok {
+		return fmt.Errorf("%w: %s", ErrAlgorithmAlreadyRegistered, algorithm)
+	}
+
+	// Register the algorithm
+	algorithms.registry[algorithm] = newAlgorithmEntry(newHash)
+
+	return nil
+}
+
+// String returns the string representation of the Algorithm.
+func (a Algorithm) String() string {
+	return string(a)
+}
+
+// IsValid checks if the Algorithm is registered.
+func (a Algorithm) IsValid() bool {
+	algorithms.RLock()
+	defer algorithms.RUnlock()
+
+	_, ok := algorithms.registry[a]
+
+	return ok
+}
+
+// New returns a new hash.Hash computing the Algorithm.
+func (a Algorithm) New() (hash.Hash, error) {
+	entry, err := a.entry()
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.newHash(), nil
+}
+
+// leadingZeroBits hashes the data with the Algorithm and returns the number of leading zero bits of the hash sum.
+// It uses a pooled hasher, so it does not allocate once the pool is warm.
+func (a Algorithm) leadingZeroBits(data string) (int, error) {
+	entry, err := a.entry()
+	if err != nil {
+		return 0, err
+	}
+
+	// Get a hasher from the pool and put it back when done
+	h, _ := entry.hashers.Get().(*hasher)
+	defer entry.hashers.Put(h)
+
+	// Copy the data to the reused buffer, to avoid converting it to a new byte slice
+	h.buf = append(h.buf[:0], data...)
+
+	// Hash the data
+	h.hash.Reset()
+	h.hash.Write(h.buf)
+	h.sum = h.hash.Sum(h.sum[:0])
+
+	return leadingZeroBits(h.sum), nil
+}
+
+// entry returns the registry entry of the Algorithm.
+func (a Algorithm) entry() (*algorithmEntry, error) {
+	algorithms.RLock()
+	entry, ok := algorithms.registry[a]
+	algorithms.RUnlock()
+
+	// Return error if the algorithm is not registered
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, a)
+	}
+
+	return entry, nil
+}
+
+// newBLAKE2b256 returns a new unkeyed BLAKE2b-256 hash.
+func newBLAKE2b256() hash.Hash {
+	// The error is only returned for keys longer than 64 bytes, so it is safe to ignore it here
+	h, _ := blake2b.New256(nil)
+
+	return h
+}
@@ -0,0 +1,115 @@
+package hashcash_test
+
+import (
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+func TestAlgorithm_IsValid(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm hashcash.Algorithm
+		want      bool
+	}{
+		{
+			name:      "SHA-1",
+			algorithm: hashcash.AlgorithmSHA1,
+			want:      true,
+		},
+		{
+			name:      "SHA-256",
+			algorithm: hashcash.AlgorithmSHA256,
+			want:      true,
+		},
+		{
+			name:      "BLAKE2b-256",
+			algorithm: hashcash.AlgorithmBLAKE2b256,
+			want:      true,
+		},
+		{
+			name:      "SHA3-256",
+			algorithm: hashcash.AlgorithmSHA3256,
+			want:      true,
+		},
+		{
+			name:      "empty",
+			algorithm: "",
+			want:      false,
+		},
+		{
+			name:      "unknown",
+			algorithm: "md5",
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.algorithm.IsValid())
+		})
+	}
+}
+
+func TestAlgorithm_New(t *testing.T) {
+	t.Run("known algorithm, should return hash function", func(t *testing.T) {
+		// Create a new hash function
+		h, err := hashcash.AlgorithmSHA3256.New()
+
+		// Check that no error is returned
+		assert.NoError(t, err)
+
+		// Check the size of the hash sum
+		assert.Equal(t, 32, h.Size())
+	})
+
+	t.Run("unknown algorithm, should return error", func(t *testing.T) {
+		// Create a new hash function
+		_, err := hashcash.Algorithm("md5").New()
+
+		// Check that the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrUnknownAlgorithm)
+	})
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	t.Run("empty algorithm, should return error", func(t *testing.T) {
+		// Register an empty algorithm
+		err := hashcash.RegisterAlgorithm("", sha512.New)
+
+		// Check that the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrUnknownAlgorithm)
+	})
+
+	t.Run("already registered algorithm, should return error", func(t *testing.T) {
+		// Register an existing algorithm
+		err := hashcash.RegisterAlgorithm(hashcash.AlgorithmSHA256, sha512.New)
+
+		// Check that the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrAlgorithmAlreadyRegistered)
+	})
+
+	t.Run("new algorithm, should be usable in hashcash", func(t *testing.T) {
+		// Register a new algorithm
+		err := hashcash.RegisterAlgorithm("sha512", sha512.New)
+		require.NoError(t, err)
+
+		// Create a new hashcash using the algorithm
+		hc, err := hashcash.New(8, 8, hashcash.DateFormatYYMMDD, "resource", hashcash.WithAlgorithm("sha512"))
+		require.NoError(t, err)
+
+		// Solve the hashcash
+		solution, err := hc.Solve()
+		require.NoError(t, err)
+
+		// Check the solution
+		passed, err := hashcash.CheckSolution(solution)
+
+		// Check that the solution passes
+		assert.NoError(t, err)
+		assert.True(t, passed)
+	})
+}
@@ -3,7 +3,13 @@ package hashcash
 import "fmt"
 
 // Check checks if the hashcash is solved and not expired.
+// Version 0 hashcashes do not carry the difficulty, so they can not be checked.
 func (h *Hashcash) Check() (bool, error) {
+	// Version 0 hashcash can not be checked against its own difficulty
+	if h != nil && h.version == Version0 {
+		return false, ErrUnknownDifficulty
+	}
+
 	// Check if the hashcash has expired
 	expired, err := h.HasExpired()
 	if err != nil {
@@ -27,13 +33,48 @@ func (h *Hashcash) Check() (bool, error) {
 }
 
 // CheckSolution parses solution string and checks if the hashcash is solved and not expired.
+// It uses the in-place Stamp parser, so it does not allocate for a valid solution.
 func CheckSolution(hashcashStr string) (bool, error) {
 	// Parse the hashcash string
-	hc, err := ParseStr(hashcashStr)
+	stamp, err := ParseStamp(hashcashStr)
 	if err != nil {
 		return false, fmt.Errorf("parsing hashcash string: %w", err)
 	}
 
 	// Check if the hashcash is solved and not expired
-	return hc.Check()
+	if err = stamp.Verify(); err != nil {
+		return false, err
+	}
+
+	// Solution is correct
+	return true, nil
+}
+
+// CheckSolutionForChallenge parses solution and challenge strings, makes sure the solution was produced
+// for exactly this challenge and checks if the hashcash is solved and not expired.
+func CheckSolutionForChallenge(hashcashStr, challengeStr string) (bool, error) {
+	// Parse the hashcash string
+	stamp, err := ParseStamp(hashcashStr)
+	if err != nil {
+		return false, fmt.Errorf("parsing hashcash string: %w", err)
+	}
+
+	// Parse the challenge string
+	challenge, err := ParseStamp(challengeStr)
+	if err != nil {
+		return false, fmt.Errorf("parsing challenge string: %w", err)
+	}
+
+	// Check that the hashcash matches the challenge
+	if err = stamp.Match(challenge); err != nil {
+		return false, fmt.Errorf("matching hashcash against challenge: %w", err)
+	}
+
+	// Check if the hashcash is solved and not expired
+	if err = stamp.Verify(); err != nil {
+		return false, err
+	}
+
+	// Solution is correct
+	return true, nil
 }
@@ -81,3 +81,63 @@ func TestCheckSolution(t *testing.T) {
 		assert.True(t, passed, "solution should pass")
 	})
 }
+
+func TestCheckSolutionForChallenge(t *testing.T) {
+	// The challenge the solution below was produced for
+	challenge := "1:20:23:some-resource::Kl7oUEQg:0"
+
+	t.Run("parsing solution failed", func(t *testing.T) {
+		// Checking solution should fail
+		passed, err := hashcash.CheckSolutionForChallenge("invalid hashcash", challenge)
+
+		// Error should not be nil
+		assert.Error(t, err, "parsing failed")
+
+		// Solution should not pass the check
+		assert.False(t, passed, "solution should not pass")
+	})
+
+	t.Run("parsing challenge failed", func(t *testing.T) {
+		// Checking solution should fail
+		passed, err := hashcash.CheckSolutionForChallenge("1:20:23:some-resource::Kl7oUEQg:4c73d", "invalid challenge")
+
+		// Error should not be nil
+		assert.Error(t, err, "parsing failed")
+
+		// Solution should not pass the check
+		assert.False(t, passed, "solution should not pass")
+	})
+
+	t.Run("solution has a lower difficulty than the challenge", func(t *testing.T) {
+		// Solved, but with a difficulty of the client's choice
+		passed, err := hashcash.CheckSolutionForChallenge("1:1:23:some-resource::Kl7oUEQg:0", challenge)
+
+		// Error should be a difficulty mismatch
+		assert.ErrorIs(t, err, hashcash.ErrDifficultyMismatch)
+
+		// Solution should not pass the check
+		assert.False(t, passed, "solution should not pass")
+	})
+
+	t.Run("solution has a salt of the client's choice", func(t *testing.T) {
+		// Salt differs from the one issued by the server
+		passed, err := hashcash.CheckSolutionForChallenge("1:20:23:some-resource::MySalt00:4c73d", challenge)
+
+		// Error should be a salt mismatch
+		assert.ErrorIs(t, err, hashcash.ErrSaltMismatch)
+
+		// Solution should not pass the check
+		assert.False(t, passed, "solution should not pass")
+	})
+
+	t.Run("solution matches the challenge and is solved", func(t *testing.T) {
+		// Checking solution should pass
+		passed, err := hashcash.CheckSolutionForChallenge("1:20:23:some-resource::Kl7oUEQg:4c73d", challenge)
+
+		// Error should be nil
+		assert.NoError(t, err, "checking solution failed")
+
+		// Solution should pass the check
+		assert.True(t, passed, "solution should pass")
+	})
+}
@@ -35,7 +35,7 @@ const (
 
 	// DateFormatYYMMDDhhmmss is the date format used in hashcash,
 	// indicating stamp validity period less than 2 minutes.
-	DateFormatYYMMDDhhmmss DateFormat = "060102150455"
+	DateFormatYYMMDDhhmmss DateFormat = "060102150405"
 
 	// MaxDurationYYMM is the maximum duration for which the date format is YYMM.
 	MaxDurationYYMM = 2 * 365 * 24 * time.Hour // 2 years
@@ -84,3 +84,23 @@ func ParseDateFormat(date string) (DateFormat, error) {
 		return "", ErrInvalidDateFormat
 	}
 }
+
+// MaxDuration returns the validity period indicated by the date format.
+// It reports false for DateFormatYY, as the stamps using it never expire.
+// Invalid date formats are valid for no time at all.
+func (df DateFormat) MaxDuration() (time.Duration, bool) {
+	switch df {
+	case DateFormatYY:
+		return 0, false
+	case DateFormatYYMM:
+		return MaxDurationYYMM, true
+	case DateFormatYYMMDD:
+		return MaxDurationYYMMDD, true
+	case DateFormatYYMMDDhhmm:
+		return MaxDurationYYMMDDhhmm, true
+	case DateFormatYYMMDDhhmmss:
+		return MaxDurationYYMMDDhhmmss, true
+	default:
+		return 0, true
+	}
+}
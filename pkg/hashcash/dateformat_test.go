@@ -38,7 +38,7 @@ func TestDateFormat_String(t *testing.T) {
 		{
 			name: "DateFormatYYMMDDhhmmss",
 			df:   hashcash.DateFormatYYMMDDhhmmss,
-			want: "060102150455",
+			want: "060102150405",
 		},
 		{
 			name: "InvalidDateFormat",
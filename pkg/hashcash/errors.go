@@ -15,9 +15,27 @@ var (
 	// ErrIncorrectNumberOfParts is returned when the number of parts contained in the hashcash string is incorrect.
 	ErrIncorrectNumberOfParts = errors.New("incorrect number of parts")
 
+	// ErrEmptyCounter is returned when the hashcash counter is empty.
+	ErrEmptyCounter = errors.New("hashcash counter is empty")
+
+	// ErrInvalidCounter is returned when the hashcash counter is neither hex nor base64.
+	ErrInvalidCounter = errors.New("hashcash counter is invalid")
+
+	// ErrInvalidExtension is returned when the hashcash extension field is malformed.
+	ErrInvalidExtension = errors.New("hashcash extension is invalid")
+
+	// ErrUnknownDifficulty is returned when checking a version 0 hashcash, that does not carry the difficulty.
+	ErrUnknownDifficulty = errors.New("hashcash difficulty is unknown")
+
 	// ErrInvalidVersion is returned when the hashcash version is invalid.
 	ErrInvalidVersion = errors.New("hashcash version is invalid")
 
+	// ErrUnknownAlgorithm is returned when the hash algorithm is not registered.
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+
+	// ErrAlgorithmAlreadyRegistered is returned when registering a hash algorithm that is already registered.
+	ErrAlgorithmAlreadyRegistered = errors.New("hash algorithm is already registered")
+
 	// ErrInvalidSaltLength is returned when the salt length is invalid.
 	ErrInvalidSaltLength = errors.New("invalid salt length")
 
@@ -30,6 +48,30 @@ var (
 	// ErrIncorrectSolution is returned when the hashcash solution is incorrect.
 	ErrIncorrectSolution = errors.New("incorrect solution")
 
+	// ErrSolvingCanceled is returned when solving the hashcash was canceled.
+	ErrSolvingCanceled = errors.New("solving hashcash was canceled")
+
 	// ErrAttemptToUseFutureHashcash is returned when the hashcash date is in the future.
 	ErrAttemptToUseFutureHashcash = errors.New("attempt to use future hashcash")
+
+	// ErrVersionMismatch is returned when the hashcash version does not match the challenge version.
+	ErrVersionMismatch = errors.New("hashcash version does not match the challenge")
+
+	// ErrDifficultyMismatch is returned when the hashcash difficulty does not match the challenge difficulty.
+	ErrDifficultyMismatch = errors.New("hashcash difficulty does not match the challenge")
+
+	// ErrDateMismatch is returned when the hashcash date does not match the challenge date.
+	ErrDateMismatch = errors.New("hashcash date does not match the challenge")
+
+	// ErrResourceMismatch is returned when the hashcash resource does not match the challenge resource.
+	ErrResourceMismatch = errors.New("hashcash resource does not match the challenge")
+
+	// ErrAlgorithmMismatch is returned when the hashcash algorithm does not match the challenge algorithm.
+	ErrAlgorithmMismatch = errors.New("hashcash algorithm does not match the challenge")
+
+	// ErrExtensionMismatch is returned when the hashcash extension does not match the challenge extension.
+	ErrExtensionMismatch = errors.New("hashcash extension does not match the challenge")
+
+	// ErrSaltMismatch is returned when the hashcash salt does not match the challenge salt.
+	ErrSaltMismatch = errors.New("hashcash salt does not match the challenge")
 )
@@ -0,0 +1,126 @@
+package hashcash
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExtensionField is a single field of the hashcash extension.
+// It is formatted as "name", if it has no values, or as "name=value1,value2,...", otherwise.
+type ExtensionField struct {
+	// Name is the name of the field.
+	Name string
+	// Values are the values of the field, in the order they were sent.
+	// It is nil, if the field has no "=" sign at all.
+	Values []string
+}
+
+// Extension is the hashcash v1 extension field: a list of fields separated by ";".
+// The order of the fields is preserved, so that a parsed extension is formatted back exactly as it was sent.
+//
+// Extract from http://hashcash.org/docs/hashcash.txt:
+//
+//	ext = [name1[=val1[,val2...]];[name2[=val1[,val2...]]...]]
+type Extension []ExtensionField
+
+// ParseExtension parses the hashcash extension field.
+// An empty string is a valid empty extension.
+func ParseExtension(extension string) (Extension, error) {
+	// Empty extension has no fields
+	if extension == "" {
+		return nil, nil
+	}
+
+	// Split the extension into the fields
+	rawFields := strings.Split(extension, ";")
+	ext := make(Extension, 0, len(rawFields))
+
+	for _, rawField := range rawFields {
+		name, values, hasValues := strings.Cut(rawField, "=")
+
+		// Every field must have a name
+		if name == "" {
+			return nil, fmt.Errorf("%w: field without a name in %q", ErrInvalidExtension, extension)
+		}
+
+		field := ExtensionField{Name: name}
+
+		// Split the values, if any
+		if hasValues {
+			field.Values = strings.Split(values, ",")
+		}
+
+		ext = append(ext, field)
+	}
+
+	return ext, nil
+}
+
+// String returns the extension formatted as the hashcash extension field.
+func (e Extension) String() string {
+	// Do not allocate for an empty extension
+	if len(e) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+
+	for i, field := range e {
+		// Separate the fields
+		if i > 0 {
+			b.WriteByte(';')
+		}
+
+		b.WriteString(field.Name)
+
+		// Field without values has no "=" sign
+		if field.Values == nil {
+			continue
+		}
+
+		b.WriteByte('=')
+		b.WriteString(strings.Join(field.Values, ","))
+	}
+
+	return b.String()
+}
+
+// Get returns the values of the first field with the given name and reports whether the field is present.
+func (e Extension) Get(name string) ([]string, bool) {
+	for _, field := range e {
+		if field.Name == name {
+			return field.Values, true
+		}
+	}
+
+	return nil, false
+}
+
+// Value returns the first value of the first field with the given name, or an empty string, if there is none.
+func (e Extension) Value(name string) string {
+	values, _ := e.Get(name)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set sets the values of the field with the given name, replacing the existing field or adding a new one to the end.
+// It returns the updated extension.
+func (e Extension) Set(name string, values ...string) Extension {
+	// Always store the values as a non-nil slice, so that the field is formatted with the "=" sign
+	field := ExtensionField{Name: name, Values: append([]string{}, values...)}
+
+	// Replace the existing field
+	for i := range e {
+		if e[i].Name == name {
+			e[i] = field
+
+			return e
+		}
+	}
+
+	// Add a new field
+	return append(e, field)
+}
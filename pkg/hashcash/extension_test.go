@@ -0,0 +1,86 @@
+package hashcash_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+func TestParseExtension(t *testing.T) {
+	t.Run("empty extension", func(t *testing.T) {
+		ext, err := hashcash.ParseExtension("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, ext)
+		assert.Equal(t, "", ext.String())
+	})
+
+	t.Run("field without a name, should return error", func(t *testing.T) {
+		_, err := hashcash.ParseExtension("a=1;;b")
+
+		assert.ErrorIs(t, err, hashcash.ErrInvalidExtension)
+	})
+
+	t.Run("fields are parsed and formatted back exactly", func(t *testing.T) {
+		// Fields with many values, without values and with an empty value
+		raw := "alg=sha256;list=1,2,3;flag;empty="
+
+		ext, err := hashcash.ParseExtension(raw)
+		require.NoError(t, err)
+
+		// Check the fields
+		assert.Equal(t, hashcash.Extension{
+			{Name: "alg", Values: []string{"sha256"}},
+			{Name: "list", Values: []string{"1", "2", "3"}},
+			{Name: "flag"},
+			{Name: "empty", Values: []string{""}},
+		}, ext)
+
+		// Check the round trip
+		assert.Equal(t, raw, ext.String())
+	})
+}
+
+func TestExtension_Get(t *testing.T) {
+	ext, err := hashcash.ParseExtension("list=1,2;flag")
+	require.NoError(t, err)
+
+	t.Run("field with values", func(t *testing.T) {
+		values, ok := ext.Get("list")
+
+		assert.True(t, ok)
+		assert.Equal(t, []string{"1", "2"}, values)
+		assert.Equal(t, "1", ext.Value("list"))
+	})
+
+	t.Run("field without values", func(t *testing.T) {
+		values, ok := ext.Get("flag")
+
+		assert.True(t, ok)
+		assert.Nil(t, values)
+		assert.Equal(t, "", ext.Value("flag"))
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		_, ok := ext.Get("missing")
+
+		assert.False(t, ok)
+	})
+}
+
+func TestExtension_Set(t *testing.T) {
+	t.Run("new field is added to the end", func(t *testing.T) {
+		ext := hashcash.Extension{{Name: "flag"}}.Set("alg", "sha256")
+
+		assert.Equal(t, "flag;alg=sha256", ext.String())
+	})
+
+	t.Run("existing field is replaced in place", func(t *testing.T) {
+		ext := hashcash.Extension{{Name: "alg", Values: []string{"sha1"}}, {Name: "flag"}}.Set("alg", "sha256")
+
+		assert.Equal(t, "alg=sha256;flag", ext.String())
+	})
+}
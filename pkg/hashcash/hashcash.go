@@ -1,4 +1,5 @@
 // Package hashcash contains the hashcash v1 implementation.
+// Version 0 stamps, minted by older tools, are parsed and verified as well.
 //
 // Hashcash is a proof-of-work system used to limit email spam and denial-of-service attacks.
 // Read more:
@@ -7,23 +8,24 @@
 package hashcash
 
 import (
+	"context"
 	"crypto/rand"
-	"crypto/sha1" //nolint:gosec // sha1 is used for hashcash by design
 	"encoding/base64"
-	"encoding/binary"
 	"fmt"
 	"hash"
-	"strconv"
 	"time"
 )
 
 // Hashcash is a representation of a hashcash version 1.
 type Hashcash struct {
 	// hash is the hashcash hash function.
-	// Currently, it is always sha1.
-	// It is added to the struct to avoid creating a new hash function every time.
+	// It is created from the algorithm and added to the struct to avoid creating a new hash function every time.
 	hash hash.Hash
 
+	// algorithm is the identifier of the hash function.
+	// It is carried in the extension field, unless it is the default one.
+	algorithm Algorithm
+
 	// Version is the hashcash version.
 	// It is always 1, as of this writing.
 	version int
@@ -41,16 +43,20 @@ type Hashcash struct {
 	// It could be an email address, a domain name, client IP address, or anything else.
 	resource string
 
-	// Extension was deprecated in version 1, so it is always empty.
-	// It made no sense to include it in the struct.
+	// Extension is a list of the extension fields, e.g. the algorithm identifier.
+	extension Extension
 
 	// Salt is a random string of characters.
 	// It is used to prevent hashcash collisions.
 	salt string
 
-	// Counter is a nonce.
-	// It is incremented until the hashcash is valid, i.e. has the required number of leading zeros.
-	counter int
+	// validity is the validity window, that HasExpired honours.
+	validity Validity
+
+	// Counter is a nonce, encoded in hex or base64.
+	// It is changed until the hashcash is valid, i.e. has the required number of leading zeros.
+	// It is kept as a string, so that counters of any length minted by other implementations are preserved.
+	counter string
 }
 
 const (
@@ -60,12 +66,30 @@ const (
 
 	// ValidPartsNumber is the number of parts in a valid hashcash string.
 	ValidPartsNumber = 7
+
+	// Version0 is the legacy hashcash version.
+	// Its stamps carry neither the difficulty, nor the extension, and are always hashed with SHA-1.
+	Version0 = 0
+
+	// ValidPartsNumberV0 is the number of parts in a valid version 0 hashcash string.
+	ValidPartsNumberV0 = 4
 )
 
+// Option is an optional parameter of a new hashcash.
+type Option func(h *Hashcash)
+
+// WithAlgorithm sets the hash algorithm of a new hashcash.
+// If it is not set, DefaultAlgorithm is used.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(h *Hashcash) {
+		h.algorithm = algorithm
+	}
+}
+
 // New creates a new hashcash.
-// It accepts the number of leading zeros and the resource.
+// It accepts the number of leading zeros, the salt length, the date format, the resource and optional parameters.
 // It returns a pointer to the hashcash and an error, if any.
-func New(difficulty, saltLen int, dateFormat DateFormat, resource string) (*Hashcash, error) {
+func New(difficulty, saltLen int, dateFormat DateFormat, resource string, opts ...Option) (*Hashcash, error) {
 	// Check if the difficulty is valid: it should be greater than 0.
 	if difficulty <= 0 {
 		return nil, ErrInvalidDifficulty
@@ -83,53 +107,89 @@ func New(difficulty, saltLen int, dateFormat DateFormat, resource string) (*Hash
 		return nil, fmt.Errorf("creating salt: %w", err)
 	}
 
-	// Return the hashcash and nil error
-	return &Hashcash{
-		hash:       sha1.New(), //nolint:gosec // sha1 is used for hashcash by design
+	// Create the hashcash
+	h := &Hashcash{
+		algorithm:  DefaultAlgorithm,
 		version:    Version,
 		difficulty: difficulty,
-		date:       time.Now(),
+		date:       time.Now().UTC(),
 		dateFormat: dateFormat,
 		salt:       salt,
 		resource:   resource,
-		// counter is 0 by default
-	}, nil
+		counter:    "0",
+	}
+
+	// Apply the optional parameters
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	// Carry the algorithm in the extension, unless it is the default one,
+	// so that the default stamps stay compatible with other hashcash v1 implementations
+	if h.algorithm != DefaultAlgorithm {
+		h.extension = h.extension.Set(AlgorithmExtensionKey, h.algorithm.String())
+	}
+
+	// Create the hash function of the chosen algorithm
+	h.hash, err = h.algorithm.New()
+	if err != nil {
+		return nil, fmt.Errorf("creating hash function: %w", err)
+	}
+
+	// Return the hashcash and nil error
+	return h, nil
+}
+
+// Algorithm returns the hash algorithm of the hashcash.
+func (h *Hashcash) Algorithm() Algorithm {
+	// Return an empty algorithm if the hashcash is nil. This is to avoid panics.
+	if h == nil {
+		return ""
+	}
+
+	return h.algorithm
 }
 
-// HasExpired checks if the hashcash has expired.
+// HasExpired checks if the hashcash has expired, honouring the validity window set with WithValidity.
 func (h *Hashcash) HasExpired() (bool, error) {
 	if h == nil {
 		return false, ErrNilHashcash
 	}
 
+	// Check the expiry time set by the issuer, if any
+	expires, ok, err := h.extension.Expires()
+	if err != nil {
+		return false, err
+	}
+
+	if ok && time.Now().After(expires.Add(h.validity.Skew)) {
+		return true, nil
+	}
+
+	// Check the hashcash date against the validity window
+	return hasExpired(h.date, h.dateFormat, h.validity)
+}
+
+// hasExpired checks if a hashcash of the given date and date format has expired under the validity window.
+func hasExpired(date time.Time, dateFormat DateFormat, validity Validity) (bool, error) {
 	// Get the duration since the hashcash date
-	duration := time.Since(h.date)
+	duration := time.Since(date)
 
-	// Check that the duration is not positive
-	if duration < 0 {
+	// Check that the date is not in the future, allowing for the clock skew
+	if duration < -validity.Skew {
 		return false, ErrAttemptToUseFutureHashcash
 	}
 
-	// Assume the hashcash has expired
-	expired := true
+	// Get for how long the hashcash is valid
+	maxAge, ok := validity.maxAge(dateFormat)
 
-	// Switch the hashcash date format
-	switch h.dateFormat {
-	case DateFormatYY:
-		// Can not expire
-		expired = false
-	case DateFormatYYMM:
-		expired = duration > MaxDurationYYMM
-	case DateFormatYYMMDD:
-		expired = duration > MaxDurationYYMMDD
-	case DateFormatYYMMDDhhmm:
-		expired = duration > MaxDurationYYMMDDhhmm
-	case DateFormatYYMMDDhhmmss:
-		expired = duration > MaxDurationYYMMDDhhmmss
+	// The hashcash can not expire
+	if !ok {
+		return false, nil
 	}
 
 	// Return the expiration status and nil error
-	return expired, nil
+	return duration > maxAge+validity.Skew, nil
 }
 
 // IsSolved checks if the hashcash is solved.
@@ -148,55 +208,59 @@ func (h *Hashcash) IsSolved() bool {
 	// Get the hash sum
 	sum := h.hash.Sum(nil)
 
-	// Cast the hash sum to uint64
-	sumUint64 := binary.BigEndian.Uint64(sum)
-
-	// Convert the hash sum to binary string
-	// This effectively removes the leading zeros
-	sumBits := strconv.FormatUint(sumUint64, 2)
-
-	// Get the number of leading zeros
-	leadingZeroes := 64 - len(sumBits)
-
-	// Check the number of leading zeros
-	return leadingZeroes >= h.difficulty
+	// Check the number of leading zero bits of the whole sum, so that any difficulty up to the hash size works
+	return leadingZeroBits(sum) >= h.difficulty
 }
 
-// Solve solves the hashcash using brute force.
+// Solve solves the hashcash using brute force in a single goroutine.
+// Use SolveContext to solve it in parallel or to be able to cancel solving.
 func (h *Hashcash) Solve() (string, error) {
-	if h == nil {
-		return "", ErrNilHashcash
+	// Solve the hashcash with a single worker, it can not be canceled
+	result, err := h.SolveContext(context.Background(), 1)
+	if err != nil {
+		return "", err
 	}
 
-	if h.hash == nil || h.date.IsZero() || h.resource == "" || h.salt == "" {
-		return "", ErrEmptyHashcash
-	}
+	// Return the hashcash and nil error
+	return result.Solution, nil
+}
 
-	// Increment the hashcash counter until it is valid
-	for !h.IsSolved() {
-		// Increment the counter
-		h.counter++
+// Extension returns the extension fields of the hashcash.
+func (h *Hashcash) Extension() Extension {
+	// Return an empty extension if the hashcash is nil. This is to avoid panics.
+	if h == nil {
+		return nil
 	}
 
-	// Return the hashcash and nil error
-	return h.String(), nil
+	return h.extension
 }
 
 // String returns the hashcash string.
+// A parsed hashcash is formatted back exactly as it was parsed.
 func (h *Hashcash) String() string {
 	// Return an empty string if the hashcash is nil. This is to avoid panics.
 	if h == nil {
 		return ""
 	}
 
+	return h.prefix() + h.counter
+}
+
+// prefix returns the hashcash string without the counter, i.e. everything up to and including the last colon.
+func (h *Hashcash) prefix() string {
+	// Version 0 stamps have neither the difficulty, nor the extension, nor the salt
+	if h.version == Version0 {
+		return fmt.Sprintf("%d:%s:%s:", h.version, h.dateString(), h.resource)
+	}
+
 	return fmt.Sprintf(
-		"%d:%d:%s:%s::%s:%x",
+		"%d:%d:%s:%s:%s:%s:",
 		h.version,
 		h.difficulty,
-		h.date.Format(h.dateFormat.String()), // Convert date to string of the given dateFormat
+		h.dateString(), // Convert date to string of the given dateFormat
 		h.resource,
+		h.extension.String(),
 		h.salt,
-		h.counter,
 	)
 }
 
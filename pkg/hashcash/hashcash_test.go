@@ -52,6 +52,31 @@ func Test_New(t *testing.T) {
 
 		// Check if the hashcash is not nil
 		assert.NotNil(t, hc, "hashcash should not be nil")
+
+		// Check if the default algorithm is used
+		assert.Equal(t, hashcash.DefaultAlgorithm, hc.Algorithm(), "default algorithm should be used")
+	})
+
+	t.Run("algorithm is unknown, should return error", func(t *testing.T) {
+		// Create a new hashcash
+		_, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDD, "resource", hashcash.WithAlgorithm("md5"))
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrUnknownAlgorithm, "creating hashcash should return an error")
+	})
+
+	t.Run("algorithm is known, should not return error", func(t *testing.T) {
+		// Create a new hashcash
+		hc, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDD, "resource", hashcash.WithAlgorithm(hashcash.AlgorithmSHA256))
+
+		// Check if the error is nil
+		assert.NoError(t, err, "creating hashcash should not return an error")
+
+		// Check if the algorithm is set
+		assert.Equal(t, hashcash.AlgorithmSHA256, hc.Algorithm(), "algorithm should be set")
+
+		// Check if the algorithm is carried in the extension
+		assert.Equal(t, "alg=sha256", strings.Split(hc.String(), ":")[4], "algorithm should be in the extension")
 	})
 }
 
@@ -67,15 +92,15 @@ func TestHashcash_String(t *testing.T) {
 		assert.Equal(t, "", stringHC, "hashcash string should be empty")
 	})
 
-	t.Run("hashcash is empty, should return string with zero values", func(t *testing.T) {
+	t.Run("hashcash is empty, should return version 0 string with zero values", func(t *testing.T) {
 		// Create a new empty hashcash
 		hc := hashcash.Hashcash{}
 
 		// Get the hashcash string
 		stringHC := hc.String()
 
-		// Check if the hashcash string is empty
-		assert.Equal(t, "0:0:::::0", stringHC, "hashcash string should be empty")
+		// Check if the hashcash string is empty, the zero version is 0
+		assert.Equal(t, "0:::", stringHC, "hashcash string should be empty")
 	})
 
 	t.Run("hashcash is not empty, should return correct string", func(t *testing.T) {
@@ -334,3 +359,33 @@ func TestHashcash_Solve(t *testing.T) {
 		assert.NotEqual(t, "", solution, "hashcash should not be empty")
 	})
 }
+
+func TestHashcash_Solve_Algorithms(t *testing.T) {
+	algorithms := []hashcash.Algorithm{
+		hashcash.AlgorithmSHA1,
+		hashcash.AlgorithmSHA256,
+		hashcash.AlgorithmBLAKE2b256,
+		hashcash.AlgorithmSHA3256,
+	}
+	for _, algorithm := range algorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			// Create a new hashcash
+			hc, err := hashcash.New(12, 8, hashcash.DateFormatYYMMDD, "resource", hashcash.WithAlgorithm(algorithm))
+			assert.NoError(t, err, "creating hashcash should not return an error")
+
+			// Solve the hashcash
+			solution, err := hc.Solve()
+			assert.NoError(t, err, "solving hashcash should not return an error")
+
+			// Parse the solution back, as the other party would do
+			parsed, err := hashcash.ParseStr(solution)
+			assert.NoError(t, err, "parsing solution should not return an error")
+
+			// Check that the algorithm survived the round trip
+			assert.Equal(t, algorithm, parsed.Algorithm(), "algorithm should be parsed from the solution")
+
+			// Check that the parsed solution is solved
+			assert.True(t, parsed.IsSolved(), "parsed solution should be solved")
+		})
+	}
+}
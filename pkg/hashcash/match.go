@@ -0,0 +1,56 @@
+package hashcash
+
+import "fmt"
+
+// Match checks that the hashcash was produced for the given challenge.
+// Every field of the hashcash, except for the counter, must be equal to the corresponding field of the challenge.
+// It returns a distinct error for each kind of mismatch, so that callers can tell them apart.
+func (h *Hashcash) Match(challenge *Hashcash) error {
+	// Return error if any of the hashcashes is nil. This is to avoid panics.
+	if h == nil || challenge == nil {
+		return ErrNilHashcash
+	}
+
+	// Check that the version matches
+	if h.version != challenge.version {
+		return fmt.Errorf("%w: expected %d, got %d", ErrVersionMismatch, challenge.version, h.version)
+	}
+
+	// Check that the algorithm matches
+	if h.algorithm != challenge.algorithm {
+		return fmt.Errorf("%w: expected %s, got %s", ErrAlgorithmMismatch, challenge.algorithm, h.algorithm)
+	}
+
+	// Check that the difficulty matches
+	if h.difficulty != challenge.difficulty {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDifficultyMismatch, challenge.difficulty, h.difficulty)
+	}
+
+	// Check that the date matches, comparing the dates in the format they were sent in
+	if h.dateString() != challenge.dateString() {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDateMismatch, challenge.dateString(), h.dateString())
+	}
+
+	// Check that the resource matches
+	if h.resource != challenge.resource {
+		return fmt.Errorf("%w: expected %s, got %s", ErrResourceMismatch, challenge.resource, h.resource)
+	}
+
+	// Check that the extension matches, so that none of the fields set by the issuer could be changed
+	if h.extension.String() != challenge.extension.String() {
+		return fmt.Errorf("%w: expected %s, got %s", ErrExtensionMismatch, challenge.extension, h.extension)
+	}
+
+	// Check that the salt matches
+	if h.salt != challenge.salt {
+		return fmt.Errorf("%w: expected %s, got %s", ErrSaltMismatch, challenge.salt, h.salt)
+	}
+
+	// All the fields match
+	return nil
+}
+
+// dateString returns the hashcash date formatted with the hashcash date format.
+func (h *Hashcash) dateString() string {
+	return h.date.Format(h.dateFormat.String())
+}
@@ -0,0 +1,88 @@
+package hashcash_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+func TestHashcash_Match(t *testing.T) {
+	// Format: <version>:<difficulty>:<date>:<resource>:<extension>:<salt>:<counter hash>
+	challengeStr := "1:20:230101:resource::salt:0"
+
+	t.Run("hashcash is nil, should return error", func(t *testing.T) {
+		// Create a new nil hashcash
+		var hc *hashcash.Hashcash
+
+		// Parse the challenge
+		challenge, err := hashcash.ParseStr(challengeStr)
+		require.NoError(t, err, "parsing challenge should not return an error")
+
+		// Match the hashcash against the challenge
+		err = hc.Match(challenge)
+
+		// Check that the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrNilHashcash)
+	})
+
+	tests := []struct {
+		name     string
+		solution string
+		wantErr  error
+	}{
+		{
+			name:     "only counter differs, should return no error",
+			solution: "1:20:230101:resource::salt:23a",
+			wantErr:  nil,
+		},
+		{
+			name:     "difficulty differs, should return ErrDifficultyMismatch",
+			solution: "1:1:230101:resource::salt:23a",
+			wantErr:  hashcash.ErrDifficultyMismatch,
+		},
+		{
+			name:     "date differs, should return ErrDateMismatch",
+			solution: "1:20:230102:resource::salt:23a",
+			wantErr:  hashcash.ErrDateMismatch,
+		},
+		{
+			name:     "date format differs, should return ErrDateMismatch",
+			solution: "1:20:2301:resource::salt:23a",
+			wantErr:  hashcash.ErrDateMismatch,
+		},
+		{
+			name:     "resource differs, should return ErrResourceMismatch",
+			solution: "1:20:230101:other-resource::salt:23a",
+			wantErr:  hashcash.ErrResourceMismatch,
+		},
+		{
+			name:     "salt differs, should return ErrSaltMismatch",
+			solution: "1:20:230101:resource::pepper:23a",
+			wantErr:  hashcash.ErrSaltMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Parse the challenge
+			challenge, err := hashcash.ParseStr(challengeStr)
+			require.NoError(t, err, "parsing challenge should not return an error")
+
+			// Parse the solution
+			solution, err := hashcash.ParseStr(tt.solution)
+			require.NoError(t, err, "parsing solution should not return an error")
+
+			// Match the solution against the challenge
+			err = solution.Match(challenge)
+
+			// Check the error
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package hashcash
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// NodeExtensionKey is the name of the extension field that carries the ID of the node, that issued the stamp.
+	NodeExtensionKey = "node"
+
+	// RouteExtensionKey is the name of the extension field that carries the route, the stamp was issued for.
+	// The route is query-escaped, as it may contain the characters that separate the stamp and extension fields.
+	RouteExtensionKey = "route"
+
+	// ExpiresExtensionKey is the name of the extension field that carries the expiry time of the stamp,
+	// as the number of seconds since the Unix epoch.
+	ExpiresExtensionKey = "exp"
+)
+
+// WithExtension sets an extension field of a new hashcash.
+// The values must not contain ":", ";", "," and "=", as they separate the stamp and extension fields.
+func WithExtension(name string, values ...string) Option {
+	return func(h *Hashcash) {
+		h.extension = h.extension.Set(name, values...)
+	}
+}
+
+// WithNode sets the ID of the node, that issues the hashcash.
+func WithNode(node string) Option {
+	return WithExtension(NodeExtensionKey, url.QueryEscape(node))
+}
+
+// WithRoute sets the route, the hashcash is issued for.
+func WithRoute(route string) Option {
+	return WithExtension(RouteExtensionKey, url.QueryEscape(route))
+}
+
+// WithExpires sets the expiry time of the hashcash.
+// The hashcash is considered expired after that time, regardless of its date format.
+func WithExpires(expires time.Time) Option {
+	return WithExtension(ExpiresExtensionKey, strconv.FormatInt(expires.Unix(), 10))
+}
+
+// Node returns the ID of the node, that issued the hashcash, or an empty string, if it is not set.
+func (e Extension) Node() string {
+	// Return the value as is, if it can not be unescaped
+	node, err := url.QueryUnescape(e.Value(NodeExtensionKey))
+	if err != nil {
+		return e.Value(NodeExtensionKey)
+	}
+
+	return node
+}
+
+// Route returns the route, the hashcash was issued for, or an empty string, if it is not set.
+func (e Extension) Route() string {
+	// Return the value as is, if it can not be unescaped
+	route, err := url.QueryUnescape(e.Value(RouteExtensionKey))
+	if err != nil {
+		return e.Value(RouteExtensionKey)
+	}
+
+	return route
+}
+
+// Expires returns the expiry time of the hashcash and reports whether it is set.
+func (e Extension) Expires() (time.Time, bool, error) {
+	// Check if the expiry time is set
+	value := e.Value(ExpiresExtensionKey)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+
+	// Parse the expiry time
+	expires, err := parseExpires(value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return expires, true, nil
+}
+
+// parseExpires parses the value of the expiry time extension field.
+func parseExpires(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: parsing expiry time: %s", ErrInvalidExtension, err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+// extensionValue returns the first value of the extension field with the given name, without parsing the extension.
+// It does not allocate, as it is used on the verification path.
+func extensionValue(extension, name string) (string, bool) {
+	for rest, more := extension, true; more; {
+		var field string
+		field, rest, more = strings.Cut(rest, ";")
+
+		fieldName, values, _ := strings.Cut(field, "=")
+		if fieldName != name {
+			continue
+		}
+
+		// Return the first value only
+		value, _, _ := strings.Cut(values, ",")
+
+		return value, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,83 @@
+package hashcash_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+func TestHashcash_Metadata(t *testing.T) {
+	t.Run("metadata survives the round trip", func(t *testing.T) {
+		expires := time.Now().Add(time.Minute).Truncate(time.Second)
+
+		// Create a new hashcash with the metadata
+		hc, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDD, "resource",
+			hashcash.WithAlgorithm(hashcash.AlgorithmSHA256),
+			hashcash.WithNode("node-1"),
+			hashcash.WithRoute("GET:/v1/quotes/random"),
+			hashcash.WithExpires(expires),
+		)
+		require.NoError(t, err)
+
+		// Parse it back, as the other party would do
+		parsed, err := hashcash.ParseStr(hc.String())
+		require.NoError(t, err)
+
+		// Check the metadata
+		ext := parsed.Extension()
+		assert.Equal(t, "node-1", ext.Node())
+		assert.Equal(t, "GET:/v1/quotes/random", ext.Route())
+
+		parsedExpires, ok, err := ext.Expires()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, expires.Equal(parsedExpires))
+
+		// Check that the algorithm is still there
+		assert.Equal(t, hashcash.AlgorithmSHA256, parsed.Algorithm())
+	})
+
+	t.Run("metadata is not set", func(t *testing.T) {
+		// Create a new hashcash without the metadata
+		hc, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDD, "resource")
+		require.NoError(t, err)
+
+		// Check the metadata
+		ext := hc.Extension()
+		assert.Empty(t, ext.Node())
+		assert.Empty(t, ext.Route())
+
+		_, ok, err := ext.Expires()
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid expiry time, should return error", func(t *testing.T) {
+		// Parse the hashcash with an invalid expiry time
+		stamp, err := hashcash.ParseStamp("1:20:23:resource:exp=soon:salt:0")
+		require.NoError(t, err)
+
+		// Check the stamp
+		assert.ErrorIs(t, stamp.Verify(), hashcash.ErrInvalidExtension)
+	})
+
+	t.Run("expiry time has passed, should be expired", func(t *testing.T) {
+		// Create a new hashcash, that has already expired
+		hc, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDD, "resource", hashcash.WithExpires(time.Now().Add(-time.Minute)))
+		require.NoError(t, err)
+
+		// Check the hashcash
+		expired, err := hc.HasExpired()
+		assert.NoError(t, err)
+		assert.True(t, expired)
+
+		// Check the stamp
+		stamp, err := hashcash.ParseStamp(hc.String())
+		require.NoError(t, err)
+		assert.ErrorIs(t, stamp.Verify(), hashcash.ErrExpiredHashcash)
+	})
+}
@@ -1,7 +1,6 @@
 package hashcash
 
 import (
-	"crypto/sha1" //nolint:gosec // sha1 is used for hashcash by design
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,47 +8,51 @@ import (
 )
 
 // ParseStr parses the hashcash string to the hashcash struct, validating it in the process.
-// It accepts the hashcash string, expecting it to be in the following format:
-// <version>:<difficulty>:<date>:<resource>:<extension>:<salt>:<counter hash>
+// It accepts the hashcash string, expecting it to be in one of the following formats:
+// <version 1>:<difficulty>:<date>:<resource>:<extension>:<salt>:<counter hash>
+// <version 0>:<date>:<resource>:<counter hash>
+// The parsed hashcash is formatted back by String exactly as it was sent.
 // It returns a pointer to the hashcash and an error, if any.
 func ParseStr(hashcash string) (*Hashcash, error) {
 	// Split the hashcash string by ":"
-	// The result is an array of strings
-	// The first element is the hashcash version
-	// The second element is the number of leading zeros required
-	// The third element is the date
-	// The fourth element is the resource
-	// The fifth element is the extension, which is always empty
-	// The sixth element is the salt
-	// The seventh element is the counter hash
+	// The first element is always the hashcash version, the rest depends on it
 	split := strings.Split(hashcash, ":")
 
-	// Check if the hashcash string has the correct number of elements
-	if len(split) != ValidPartsNumber {
-		return nil, fmt.Errorf("%w: expected %d, got %d", ErrIncorrectNumberOfParts, ValidPartsNumber, len(split))
-	}
-
 	// Convert the hashcash version to int
 	version, err := strconv.Atoi(split[0])
 	if err != nil {
 		return nil, fmt.Errorf("converting hashcash version to int: %w", err)
 	}
 
-	// Check if the hashcash version is valid
-	if version != Version {
-		return nil, fmt.Errorf("%w: expected %d, got %d", ErrInvalidVersion, Version, version)
+	// Parse the rest of the hashcash according to its version
+	switch version {
+	case Version:
+		return parseV1(split)
+	case Version0:
+		return parseV0(split)
+	default:
+		return nil, fmt.Errorf("%w: expected %d or %d, got %d", ErrInvalidVersion, Version, Version0, version)
 	}
+}
 
-	// Check if the date format is valid.
-	dateFormat, err := ParseDateFormat(split[2])
-	if err != nil {
-		return nil, fmt.Errorf("parsing hashcash date format: %w", err)
+// parseV1 parses the fields of a version 1 hashcash string.
+// The first element is the hashcash version
+// The second element is the number of leading zeros required
+// The third element is the date
+// The fourth element is the resource
+// The fifth element is the extension, a list of fields, e.g. the algorithm identifier
+// The sixth element is the salt
+// The seventh element is the counter hash
+func parseV1(split []string) (*Hashcash, error) {
+	// Check if the hashcash string has the correct number of elements
+	if len(split) != ValidPartsNumber {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrIncorrectNumberOfParts, ValidPartsNumber, len(split))
 	}
 
 	// Parse the date
-	date, err := time.Parse(dateFormat.String(), split[2])
+	date, dateFormat, err := parseDate(split[2])
 	if err != nil {
-		return nil, fmt.Errorf("parsing hashcash date: %w", err)
+		return nil, err
 	}
 
 	// Convert the number of leading zeros required to int
@@ -58,21 +61,155 @@ func ParseStr(hashcash string) (*Hashcash, error) {
 		return nil, fmt.Errorf("converting number of leading zeros required to int: %w", err)
 	}
 
-	// Parse the counter_hash back to counter (from hex to int)
-	counter, err := strconv.ParseInt(split[6], 16, 32)
+	// Check the counter, it is kept as is
+	if err = validateCounter(split[6]); err != nil {
+		return nil, err
+	}
+
+	// Parse the extension
+	extension, err := ParseExtension(split[4])
+	if err != nil {
+		return nil, fmt.Errorf("parsing hashcash extension: %w", err)
+	}
+
+	// Parse the algorithm from the extension
+	algorithm, err := parseAlgorithm(split[4])
 	if err != nil {
-		return nil, fmt.Errorf("parsing counter hash back to counter: %w", err)
+		return nil, fmt.Errorf("parsing hashcash algorithm: %w", err)
+	}
+
+	// Create the hash function of the algorithm
+	hashFunc, err := algorithm.New()
+	if err != nil {
+		return nil, fmt.Errorf("creating hash function: %w", err)
 	}
 
 	// Return the hashcash and nil error
 	return &Hashcash{
-		hash:       sha1.New(), //nolint:gosec // sha1 is used for hashcash by design
-		version:    version,
+		hash:       hashFunc,
+		algorithm:  algorithm,
+		version:    Version,
 		difficulty: difficulty,
 		date:       date,
 		dateFormat: dateFormat,
 		resource:   split[3],
+		extension:  extension,
 		salt:       split[5],
-		counter:    int(counter),
+		counter:    split[6],
+	}, nil
+}
+
+// parseV0 parses the fields of a version 0 hashcash string.
+// The first element is the hashcash version
+// The second element is the date
+// The third element is the resource
+// The fourth element is the counter hash
+func parseV0(split []string) (*Hashcash, error) {
+	// Check if the hashcash string has the correct number of elements
+	if len(split) != ValidPartsNumberV0 {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrIncorrectNumberOfParts, ValidPartsNumberV0, len(split))
+	}
+
+	// Parse the date
+	date, dateFormat, err := parseDate(split[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the counter, it is kept as is
+	if err = validateCounter(split[3]); err != nil {
+		return nil, err
+	}
+
+	// Version 0 stamps are always hashed with SHA-1
+	hashFunc, err := AlgorithmSHA1.New()
+	if err != nil {
+		return nil, fmt.Errorf("creating hash function: %w", err)
+	}
+
+	// Return the hashcash and nil error
+	return &Hashcash{
+		hash:       hashFunc,
+		algorithm:  AlgorithmSHA1,
+		version:    Version0,
+		date:       date,
+		dateFormat: dateFormat,
+		resource:   split[2],
+		counter:    split[3],
 	}, nil
 }
+
+// parseDate parses the hashcash date, detecting its format by the length.
+func parseDate(date string) (time.Time, DateFormat, error) {
+	// Check if the date format is valid.
+	dateFormat, err := ParseDateFormat(date)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("parsing hashcash date format: %w", err)
+	}
+
+	// Parse the date
+	parsed, err := time.Parse(dateFormat.String(), date)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("parsing hashcash date: %w", err)
+	}
+
+	return parsed, dateFormat, nil
+}
+
+// validateCounter checks that the counter is not empty and is encoded in hex or base64.
+// Hex is a subset of the base64 alphabet, so only the latter is checked.
+func validateCounter(counter string) error {
+	// Check that the counter is present
+	if counter == "" {
+		return ErrEmptyCounter
+	}
+
+	// Check every character of the counter
+	for i := 0; i < len(counter); i++ {
+		if !isBase64Char(counter[i]) {
+			return fmt.Errorf("%w: unexpected character %q", ErrInvalidCounter, counter[i])
+		}
+	}
+
+	return nil
+}
+
+// isBase64Char reports whether the character belongs to the base64 alphabet, including the padding.
+func isBase64Char(c byte) bool {
+	switch {
+	case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		return true
+	case c == '+', c == '/', c == '=':
+		return true
+	default:
+		return false
+	}
+}
+
+// parseAlgorithm parses the algorithm identifier from the hashcash extension field.
+// The extension is a list of fields separated by ";", each being either "name" or "name=value".
+// If the extension does not carry the algorithm, DefaultAlgorithm is returned.
+// It does not allocate, as it is used on the verification path.
+func parseAlgorithm(extension string) (Algorithm, error) {
+	// Go through the extension fields looking for the algorithm, without splitting the extension up front
+	for rest, more := extension, true; more; {
+		var field string
+		field, rest, more = strings.Cut(rest, ";")
+
+		name, value, found := strings.Cut(field, "=")
+		if name != AlgorithmExtensionKey {
+			continue
+		}
+
+		// Check that the algorithm is known
+		algorithm := Algorithm(value)
+		if !found || !algorithm.IsValid() {
+			return "", fmt.Errorf("%w: %q", ErrUnknownAlgorithm, value)
+		}
+
+		return algorithm, nil
+	}
+
+	// Extension does not carry the algorithm, use the default one
+	return DefaultAlgorithm, nil
+}
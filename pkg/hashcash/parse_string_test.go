@@ -53,10 +53,96 @@ func TestParseStr(t *testing.T) {
 
 	t.Run("invalid string - incorrect counter hash", func(t *testing.T) {
 		// Parse invalid hashcash string
-		_, err := hashcash.ParseStr("1:20:0212:resource::salt:NaN")
+		_, err := hashcash.ParseStr("1:20:0212:resource::salt:N@N")
 
 		// Check if the error is returned
-		assert.Error(t, err, "parsing counter hash back to counter: strconv.ParseInt: parsing \"NaN\": invalid syntax")
+		assert.ErrorIs(t, err, hashcash.ErrInvalidCounter, "counter should be hex or base64")
+	})
+
+	t.Run("invalid string - empty counter", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStr("1:20:0212:resource::salt:")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrEmptyCounter, "counter should not be empty")
+	})
+
+	t.Run("invalid string - extension field without a name", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStr("1:20:0212:resource:a=1;=2:salt:23a")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrInvalidExtension, "extension fields should have names")
+	})
+
+	t.Run("invalid string - version 0 with incorrect number of elements", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStr("0:230520:resource::salt:23a")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrIncorrectNumberOfParts, "incorrect number of parts: expected 4, got 6")
+	})
+
+	t.Run("invalid string - unknown algorithm", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStr("1:20:041010:resource:alg=md5:salt:23a")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrUnknownAlgorithm, "parsing unknown algorithm should fail")
+	})
+
+	t.Run("valid string with algorithm", func(t *testing.T) {
+		// Parse valid hashcash string
+		validHCString := "1:20:041010:resource:alg=sha3-256:salt:23a"
+
+		hc, err := hashcash.ParseStr(validHCString)
+
+		// Check that no error is returned
+		assert.NoError(t, err)
+
+		// Check if the algorithm is parsed
+		assert.Equal(t, hashcash.AlgorithmSHA3256, hc.Algorithm())
+
+		// Check if the hashcash is correct
+		assert.Equal(t, validHCString, hc.String())
+	})
+
+	t.Run("valid strings are formatted back exactly", func(t *testing.T) {
+		validHCStrings := []string{
+			// Minted by other implementations: extension lists and base64 counters of any length
+			"1:16:23:foo@example.com:a=1,2;b;c=:McMybZIhxKXu57jd:AAAAAChK",
+			"1:20:230520:foo@example.com:alg=sha256;note=x:salt:00000000000000000000000000000000ff",
+			// Version 0
+			"0:23:foo@example.com:AAAAARhv",
+		}
+
+		for _, validHCString := range validHCStrings {
+			hc, err := hashcash.ParseStr(validHCString)
+
+			// Check that no error is returned
+			assert.NoError(t, err)
+
+			// Check if the hashcash is formatted back exactly
+			assert.Equal(t, validHCString, hc.String())
+		}
+	})
+
+	t.Run("valid string with extension fields", func(t *testing.T) {
+		// Parse valid hashcash string
+		hc, err := hashcash.ParseStr("1:16:23:foo@example.com:a=1,2;b;c=:McMybZIhxKXu57jd:AAAAAChK")
+
+		// Check that no error is returned
+		assert.NoError(t, err)
+
+		// Check if the extension fields are parsed
+		assert.Equal(t, hashcash.Extension{
+			{Name: "a", Values: []string{"1", "2"}},
+			{Name: "b"},
+			{Name: "c", Values: []string{""}},
+		}, hc.Extension())
+
+		// Check that the stamp minted elsewhere is solved
+		assert.True(t, hc.IsSolved())
 	})
 
 	t.Run("valid string", func(t *testing.T) {
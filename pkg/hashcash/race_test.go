@@ -0,0 +1,6 @@
+//go:build race
+
+package hashcash_test
+
+// raceEnabled reports whether the tests run with the race detector, which makes some allocations of its own.
+const raceEnabled = true
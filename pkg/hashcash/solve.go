@@ -0,0 +1,186 @@
+package hashcash
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"math/bits"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cancelCheckInterval is the number of attempts a worker makes between checking if solving was canceled.
+// Checking the context on every attempt would noticeably slow down the workers.
+const cancelCheckInterval = 1 << 10
+
+// SolveResult is the result of solving a hashcash.
+type SolveResult struct {
+	// Solution is the solved hashcash string.
+	// It is empty, if the hashcash was not solved.
+	Solution string
+	// Attempts is the number of hashes computed by all the workers.
+	Attempts uint64
+	// Duration is the time spent solving.
+	Duration time.Duration
+}
+
+// HashRate returns the number of hashes computed per second.
+func (r *SolveResult) HashRate() float64 {
+	// Return 0 if nothing was measured. This is to avoid division by zero.
+	if r == nil || r.Duration <= 0 {
+		return 0
+	}
+
+	return float64(r.Attempts) / r.Duration.Seconds()
+}
+
+// SolveContext solves the hashcash using brute force, splitting the counter space across the given number of workers.
+// Worker i tries the counters i, i+workers, i+2*workers and so on, starting from the current counter.
+// If workers is not positive, the number of CPUs is used.
+// Solving stops as soon as one of the workers finds a solution or the context is done.
+// In the latter case the result is returned along with ErrSolvingCanceled, so that the attempts are still reported.
+func (h *Hashcash) SolveContext(ctx context.Context, workers int) (*SolveResult, error) {
+	if h == nil {
+		return nil, ErrNilHashcash
+	}
+
+	if h.hash == nil || h.date.IsZero() || h.resource == "" || h.salt == "" {
+		return nil, ErrEmptyHashcash
+	}
+
+	// Use all the CPUs, if the number of workers is not set
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Create the hash functions upfront, as hash.Hash is not safe for concurrent use
+	hashes := make([]hash.Hash, workers)
+	for i := range hashes {
+		hashFunc, err := h.algorithm.New()
+		if err != nil {
+			return nil, fmt.Errorf("creating hash function: %w", err)
+		}
+
+		hashes[i] = hashFunc
+	}
+
+	// Stop the rest of the workers as soon as one of them finds a solution
+	workersCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Everything but the counter stays the same, so the prefix is only formatted once
+	prefix := []byte(h.prefix())
+
+	// Continue from the current counter, if it is a hex number, otherwise start over
+	start, err := strconv.ParseUint(h.counter, 16, 64)
+	if err != nil {
+		start = 0
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		attempts uint64
+		solution uint64
+		solved   bool
+	)
+
+	startedAt := time.Now()
+
+	// Start the workers
+	for i, hashFunc := range hashes {
+		wg.Add(1)
+
+		go func(first uint64, hashFunc hash.Hash) {
+			defer wg.Done()
+
+			counter, n, ok := solveStride(workersCtx, hashFunc, prefix, h.difficulty, first, uint64(workers))
+
+			// Sum up the attempts of all the workers
+			atomic.AddUint64(&attempts, n)
+
+			// Only the first solution found is used
+			if ok {
+				once.Do(func() {
+					solution, solved = counter, true
+
+					cancel()
+				})
+			}
+		}(start+uint64(i), hashFunc)
+	}
+
+	// Wait for all the workers to stop
+	wg.Wait()
+
+	result := &SolveResult{Attempts: attempts, Duration: time.Since(startedAt)}
+
+	// The context was done before any of the workers found a solution
+	if !solved {
+		return result, fmt.Errorf("%w: %s", ErrSolvingCanceled, ctx.Err())
+	}
+
+	// Set the counter to the solution, in hex
+	h.counter = strconv.FormatUint(solution, 16)
+	result.Solution = h.String()
+
+	// Return the result and nil error
+	return result, nil
+}
+
+// solveStride tries the counters first, first+stride, first+2*stride and so on,
+// until the hash of the prefix followed by the counter has the required number of leading zero bits.
+// It returns the counter found, the number of attempts made and whether the solution was found.
+func solveStride(ctx context.Context, hashFunc hash.Hash, prefix []byte, difficulty int, first, stride uint64) (uint64, uint64, bool) {
+	// Reuse the same buffers for every attempt, so that the loop does not allocate
+	buf := make([]byte, len(prefix), len(prefix)+16)
+	copy(buf, prefix)
+
+	sum := make([]byte, 0, hashFunc.Size())
+
+	var attempts uint64
+
+	for counter := first; ; counter += stride {
+		// Check if solving was canceled every now and then
+		if attempts%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return 0, attempts, false
+		}
+
+		attempts++
+
+		// Append the counter in hex to the prefix, the same way String does
+		buf = strconv.AppendUint(buf[:len(prefix)], counter, 16)
+
+		// Hash the stamp
+		hashFunc.Reset()
+		hashFunc.Write(buf)
+		sum = hashFunc.Sum(sum[:0])
+
+		// Check the number of leading zeros
+		if leadingZeroBits(sum) >= difficulty {
+			return counter, attempts, true
+		}
+	}
+}
+
+// leadingZeroBits returns the number of leading zero bits of the hash sum.
+func leadingZeroBits(sum []byte) int {
+	zeros := 0
+
+	for _, b := range sum {
+		// Count the whole zero bytes
+		if b == 0 {
+			zeros += 8
+
+			continue
+		}
+
+		// Count the leading zeros of the first non-zero byte and stop
+		return zeros + bits.LeadingZeros8(b)
+	}
+
+	return zeros
+}
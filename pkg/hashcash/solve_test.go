@@ -0,0 +1,95 @@
+package hashcash_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+func TestHashcash_SolveContext(t *testing.T) {
+	t.Run("hashcash is nil, should return error", func(t *testing.T) {
+		// Create a nil hashcash
+		var hc *hashcash.Hashcash
+
+		// Solve the hashcash
+		_, err := hc.SolveContext(context.Background(), 4)
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrNilHashcash)
+	})
+
+	t.Run("hashcash is empty, should return error", func(t *testing.T) {
+		// Create an empty hashcash
+		hc := &hashcash.Hashcash{}
+
+		// Solve the hashcash
+		_, err := hc.SolveContext(context.Background(), 4)
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrEmptyHashcash)
+	})
+
+	for _, workers := range []int{0, 1, 4} {
+		workers := workers
+
+		t.Run(fmt.Sprintf("hashcash is solved by %d workers", workers), func(t *testing.T) {
+			// Create a new hashcash
+			hc, err := hashcash.New(12, 8, hashcash.DateFormatYYMMDD, "resource")
+			require.NoError(t, err)
+
+			// Solve the hashcash
+			result, err := hc.SolveContext(context.Background(), workers)
+			require.NoError(t, err)
+
+			// Check that the solution is correct
+			solved, err := hashcash.CheckSolution(result.Solution)
+			assert.NoError(t, err)
+			assert.True(t, solved, "solution %q with %d workers should be correct", result.Solution, workers)
+
+			// Check that the attempts are reported
+			assert.Positive(t, result.Attempts)
+			assert.Positive(t, result.HashRate())
+		})
+	}
+
+	t.Run("solving is canceled, should return error and the attempts made", func(t *testing.T) {
+		// Create a new hashcash, that can not be solved in time
+		hc, err := hashcash.New(60, 8, hashcash.DateFormatYYMMDD, "resource")
+		require.NoError(t, err)
+
+		// Give the workers a little time
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// Solve the hashcash
+		result, err := hc.SolveContext(ctx, 2)
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrSolvingCanceled)
+
+		// Check that the attempts are still reported
+		require.NotNil(t, result)
+		assert.Empty(t, result.Solution)
+		assert.Positive(t, result.Attempts)
+	})
+}
+
+func TestSolveResult_HashRate(t *testing.T) {
+	t.Run("result is nil, should return 0", func(t *testing.T) {
+		var result *hashcash.SolveResult
+
+		assert.Zero(t, result.HashRate())
+	})
+
+	t.Run("result is not empty, should return attempts per second", func(t *testing.T) {
+		result := &hashcash.SolveResult{Attempts: 1000, Duration: 2 * time.Second}
+
+		assert.Equal(t, float64(500), result.HashRate())
+	})
+}
@@ -0,0 +1,389 @@
+package hashcash
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stamp is a read-only view of a hashcash string, split into its fields in place.
+// Unlike ParseStr, parsing a stamp does not allocate: the fields are substrings of the original string,
+// and the stamp is verified by hashing the original string as is, without formatting it again.
+// This makes it suitable for the verification of every incoming solution.
+type Stamp struct {
+	// raw is the original hashcash string.
+	raw string
+
+	// version is the hashcash version.
+	version int
+
+	// difficulty is the number of leading zero bits required.
+	difficulty int
+
+	// algorithm is the hash algorithm, carried in the extension field.
+	algorithm Algorithm
+
+	// date is the date field, as it was sent.
+	date string
+
+	// dateFormat is the date format, detected from the date field.
+	dateFormat DateFormat
+
+	// resource is the resource to which the stamp is tied.
+	resource string
+
+	// extension is the extension field, as it was sent.
+	extension string
+
+	// salt is the salt field.
+	salt string
+
+	// counter is the counter field, as it was sent.
+	counter string
+
+	// validity is the validity window, that the stamp is verified against.
+	validity Validity
+}
+
+// ParseStamp splits the hashcash string into its fields in place, validating them in the process.
+// It expects the string to be in one of the following formats:
+// <version 1>:<difficulty>:<date>:<resource>:<extension>:<salt>:<counter hash>
+// <version 0>:<date>:<resource>:<counter hash>
+// The errors are the same as the ones returned by ParseStr.
+func ParseStamp(stamp string) (Stamp, error) {
+	// Cut the version off, the rest of the fields depend on it
+	versionStr, _, _ := strings.Cut(stamp, ":")
+
+	// Convert the hashcash version to int
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		return Stamp{}, fmt.Errorf("converting hashcash version to int: %w", err)
+	}
+
+	// Parse the rest of the stamp according to its version
+	switch version {
+	case Version:
+		return parseStampV1(stamp)
+	case Version0:
+		return parseStampV0(stamp)
+	default:
+		return Stamp{}, fmt.Errorf("%w: expected %d or %d, got %d", ErrInvalidVersion, Version, Version0, version)
+	}
+}
+
+// parseStampV1 parses a version 1 hashcash string in place.
+func parseStampV1(stamp string) (Stamp, error) {
+	// Split the string into the fields without allocating a slice
+	var fields [ValidPartsNumber]string
+	if err := splitFields(stamp, fields[:]); err != nil {
+		return Stamp{}, err
+	}
+
+	// Convert the number of leading zeros required to int
+	difficulty, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Stamp{}, fmt.Errorf("converting number of leading zeros required to int: %w", err)
+	}
+
+	// Detect the date format
+	dateFormat, err := ParseDateFormat(fields[2])
+	if err != nil {
+		return Stamp{}, fmt.Errorf("parsing hashcash date format: %w", err)
+	}
+
+	// Check that every extension field has a name
+	if err = validateExtension(fields[4]); err != nil {
+		return Stamp{}, fmt.Errorf("parsing hashcash extension: %w", err)
+	}
+
+	// Parse the algorithm from the extension
+	algorithm, err := parseAlgorithm(fields[4])
+	if err != nil {
+		return Stamp{}, fmt.Errorf("parsing hashcash algorithm: %w", err)
+	}
+
+	// Check the counter
+	if err = validateCounter(fields[6]); err != nil {
+		return Stamp{}, err
+	}
+
+	// Return the stamp and nil error
+	return Stamp{
+		raw:        stamp,
+		version:    Version,
+		difficulty: difficulty,
+		algorithm:  algorithm,
+		date:       fields[2],
+		dateFormat: dateFormat,
+		resource:   fields[3],
+		extension:  fields[4],
+		salt:       fields[5],
+		counter:    fields[6],
+	}, nil
+}
+
+// parseStampV0 parses a version 0 hashcash string in place.
+func parseStampV0(stamp string) (Stamp, error) {
+	// Split the string into the fields without allocating a slice
+	var fields [ValidPartsNumberV0]string
+	if err := splitFields(stamp, fields[:]); err != nil {
+		return Stamp{}, err
+	}
+
+	// Detect the date format
+	dateFormat, err := ParseDateFormat(fields[1])
+	if err != nil {
+		return Stamp{}, fmt.Errorf("parsing hashcash date format: %w", err)
+	}
+
+	// Check the counter
+	if err = validateCounter(fields[3]); err != nil {
+		return Stamp{}, err
+	}
+
+	// Return the stamp and nil error, version 0 stamps are always hashed with SHA-1
+	return Stamp{
+		raw:        stamp,
+		version:    Version0,
+		algorithm:  AlgorithmSHA1,
+		date:       fields[1],
+		dateFormat: dateFormat,
+		resource:   fields[2],
+		counter:    fields[3],
+	}, nil
+}
+
+// splitFields splits the hashcash string by ":" into exactly len(fields) fields.
+func splitFields(stamp string, fields []string) error {
+	rest := stamp
+
+	for i := 0; i < len(fields)-1; i++ {
+		field, tail, found := strings.Cut(rest, ":")
+		// There are fewer fields than expected
+		if !found {
+			return fmt.Errorf("%w: expected %d, got %d", ErrIncorrectNumberOfParts, len(fields), i+1)
+		}
+
+		fields[i], rest = field, tail
+	}
+
+	// There are more fields than expected
+	if extra := strings.Count(rest, ":"); extra > 0 {
+		return fmt.Errorf("%w: expected %d, got %d", ErrIncorrectNumberOfParts, len(fields), len(fields)+extra)
+	}
+
+	// The rest is the last field
+	fields[len(fields)-1] = rest
+
+	return nil
+}
+
+// validateExtension checks that every field of the extension has a name, without splitting the extension.
+func validateExtension(extension string) error {
+	// Empty extension is valid
+	if extension == "" {
+		return nil
+	}
+
+	for rest, more := extension, true; more; {
+		var field string
+		field, rest, more = strings.Cut(rest, ";")
+
+		// Every field must have a name
+		if name, _, _ := strings.Cut(field, "="); name == "" {
+			return fmt.Errorf("%w: field without a name in %q", ErrInvalidExtension, extension)
+		}
+	}
+
+	return nil
+}
+
+// String returns the original hashcash string.
+func (s Stamp) String() string {
+	return s.raw
+}
+
+// Algorithm returns the hash algorithm of the stamp.
+func (s Stamp) Algorithm() Algorithm {
+	return s.algorithm
+}
+
+// Version returns the hashcash version of the stamp.
+func (s Stamp) Version() int {
+	return s.version
+}
+
+// Difficulty returns the number of leading zero bits required.
+// It is 0 for version 0 stamps, as they do not carry the difficulty.
+func (s Stamp) Difficulty() int {
+	return s.difficulty
+}
+
+// Resource returns the resource to which the stamp is tied.
+func (s Stamp) Resource() string {
+	return s.resource
+}
+
+// Extension returns the extension field of the stamp, as it was sent.
+// Use ParseExtension to get the individual fields.
+func (s Stamp) Extension() string {
+	return s.extension
+}
+
+// Salt returns the salt field of the stamp.
+// It is empty for version 0 stamps, as they do not carry the salt.
+func (s Stamp) Salt() string {
+	return s.salt
+}
+
+// Counter returns the counter field of the stamp, as it was sent.
+func (s Stamp) Counter() string {
+	return s.counter
+}
+
+// Match checks that the stamp was produced for the given challenge.
+// Every field of the stamp, except for the counter, must be equal to the corresponding field of the challenge.
+// It returns the same errors as Hashcash.Match.
+func (s Stamp) Match(challenge Stamp) error {
+	// Check that the version matches
+	if s.version != challenge.version {
+		return fmt.Errorf("%w: expected %d, got %d", ErrVersionMismatch, challenge.version, s.version)
+	}
+
+	// Check that the algorithm matches
+	if s.algorithm != challenge.algorithm {
+		return fmt.Errorf("%w: expected %s, got %s", ErrAlgorithmMismatch, challenge.algorithm, s.algorithm)
+	}
+
+	// Check that the difficulty matches
+	if s.difficulty != challenge.difficulty {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDifficultyMismatch, challenge.difficulty, s.difficulty)
+	}
+
+	// Check that the date matches
+	if s.date != challenge.date {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDateMismatch, challenge.date, s.date)
+	}
+
+	// Check that the resource matches
+	if s.resource != challenge.resource {
+		return fmt.Errorf("%w: expected %s, got %s", ErrResourceMismatch, challenge.resource, s.resource)
+	}
+
+	// Check that the extension matches
+	if s.extension != challenge.extension {
+		return fmt.Errorf("%w: expected %s, got %s", ErrExtensionMismatch, challenge.extension, s.extension)
+	}
+
+	// Check that the salt matches
+	if s.salt != challenge.salt {
+		return fmt.Errorf("%w: expected %s, got %s", ErrSaltMismatch, challenge.salt, s.salt)
+	}
+
+	// All the fields match
+	return nil
+}
+
+// WithValidity returns a copy of the stamp, that is verified against the given validity window.
+func (s Stamp) WithValidity(validity Validity) Stamp {
+	s.validity = validity
+
+	return s
+}
+
+// Verify checks that the stamp is solved and not expired.
+// It hashes the original string and does not allocate, unless the stamp is invalid.
+// Version 0 stamps do not carry the difficulty, so they have to be checked with VerifyBits.
+func (s Stamp) Verify() error {
+	// Version 0 stamps can not be checked against their own difficulty
+	if s.version == Version0 {
+		return ErrUnknownDifficulty
+	}
+
+	return s.VerifyBits(s.difficulty)
+}
+
+// VerifyBits checks that the stamp is not expired and has at least the given number of leading zero bits.
+func (s Stamp) VerifyBits(bits int) error {
+	// Parse the date
+	date, err := time.Parse(s.dateFormat.String(), s.date)
+	if err != nil {
+		return fmt.Errorf("parsing hashcash date: %w", err)
+	}
+
+	// Check if the stamp has expired
+	expired, err := hasExpired(date, s.dateFormat, s.validity)
+	if err != nil {
+		return fmt.Errorf("checking if hashcash has expired: %w", err)
+	}
+
+	if expired {
+		return ErrExpiredHashcash
+	}
+
+	// Check the expiry time set by the issuer, if any
+	if value, ok := extensionValue(s.extension, ExpiresExtensionKey); ok {
+		expires, err := parseExpires(value)
+		if err != nil {
+			return err
+		}
+
+		if time.Now().After(expires.Add(s.validity.Skew)) {
+			return ErrExpiredHashcash
+		}
+	}
+
+	// Hash the original string
+	zeros, err := s.algorithm.leadingZeroBits(s.raw)
+	if err != nil {
+		return fmt.Errorf("hashing hashcash: %w", err)
+	}
+
+	// Check the number of leading zeros
+	if zeros < bits {
+		return ErrIncorrectSolution
+	}
+
+	// Solution is correct
+	return nil
+}
+
+// Expires returns the time the stamp is no longer accepted after, that is the expiry time set by the issuer,
+// or the end of the validity window, whichever comes first, plus the clock skew allowance.
+// It reports false, if the stamp never expires.
+func (s Stamp) Expires() (time.Time, bool, error) {
+	// Parse the date
+	date, err := time.Parse(s.dateFormat.String(), s.date)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("parsing hashcash date: %w", err)
+	}
+
+	// Get the end of the validity window, if any
+	var expires time.Time
+
+	maxAge, ok := s.validity.maxAge(s.dateFormat)
+	if ok {
+		expires = date.Add(maxAge)
+	}
+
+	// Check the expiry time set by the issuer, if any
+	if value, found := extensionValue(s.extension, ExpiresExtensionKey); found {
+		issuerExpires, err := parseExpires(value)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		// The earliest of the two wins
+		if !ok || issuerExpires.Before(expires) {
+			expires, ok = issuerExpires, true
+		}
+	}
+
+	// The stamp never expires
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	return expires.Add(s.validity.Skew), true, nil
+}
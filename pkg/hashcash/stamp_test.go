@@ -0,0 +1,296 @@
+package hashcash_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+// solvedStamp is a valid solved hashcash, that does not expire.
+const solvedStamp = "1:20:23:some-resource::Kl7oUEQg:4c73d"
+
+func TestParseStamp(t *testing.T) {
+	t.Run("invalid string - too few fields", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStamp("1:20:060102150405::salt")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrIncorrectNumberOfParts)
+	})
+
+	t.Run("invalid string - too many fields", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStamp("1:20:23:some-resource::salt:23a:extra")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrIncorrectNumberOfParts)
+	})
+
+	t.Run("invalid string - incorrect hashcash version", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStamp("2:20:23:some-resource::salt:23a")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrInvalidVersion)
+	})
+
+	t.Run("invalid string - incorrect date format", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStamp("1:20:12345:some-resource::salt:23a")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrInvalidDateFormat)
+	})
+
+	t.Run("invalid string - unknown algorithm", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStamp("1:20:23:some-resource:alg=md5:salt:23a")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrUnknownAlgorithm)
+	})
+
+	t.Run("invalid string - empty counter", func(t *testing.T) {
+		// Parse invalid hashcash string
+		_, err := hashcash.ParseStamp("1:20:23:some-resource::salt:")
+
+		// Check if the error is returned
+		assert.ErrorIs(t, err, hashcash.ErrEmptyCounter)
+	})
+
+	t.Run("valid string", func(t *testing.T) {
+		// Parse valid hashcash string
+		stamp, err := hashcash.ParseStamp("1:20:23:some-resource:alg=sha256:salt:23a")
+
+		// Check that no error is returned
+		require.NoError(t, err)
+
+		// Check the fields
+		assert.Equal(t, 20, stamp.Difficulty())
+		assert.Equal(t, "some-resource", stamp.Resource())
+		assert.Equal(t, hashcash.AlgorithmSHA256, stamp.Algorithm())
+		assert.Equal(t, "1:20:23:some-resource:alg=sha256:salt:23a", stamp.String())
+	})
+}
+
+func TestStamp_Verify(t *testing.T) {
+	t.Run("stamp is not solved, should return error", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("1:20:23:some-resource::salt:23a")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, stamp.Verify(), hashcash.ErrIncorrectSolution)
+	})
+
+	t.Run("stamp is expired, should return error", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("1:20:060102:some-resource::salt:23a")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, stamp.Verify(), hashcash.ErrExpiredHashcash)
+	})
+
+	t.Run("stamp is solved, should return nil", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp(solvedStamp)
+		require.NoError(t, err)
+
+		assert.NoError(t, stamp.Verify())
+	})
+
+	t.Run("stamp is solved with another algorithm, should return nil", func(t *testing.T) {
+		// Solve a new hashcash with SHA3-256
+		hc, err := hashcash.New(12, 8, hashcash.DateFormatYYMMDD, "resource", hashcash.WithAlgorithm(hashcash.AlgorithmSHA3256))
+		require.NoError(t, err)
+
+		result, err := hc.SolveContext(context.Background(), 2)
+		require.NoError(t, err)
+
+		// Verify the solution
+		stamp, err := hashcash.ParseStamp(result.Solution)
+		require.NoError(t, err)
+
+		assert.NoError(t, stamp.Verify())
+	})
+
+	t.Run("difficulty is above 64 bits, should be checked against the whole hash", func(t *testing.T) {
+		// Such a difficulty can not be solved, but it must not be capped at 64 bits either
+		stamp, err := hashcash.ParseStamp("1:65:23:some-resource::Kl7oUEQg:4c73d")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, stamp.Verify(), hashcash.ErrIncorrectSolution)
+	})
+
+	t.Run("stamp minted by another implementation, should return nil", func(t *testing.T) {
+		// SHA-1 stamp with an extension list and a base64 counter
+		stamp, err := hashcash.ParseStamp("1:16:23:foo@example.com:a=1,2;b;c=:McMybZIhxKXu57jd:AAAAAChK")
+		require.NoError(t, err)
+
+		assert.NoError(t, stamp.Verify())
+	})
+
+	t.Run("version 0 stamp, should be verified against the given number of bits", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("0:23:foo@example.com:AAAAARhv")
+		require.NoError(t, err)
+
+		// The stamp does not carry the difficulty
+		assert.ErrorIs(t, stamp.Verify(), hashcash.ErrUnknownDifficulty)
+
+		// The stamp has 16 leading zero bits
+		assert.NoError(t, stamp.VerifyBits(16))
+		assert.ErrorIs(t, stamp.VerifyBits(40), hashcash.ErrIncorrectSolution)
+	})
+
+	t.Run("valid stamp does not allocate", func(t *testing.T) {
+		// The race detector allocates on its own
+		if raceEnabled {
+			t.Skip("allocations are not counted with the race detector")
+		}
+
+		// Warm up the hasher pool
+		_, err := hashcash.CheckSolution(solvedStamp)
+		require.NoError(t, err)
+
+		// Count the allocations of the verification path
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = hashcash.CheckSolution(solvedStamp)
+		})
+
+		assert.Zero(t, allocs)
+	})
+}
+
+func TestStamp_Match(t *testing.T) {
+	// Parse the challenge
+	challenge, err := hashcash.ParseStamp("1:20:23:some-resource::Kl7oUEQg:0")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		solution string
+		err      error
+	}{
+		{name: "same fields", solution: solvedStamp, err: nil},
+		{name: "another algorithm", solution: "1:20:23:some-resource:alg=sha256:Kl7oUEQg:4c73d", err: hashcash.ErrAlgorithmMismatch},
+		{name: "another difficulty", solution: "1:10:23:some-resource::Kl7oUEQg:4c73d", err: hashcash.ErrDifficultyMismatch},
+		{name: "another date", solution: "1:20:22:some-resource::Kl7oUEQg:4c73d", err: hashcash.ErrDateMismatch},
+		{name: "another resource", solution: "1:20:23:another-resource::Kl7oUEQg:4c73d", err: hashcash.ErrResourceMismatch},
+		{name: "another extension", solution: "1:20:23:some-resource:note=x:Kl7oUEQg:4c73d", err: hashcash.ErrExtensionMismatch},
+		{name: "another salt", solution: "1:20:23:some-resource::salt:4c73d", err: hashcash.ErrSaltMismatch},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Parse the solution
+			stamp, err := hashcash.ParseStamp(tt.solution)
+			require.NoError(t, err)
+
+			// Match it against the challenge
+			err = stamp.Match(challenge)
+
+			// Check the error
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestStamp_Expires(t *testing.T) {
+	t.Run("date format that never expires", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp(solvedStamp)
+		require.NoError(t, err)
+
+		_, ok, err := stamp.Expires()
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("end of the date format validity period", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("1:20:230101:some-resource::salt:23a")
+		require.NoError(t, err)
+
+		expires, ok, err := stamp.Expires()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Add(hashcash.MaxDurationYYMMDD), expires)
+	})
+
+	t.Run("expiry time set by the issuer comes first", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("1:20:230101:some-resource:exp=1672617600:salt:23a")
+		require.NoError(t, err)
+
+		expires, ok, err := stamp.Expires()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(1672617600), expires.Unix())
+	})
+
+	t.Run("expiry time set by the issuer for a stamp that never expires", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("1:20:23:some-resource:exp=1672617600:salt:23a")
+		require.NoError(t, err)
+
+		expires, ok, err := stamp.Expires()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(1672617600), expires.Unix())
+	})
+
+	t.Run("invalid expiry time", func(t *testing.T) {
+		stamp, err := hashcash.ParseStamp("1:20:23:some-resource:exp=soon:salt:23a")
+		require.NoError(t, err)
+
+		_, _, err = stamp.Expires()
+		assert.Error(t, err)
+	})
+}
+
+// BenchmarkVerify_ParseStr measures the verification path based on ParseStr, that formats the hashcash again to hash it.
+func BenchmarkVerify_ParseStr(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		hc, err := hashcash.ParseStr(solvedStamp)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err = hc.Check(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVerify_Stamp measures the in-place verification path, that hashes the original string.
+func BenchmarkVerify_Stamp(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		stamp, err := hashcash.ParseStamp(solvedStamp)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if err = stamp.Verify(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVerify_StampForChallenge measures the in-place verification path, including matching the challenge.
+func BenchmarkVerify_StampForChallenge(b *testing.B) {
+	b.ReportAllocs()
+
+	challenge := "1:20:23:some-resource::Kl7oUEQg:0"
+
+	for i := 0; i < b.N; i++ {
+		if _, err := hashcash.CheckSolutionForChallenge(solvedStamp, challenge); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package hashcash
+
+import "time"
+
+// Validity is the validity window of the hashcash stamps, set by the verifier.
+// The zero value keeps the validity period indicated by the date format and allows no clock skew.
+type Validity struct {
+	// TTL is for how long a stamp is valid, counted from its date.
+	// As the date is rounded down to the precision of the date format, use DateFormatFor to pick a format precise enough.
+	// If it is zero, the validity period indicated by the date format is used.
+	TTL time.Duration
+
+	// Skew is the allowance for the clock skew between the minter and the verifier.
+	// A stamp is accepted up to Skew before its date and up to Skew after it expires.
+	Skew time.Duration
+}
+
+// WithValidity sets the validity window of a new hashcash, that HasExpired honours.
+func WithValidity(validity Validity) Option {
+	return func(h *Hashcash) {
+		h.validity = validity
+	}
+}
+
+// DateFormatFor returns the least precise date format, that is still precise enough for the given validity period,
+// following the hashcash spec. E.g. the stamps valid for 10 minutes are dated to the minute,
+// while the stamps valid for 30 seconds are dated to the second.
+func DateFormatFor(ttl time.Duration) DateFormat {
+	switch {
+	case ttl <= MaxDurationYYMMDDhhmmss:
+		return DateFormatYYMMDDhhmmss
+	case ttl <= MaxDurationYYMMDDhhmm:
+		return DateFormatYYMMDDhhmm
+	case ttl <= MaxDurationYYMMDD:
+		return DateFormatYYMMDD
+	case ttl <= MaxDurationYYMM:
+		return DateFormatYYMM
+	default:
+		return DateFormatYY
+	}
+}
+
+// maxAge returns for how long a stamp of the given date format is valid under the validity window.
+// It reports false, if the stamp never expires.
+func (v Validity) maxAge(dateFormat DateFormat) (time.Duration, bool) {
+	// The configured TTL takes precedence over the date format
+	if v.TTL > 0 {
+		return v.TTL, true
+	}
+
+	return dateFormat.MaxDuration()
+}
@@ -0,0 +1,113 @@
+package hashcash_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+func TestDateFormatFor(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want hashcash.DateFormat
+	}{
+		{name: "seconds", ttl: 30 * time.Second, want: hashcash.DateFormatYYMMDDhhmmss},
+		{name: "2 minutes", ttl: 2 * time.Minute, want: hashcash.DateFormatYYMMDDhhmmss},
+		{name: "minutes", ttl: 10 * time.Minute, want: hashcash.DateFormatYYMMDDhhmm},
+		{name: "hours", ttl: 6 * time.Hour, want: hashcash.DateFormatYYMMDDhhmm},
+		{name: "days", ttl: 7 * 24 * time.Hour, want: hashcash.DateFormatYYMMDD},
+		{name: "months", ttl: 180 * 24 * time.Hour, want: hashcash.DateFormatYYMM},
+		{name: "years", ttl: 3 * 365 * 24 * time.Hour, want: hashcash.DateFormatYY},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hashcash.DateFormatFor(tt.ttl))
+		})
+	}
+}
+
+func TestHashcash_HasExpired_Validity(t *testing.T) {
+	t.Run("hashcash is within the TTL, should return false", func(t *testing.T) {
+		hc, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDDhhmmss, "resource",
+			hashcash.WithValidity(hashcash.Validity{TTL: time.Minute}))
+		require.NoError(t, err)
+
+		expired, err := hc.HasExpired()
+		assert.NoError(t, err)
+		assert.False(t, expired, "hashcash should not have expired")
+	})
+
+	t.Run("hashcash is older than the TTL, should return true", func(t *testing.T) {
+		hc, err := hashcash.New(20, 8, hashcash.DateFormatYYMMDDhhmmss, "resource",
+			hashcash.WithValidity(hashcash.Validity{TTL: time.Millisecond}))
+		require.NoError(t, err)
+
+		// Let the TTL pass, the date format alone would keep the hashcash valid for 2 minutes
+		time.Sleep(5 * time.Millisecond)
+
+		expired, err := hc.HasExpired()
+		assert.NoError(t, err)
+		assert.True(t, expired, "hashcash should have expired")
+	})
+
+	t.Run("stamp is older than the TTL, but within the clock skew, should return nil", func(t *testing.T) {
+		date := time.Now().UTC().Add(-70 * time.Second).Format("060102150405")
+
+		stamp, err := hashcash.ParseStamp("1:1:" + date + ":resource::salt:0")
+		require.NoError(t, err)
+
+		// Without the skew the stamp has expired
+		assert.ErrorIs(t, stamp.WithValidity(hashcash.Validity{TTL: time.Minute}).VerifyBits(0), hashcash.ErrExpiredHashcash)
+
+		// With the skew it is still accepted
+		assert.NoError(t, stamp.WithValidity(hashcash.Validity{TTL: time.Minute, Skew: 30 * time.Second}).VerifyBits(0))
+	})
+
+	t.Run("stamp is dated in the future within the clock skew, should return nil", func(t *testing.T) {
+		date := time.Now().UTC().Add(10 * time.Second).Format("060102150405")
+
+		stamp, err := hashcash.ParseStamp("1:1:" + date + ":resource::salt:0")
+		require.NoError(t, err)
+
+		// Without the skew the stamp is from the future
+		assert.ErrorIs(t, stamp.WithValidity(hashcash.Validity{TTL: time.Minute}).VerifyBits(0), hashcash.ErrAttemptToUseFutureHashcash)
+
+		// With the skew it is accepted
+		assert.NoError(t, stamp.WithValidity(hashcash.Validity{TTL: time.Minute, Skew: 30 * time.Second}).VerifyBits(0))
+	})
+
+	t.Run("stamp expires at the end of the TTL plus the clock skew", func(t *testing.T) {
+		date := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		stamp, err := hashcash.ParseStamp("1:1:" + date.Format("060102150405") + ":resource::salt:0")
+		require.NoError(t, err)
+
+		expires, ok, err := stamp.WithValidity(hashcash.Validity{TTL: time.Minute, Skew: 5 * time.Second}).Expires()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, date.Add(time.Minute+5*time.Second), expires)
+	})
+}
+
+func TestHashcash_SolveWithSecondsDateFormat(t *testing.T) {
+	// Create a new hashcash dated to the second
+	hc, err := hashcash.New(8, 8, hashcash.DateFormatYYMMDDhhmmss, "resource")
+	require.NoError(t, err)
+
+	// Solve it
+	result, err := hc.SolveContext(context.Background(), 1)
+	require.NoError(t, err)
+
+	// The date survives the round trip, so the solution is accepted
+	stamp, err := hashcash.ParseStamp(result.Solution)
+	require.NoError(t, err)
+	assert.NoError(t, stamp.WithValidity(hashcash.Validity{TTL: time.Minute}).Verify())
+}
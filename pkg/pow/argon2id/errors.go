@@ -0,0 +1,47 @@
+package argon2id
+
+import "errors"
+
+var (
+	// ErrNilPuzzle is returned when the puzzle is nil.
+	ErrNilPuzzle = errors.New("puzzle is nil")
+
+	// ErrEmptyPuzzle is returned when the puzzle is empty.
+	ErrEmptyPuzzle = errors.New("puzzle is empty")
+
+	// ErrExpiredPuzzle is returned when the puzzle is expired.
+	ErrExpiredPuzzle = errors.New("puzzle is expired")
+
+	// ErrInvalidPrefix is returned when the puzzle string does not start with the puzzle prefix.
+	ErrInvalidPrefix = errors.New("puzzle prefix is invalid")
+
+	// ErrIncorrectNumberOfParts is returned when the number of parts contained in the puzzle string is incorrect.
+	ErrIncorrectNumberOfParts = errors.New("incorrect number of parts")
+
+	// ErrInvalidVersion is returned when the puzzle version is invalid.
+	ErrInvalidVersion = errors.New("puzzle version is invalid")
+
+	// ErrInvalidDifficulty is returned when the difficulty is invalid.
+	ErrInvalidDifficulty = errors.New("invalid difficulty")
+
+	// ErrInvalidParams is returned when the Argon2id parameters are invalid.
+	ErrInvalidParams = errors.New("invalid argon2id parameters")
+
+	// ErrInvalidValidityPeriod is returned when the validity period is invalid.
+	ErrInvalidValidityPeriod = errors.New("invalid validity period")
+
+	// ErrInvalidSaltLength is returned when the salt length is invalid.
+	ErrInvalidSaltLength = errors.New("invalid salt length")
+
+	// ErrIncorrectSolution is returned when the puzzle solution is incorrect.
+	ErrIncorrectSolution = errors.New("incorrect solution")
+
+	// ErrDifficultyMismatch is returned when the puzzle difficulty does not match the challenge difficulty.
+	ErrDifficultyMismatch = errors.New("puzzle difficulty does not match the challenge")
+
+	// ErrChallengeMismatch is returned when the solution was not produced for the given challenge.
+	ErrChallengeMismatch = errors.New("puzzle does not match the challenge")
+
+	// ErrSolvingCanceled is returned when solving the puzzle was canceled.
+	ErrSolvingCanceled = errors.New("solving puzzle was canceled")
+)
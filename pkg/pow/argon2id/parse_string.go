@@ -0,0 +1,87 @@
+package argon2id
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseStr parses the puzzle string to the puzzle struct, validating it in the process.
+// It accepts the puzzle string, expecting it to be in the following format:
+// argon2id:<version>:<difficulty>:<memory>:<iterations>:<parallelism>:<expires>:<resource>:<salt>:<nonce>
+// It returns a pointer to the puzzle and an error, if any.
+func ParseStr(puzzle string) (*Puzzle, error) {
+	// Split the puzzle string by ":"
+	split := strings.Split(puzzle, ":")
+
+	// Check if the puzzle string has the correct number of elements
+	if len(split) != ValidPartsNumber {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrIncorrectNumberOfParts, ValidPartsNumber, len(split))
+	}
+
+	// Check the prefix
+	if split[0] != Prefix {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrInvalidPrefix, Prefix, split[0])
+	}
+
+	// Parse and check the version
+	version, err := strconv.Atoi(split[1])
+	if err != nil {
+		return nil, fmt.Errorf("converting puzzle version to int: %w", err)
+	}
+
+	if version != Version {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrInvalidVersion, Version, version)
+	}
+
+	// Parse the difficulty
+	difficulty, err := strconv.Atoi(split[2])
+	if err != nil {
+		return nil, fmt.Errorf("converting difficulty to int: %w", err)
+	}
+
+	// Parse the Argon2id parameters
+	memory, err := strconv.ParseUint(split[3], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parsing memory: %w", err)
+	}
+
+	iterations, err := strconv.ParseUint(split[4], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parsing iterations: %w", err)
+	}
+
+	parallelism, err := strconv.ParseUint(split[5], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("parsing parallelism: %w", err)
+	}
+
+	params := Params{Memory: uint32(memory), Iterations: uint32(iterations), Parallelism: uint8(parallelism)}
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Parse the expiration time
+	expires, err := strconv.ParseInt(split[6], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing expiration time: %w", err)
+	}
+
+	// Parse the nonce
+	nonce, err := strconv.ParseUint(split[9], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing nonce: %w", err)
+	}
+
+	// Return the puzzle and nil error
+	return &Puzzle{
+		version:    version,
+		difficulty: difficulty,
+		params:     params,
+		expires:    time.Unix(expires, 0),
+		resource:   split[7],
+		salt:       split[8],
+		nonce:      nonce,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package argon2id_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
+)
+
+func TestParseStr(t *testing.T) {
+	// Format: argon2id:<version>:<difficulty>:<memory>:<iterations>:<parallelism>:<expires>:<resource>:<salt>:<nonce>
+
+	tests := []struct {
+		name    string
+		puzzle  string
+		wantErr error
+	}{
+		{
+			name:    "incorrect number of parts",
+			puzzle:  "argon2id:1:4:64:1:1",
+			wantErr: argon2id.ErrIncorrectNumberOfParts,
+		},
+		{
+			name:    "incorrect prefix",
+			puzzle:  "1:1:4:64:1:1:2000000000:resource:salt:0",
+			wantErr: argon2id.ErrInvalidPrefix,
+		},
+		{
+			name:    "incorrect version",
+			puzzle:  "argon2id:2:4:64:1:1:2000000000:resource:salt:0",
+			wantErr: argon2id.ErrInvalidVersion,
+		},
+		{
+			name:    "invalid parameters",
+			puzzle:  "argon2id:1:4:64:0:1:2000000000:resource:salt:0",
+			wantErr: argon2id.ErrInvalidParams,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Parse the puzzle string
+			_, err := argon2id.ParseStr(tt.puzzle)
+
+			// Check the error
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("invalid numbers", func(t *testing.T) {
+		for _, puzzle := range []string{
+			"argon2id:NaN:4:64:1:1:2000000000:resource:salt:0",
+			"argon2id:1:NaN:64:1:1:2000000000:resource:salt:0",
+			"argon2id:1:4:NaN:1:1:2000000000:resource:salt:0",
+			"argon2id:1:4:64:NaN:1:2000000000:resource:salt:0",
+			"argon2id:1:4:64:1:NaN:2000000000:resource:salt:0",
+			"argon2id:1:4:64:1:1:NaN:resource:salt:0",
+			"argon2id:1:4:64:1:1:2000000000:resource:salt:NaN",
+		} {
+			// Parse the puzzle string
+			_, err := argon2id.ParseStr(puzzle)
+
+			// Check the error
+			assert.Error(t, err, puzzle)
+		}
+	})
+
+	t.Run("valid string", func(t *testing.T) {
+		// Parse valid puzzle string
+		validPuzzle := "argon2id:1:4:64:1:1:2000000000:resource:salt:23a"
+
+		puzzle, err := argon2id.ParseStr(validPuzzle)
+
+		// Check that no error is returned
+		assert.NoError(t, err)
+
+		// Check if the puzzle is correct
+		assert.Equal(t, validPuzzle, puzzle.String())
+	})
+}
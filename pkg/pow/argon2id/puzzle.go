@@ -0,0 +1,321 @@
+// Package argon2id contains a memory-hard proof-of-work puzzle, based on the Argon2id key derivation function.
+//
+// Unlike hashcash, every attempt to solve the puzzle requires a configurable amount of memory,
+// which makes GPUs and ASICs much less efficient at solving it compared to regular CPUs of the clients.
+// Read more:
+// - https://datatracker.ietf.org/doc/html/rfc9106
+package argon2id
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Puzzle is a representation of an Argon2id proof-of-work puzzle.
+// Its string representation is:
+// argon2id:<version>:<difficulty>:<memory>:<iterations>:<parallelism>:<expires>:<resource>:<salt>:<nonce>.
+type Puzzle struct {
+	// version is the puzzle version.
+	version int
+
+	// difficulty is the number of leading zero bits required in the Argon2id hash.
+	difficulty int
+
+	// params are the Argon2id parameters.
+	params Params
+
+	// expires is the time after which the puzzle is no longer valid.
+	expires time.Time
+
+	// resource is the resource to which the puzzle is tied.
+	resource string
+
+	// salt is a random string of characters, making every puzzle unique.
+	salt string
+
+	// nonce is incremented until the puzzle is solved.
+	nonce uint64
+}
+
+// Params are the Argon2id parameters of the puzzle.
+type Params struct {
+	// Memory is the amount of memory used by a single attempt, in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Parallelism is the number of threads used by a single attempt.
+	Parallelism uint8
+}
+
+const (
+	// Prefix is the prefix of every puzzle string, identifying the puzzle type.
+	Prefix = "argon2id"
+
+	// Version is the puzzle version.
+	Version = 1
+
+	// ValidPartsNumber is the number of parts in a valid puzzle string.
+	ValidPartsNumber = 10
+
+	// keyLength is the length of the Argon2id hash, in bytes.
+	keyLength = 32
+
+	// minMemoryPerThread is the minimal amount of memory per thread, in KiB, required by Argon2.
+	minMemoryPerThread = 8
+)
+
+// New creates a new puzzle.
+// It accepts the number of leading zero bits, the salt length, the Argon2id parameters,
+// the validity period and the resource.
+// It returns a pointer to the puzzle and an error, if any.
+func New(difficulty, saltLen int, params Params, validFor time.Duration, resource string) (*Puzzle, error) {
+	// Check if the difficulty is valid: it should be greater than 0 and not exceed the hash length.
+	if difficulty <= 0 || difficulty > keyLength*8 {
+		return nil, ErrInvalidDifficulty
+	}
+
+	// Check if the Argon2id parameters are valid.
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Check if the validity period is valid.
+	if validFor <= 0 {
+		return nil, ErrInvalidValidityPeriod
+	}
+
+	// Create salt of the given length
+	salt, err := newSalt(saltLen)
+	if err != nil {
+		return nil, fmt.Errorf("creating salt: %w", err)
+	}
+
+	// Return the puzzle and nil error
+	return &Puzzle{
+		version:    Version,
+		difficulty: difficulty,
+		params:     params,
+		expires:    time.Now().Add(validFor).Truncate(time.Second),
+		resource:   resource,
+		salt:       salt,
+		// nonce is 0 by default
+	}, nil
+}
+
+// Validate checks if the Argon2id parameters are valid.
+func (p Params) Validate() error {
+	if p.Iterations == 0 || p.Parallelism == 0 || p.Memory < minMemoryPerThread*uint32(p.Parallelism) {
+		return fmt.Errorf("%w: memory=%d, iterations=%d, parallelism=%d",
+			ErrInvalidParams, p.Memory, p.Iterations, p.Parallelism)
+	}
+
+	return nil
+}
+
+// Difficulty returns the number of leading zero bits required.
+func (p *Puzzle) Difficulty() int {
+	// Return zero if the puzzle is nil. This is to avoid panics.
+	if p == nil {
+		return 0
+	}
+
+	return p.difficulty
+}
+
+// Params returns the Argon2id parameters of the puzzle.
+func (p *Puzzle) Params() Params {
+	// Return zero params if the puzzle is nil. This is to avoid panics.
+	if p == nil {
+		return Params{}
+	}
+
+	return p.params
+}
+
+// Expires returns the time after which the puzzle is no longer valid.
+func (p *Puzzle) Expires() time.Time {
+	// Return zero time if the puzzle is nil. This is to avoid panics.
+	if p == nil {
+		return time.Time{}
+	}
+
+	return p.expires
+}
+
+// Salt returns the salt of the puzzle, which is random and thus identifies the puzzle.
+func (p *Puzzle) Salt() string {
+	// Return an empty string if the puzzle is nil. This is to avoid panics.
+	if p == nil {
+		return ""
+	}
+
+	return p.salt
+}
+
+// Nonce returns the nonce of the puzzle.
+func (p *Puzzle) Nonce() uint64 {
+	// Return zero if the puzzle is nil. This is to avoid panics.
+	if p == nil {
+		return 0
+	}
+
+	return p.nonce
+}
+
+// HasExpired checks if the puzzle has expired.
+func (p *Puzzle) HasExpired() (bool, error) {
+	if p == nil {
+		return false, ErrNilPuzzle
+	}
+
+	return time.Now().After(p.expires), nil
+}
+
+// IsSolved checks if the puzzle is solved.
+func (p *Puzzle) IsSolved() bool {
+	// Return false if the puzzle is nil or its parameters are invalid. This is to avoid panics.
+	if p == nil || p.params.Validate() != nil {
+		return false
+	}
+
+	// Compute the Argon2id hash of the puzzle string, using the puzzle salt
+	sum := argon2.IDKey([]byte(p.String()), []byte(p.salt), p.params.Iterations, p.params.Memory, p.params.Parallelism, keyLength)
+
+	// Check the number of leading zero bits
+	return leadingZeroBits(sum) >= p.difficulty
+}
+
+// Solve solves the puzzle using brute force.
+// It stops when the puzzle is solved or when the context is done.
+func (p *Puzzle) Solve(ctx context.Context) (string, error) {
+	if p == nil {
+		return "", ErrNilPuzzle
+	}
+
+	if p.expires.IsZero() || p.salt == "" {
+		return "", ErrEmptyPuzzle
+	}
+
+	// Increment the nonce until the puzzle is solved
+	for !p.IsSolved() {
+		// Stop if the context is done, every attempt is expensive, so it is checked every time
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("%w: %s", ErrSolvingCanceled, err)
+		}
+
+		// Increment the nonce
+		p.nonce++
+	}
+
+	// Return the solution and nil error
+	return p.String(), nil
+}
+
+// Check checks if the puzzle is solved and not expired.
+func (p *Puzzle) Check() (bool, error) {
+	// Check if the puzzle has expired
+	expired, err := p.HasExpired()
+	if err != nil {
+		return false, fmt.Errorf("checking if puzzle has expired: %w", err)
+	}
+
+	if expired {
+		return false, ErrExpiredPuzzle
+	}
+
+	// Check if the puzzle is solved
+	if !p.IsSolved() {
+		return false, ErrIncorrectSolution
+	}
+
+	// Solution is correct
+	return true, nil
+}
+
+// Match checks that the puzzle was produced for the given challenge, i.e. all the fields except for the nonce are equal.
+func (p *Puzzle) Match(challenge *Puzzle) error {
+	// Return error if any of the puzzles is nil. This is to avoid panics.
+	if p == nil || challenge == nil {
+		return ErrNilPuzzle
+	}
+
+	// Tell the difficulty apart, as the clients are told to solve a new challenge of the right difficulty
+	if p.difficulty != challenge.difficulty {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDifficultyMismatch, challenge.difficulty, p.difficulty)
+	}
+
+	// Compare every other field except for the nonce
+	if p.version != challenge.version ||
+		p.params != challenge.params ||
+		!p.expires.Equal(challenge.expires) ||
+		p.resource != challenge.resource ||
+		p.salt != challenge.salt {
+		return ErrChallengeMismatch
+	}
+
+	return nil
+}
+
+// String returns the puzzle string.
+func (p *Puzzle) String() string {
+	// Return an empty string if the puzzle is nil. This is to avoid panics.
+	if p == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"%s:%d:%d:%d:%d:%d:%d:%s:%s:%x",
+		Prefix,
+		p.version,
+		p.difficulty,
+		p.params.Memory,
+		p.params.Iterations,
+		p.params.Parallelism,
+		p.expires.Unix(),
+		p.resource,
+		p.salt,
+		p.nonce,
+	)
+}
+
+// leadingZeroBits returns the number of leading zero bits in the given byte slice.
+func leadingZeroBits(sum []byte) int {
+	zeros := 0
+
+	for _, b := range sum {
+		// Whole byte is zero, continue with the next one
+		if b == 0 {
+			zeros += 8
+			continue
+		}
+
+		// Count the leading zeros in the first non-zero byte
+		return zeros + bits.LeadingZeros8(b)
+	}
+
+	return zeros
+}
+
+// newSalt creates a salt of the given length.
+func newSalt(saltLen int) (string, error) {
+	// Check if the salt length is valid
+	if saltLen <= 0 {
+		return "", ErrInvalidSaltLength
+	}
+
+	// Read random bytes
+	buf := make([]byte, saltLen)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+
+	// Encode the random bytes to base64 without the separators used in the puzzle string
+	return base64.RawURLEncoding.EncodeToString(buf)[:saltLen], nil
+}
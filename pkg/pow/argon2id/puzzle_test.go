@@ -0,0 +1,260 @@
+package argon2id_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
+)
+
+// testParams are cheap Argon2id parameters, so that the tests run fast.
+var testParams = argon2id.Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty int
+		saltLen    int
+		params     argon2id.Params
+		validFor   time.Duration
+		wantErr    error
+	}{
+		{
+			name:       "difficulty is zero, should return error",
+			difficulty: 0,
+			saltLen:    8,
+			params:     testParams,
+			validFor:   time.Minute,
+			wantErr:    argon2id.ErrInvalidDifficulty,
+		},
+		{
+			name:       "difficulty exceeds hash length, should return error",
+			difficulty: 257,
+			saltLen:    8,
+			params:     testParams,
+			validFor:   time.Minute,
+			wantErr:    argon2id.ErrInvalidDifficulty,
+		},
+		{
+			name:       "salt length is zero, should return error",
+			difficulty: 4,
+			saltLen:    0,
+			params:     testParams,
+			validFor:   time.Minute,
+			wantErr:    argon2id.ErrInvalidSaltLength,
+		},
+		{
+			name:       "memory is too low, should return error",
+			difficulty: 4,
+			saltLen:    8,
+			params:     argon2id.Params{Memory: 4, Iterations: 1, Parallelism: 1},
+			validFor:   time.Minute,
+			wantErr:    argon2id.ErrInvalidParams,
+		},
+		{
+			name:       "validity period is zero, should return error",
+			difficulty: 4,
+			saltLen:    8,
+			params:     testParams,
+			validFor:   0,
+			wantErr:    argon2id.ErrInvalidValidityPeriod,
+		},
+		{
+			name:       "all parameters are valid, should not return error",
+			difficulty: 4,
+			saltLen:    8,
+			params:     testParams,
+			validFor:   time.Minute,
+			wantErr:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create a new puzzle
+			puzzle, err := argon2id.New(tt.difficulty, tt.saltLen, tt.params, tt.validFor, "resource")
+
+			// Check the error
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.difficulty, puzzle.Difficulty())
+			assert.Equal(t, tt.params, puzzle.Params())
+		})
+	}
+}
+
+func TestPuzzle_String(t *testing.T) {
+	t.Run("puzzle is nil, should return empty string", func(t *testing.T) {
+		// Create a new nil puzzle
+		var puzzle *argon2id.Puzzle
+
+		// Check the string
+		assert.Equal(t, "", puzzle.String())
+	})
+
+	t.Run("puzzle is not empty, should return correct string", func(t *testing.T) {
+		// Create a new puzzle
+		puzzle, err := argon2id.New(4, 8, testParams, time.Minute, "resource")
+		require.NoError(t, err)
+
+		// Split the puzzle string
+		split := strings.Split(puzzle.String(), ":")
+
+		// Check the parts
+		assert.Len(t, split, argon2id.ValidPartsNumber)
+		assert.Equal(t, []string{"argon2id", "1", "4", "64", "1", "1"}, split[:6])
+		assert.Equal(t, "resource", split[7])
+		assert.Len(t, split[8], 8)
+		assert.Equal(t, "0", split[9])
+	})
+}
+
+func TestPuzzle_Solve(t *testing.T) {
+	t.Run("puzzle is nil, should return error", func(t *testing.T) {
+		// Create a new nil puzzle
+		var puzzle *argon2id.Puzzle
+
+		// Solve the puzzle
+		_, err := puzzle.Solve(context.Background())
+
+		// Check the error
+		assert.ErrorIs(t, err, argon2id.ErrNilPuzzle)
+	})
+
+	t.Run("puzzle is empty, should return error", func(t *testing.T) {
+		// Create a new empty puzzle
+		puzzle := argon2id.Puzzle{}
+
+		// Solve the puzzle
+		_, err := puzzle.Solve(context.Background())
+
+		// Check the error
+		assert.ErrorIs(t, err, argon2id.ErrEmptyPuzzle)
+	})
+
+	t.Run("context is canceled, should return error", func(t *testing.T) {
+		// Create a puzzle that is practically impossible to solve
+		puzzle, err := argon2id.New(64, 8, testParams, time.Minute, "resource")
+		require.NoError(t, err)
+
+		// Create a canceled context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Solve the puzzle
+		_, err = puzzle.Solve(ctx)
+
+		// Check the error
+		assert.ErrorIs(t, err, argon2id.ErrSolvingCanceled)
+	})
+
+	t.Run("puzzle is valid, should be solved", func(t *testing.T) {
+		// Create a new puzzle
+		puzzle, err := argon2id.New(4, 8, testParams, time.Minute, "resource")
+		require.NoError(t, err)
+
+		// Solve the puzzle
+		solution, err := puzzle.Solve(context.Background())
+		require.NoError(t, err)
+
+		// Parse the solution back, as the other party would do
+		parsed, err := argon2id.ParseStr(solution)
+		require.NoError(t, err)
+
+		// Check the solution
+		passed, err := parsed.Check()
+		assert.NoError(t, err)
+		assert.True(t, passed)
+	})
+}
+
+func TestPuzzle_Check(t *testing.T) {
+	t.Run("puzzle is expired, should return error", func(t *testing.T) {
+		// Parse an expired puzzle
+		puzzle, err := argon2id.ParseStr("argon2id:1:4:64:1:1:1000000000:resource:salt:0")
+		require.NoError(t, err)
+
+		// Check the puzzle
+		passed, err := puzzle.Check()
+
+		// Check the error
+		assert.ErrorIs(t, err, argon2id.ErrExpiredPuzzle)
+		assert.False(t, passed)
+	})
+
+	t.Run("puzzle is not solved, should return error", func(t *testing.T) {
+		// Create a puzzle that is practically impossible to solve by chance
+		puzzle, err := argon2id.New(64, 8, testParams, time.Minute, "resource")
+		require.NoError(t, err)
+
+		// Check the puzzle
+		passed, err := puzzle.Check()
+
+		// Check the error
+		assert.ErrorIs(t, err, argon2id.ErrIncorrectSolution)
+		assert.False(t, passed)
+	})
+}
+
+func TestPuzzle_Match(t *testing.T) {
+	challenge := "argon2id:1:4:64:1:1:2000000000:resource:salt:0"
+
+	tests := []struct {
+		name     string
+		solution string
+		wantErr  error
+	}{
+		{
+			name:     "only nonce differs, should return no error",
+			solution: "argon2id:1:4:64:1:1:2000000000:resource:salt:1f",
+			wantErr:  nil,
+		},
+		{
+			name:     "difficulty differs, should return ErrDifficultyMismatch",
+			solution: "argon2id:1:1:64:1:1:2000000000:resource:salt:1f",
+			wantErr:  argon2id.ErrDifficultyMismatch,
+		},
+		{
+			name:     "memory differs, should return error",
+			solution: "argon2id:1:4:8:1:1:2000000000:resource:salt:1f",
+			wantErr:  argon2id.ErrChallengeMismatch,
+		},
+		{
+			name:     "expiration time differs, should return error",
+			solution: "argon2id:1:4:64:1:1:2000000001:resource:salt:1f",
+			wantErr:  argon2id.ErrChallengeMismatch,
+		},
+		{
+			name:     "salt differs, should return error",
+			solution: "argon2id:1:4:64:1:1:2000000000:resource:pepper:1f",
+			wantErr:  argon2id.ErrChallengeMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Parse the challenge and the solution
+			issued, err := argon2id.ParseStr(challenge)
+			require.NoError(t, err)
+			solution, err := argon2id.ParseStr(tt.solution)
+			require.NoError(t, err)
+
+			// Match the solution against the challenge
+			err = solution.Match(issued)
+
+			// Check the error
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"go.uber.org/zap"
 )
 
-// CheckSolution checks if the solution with the given challenge key exists in the store and if it is correct.
+// CheckSolution checks if the challenge with the given challenge key exists in the store
+// and if the solution is a correct solution of exactly that challenge.
+// In the stateless mode, it checks the challenge signature instead of the store.
+// In both modes, the correct solution is spent, and using it again returns ErrStampAlreadySpent.
+// In the stateful mode, the spent store is only best-effort, as the consumed challenge can not be redeemed again.
 func (s *Service) CheckSolution(ctx context.Context, solution string, key Key) (bool, error) {
 	// If the key is nil, return error
 	if key == nil {
@@ -22,31 +26,105 @@ func (s *Service) CheckSolution(ctx context.Context, solution string, key Key) (
 		return false, ErrChallengeKeyEmpty
 	}
 
-	// Check if the solution exists in the store
-	exists, err := s.Store.Get(ctx, stringKey)
+	// Check the mode
+	stateless, err := s.isStateless()
 	if err != nil {
-		return false, fmt.Errorf("getting challenge from store: %w", err)
+		return false, err
 	}
 
-	// If the solution does not exist in the store, return error
+	// In the stateless mode, the challenge is carried in the solution itself
+	if stateless {
+		correct, checkErr := s.checkStatelessSolution(ctx, solution, key)
+
+		// Attribute the solution to its origin
+		s.logSolution(s.hashcash, solution, correct, checkErr)
+
+		return correct, checkErr
+	}
+
+	// Detect the scheme of the solution, to read the ID of the challenge it was produced for
+	solutionScheme, err := DetectScheme(solution, s.schemes...)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrMalformedSolution, err)
+	}
+
+	id, err := challengeID(solutionScheme, solution)
+	if err != nil {
+		return false, err
+	}
+
+	// Take the issued challenge out of the store, so that of the concurrent requests only one checks the solution.
+	// The challenge is consumed even if the solution is wrong, the client is given a new one then.
+	challenge, exists, err := s.Store.Consume(ctx, challengeStoreKey(key, id))
+	if err != nil {
+		return false, fmt.Errorf("consuming challenge from store: %w", err)
+	}
+
+	// If the challenge does not exist in the store, return error
 	if !exists {
+		// The challenge is consumed once checked, so tell the reused solutions apart
+		spent, err := s.isSolutionSpent(ctx, solution)
+		if err != nil {
+			return false, err
+		}
+
+		if spent {
+			return false, ErrStampAlreadySpent
+		}
+
 		return false, ErrChallengeNotFound
 	}
 
-	// Check if the solution is correct
-	correct, err := hashcash.CheckSolution(solution)
+	// Detect the scheme of the issued challenge
+	scheme, err := DetectScheme(challenge, s.schemes...)
+	if err != nil {
+		return false, fmt.Errorf("detecting challenge scheme: %w", err)
+	}
+
+	// Check if the solution is correct and was produced for the issued challenge
+	correct, err := scheme.Verify(challenge, solution)
+
+	// Attribute the solution to its origin
+	s.logSolution(scheme, solution, correct, err)
+
 	if err != nil {
 		return false, fmt.Errorf("checking solution: %w", err)
 	}
 
-	// if the solution is correct, delete it from the store
+	// if the solution is correct, spend it, the challenge is already gone from the store.
+	// The consumed challenge can not be redeemed again, so the spent solutions only tell the replays apart,
+	// and the solution is not rejected, if it could not be remembered, e.g. when the spent store is full.
 	if correct {
-		err = s.Store.Delete(ctx, stringKey)
-		if err != nil {
-			return false, fmt.Errorf("deleting challenge from store: %w", err)
+		if err = s.spendSolution(ctx, scheme, solution); err != nil {
+			s.logger.Debug("solution accepted without being remembered as spent", zap.Error(err))
 		}
 	}
 
 	// Return the result
 	return correct, nil
 }
+
+// logSolution logs the result of checking the solution, along with the metadata carried in it.
+func (s *Service) logSolution(scheme Scheme, solution string, correct bool, checkErr error) {
+	fields := []zap.Field{
+		zap.String("scheme", scheme.Name()),
+		zap.Bool("correct", correct),
+	}
+
+	// Add the metadata, if the solution carries it
+	metadata, err := scheme.Metadata(solution)
+	if err == nil {
+		fields = append(fields, zap.String("node", metadata.Node), zap.String("route", metadata.Route))
+
+		if !metadata.Expires.IsZero() {
+			fields = append(fields, zap.Time("expires", metadata.Expires))
+		}
+	}
+
+	// Add the error, if the solution is rejected
+	if checkErr != nil {
+		fields = append(fields, zap.NamedError("reason", checkErr))
+	}
+
+	s.logger.Debug("solution checked", fields...)
+}
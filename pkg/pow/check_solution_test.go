@@ -3,11 +3,16 @@ package pow_test
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
 	"github.com/daniel-orlov/quotes-server/pkg/pow"
 	"github.com/daniel-orlov/quotes-server/pkg/pow/mocks"
 )
@@ -16,7 +21,7 @@ func TestService_CheckSolution(t *testing.T) {
 	t.Run("Wrong key passed", func(t *testing.T) {
 		t.Run("Nil key", func(t *testing.T) {
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
 			isCorrect, err := service.CheckSolution(context.TODO(), "", nil)
@@ -30,7 +35,7 @@ func TestService_CheckSolution(t *testing.T) {
 
 		t.Run("Empty key", func(t *testing.T) {
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
 			isCorrect, err := service.CheckSolution(context.TODO(), "", &pow.ChallengeKey{})
@@ -49,10 +54,10 @@ func TestService_CheckSolution(t *testing.T) {
 			store := mocks.NewMockChallengeStorage(nil, nil)
 
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), store)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
-			isCorrect, err := service.CheckSolution(context.TODO(), "", pow.NewChallengeKey("clientID", "resourceID"))
+			isCorrect, err := service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", pow.NewChallengeKey("clientID", "resourceID"))
 
 			// Expect an error - ErrChallengeNotFound
 			assert.ErrorIs(t, err, pow.ErrChallengeNotFound, "expected ErrChallengeNotFound")
@@ -66,7 +71,7 @@ func TestService_CheckSolution(t *testing.T) {
 			store := mocks.NewMockChallengeStorage(nil, errors.New("error"))
 
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), store)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
 			isCorrect, err := service.CheckSolution(context.TODO(), "", pow.NewChallengeKey("clientID", "resourceID"))
@@ -84,11 +89,11 @@ func TestService_CheckSolution(t *testing.T) {
 			// Create mock storage
 			store := mocks.NewMockChallengeStorage(
 				map[string]string{
-					"clientID:resourceID": "1:20:23:not-solved::Kl7oUEQg:4c73d",
+					"clientID:resourceID:Kl7oUEQg": "1:20:23:not-solved::Kl7oUEQg:4c73d",
 				}, nil)
 
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), store)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
 			isCorrect, err := service.CheckSolution(context.TODO(), "1:20:23:not-solved::Kl7oUEQg:4c73d", pow.NewChallengeKey("clientID", "resourceID"))
@@ -100,14 +105,38 @@ func TestService_CheckSolution(t *testing.T) {
 			assert.False(t, isCorrect, "expected false")
 		})
 
+		t.Run("Solution is malformed", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(map[string]string{
+				"clientID:resourceID:Kl7oUEQg": "1:20:23:some-resource::Kl7oUEQg:0",
+			}, nil)
+
+			// Create a new service
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
+
+			// Check if the solution is correct
+			isCorrect, err := service.CheckSolution(context.TODO(), "not-a-solution", pow.NewChallengeKey("clientID", "resourceID"))
+
+			// Expect an error - ErrMalformedSolution
+			assert.ErrorIs(t, err, pow.ErrMalformedSolution, "expected ErrMalformedSolution")
+
+			// Expect the solution to be incorrect
+			assert.False(t, isCorrect, "expected false")
+
+			// Expect the challenge to stay in the store, as the solution does not tell which one it is for
+			_, ok, err := store.Get(context.TODO(), "clientID:resourceID:Kl7oUEQg")
+			require.NoError(t, err, "expected no error")
+			assert.True(t, ok, "expected the challenge to stay in the store")
+		})
+
 		t.Run("Solution is invalid", func(t *testing.T) {
 			// Create mock storage
 			store := mocks.NewMockChallengeStorage(map[string]string{
-				"clientID:resourceID": "invalid",
+				"clientID:resourceID:Kl7oUEQg": "invalid",
 			}, nil)
 
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), store)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
 			isCorrect, err := service.CheckSolution(context.TODO(), "invalid", pow.NewChallengeKey("clientID", "resourceID"))
@@ -120,16 +149,67 @@ func TestService_CheckSolution(t *testing.T) {
 		})
 	})
 
+	t.Run("Solution does not match the challenge", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			solution string
+			wantErr  error
+		}{
+			{
+				name:     "Lower difficulty",
+				solution: "1:1:23:some-resource::Kl7oUEQg:0",
+				wantErr:  pow.ErrDifficultyMismatch,
+			},
+			{
+				name:     "Different date",
+				solution: "1:20:22:some-resource::Kl7oUEQg:4c73d",
+				wantErr:  hashcash.ErrDateMismatch,
+			},
+			{
+				name:     "Different resource",
+				solution: "1:20:23:other-resource::Kl7oUEQg:4c73d",
+				wantErr:  hashcash.ErrResourceMismatch,
+			},
+			{
+				// The salt is the ID of the challenge, so the solution is for a challenge, that was never issued
+				name:     "Different salt",
+				solution: "1:20:23:some-resource::MySalt00:4c73d",
+				wantErr:  pow.ErrChallengeNotFound,
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// Create mock storage
+				store := mocks.NewMockChallengeStorage(
+					map[string]string{
+						"clientID:resourceID:Kl7oUEQg": "1:20:23:some-resource::Kl7oUEQg:0",
+					}, nil)
+
+				// Create a new service
+				service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
+
+				// Check if the solution is correct
+				isCorrect, err := service.CheckSolution(context.TODO(), tt.solution, pow.NewChallengeKey("clientID", "resourceID"))
+
+				// Expect a mismatch error
+				assert.ErrorIs(t, err, tt.wantErr, "expected mismatch error")
+
+				// Expect the solution to be incorrect
+				assert.False(t, isCorrect, "expected false")
+			})
+		}
+	})
+
 	t.Run("Solution is correct", func(t *testing.T) {
 		t.Run("Solution is correct", func(t *testing.T) {
 			// Create mock storage
 			store := mocks.NewMockChallengeStorage(
 				map[string]string{
-					"clientID:resourceID": "1:20:23:some-resource::Kl7oUEQg:0",
+					"clientID:resourceID:Kl7oUEQg": "1:20:23:some-resource::Kl7oUEQg:0",
 				}, nil)
 
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), store)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 			// Check if the solution is correct
 			isCorrect, err := service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", pow.NewChallengeKey("clientID", "resourceID"))
@@ -140,5 +220,319 @@ func TestService_CheckSolution(t *testing.T) {
 			// Expect the solution
 			assert.True(t, isCorrect, "expected true")
 		})
+
+		t.Run("Solution is reused", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(
+				map[string]string{
+					"clientID:resourceID:Kl7oUEQg": "1:20:23:some-resource::Kl7oUEQg:0",
+				}, nil)
+
+			// Create a new service
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
+
+			// Spend the solution
+			isCorrect, err := service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", pow.NewChallengeKey("clientID", "resourceID"))
+			require.NoError(t, err, "expected no error")
+			require.True(t, isCorrect, "expected true")
+
+			// Use the solution again
+			isCorrect, err = service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", pow.NewChallengeKey("clientID", "resourceID"))
+
+			// Expect an error - ErrStampAlreadySpent
+			assert.ErrorIs(t, err, pow.ErrStampAlreadySpent, "expected ErrStampAlreadySpent")
+
+			// Expect the solution to be rejected
+			assert.False(t, isCorrect, "expected false")
+		})
+
+		t.Run("Solution is redeemed once by the concurrent requests", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(
+				map[string]string{
+					"clientID:resourceID:Kl7oUEQg": "1:20:23:some-resource::Kl7oUEQg:0",
+				}, nil)
+
+			// Create a new service, whose spent storage never catches a reuse, so only consuming the challenge does
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, forgetfulSpentStorage{})
+
+			// Check the same solution concurrently
+			var redeemed int32
+			var wg sync.WaitGroup
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					isCorrect, _ := service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", pow.NewChallengeKey("clientID", "resourceID"))
+					if isCorrect {
+						atomic.AddInt32(&redeemed, 1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// Expect the solution to be redeemed only once
+			assert.Equal(t, int32(1), redeemed, "expected the solution to be redeemed once")
+		})
+
+		t.Run("Spent storage is full", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(
+				map[string]string{
+					"clientID:resourceID:Kl7oUEQg": "1:20:23:some-resource::Kl7oUEQg:0",
+				}, nil)
+
+			// Create a new service, whose spent storage has no room for one more solution
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(errors.New("spent storage is full")))
+
+			// Check if the solution is correct
+			key := pow.NewChallengeKey("clientID", "resourceID")
+			isCorrect, err := service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", key)
+
+			// Expect no error, the solution is accepted, as its challenge is consumed anyway
+			assert.NoError(t, err, "expected no error")
+			assert.True(t, isCorrect, "expected true")
+
+			// Expect the solution not to be accepted again, as its challenge is gone
+			_, err = service.CheckSolution(context.TODO(), "1:20:23:some-resource::Kl7oUEQg:4c73d", key)
+			assert.Error(t, err, "expected the reused solution to be rejected")
+		})
+	})
+	t.Run("Solution algorithm", func(t *testing.T) {
+		// SHA-1 challenge and its solution
+		challenge := "1:20:23:some-resource::Kl7oUEQg:0"
+		solution := "1:20:23:some-resource::Kl7oUEQg:4c73d"
+
+		// End of the migration to SHA-256
+		until := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		t.Run("Legacy algorithm before the end of the migration", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(map[string]string{"clientID:resourceID:Kl7oUEQg": challenge}, nil)
+
+			// Create a new service, accepting SHA-1 for one more second
+			service := pow.NewService(zap.NewNop(), &pow.Config{
+				Hashcash: pow.HashcashConfig{
+					Algorithm:             hashcash.AlgorithmSHA256,
+					LegacyAlgorithms:      []hashcash.Algorithm{hashcash.AlgorithmSHA1},
+					LegacyAlgorithmsUntil: until,
+					Clock:                 func() time.Time { return until.Add(-time.Second) },
+				},
+			}, store, mocks.NewMockSpentStorage(nil))
+
+			// Check if the solution is correct
+			isCorrect, err := service.CheckSolution(context.TODO(), solution, pow.NewChallengeKey("clientID", "resourceID"))
+
+			// Expect no error
+			assert.NoError(t, err, "expected no error")
+
+			// Expect the solution to be correct
+			assert.True(t, isCorrect, "expected true")
+		})
+
+		t.Run("Legacy algorithm after the end of the migration", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(map[string]string{"clientID:resourceID:Kl7oUEQg": challenge}, nil)
+
+			// Create a new service, just created after the end of the migration, e.g. restarted by a deploy
+			service := pow.NewService(zap.NewNop(), &pow.Config{
+				Hashcash: pow.HashcashConfig{
+					Algorithm:             hashcash.AlgorithmSHA256,
+					LegacyAlgorithms:      []hashcash.Algorithm{hashcash.AlgorithmSHA1},
+					LegacyAlgorithmsUntil: until,
+					Clock:                 func() time.Time { return until },
+				},
+			}, store, mocks.NewMockSpentStorage(nil))
+
+			// Check if the solution is correct
+			isCorrect, err := service.CheckSolution(context.TODO(), solution, pow.NewChallengeKey("clientID", "resourceID"))
+
+			// Expect an error - ErrAlgorithmNotAccepted
+			assert.ErrorIs(t, err, pow.ErrAlgorithmNotAccepted, "expected ErrAlgorithmNotAccepted")
+
+			// Expect the solution to be incorrect
+			assert.False(t, isCorrect, "expected false")
+		})
+
+		t.Run("Configured algorithm", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(nil, nil)
+
+			// Create a new service, using SHA-256
+			service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{Algorithm: hashcash.AlgorithmSHA256}}, store, mocks.NewMockSpentStorage(nil))
+
+			// Issue a new challenge
+			key := pow.NewChallengeKey("clientID", "resourceID")
+			issued, err := service.NewChallenge(context.TODO(), key, 8, 8)
+			require.NoError(t, err, "expected no error")
+
+			// Solve the challenge
+			hc, err := hashcash.ParseStr(issued)
+			require.NoError(t, err, "expected no error")
+			solved, err := hc.Solve()
+			require.NoError(t, err, "expected no error")
+
+			// Check if the solution is correct
+			isCorrect, err := service.CheckSolution(context.TODO(), solved, key)
+
+			// Expect no error
+			assert.NoError(t, err, "expected no error")
+
+			// Expect the solution to be correct
+			assert.True(t, isCorrect, "expected true")
+		})
+
+		t.Run("Extra algorithm", func(t *testing.T) {
+			// Create mock storage
+			store := mocks.NewMockChallengeStorage(nil, nil)
+
+			// Create a new service, using SHA-256 and offering BLAKE2b-256
+			service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{
+				Algorithm:       hashcash.AlgorithmSHA256,
+				ExtraAlgorithms: []hashcash.Algorithm{hashcash.AlgorithmBLAKE2b256},
+			}}, store, mocks.NewMockSpentStorage(nil))
+
+			// Issue a new challenge using the extra algorithm
+			key := pow.NewChallengeKey("clientID", "resourceID")
+			issued, err := service.NewChallenge(context.TODO(), key, 8, 8, pow.WithAlgorithm(hashcash.AlgorithmBLAKE2b256))
+			require.NoError(t, err, "expected no error")
+
+			// Solve the challenge
+			hc, err := hashcash.ParseStr(issued)
+			require.NoError(t, err, "expected no error")
+			solved, err := hc.Solve()
+			require.NoError(t, err, "expected no error")
+
+			// Check if the solution is correct
+			isCorrect, err := service.CheckSolution(context.TODO(), solved, key)
+
+			// Expect no error
+			assert.NoError(t, err, "expected no error")
+
+			// Expect the solution to be correct
+			assert.True(t, isCorrect, "expected true")
+		})
+	})
+
+	t.Run("Outstanding challenges", func(t *testing.T) {
+		// issueAndSolve issues the given number of challenges for the same key and returns their solutions
+		issueAndSolve := func(t *testing.T, service *pow.Service, key pow.Key, n int, opts ...pow.ChallengeOption) []string {
+			t.Helper()
+
+			solutions := make([]string, 0, n)
+
+			for i := 0; i < n; i++ {
+				issued, err := service.NewChallenge(context.TODO(), key, 8, 8, opts...)
+				require.NoError(t, err, "expected no error")
+
+				result, err := pow.Solve(context.TODO(), issued, 1)
+				require.NoError(t, err, "expected no error")
+
+				solutions = append(solutions, result.Solution)
+			}
+
+			return solutions
+		}
+
+		t.Run("Any of the challenges is redeemable", func(t *testing.T) {
+			// Create a new service
+			service := pow.NewService(zap.NewNop(), &pow.Config{},
+				mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+			// Issue several challenges for the same client and resource, as the parallel requests do
+			key := pow.NewChallengeKey("clientID", "resourceID")
+			solutions := issueAndSolve(t, service, key, 3)
+
+			// Expect every solution to be correct, in any order
+			for i := len(solutions) - 1; i >= 0; i-- {
+				isCorrect, err := service.CheckSolution(context.TODO(), solutions[i], key)
+				assert.NoError(t, err, "expected no error")
+				assert.True(t, isCorrect, "expected true")
+			}
+		})
+
+		t.Run("Challenges over the limit are dropped", func(t *testing.T) {
+			// Create a new service, keeping at most 2 challenges of a client
+			service := pow.NewService(zap.NewNop(), &pow.Config{
+				MaxChallengesPerClient: 2,
+				Hashcash:               pow.HashcashConfig{ValidFor: time.Hour},
+			}, mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+			// Issue one more challenge than the limit, across the resources of the client
+			first := issueAndSolve(t, service, pow.NewChallengeKey("clientID", "resourceID"), 1, pow.WithValidFor(time.Minute))
+			rest := issueAndSolve(t, service, pow.NewChallengeKey("clientID", "otherResourceID"), 2)
+
+			// Expect the challenge expiring the soonest to be dropped
+			isCorrect, err := service.CheckSolution(context.TODO(), first[0], pow.NewChallengeKey("clientID", "resourceID"))
+			assert.ErrorIs(t, err, pow.ErrChallengeNotFound, "expected ErrChallengeNotFound")
+			assert.False(t, isCorrect, "expected false")
+
+			// Expect the others to be redeemable
+			for _, solution := range rest {
+				isCorrect, err = service.CheckSolution(context.TODO(), solution, pow.NewChallengeKey("clientID", "otherResourceID"))
+				assert.NoError(t, err, "expected no error")
+				assert.True(t, isCorrect, "expected true")
+			}
+		})
+
+		t.Run("Clients have their own limits", func(t *testing.T) {
+			// Create a new service, keeping a single challenge of a client
+			service := pow.NewService(zap.NewNop(), &pow.Config{MaxChallengesPerClient: 1},
+				mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+			// Issue a challenge to each of the clients
+			first := issueAndSolve(t, service, pow.NewChallengeKey("clientID", "resourceID"), 1)
+			second := issueAndSolve(t, service, pow.NewChallengeKey("anotherClientID", "resourceID"), 1)
+
+			// Expect both solutions to be correct
+			isCorrect, err := service.CheckSolution(context.TODO(), first[0], pow.NewChallengeKey("clientID", "resourceID"))
+			assert.NoError(t, err, "expected no error")
+			assert.True(t, isCorrect, "expected true")
+
+			isCorrect, err = service.CheckSolution(context.TODO(), second[0], pow.NewChallengeKey("anotherClientID", "resourceID"))
+			assert.NoError(t, err, "expected no error")
+			assert.True(t, isCorrect, "expected true")
+		})
+	})
+
+	t.Run("Challenge validity period", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(nil, nil)
+
+		// Create a new service, whose challenges are only valid for 10ms
+		service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{ValidFor: 10 * time.Millisecond}},
+			store, mocks.NewMockSpentStorage(nil))
+
+		// Issue a new challenge valid for an hour
+		key := pow.NewChallengeKey("clientID", "resourceID")
+		issued, err := service.NewChallenge(context.TODO(), key, 8, 8, pow.WithValidFor(time.Hour))
+		require.NoError(t, err, "expected no error")
+
+		// Solve the challenge after the configured validity period
+		hc, err := hashcash.ParseStr(issued)
+		require.NoError(t, err, "expected no error")
+		solved, err := hc.Solve()
+		require.NoError(t, err, "expected no error")
+		time.Sleep(20 * time.Millisecond)
+
+		// Check if the solution is correct
+		isCorrect, err := service.CheckSolution(context.TODO(), solved, key)
+
+		// Expect no error, as the validity period of the challenge applies
+		assert.NoError(t, err, "expected no error")
+
+		// Expect the solution to be correct
+		assert.True(t, isCorrect, "expected true")
 	})
 }
+
+// forgetfulSpentStorage is a spent storage, that never remembers the spent solutions.
+type forgetfulSpentStorage struct{}
+
+// Spend reports that the key was never spent before.
+func (forgetfulSpentStorage) Spend(context.Context, string, time.Time) (bool, error) {
+	return true, nil
+}
+
+// IsSpent reports that the key is not spent.
+func (forgetfulSpentStorage) IsSpent(context.Context, string) (bool, error) { return false, nil }
@@ -0,0 +1,24 @@
+package pow
+
+// Config is the configuration for the PoW service.
+type Config struct {
+	// NodeID is the ID of the node, that issues the challenges.
+	// It is carried in the challenges, so that the solutions could be attributed to the node that issued them.
+	NodeID string
+	// Mode is either ModeStateful or ModeStateless.
+	// If it is empty, the stateful mode is used.
+	Mode string
+	// Keyring holds the keys signing the challenges in the stateless mode.
+	Keyring *Keyring
+	// MaxChallengesPerClient is the maximum number of the outstanding challenges of a client in the stateful mode,
+	// any of which can be solved. When a client is issued more, its challenges expiring the soonest are dropped.
+	// If it is zero, DefaultMaxChallengesPerClient is used.
+	MaxChallengesPerClient int
+	// Scheme is the name of the scheme used for the new challenges.
+	// If it is empty, hashcash is used.
+	Scheme string
+	// Hashcash is the configuration for the hashcash scheme.
+	Hashcash HashcashConfig
+	// Argon2id is the configuration for the Argon2id puzzle scheme.
+	Argon2id Argon2idConfig
+}
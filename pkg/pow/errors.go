@@ -13,6 +13,46 @@ var (
 	// ErrChallengeDifficultyInvalid is returned when the challenge difficulty is invalid.
 	ErrChallengeDifficultyInvalid = errors.New("challenge difficulty is invalid")
 
+	// ErrDifficultyMismatch is returned when the solution difficulty does not match the challenge difficulty,
+	// whichever the scheme is.
+	ErrDifficultyMismatch = errors.New("solution difficulty does not match the challenge")
+
+	// ErrAlgorithmNotAccepted is returned when the solution uses a hash algorithm that is no longer accepted.
+	ErrAlgorithmNotAccepted = errors.New("hash algorithm is not accepted")
+
+	// ErrUnknownScheme is returned when the challenge scheme is unknown.
+	ErrUnknownScheme = errors.New("unknown challenge scheme")
+
+	// ErrNoSigningKeys is returned when the stateless mode is used without any signing keys.
+	ErrNoSigningKeys = errors.New("no signing keys")
+
+	// ErrInvalidSigningKey is returned when a signing key is malformed.
+	ErrInvalidSigningKey = errors.New("invalid signing key")
+
+	// ErrUnknownSigningKey is returned when the challenge is signed with a key that is not in the keyring.
+	ErrUnknownSigningKey = errors.New("unknown signing key")
+
+	// ErrInvalidSignature is returned when the challenge signature is missing or does not match the challenge.
+	ErrInvalidSignature = errors.New("invalid challenge signature")
+
+	// ErrChallengeBindingMismatch is returned when the solution was issued to another client or for another route.
+	ErrChallengeBindingMismatch = errors.New("challenge was issued for another client or route")
+
+	// ErrChallengeExpiryMissing is returned when a stateless challenge does not carry the expiry time.
+	ErrChallengeExpiryMissing = errors.New("challenge expiry time is missing")
+
+	// ErrMalformedSolution is returned when the solution could not be parsed.
+	ErrMalformedSolution = errors.New("solution is malformed")
+
+	// ErrStampAlreadySpent is returned when a solution, that was already accepted once, is used again.
+	ErrStampAlreadySpent = errors.New("stamp was already spent")
+
+	// ErrUnknownMode is returned when the service mode is unknown.
+	ErrUnknownMode = errors.New("unknown mode")
+
+	// ErrSchemeNotStateless is returned when the stateless mode is used with a scheme that does not support it.
+	ErrSchemeNotStateless = errors.New("scheme does not support the stateless mode")
+
 	// ErrChallengeSaltLengthInvalid is returned when the challenge salt length is invalid.
 	ErrChallengeSaltLengthInvalid = errors.New("challenge salt length is invalid")
 )
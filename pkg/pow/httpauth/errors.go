@@ -0,0 +1,14 @@
+package httpauth
+
+import "errors"
+
+var (
+	// ErrNotPoWScheme is returned when the header belongs to another authentication scheme, e.g. Bearer.
+	ErrNotPoWScheme = errors.New("authentication scheme is not PoW")
+
+	// ErrMalformedHeader is returned when the parameters of the header could not be parsed.
+	ErrMalformedHeader = errors.New("malformed PoW header")
+
+	// ErrMissingParameter is returned when a required parameter of the header is missing.
+	ErrMissingParameter = errors.New("missing PoW header parameter")
+)
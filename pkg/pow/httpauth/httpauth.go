@@ -0,0 +1,150 @@
+// Package httpauth implements the PoW HTTP authentication scheme in the style of RFC 7235.
+// The server sends the challenge in the WWW-Authenticate header:
+//
+//	WWW-Authenticate: PoW challenge="1:20:230520:...", algorithm="sha1", difficulty="20", expires="2023-05-20T12:00:00Z"
+//
+// and the client sends the solution back in the Authorization header:
+//
+//	Authorization: PoW solution="1:20:230520:...:4c73d"
+//
+// When a solution is rejected, the new challenge carries the reason in the error parameter.
+package httpauth
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Scheme is the name of the authentication scheme.
+const Scheme = "PoW"
+
+const (
+	// ChallengeHeader is the name of the response header carrying the challenge.
+	ChallengeHeader = "WWW-Authenticate"
+	// AuthorizationHeader is the name of the request header carrying the solution.
+	AuthorizationHeader = "Authorization"
+)
+
+// Names of the parameters of the scheme.
+const (
+	paramChallenge  = "challenge"
+	paramAlgorithm  = "algorithm"
+	paramDifficulty = "difficulty"
+	paramExpires    = "expires"
+	paramError      = "error"
+	paramSolution   = "solution"
+)
+
+// ErrorCode is the machine-readable reason, why a solution was rejected.
+type ErrorCode string
+
+const (
+	// ErrorMalformedSolution means the solution could not be parsed.
+	ErrorMalformedSolution ErrorCode = "malformed_solution"
+	// ErrorExpiredSolution means the challenge of the solution has expired.
+	ErrorExpiredSolution ErrorCode = "expired_solution"
+	// ErrorWrongDifficulty means the solution was produced for another difficulty than the challenge.
+	ErrorWrongDifficulty ErrorCode = "wrong_difficulty"
+	// ErrorReplayedSolution means the solution was already accepted once.
+	ErrorReplayedSolution ErrorCode = "replayed_solution"
+	// ErrorInvalidSolution means the solution is wrong for any other reason, e.g. it does not solve the challenge.
+	ErrorInvalidSolution ErrorCode = "invalid_solution"
+)
+
+// Challenge is the challenge carried in the WWW-Authenticate header.
+type Challenge struct {
+	// Challenge is the challenge string to solve.
+	Challenge string
+	// Algorithm is the algorithm of the challenge, e.g. sha1 for hashcash or argon2id.
+	Algorithm string
+	// Difficulty is the difficulty of the challenge, its meaning depends on the algorithm.
+	Difficulty int
+	// Expires is the expiry time of the challenge, it is zero if the challenge never expires.
+	Expires time.Time
+	// Error is the reason the previous solution was rejected, it is empty if no solution was sent.
+	Error ErrorCode
+}
+
+// String returns the value of the WWW-Authenticate header carrying the challenge.
+func (c Challenge) String() string {
+	params := []param{{name: paramChallenge, value: c.Challenge}}
+
+	// Add the optional parameters, if they are set
+	if c.Algorithm != "" {
+		params = append(params, param{name: paramAlgorithm, value: c.Algorithm})
+	}
+
+	if c.Difficulty > 0 {
+		params = append(params, param{name: paramDifficulty, value: strconv.Itoa(c.Difficulty)})
+	}
+
+	if !c.Expires.IsZero() {
+		params = append(params, param{name: paramExpires, value: c.Expires.UTC().Format(time.RFC3339)})
+	}
+
+	if c.Error != "" {
+		params = append(params, param{name: paramError, value: string(c.Error)})
+	}
+
+	return formatHeader(params)
+}
+
+// ParseChallenge parses the value of the WWW-Authenticate header carrying the challenge.
+// It returns ErrNotPoWScheme, if the header belongs to another scheme, so that the caller could try the next one.
+func ParseChallenge(header string) (Challenge, error) {
+	// Parse the parameters of the header
+	params, err := parseHeader(header)
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	// The challenge is the only required parameter
+	challenge := Challenge{
+		Challenge: params[paramChallenge],
+		Algorithm: params[paramAlgorithm],
+		Error:     ErrorCode(params[paramError]),
+	}
+
+	if challenge.Challenge == "" {
+		return Challenge{}, fmt.Errorf("%w: %s", ErrMissingParameter, paramChallenge)
+	}
+
+	// Parse the optional parameters, if they are set
+	if raw, ok := params[paramDifficulty]; ok {
+		if challenge.Difficulty, err = strconv.Atoi(raw); err != nil {
+			return Challenge{}, fmt.Errorf("%w: difficulty: %s", ErrMalformedHeader, err)
+		}
+	}
+
+	if raw, ok := params[paramExpires]; ok {
+		if challenge.Expires, err = time.Parse(time.RFC3339, raw); err != nil {
+			return Challenge{}, fmt.Errorf("%w: expires: %s", ErrMalformedHeader, err)
+		}
+	}
+
+	return challenge, nil
+}
+
+// Authorization returns the value of the Authorization header carrying the solution.
+func Authorization(solution string) string {
+	return formatHeader([]param{{name: paramSolution, value: solution}})
+}
+
+// ParseAuthorization parses the value of the Authorization header and returns the solution it carries.
+// It returns ErrNotPoWScheme, if the header belongs to another scheme, e.g. Bearer.
+func ParseAuthorization(header string) (string, error) {
+	// Parse the parameters of the header
+	params, err := parseHeader(header)
+	if err != nil {
+		return "", err
+	}
+
+	// The solution is required
+	solution := params[paramSolution]
+	if solution == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingParameter, paramSolution)
+	}
+
+	return solution, nil
+}
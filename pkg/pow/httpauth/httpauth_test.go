@@ -0,0 +1,100 @@
+package httpauth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
+)
+
+func TestChallenge(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		challenge := httpauth.Challenge{
+			Challenge:  `1:20:230520120000:client:r="GET:/v1/quotes/random":salt:0`,
+			Algorithm:  "sha1",
+			Difficulty: 20,
+			Expires:    time.Date(2023, 5, 20, 12, 0, 0, 0, time.UTC),
+			Error:      httpauth.ErrorExpiredSolution,
+		}
+
+		// Format the header
+		header := challenge.String()
+		assert.Equal(t,
+			`PoW challenge="1:20:230520120000:client:r=\"GET:/v1/quotes/random\":salt:0", algorithm="sha1", `+
+				`difficulty="20", expires="2023-05-20T12:00:00Z", error="expired_solution"`,
+			header,
+		)
+
+		// Parse it back
+		parsed, err := httpauth.ParseChallenge(header)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, challenge, parsed)
+	})
+
+	t.Run("Only the challenge", func(t *testing.T) {
+		// Format the header without the optional parameters
+		header := httpauth.Challenge{Challenge: "challenge"}.String()
+		assert.Equal(t, `PoW challenge="challenge"`, header)
+	})
+
+	testCases := []struct {
+		name        string
+		header      string
+		expected    httpauth.Challenge
+		expectedErr error
+	}{
+		{
+			name:     "Token values and case-insensitive names",
+			header:   `pow Challenge=abc,difficulty=4`,
+			expected: httpauth.Challenge{Challenge: "abc", Difficulty: 4},
+		},
+		{name: "Another scheme", header: `Bearer realm="api"`, expectedErr: httpauth.ErrNotPoWScheme},
+		{name: "Missing challenge", header: `PoW difficulty="4"`, expectedErr: httpauth.ErrMissingParameter},
+		{name: "Malformed difficulty", header: `PoW challenge="abc", difficulty="many"`, expectedErr: httpauth.ErrMalformedHeader},
+		{name: "Malformed expiry time", header: `PoW challenge="abc", expires="soon"`, expectedErr: httpauth.ErrMalformedHeader},
+		{name: "Unterminated quoted string", header: `PoW challenge="abc`, expectedErr: httpauth.ErrMalformedHeader},
+		{name: "Parameter without a value", header: `PoW challenge`, expectedErr: httpauth.ErrMalformedHeader},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Parse the header
+			challenge, err := httpauth.ParseChallenge(tc.header)
+
+			// Assertions
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, tc.expected, challenge)
+		})
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		// Format the header
+		header := httpauth.Authorization("1:20:230520:client::salt:4c73d")
+		assert.Equal(t, `PoW solution="1:20:230520:client::salt:4c73d"`, header)
+
+		// Parse it back
+		solution, err := httpauth.ParseAuthorization(header)
+		require.NoError(t, err, "expected no error")
+		assert.Equal(t, "1:20:230520:client::salt:4c73d", solution)
+	})
+
+	t.Run("Another scheme", func(t *testing.T) {
+		_, err := httpauth.ParseAuthorization("Bearer token")
+		assert.ErrorIs(t, err, httpauth.ErrNotPoWScheme)
+	})
+
+	t.Run("Missing solution", func(t *testing.T) {
+		_, err := httpauth.ParseAuthorization("PoW")
+		assert.ErrorIs(t, err, httpauth.ErrMissingParameter)
+	})
+}
@@ -0,0 +1,125 @@
+package httpauth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// param is a parameter of the header, the order of the parameters is kept for the readability of the headers.
+type param struct {
+	name  string
+	value string
+}
+
+// formatHeader formats the header of the scheme, quoting all the parameter values.
+func formatHeader(params []param) string {
+	var b strings.Builder
+
+	b.WriteString(Scheme)
+
+	for i, p := range params {
+		// Separate the parameters with a comma, and the first one from the scheme with a space
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteByte(' ')
+		b.WriteString(p.name)
+		b.WriteByte('=')
+		b.WriteString(quote(p.value))
+	}
+
+	return b.String()
+}
+
+// quote quotes the value as a quoted-string, escaping the quotes and the backslashes.
+func quote(value string) string {
+	var b strings.Builder
+
+	b.Grow(len(value) + 2)
+	b.WriteByte('"')
+
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' || value[i] == '\\' {
+			b.WriteByte('\\')
+		}
+
+		b.WriteByte(value[i])
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// parseHeader parses the header of the scheme into its parameters, keyed by their lower-cased names.
+// The values may be tokens or quoted-strings, the parameters are separated by commas.
+func parseHeader(header string) (map[string]string, error) {
+	// Split the scheme off, the scheme names are case-insensitive
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, Scheme) {
+		return nil, ErrNotPoWScheme
+	}
+
+	params := make(map[string]string)
+
+	for {
+		// Skip the separators between the parameters
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return params, nil
+		}
+
+		// Read the parameter name
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("%w: parameter without a value", ErrMalformedHeader)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimLeft(rest[eq+1:], " \t")
+
+		// Read the parameter value
+		value, tail, err := readValue(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		params[name], rest = value, tail
+	}
+}
+
+// readValue reads a token or a quoted-string value from the beginning of the string and returns the rest of it.
+func readValue(s string) (string, string, error) {
+	// A token lasts until the next separator
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " \t,")
+		if end < 0 {
+			end = len(s)
+		}
+
+		return s[:end], s[end:], nil
+	}
+
+	// A quoted-string lasts until the next unescaped quote
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			// Take the escaped character as is
+			i++
+			if i == len(s) {
+				return "", "", fmt.Errorf("%w: unterminated quoted string", ErrMalformedHeader)
+			}
+
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return "", "", fmt.Errorf("%w: unterminated quoted string", ErrMalformedHeader)
+}
@@ -0,0 +1,86 @@
+package pow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SigningKey is an HMAC key used to sign the stateless challenges.
+type SigningKey struct {
+	// ID is the identifier of the key, carried in the signed challenges.
+	ID string
+	// Secret is the HMAC secret.
+	Secret []byte
+}
+
+// Keyring is a set of the active signing keys.
+// The first key is the primary one, it signs the new challenges,
+// while all the keys are used to verify the solutions, so that the keys could be rotated without downtime:
+// add a new key in front of the list, and remove the old one once its challenges have expired.
+type Keyring struct {
+	keys []SigningKey
+}
+
+// NewKeyring creates a new keyring from the signing keys, the first one being the primary key.
+func NewKeyring(keys ...SigningKey) (*Keyring, error) {
+	// At least one key is required to sign the challenges
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKeys
+	}
+
+	// Check the keys
+	seen := make(map[string]struct{}, len(keys))
+
+	for _, key := range keys {
+		// Key ID is carried in the challenge extension, so it must not contain the separators
+		if key.ID == "" || strings.ContainsAny(key.ID, ":;,=") {
+			return nil, fmt.Errorf("%w: invalid key ID %q", ErrInvalidSigningKey, key.ID)
+		}
+
+		// Empty secret would make the signature trivial to forge
+		if len(key.Secret) == 0 {
+			return nil, fmt.Errorf("%w: empty secret of the key %q", ErrInvalidSigningKey, key.ID)
+		}
+
+		// Key IDs must be unique
+		if _, ok := seen[key.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate key ID %q", ErrInvalidSigningKey, key.ID)
+		}
+
+		seen[key.ID] = struct{}{}
+	}
+
+	return &Keyring{keys: keys}, nil
+}
+
+// ParseSigningKeys parses the signing keys from the "<id>:<secret>" strings, keeping their order.
+func ParseSigningKeys(rawKeys []string) ([]SigningKey, error) {
+	keys := make([]SigningKey, 0, len(rawKeys))
+
+	for _, rawKey := range rawKeys {
+		id, secret, found := strings.Cut(rawKey, ":")
+		if !found {
+			return nil, fmt.Errorf("%w: expected <id>:<secret>, got a value without a colon", ErrInvalidSigningKey)
+		}
+
+		keys = append(keys, SigningKey{ID: id, Secret: []byte(secret)})
+	}
+
+	return keys, nil
+}
+
+// Primary returns the key used to sign the new challenges.
+func (k *Keyring) Primary() SigningKey {
+	return k.keys[0]
+}
+
+// Get returns the key with the given ID and reports whether it is in the keyring.
+func (k *Keyring) Get(id string) (SigningKey, bool) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+
+	return SigningKey{}, false
+}
@@ -0,0 +1,74 @@
+package pow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+func TestNewKeyring(t *testing.T) {
+	t.Run("No keys", func(t *testing.T) {
+		_, err := pow.NewKeyring()
+
+		assert.ErrorIs(t, err, pow.ErrNoSigningKeys, "expected ErrNoSigningKeys")
+	})
+
+	tests := []struct {
+		name string
+		keys []pow.SigningKey
+	}{
+		{name: "Empty key ID", keys: []pow.SigningKey{{ID: "", Secret: []byte("secret")}}},
+		{name: "Key ID with a separator", keys: []pow.SigningKey{{ID: "k;1", Secret: []byte("secret")}}},
+		{name: "Empty secret", keys: []pow.SigningKey{{ID: "k1"}}},
+		{name: "Duplicate key ID", keys: []pow.SigningKey{{ID: "k1", Secret: []byte("a")}, {ID: "k1", Secret: []byte("b")}}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pow.NewKeyring(tt.keys...)
+
+			assert.ErrorIs(t, err, pow.ErrInvalidSigningKey, "expected ErrInvalidSigningKey")
+		})
+	}
+
+	t.Run("Valid keys", func(t *testing.T) {
+		keyring, err := pow.NewKeyring(
+			pow.SigningKey{ID: "k2", Secret: []byte("new")},
+			pow.SigningKey{ID: "k1", Secret: []byte("old")},
+		)
+		require.NoError(t, err, "expected no error")
+
+		// The first key is the primary one
+		assert.Equal(t, "k2", keyring.Primary().ID)
+
+		// All the keys could be found
+		key, ok := keyring.Get("k1")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("old"), key.Secret)
+
+		_, ok = keyring.Get("k3")
+		assert.False(t, ok)
+	})
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	t.Run("Valid keys keep their order", func(t *testing.T) {
+		keys, err := pow.ParseSigningKeys([]string{"k2:new:secret", "k1:old"})
+
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, []pow.SigningKey{
+			{ID: "k2", Secret: []byte("new:secret")},
+			{ID: "k1", Secret: []byte("old")},
+		}, keys)
+	})
+
+	t.Run("Key without a secret", func(t *testing.T) {
+		_, err := pow.ParseSigningKeys([]string{"k1"})
+
+		assert.ErrorIs(t, err, pow.ErrInvalidSigningKey, "expected ErrInvalidSigningKey")
+	})
+}
@@ -0,0 +1,24 @@
+package pow
+
+import "fmt"
+
+// Metadata returns the metadata carried in the challenge or solution string, along with the name of its scheme.
+// It lets the clients be told the parameters of the challenge, without parsing it themselves.
+func (s *Service) Metadata(challenge string) (Metadata, error) {
+	// Detect the scheme of the challenge
+	scheme, err := DetectScheme(challenge, s.schemes...)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("detecting challenge scheme: %w", err)
+	}
+
+	// Get the metadata carried in the challenge
+	metadata, err := scheme.Metadata(challenge)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("getting challenge metadata: %w", err)
+	}
+
+	// Tell the scheme of the challenge
+	metadata.Scheme = scheme.Name()
+
+	return metadata, nil
+}
@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MockSpentStorage is a mock for spent solutions storage.
+// It does not evict the expired keys. It is safe for concurrent use.
+type MockSpentStorage struct {
+	mu           sync.Mutex
+	spent        map[string]time.Time
+	storageError error
+}
+
+// NewMockSpentStorage creates a new mock for spent solutions storage.
+func NewMockSpentStorage(storageError error) *MockSpentStorage {
+	return &MockSpentStorage{spent: make(map[string]time.Time), storageError: storageError}
+}
+
+// Spend marks the key as spent until the expiry time and reports whether it was not spent before.
+func (m *MockSpentStorage) Spend(_ context.Context, key string, expires time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set
+	if m.storageError != nil {
+		return false, m.storageError
+	}
+
+	// check if the key was spent before
+	if _, ok := m.spent[key]; ok {
+		return false, nil
+	}
+
+	// mark the key as spent
+	m.spent[key] = expires
+
+	// return the result
+	return true, nil
+}
+
+// IsSpent reports whether the key is spent.
+func (m *MockSpentStorage) IsSpent(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set
+	if m.storageError != nil {
+		return false, m.storageError
+	}
+
+	// check if the key was spent
+	_, ok := m.spent[key]
+
+	// return the result
+	return ok, nil
+}
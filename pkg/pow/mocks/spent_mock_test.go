@@ -0,0 +1,78 @@
+package mocks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow/mocks"
+)
+
+func TestMockSpentStorage_Spend(t *testing.T) {
+	t.Run("Error was set", func(t *testing.T) {
+		// Create a new mock store with an error
+		store := mocks.NewMockSpentStorage(errors.New("error"))
+
+		// Spend a key
+		fresh, err := store.Spend(context.TODO(), "key", time.Now().Add(time.Minute))
+
+		// Check if the error is correct
+		assert.Error(t, err, "expected error")
+
+		// Check if the result is correct
+		assert.False(t, fresh, "expected false")
+	})
+
+	t.Run("Error was not set", func(t *testing.T) {
+		// Create a new mock store
+		store := mocks.NewMockSpentStorage(nil)
+
+		// Spend a key
+		fresh, err := store.Spend(context.TODO(), "key", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, fresh, "expected true")
+
+		// Spend it again
+		fresh, err = store.Spend(context.TODO(), "key", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, fresh, "expected false")
+	})
+}
+
+func TestMockSpentStorage_IsSpent(t *testing.T) {
+	t.Run("Error was set", func(t *testing.T) {
+		// Create a new mock store with an error
+		store := mocks.NewMockSpentStorage(errors.New("error"))
+
+		// Check a key
+		spent, err := store.IsSpent(context.TODO(), "key")
+
+		// Check if the error is correct
+		assert.Error(t, err, "expected error")
+
+		// Check if the result is correct
+		assert.False(t, spent, "expected false")
+	})
+
+	t.Run("Error was not set", func(t *testing.T) {
+		// Create a new mock store
+		store := mocks.NewMockSpentStorage(nil)
+
+		// The key is not spent yet
+		spent, err := store.IsSpent(context.TODO(), "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, spent, "expected false")
+
+		// Spend the key
+		_, err = store.Spend(context.TODO(), "key", time.Now().Add(time.Minute))
+		assert.NoError(t, err, "expected no error")
+
+		// The key is spent
+		spent, err = store.IsSpent(context.TODO(), "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, spent, "expected true")
+	})
+}
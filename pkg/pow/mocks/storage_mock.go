@@ -1,11 +1,21 @@
 package mocks
 
-import "context"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
-// MockChallengeStorage is a mock for challenge storage.
+// MockChallengeStorage is a mock for challenge storage, safe for concurrent use.
+// It behaves as a real storage, passing the storetest suite, unless the storage error is set.
+// The challenges given to the constructor never expire.
 type MockChallengeStorage struct {
+	mu           sync.Mutex
 	challenges   map[string]string
+	expires      map[string]time.Time
+	groups       map[string][]string
 	storageError error
+	lastTTL      time.Duration
 }
 
 // NewMockChallengeStorage creates a new mock for challenge storage.
@@ -15,47 +25,158 @@ func NewMockChallengeStorage(challenges map[string]string, storageError error) *
 		challenges = make(map[string]string)
 	}
 
-	return &MockChallengeStorage{challenges: challenges, storageError: storageError}
+	return &MockChallengeStorage{
+		challenges:   challenges,
+		expires:      make(map[string]time.Time),
+		groups:       make(map[string][]string),
+		storageError: storageError,
+	}
 }
 
-// Add adds a challenge to the store.
-func (m *MockChallengeStorage) Add(_ context.Context, key, value string) error {
-	// check if error was set
-	if m.storageError != nil {
-		return m.storageError
+// Add adds a challenge to the store for the ttl.
+func (m *MockChallengeStorage) Add(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set or the context is canceled
+	if err := m.err(ctx); err != nil {
+		return err
 	}
 
-	// add the challenge to the map
+	// add the challenge to the map and remember its time to live
 	m.challenges[key] = value
+	m.expires[key] = time.Now().Add(ttl)
+	m.lastTTL = ttl
 
 	// return no error
 	return nil
 }
 
-// Get checks if a challenge is in the store.
-func (m *MockChallengeStorage) Get(_ context.Context, key string) (bool, error) {
-	// check if error was set
-	if m.storageError != nil {
-		return false, m.storageError
+// Get returns the challenge stored under the key and reports whether it is in the store and has not expired yet.
+func (m *MockChallengeStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set or the context is canceled
+	if err := m.err(ctx); err != nil {
+		return "", false, err
 	}
 
 	// check if the challenge exists in the map
-	_, ok := m.challenges[key]
+	value, ok := m.live(key)
 
 	// return the result
-	return ok, nil
+	return value, ok, nil
 }
 
 // Delete deletes a challenge from the store.
-func (m *MockChallengeStorage) Delete(_ context.Context, key string) error {
-	// check if error was set
-	if m.storageError != nil {
-		return m.storageError
+func (m *MockChallengeStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set or the context is canceled
+	if err := m.err(ctx); err != nil {
+		return err
 	}
 
 	// delete the challenge from the map
 	delete(m.challenges, key)
+	delete(m.expires, key)
 
 	// return no error
 	return nil
 }
+
+// Consume returns the challenge stored under the key and deletes it in one step.
+func (m *MockChallengeStorage) Consume(ctx context.Context, key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set or the context is canceled
+	if err := m.err(ctx); err != nil {
+		return "", false, err
+	}
+
+	// take the challenge out of the map
+	value, ok := m.live(key)
+	delete(m.challenges, key)
+	delete(m.expires, key)
+
+	// return the result
+	return value, ok, nil
+}
+
+// AddToGroup adds a challenge to the store for the ttl and makes it a member of the group,
+// deleting the other members expiring the soonest over the limit.
+func (m *MockChallengeStorage) AddToGroup(ctx context.Context, group, key, value string, ttl time.Duration, limit int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// check if error was set or the context is canceled
+	if err := m.err(ctx); err != nil {
+		return err
+	}
+
+	// add the challenge to the map and remember its time to live
+	m.challenges[key] = value
+	m.expires[key] = time.Now().Add(ttl)
+	m.lastTTL = ttl
+
+	// forget the members, that are gone, and add the challenge to the group
+	members := []string{key}
+	for _, member := range m.groups[group] {
+		if _, ok := m.live(member); ok && member != key {
+			members = append(members, member)
+		}
+	}
+
+	// delete the other members expiring the soonest over the limit
+	for len(members) > limit && len(members) > 1 {
+		first := 1
+		for i := 2; i < len(members); i++ {
+			if m.expires[members[i]].Before(m.expires[members[first]]) {
+				first = i
+			}
+		}
+
+		delete(m.challenges, members[first])
+		delete(m.expires, members[first])
+		members = append(members[:first], members[first+1:]...)
+	}
+
+	m.groups[group] = members
+
+	// return no error
+	return nil
+}
+
+// LastTTL returns the time to live of the last added challenge.
+func (m *MockChallengeStorage) LastTTL() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastTTL
+}
+
+// err returns the storage error, if it was set, or the error of the context.
+func (m *MockChallengeStorage) err(ctx context.Context) error {
+	if m.storageError != nil {
+		return m.storageError
+	}
+
+	return ctx.Err()
+}
+
+// live returns the challenge stored under the key and reports whether it is there and has not expired yet.
+func (m *MockChallengeStorage) live(key string) (string, bool) {
+	value, ok := m.challenges[key]
+	if !ok {
+		return "", false
+	}
+
+	if expires, ok := m.expires[key]; ok && !time.Now().Before(expires) {
+		return "", false
+	}
+
+	return value, true
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
 	"github.com/daniel-orlov/quotes-server/pkg/pow/mocks"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/storetest"
 )
 
 func TestMockChallengeStorage_Add(t *testing.T) {
@@ -16,7 +19,7 @@ func TestMockChallengeStorage_Add(t *testing.T) {
 		store := mocks.NewMockChallengeStorage(nil, errors.New("error"))
 
 		// Add a challenge to the store
-		err := store.Add(context.TODO(), "key", "value")
+		err := store.Add(context.TODO(), "key", "value", time.Minute)
 
 		// Check if the error is correct
 		assert.Error(t, err, "expected error")
@@ -27,7 +30,7 @@ func TestMockChallengeStorage_Add(t *testing.T) {
 		store := mocks.NewMockChallengeStorage(nil, nil)
 
 		// Add a challenge to the store
-		err := store.Add(context.TODO(), "key", "value")
+		err := store.Add(context.TODO(), "key", "value", time.Minute)
 
 		// Check if the error is correct
 		assert.NoError(t, err, "expected no error")
@@ -40,7 +43,7 @@ func TestMockChallengeStorage_Get(t *testing.T) {
 		store := mocks.NewMockChallengeStorage(nil, errors.New("error"))
 
 		// Get a challenge from the store
-		exists, err := store.Get(context.TODO(), "key")
+		_, exists, err := store.Get(context.TODO(), "key")
 
 		// Check if the error is correct
 		assert.Error(t, err, "expected error")
@@ -55,13 +58,16 @@ func TestMockChallengeStorage_Get(t *testing.T) {
 			store := mocks.NewMockChallengeStorage(map[string]string{"key": "value"}, nil)
 
 			// Get a challenge from the store
-			exists, err := store.Get(context.TODO(), "key")
+			value, exists, err := store.Get(context.TODO(), "key")
 
 			// Check if the error is correct
 			assert.NoError(t, err, "expected no error")
 
 			// Check if the result is correct
 			assert.True(t, exists, "expected true")
+
+			// Check if the stored challenge is returned
+			assert.Equal(t, "value", value, "expected stored challenge")
 		})
 
 		t.Run("Challenge does not exist", func(t *testing.T) {
@@ -69,7 +75,7 @@ func TestMockChallengeStorage_Get(t *testing.T) {
 			store := mocks.NewMockChallengeStorage(map[string]string{"key": "value"}, nil)
 
 			// Get a challenge from the store
-			exists, err := store.Get(context.TODO(), "key2")
+			_, exists, err := store.Get(context.TODO(), "key2")
 
 			// Check if the error is correct
 			assert.NoError(t, err, "expected no error")
@@ -103,3 +109,45 @@ func TestMockChallengeStorage_Delete(t *testing.T) {
 		assert.NoError(t, err, "expected no error")
 	})
 }
+
+func TestMockChallengeStorage_Consume(t *testing.T) {
+	t.Run("Error was set", func(t *testing.T) {
+		// Create a new mock store with an error
+		store := mocks.NewMockChallengeStorage(map[string]string{"key": "value"}, errors.New("error"))
+
+		// Consume a challenge from the store
+		_, exists, err := store.Consume(context.TODO(), "key")
+
+		// Check if the error is correct
+		assert.Error(t, err, "expected error")
+
+		// Check if the result is correct
+		assert.False(t, exists, "expected false")
+	})
+
+	t.Run("Error was not set", func(t *testing.T) {
+		// Create a new mock store
+		store := mocks.NewMockChallengeStorage(map[string]string{"key": "value"}, nil)
+
+		// Consume a challenge from the store
+		value, exists, err := store.Consume(context.TODO(), "key")
+
+		// Check if the result is correct
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, exists, "expected true")
+		assert.Equal(t, "value", value, "expected value")
+
+		// Consume the challenge again
+		_, exists, err = store.Consume(context.TODO(), "key")
+
+		// Check if the challenge is gone
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, exists, "expected false")
+	})
+}
+
+func TestMockChallengeStorage_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (pow.ChallengeStore, func(time.Duration)) {
+		return mocks.NewMockChallengeStorage(nil, nil), time.Sleep
+	})
+}
@@ -3,12 +3,14 @@ package pow
 import (
 	"context"
 	"fmt"
-
-	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"time"
 )
 
 // NewChallenge generates a new challenge, saves it to the store and returns it.
-func (s *Service) NewChallenge(ctx context.Context, key Key, difficulty, saltLength int) (string, error) {
+// The options override the configured validity period and algorithm for this challenge only.
+func (s *Service) NewChallenge(
+	ctx context.Context, key Key, difficulty, saltLength int, opts ...ChallengeOption,
+) (string, error) {
 	// Check if the challenge key is empty
 	if key == nil || key.String() == "" || key.ResourceID() == "" || key.ClientID() == "" {
 		// Return an error if the challenge key is empty
@@ -27,17 +29,58 @@ func (s *Service) NewChallenge(ctx context.Context, key Key, difficulty, saltLen
 		return "", ErrChallengeSaltLengthInvalid
 	}
 
+	// Tie the challenge to the client and the route
+	params := ChallengeParams{
+		Resource:   challengeResource(key.ClientID()),
+		Route:      key.ResourceID(),
+		Node:       s.cfg.NodeID,
+		Difficulty: difficulty,
+		SaltLength: saltLength,
+	}
+
+	// Apply the challenge options
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	// Check the mode
+	stateless, err := s.isStateless()
+	if err != nil {
+		return "", err
+	}
+
+	// In the stateless mode, the challenge is signed instead of being saved to the store
+	if stateless {
+		return s.newStatelessChallenge(params)
+	}
+
+	// Get the scheme for the new challenges
+	scheme, err := s.scheme()
+	if err != nil {
+		return "", fmt.Errorf("getting challenge scheme: %w", err)
+	}
+
 	// Generate a new challenge
-	challenge, err := hashcash.New(difficulty, saltLength, hashcash.DateFormatYYMMDD, key.ClientID())
+	challengeStr, err := scheme.NewChallenge(params)
 	if err != nil {
 		return "", fmt.Errorf("generating new challenge: %w", err)
 	}
 
-	// Stringify the challenge
-	challengeStr := challenge.String()
+	// Get for how long the challenge can be solved
+	ttl, err := challengeTTL(scheme, challengeStr)
+	if err != nil {
+		return "", err
+	}
+
+	// Get the ID of the challenge, that its solution will carry as well
+	id, err := challengeID(scheme, challengeStr)
+	if err != nil {
+		return "", err
+	}
 
-	// Save the challenge to the store
-	err = s.Store.Add(ctx, key.String(), challengeStr)
+	// Save the challenge to the store, for as long as it can be solved, among the other challenges of the client,
+	// so that the client can solve any of them, but only has that many at once
+	err = s.Store.AddToGroup(ctx, key.ClientID(), challengeStoreKey(key, id), challengeStr, ttl, s.maxChallengesPerClient())
 	if err != nil {
 		return "", fmt.Errorf("saving challenge to store: %w", err)
 	}
@@ -45,3 +88,36 @@ func (s *Service) NewChallenge(ctx context.Context, key Key, difficulty, saltLen
 	// Return the challenge
 	return challengeStr, nil
 }
+
+// challengeID returns the ID of the challenge or solution.
+// It returns ErrMalformedSolution, if the ID could not be read, as only the solutions can be malformed.
+func challengeID(scheme Scheme, challenge string) (string, error) {
+	metadata, err := scheme.Metadata(challenge)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformedSolution, err)
+	}
+
+	// The challenges without the ID could not be told apart
+	if metadata.ID == "" {
+		return "", fmt.Errorf("%w: challenge ID is missing", ErrMalformedSolution)
+	}
+
+	return metadata.ID, nil
+}
+
+// challengeTTL returns for how long the challenge can be solved, but no longer than MaxSpentRetention,
+// so that the challenges, that never expire, are not kept in the store forever either.
+func challengeTTL(scheme Scheme, challenge string) (time.Duration, error) {
+	// Get the expiry time of the challenge
+	metadata, err := scheme.Metadata(challenge)
+	if err != nil {
+		return 0, fmt.Errorf("getting challenge metadata: %w", err)
+	}
+
+	// Cap the time to live of the challenges, that never expire or expire too late
+	if metadata.Expires.IsZero() || time.Until(metadata.Expires) > MaxSpentRetention {
+		return MaxSpentRetention, nil
+	}
+
+	return time.Until(metadata.Expires), nil
+}
@@ -3,11 +3,15 @@ package pow_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
 	"github.com/daniel-orlov/quotes-server/pkg/pow"
 	"github.com/daniel-orlov/quotes-server/pkg/pow/mocks"
 )
@@ -16,7 +20,7 @@ func TestService_NewChallenge(t *testing.T) {
 	t.Run("Challenge Key is empty", func(t *testing.T) {
 		t.Run("Nil key", func(t *testing.T) {
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 			// Generate a new challenge
 			challenge, err := service.NewChallenge(context.TODO(), nil, 20, 8)
@@ -30,7 +34,7 @@ func TestService_NewChallenge(t *testing.T) {
 
 		t.Run("Empty key", func(t *testing.T) {
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 			// Generate a new challenge
 			challenge, err := service.NewChallenge(context.TODO(), &pow.ChallengeKey{}, 20, 8)
@@ -44,7 +48,7 @@ func TestService_NewChallenge(t *testing.T) {
 
 		t.Run("Empty clientID", func(t *testing.T) {
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 			// Generate a new challenge
 			challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("", "resourceID"), 20, 8)
@@ -58,7 +62,7 @@ func TestService_NewChallenge(t *testing.T) {
 
 		t.Run("Empty resourceID", func(t *testing.T) {
 			// Create a new service
-			service := pow.NewService(zap.NewNop(), nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 			// Generate a new challenge
 			challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", ""), 20, 8)
@@ -73,7 +77,7 @@ func TestService_NewChallenge(t *testing.T) {
 
 	t.Run("Difficulty is invalid", func(t *testing.T) {
 		// Create a new service
-		service := pow.NewService(zap.NewNop(), nil)
+		service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 		// Generate a new challenge
 		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), -1, 8)
@@ -87,7 +91,7 @@ func TestService_NewChallenge(t *testing.T) {
 
 	t.Run("Salt length is invalid", func(t *testing.T) {
 		// Create a new service
-		service := pow.NewService(zap.NewNop(), nil)
+		service := pow.NewService(zap.NewNop(), &pow.Config{}, nil, mocks.NewMockSpentStorage(nil))
 
 		// Generate a new challenge
 		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, -1)
@@ -104,7 +108,7 @@ func TestService_NewChallenge(t *testing.T) {
 		store := mocks.NewMockChallengeStorage(nil, errors.New("storage error"))
 
 		// Create a new service
-		service := pow.NewService(zap.NewNop(), store)
+		service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 		// Generate a new challenge
 		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8)
@@ -121,7 +125,7 @@ func TestService_NewChallenge(t *testing.T) {
 		store := mocks.NewMockChallengeStorage(nil, nil)
 
 		// Create a new service
-		service := pow.NewService(zap.NewNop(), store)
+		service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
 
 		// Generate a new challenge
 		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8)
@@ -132,4 +136,124 @@ func TestService_NewChallenge(t *testing.T) {
 		// Expect the challenge to be not empty
 		assert.NotEmpty(t, challenge, "expected not empty challenge")
 	})
+
+	t.Run("Success with configured algorithm", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(nil, nil)
+
+		// Create a new service
+		service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{Algorithm: hashcash.AlgorithmBLAKE2b256}}, store, mocks.NewMockSpentStorage(nil))
+
+		// Generate a new challenge
+		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8)
+
+		// Expect no error
+		assert.NoError(t, err, "expected no error")
+
+		// Parse the challenge
+		hc, err := hashcash.ParseStr(challenge)
+
+		// Expect no error
+		assert.NoError(t, err, "expected no error")
+
+		// Expect the challenge to use the configured algorithm
+		assert.Equal(t, hashcash.AlgorithmBLAKE2b256, hc.Algorithm(), "expected configured algorithm")
+	})
+
+	t.Run("Challenge options", func(t *testing.T) {
+		t.Run("Validity period", func(t *testing.T) {
+			// Create a new service with the validity period, that the challenge overrides
+			service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{ValidFor: 10 * time.Minute}},
+				mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+			// Generate a new challenge valid for 30 seconds
+			challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8,
+				pow.WithValidFor(30*time.Second))
+			require.NoError(t, err, "expected no error")
+
+			// Expect the challenge to be dated to the second
+			date := strings.Split(challenge, ":")[2]
+			assert.Len(t, date, len(hashcash.DateFormatYYMMDDhhmmss.String()), "expected date format precise enough")
+
+			// Expect the challenge to expire within 30 seconds
+			metadata, err := pow.NewHashcashScheme(pow.HashcashConfig{}).Metadata(challenge)
+			require.NoError(t, err, "expected no error")
+			assert.WithinDuration(t, time.Now().Add(30*time.Second), metadata.Expires, 2*time.Second)
+		})
+
+		t.Run("Stored for as long as it can be solved", func(t *testing.T) {
+			// Create a new service
+			store := mocks.NewMockChallengeStorage(nil, nil)
+			service := pow.NewService(zap.NewNop(), &pow.Config{}, store, mocks.NewMockSpentStorage(nil))
+
+			// Generate a new challenge valid for 30 seconds
+			_, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8,
+				pow.WithValidFor(30*time.Second))
+			require.NoError(t, err, "expected no error")
+
+			// Expect the challenge to be stored until it expires
+			assert.InDelta(t, 30*time.Second, store.LastTTL(), float64(2*time.Second), "expected ttl of the challenge")
+		})
+
+		t.Run("Extra algorithm", func(t *testing.T) {
+			// Create a new service, offering BLAKE2b-256 besides SHA-256
+			service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{
+				Algorithm:       hashcash.AlgorithmSHA256,
+				ExtraAlgorithms: []hashcash.Algorithm{hashcash.AlgorithmBLAKE2b256},
+			}}, mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+			// Generate a new challenge using the extra algorithm
+			challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8,
+				pow.WithAlgorithm(hashcash.AlgorithmBLAKE2b256))
+			require.NoError(t, err, "expected no error")
+
+			// Expect the challenge to use the extra algorithm
+			hc, err := hashcash.ParseStr(challenge)
+			require.NoError(t, err, "expected no error")
+			assert.Equal(t, hashcash.AlgorithmBLAKE2b256, hc.Algorithm(), "expected extra algorithm")
+		})
+
+		t.Run("Algorithm is not offered", func(t *testing.T) {
+			// Create a new service, offering SHA-256 only
+			service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{Algorithm: hashcash.AlgorithmSHA256}},
+				mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+			// Generate a new challenge using another algorithm
+			_, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8,
+				pow.WithAlgorithm(hashcash.AlgorithmSHA3256))
+
+			// Expect an error - ErrAlgorithmNotAccepted
+			assert.ErrorIs(t, err, pow.ErrAlgorithmNotAccepted, "expected ErrAlgorithmNotAccepted")
+		})
+	})
+
+	t.Run("Success with configured validity period", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			validFor   time.Duration
+			dateFormat hashcash.DateFormat
+		}{
+			{name: "No validity period", validFor: 0, dateFormat: hashcash.DateFormatYYMMDD},
+			{name: "Seconds", validFor: 30 * time.Second, dateFormat: hashcash.DateFormatYYMMDDhhmmss},
+			{name: "Minutes", validFor: 10 * time.Minute, dateFormat: hashcash.DateFormatYYMMDDhhmm},
+			{name: "Hours", validFor: 6 * time.Hour, dateFormat: hashcash.DateFormatYYMMDDhhmm},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// Create a new service
+				service := pow.NewService(zap.NewNop(), &pow.Config{Hashcash: pow.HashcashConfig{ValidFor: tt.validFor}},
+					mocks.NewMockChallengeStorage(nil, nil), mocks.NewMockSpentStorage(nil))
+
+				// Generate a new challenge
+				challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 20, 8)
+
+				// Expect no error
+				assert.NoError(t, err, "expected no error")
+
+				// Expect the challenge to be dated precisely enough for its validity period
+				date := strings.Split(challenge, ":")[2]
+				assert.Len(t, date, len(tt.dateFormat.String()), "expected date format precise enough")
+			})
+		}
+	})
 }
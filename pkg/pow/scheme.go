@@ -0,0 +1,139 @@
+package pow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+// Scheme is a proof-of-work challenge scheme, e.g. hashcash or Argon2id puzzle.
+// It is responsible for the format of the challenges, as well as for solving and verifying them.
+type Scheme interface {
+	// Name returns the name of the scheme.
+	Name() string
+	// Detect reports whether the challenge or solution string belongs to the scheme.
+	Detect(challenge string) bool
+	// NewChallenge creates a new challenge string.
+	NewChallenge(params ChallengeParams) (string, error)
+	// Verify checks that the solution is correct and was produced for exactly the given challenge.
+	Verify(challenge, solution string) (bool, error)
+	// Metadata returns the metadata carried in the challenge or solution string, if the scheme supports it.
+	Metadata(challenge string) (Metadata, error)
+	// Solve solves the challenge using the given number of workers, if the scheme supports parallel solving.
+	Solve(ctx context.Context, challenge string, workers int) (*SolveResult, error)
+}
+
+// SolveResult is the result of solving a challenge.
+type SolveResult struct {
+	// Solution is the solved challenge string.
+	Solution string
+	// Attempts is the number of hashes computed.
+	Attempts uint64
+	// Duration is the time spent solving.
+	Duration time.Duration
+}
+
+// HashRate returns the number of hashes computed per second.
+func (r *SolveResult) HashRate() float64 {
+	// Return 0 if nothing was measured. This is to avoid division by zero.
+	if r == nil || r.Duration <= 0 {
+		return 0
+	}
+
+	return float64(r.Attempts) / r.Duration.Seconds()
+}
+
+// Metadata is the server-side metadata carried in a challenge.
+// It allows attributing a solution to its origin without looking the challenge up in the store.
+type Metadata struct {
+	// Scheme is the name of the scheme of the challenge, it is set by the service, not by the scheme itself.
+	Scheme string
+	// ID is the ID of the challenge, shared by the challenge and its solutions, e.g. the random salt.
+	// It is empty, if the challenge does not carry one.
+	ID string
+	// Node is the ID of the node, that issued the challenge.
+	Node string
+	// Route is the route the challenge was issued for.
+	Route string
+	// Expires is the expiry time of the challenge, it is zero if the challenge never expires.
+	Expires time.Time
+	// Difficulty is the difficulty of the challenge, its meaning depends on the scheme.
+	Difficulty int
+	// Algorithm is the hash algorithm of the challenge, it is empty for the schemes, that have no choice of the algorithm.
+	Algorithm hashcash.Algorithm
+}
+
+// ChallengeParams are the parameters of a new challenge.
+type ChallengeParams struct {
+	// Resource is the resource to which the challenge is tied.
+	Resource string
+	// Route is the route the challenge is issued for.
+	Route string
+	// Node is the ID of the node, that issues the challenge.
+	Node string
+	// Difficulty is the number of leading zero bits required.
+	Difficulty int
+	// SaltLength is the length of the salt.
+	SaltLength int
+	// ValidFor is the validity period of the challenge, if it is zero, the one configured for the scheme is used.
+	ValidFor time.Duration
+	// Algorithm is the hash algorithm of the challenge, if it is empty, the one configured for the scheme is used.
+	// It is ignored by the schemes, that have no choice of the algorithm.
+	Algorithm hashcash.Algorithm
+}
+
+// ChallengeOption is an optional parameter of a new challenge, overriding the service configuration.
+type ChallengeOption func(params *ChallengeParams)
+
+// WithValidFor sets the validity period of the new challenge.
+func WithValidFor(validFor time.Duration) ChallengeOption {
+	return func(params *ChallengeParams) {
+		params.ValidFor = validFor
+	}
+}
+
+// WithAlgorithm sets the hash algorithm of the new challenge.
+// It must be either the algorithm configured for the new challenges or one of the additional algorithms.
+func WithAlgorithm(algorithm hashcash.Algorithm) ChallengeOption {
+	return func(params *ChallengeParams) {
+		params.Algorithm = algorithm
+	}
+}
+
+const (
+	// SchemeHashcash is the name of the hashcash scheme.
+	SchemeHashcash = "hashcash"
+	// SchemeArgon2id is the name of the Argon2id puzzle scheme.
+	SchemeArgon2id = "argon2id"
+)
+
+// DetectScheme returns the scheme, which the challenge or solution string belongs to.
+func DetectScheme(challenge string, schemes ...Scheme) (Scheme, error) {
+	// Go through the schemes and return the first one that recognizes the challenge
+	for _, scheme := range schemes {
+		if scheme.Detect(challenge) {
+			return scheme, nil
+		}
+	}
+
+	// None of the schemes recognized the challenge
+	return nil, ErrUnknownScheme
+}
+
+// Solve detects the scheme of the challenge and solves it using the given number of workers.
+// It is meant to be used by the clients, that do not know in advance which scheme the server uses.
+func Solve(ctx context.Context, challenge string, workers int) (*SolveResult, error) {
+	// Detect the scheme of the challenge, solving does not depend on the server configuration
+	scheme, err := DetectScheme(challenge,
+		NewHashcashScheme(HashcashConfig{}),
+		NewArgon2idScheme(Argon2idConfig{}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("detecting challenge scheme: %w", err)
+	}
+
+	// Solve the challenge
+	return scheme.Solve(ctx, challenge, workers)
+}
@@ -0,0 +1,123 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
+)
+
+// Argon2idConfig is the configuration for the Argon2id puzzle scheme.
+type Argon2idConfig struct {
+	// Params are the Argon2id parameters of the new puzzles.
+	Params argon2id.Params
+	// ValidFor is the validity period of the new puzzles.
+	ValidFor time.Duration
+}
+
+// Argon2idScheme is the memory-hard Argon2id puzzle scheme.
+type Argon2idScheme struct {
+	cfg Argon2idConfig
+}
+
+// NewArgon2idScheme creates a new Argon2id puzzle scheme.
+func NewArgon2idScheme(cfg Argon2idConfig) *Argon2idScheme {
+	return &Argon2idScheme{cfg: cfg}
+}
+
+// Name returns the name of the scheme.
+func (s *Argon2idScheme) Name() string {
+	return SchemeArgon2id
+}
+
+// Detect reports whether the challenge is an Argon2id puzzle string.
+func (s *Argon2idScheme) Detect(challenge string) bool {
+	return strings.HasPrefix(challenge, argon2id.Prefix+":")
+}
+
+// NewChallenge creates a new Argon2id puzzle.
+func (s *Argon2idScheme) NewChallenge(params ChallengeParams) (string, error) {
+	// Use the validity period of the challenge, if it is set
+	validFor := s.cfg.ValidFor
+	if params.ValidFor > 0 {
+		validFor = params.ValidFor
+	}
+
+	// Generate a new puzzle
+	puzzle, err := argon2id.New(params.Difficulty, params.SaltLength, s.cfg.Params, validFor, params.Resource)
+	if err != nil {
+		return "", fmt.Errorf("generating new argon2id puzzle: %w", err)
+	}
+
+	// Return the stringified puzzle
+	return puzzle.String(), nil
+}
+
+// Verify checks that the puzzle solution is correct and was produced for exactly the given challenge.
+func (s *Argon2idScheme) Verify(challenge, solution string) (bool, error) {
+	// Parse the issued challenge
+	issued, err := argon2id.ParseStr(challenge)
+	if err != nil {
+		return false, fmt.Errorf("parsing challenge: %w", err)
+	}
+
+	// Parse the solution
+	puzzle, err := argon2id.ParseStr(solution)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrMalformedSolution, err)
+	}
+
+	// Check that the solution was produced for the issued challenge
+	if err = puzzle.Match(issued); err != nil {
+		if errors.Is(err, argon2id.ErrDifficultyMismatch) {
+			return false, fmt.Errorf("%w: %s", ErrDifficultyMismatch, err)
+		}
+
+		return false, fmt.Errorf("matching solution against challenge: %w", err)
+	}
+
+	// Check if the solution is correct
+	return puzzle.Check()
+}
+
+// Metadata returns the ID, the expiry time and the difficulty of the Argon2id puzzle,
+// as it does not carry the node and the route.
+func (s *Argon2idScheme) Metadata(challenge string) (Metadata, error) {
+	// Parse the puzzle
+	puzzle, err := argon2id.ParseStr(challenge)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("parsing argon2id puzzle: %w", err)
+	}
+
+	return Metadata{ID: puzzle.Salt(), Expires: puzzle.Expires(), Difficulty: puzzle.Difficulty()}, nil
+}
+
+// Solve solves the Argon2id puzzle.
+// The workers are not used, as every hash already uses as many threads as the puzzle parallelism parameter sets.
+func (s *Argon2idScheme) Solve(ctx context.Context, challenge string, _ int) (*SolveResult, error) {
+	// Parse the puzzle
+	puzzle, err := argon2id.ParseStr(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("parsing argon2id puzzle: %w", err)
+	}
+
+	// Remember where solving starts, to count the attempts
+	firstNonce := puzzle.Nonce()
+	start := time.Now()
+
+	// Solve the puzzle
+	solution, err := puzzle.Solve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("solving argon2id puzzle: %w", err)
+	}
+
+	// Return the result
+	return &SolveResult{
+		Solution: solution,
+		Attempts: puzzle.Nonce() - firstNonce + 1,
+		Duration: time.Since(start),
+	}, nil
+}
@@ -0,0 +1,315 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+// HashcashConfig is the configuration for the hashcash scheme.
+type HashcashConfig struct {
+	// Algorithm is the hash algorithm used for the new hashcash challenges.
+	Algorithm hashcash.Algorithm
+	// LegacyAlgorithms are the hash algorithms that are no longer used for the new challenges,
+	// but whose solutions are still accepted until the end of the migration.
+	LegacyAlgorithms []hashcash.Algorithm
+	// LegacyAlgorithmsUntil is the end of the migration, after which the solutions using legacy algorithms
+	// are rejected. It is an absolute time, so that restarting the nodes does not extend the migration.
+	// If it is zero, the legacy algorithms are not accepted at all.
+	LegacyAlgorithmsUntil time.Time
+	// ExtraAlgorithms are the hash algorithms, that the new challenges could be requested with besides Algorithm,
+	// e.g. by the route policies. Their solutions are always accepted.
+	ExtraAlgorithms []hashcash.Algorithm
+	// ValidFor is the validity period of the new challenges, carried in the challenge as the expiry time.
+	// It also picks the date format of the new challenges and is the TTL the solutions are verified against.
+	// If it is zero, the challenges are dated to the day and only expire according to their date format.
+	ValidFor time.Duration
+	// ClockSkew is the allowance for the clock skew between the nodes issuing and verifying the challenges.
+	ClockSkew time.Duration
+	// Clock returns the current time, that the end of the migration is checked against.
+	// If it is nil, time.Now is used.
+	Clock func() time.Time
+}
+
+// HashcashScheme is the hashcash v1 challenge scheme.
+type HashcashScheme struct {
+	cfg HashcashConfig
+}
+
+// NewHashcashScheme creates a new hashcash scheme.
+func NewHashcashScheme(cfg HashcashConfig) *HashcashScheme {
+	// Fall back to the wall clock
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+
+	return &HashcashScheme{cfg: cfg}
+}
+
+// Name returns the name of the scheme.
+func (s *HashcashScheme) Name() string {
+	return SchemeHashcash
+}
+
+// Detect reports whether the challenge is a hashcash string, i.e. it starts with a numeric version.
+func (s *HashcashScheme) Detect(challenge string) bool {
+	version, _, found := strings.Cut(challenge, ":")
+	if !found {
+		return false
+	}
+
+	_, err := strconv.Atoi(version)
+
+	return err == nil
+}
+
+// NewChallenge creates a new hashcash challenge.
+// The node, the route and the expiry time are carried in the challenge extension.
+func (s *HashcashScheme) NewChallenge(params ChallengeParams) (string, error) {
+	// Use the algorithm of the challenge, if it is set and offered
+	algorithm := s.algorithm()
+	if params.Algorithm != "" {
+		if !s.isAlgorithmOffered(params.Algorithm) {
+			return "", fmt.Errorf("%w: %s", ErrAlgorithmNotAccepted, params.Algorithm)
+		}
+
+		algorithm = params.Algorithm
+	}
+
+	// Use the validity period of the challenge, if it is set
+	validFor := s.cfg.ValidFor
+	if params.ValidFor > 0 {
+		validFor = params.ValidFor
+	}
+
+	// Collect the options of the new hashcash
+	opts := []hashcash.Option{hashcash.WithAlgorithm(algorithm)}
+
+	if params.Node != "" {
+		opts = append(opts, hashcash.WithNode(params.Node))
+	}
+
+	if params.Route != "" {
+		opts = append(opts, hashcash.WithRoute(params.Route))
+	}
+
+	if validFor > 0 {
+		opts = append(opts, hashcash.WithExpires(time.Now().Add(validFor)))
+	}
+
+	// Generate a new hashcash
+	challenge, err := hashcash.New(
+		params.Difficulty,
+		params.SaltLength,
+		dateFormatFor(validFor),
+		params.Resource,
+		opts...,
+	)
+	if err != nil {
+		return "", fmt.Errorf("generating new hashcash: %w", err)
+	}
+
+	// Return the stringified hashcash
+	return challenge.String(), nil
+}
+
+// Verify checks that the hashcash solution is correct and was produced for exactly the given challenge.
+// Both strings are parsed in place, as this runs on every protected request.
+func (s *HashcashScheme) Verify(challenge, solution string) (bool, error) {
+	// Parse the issued challenge
+	issued, err := hashcash.ParseStamp(challenge)
+	if err != nil {
+		return false, fmt.Errorf("parsing challenge: %w", err)
+	}
+
+	// Parse the solution
+	stamp, err := hashcash.ParseStamp(solution)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrMalformedSolution, err)
+	}
+
+	// Check that the solution was produced for the issued challenge
+	if err = stamp.Match(issued); err != nil {
+		if errors.Is(err, hashcash.ErrDifficultyMismatch) {
+			return false, fmt.Errorf("%w: %s", ErrDifficultyMismatch, err)
+		}
+
+		return false, fmt.Errorf("matching solution against challenge: %w", err)
+	}
+
+	// Check if the solution is correct
+	if err = s.verifyStamp(stamp); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// verifyStamp checks that the algorithm of the stamp is still accepted and that the stamp is solved and not expired.
+func (s *HashcashScheme) verifyStamp(stamp hashcash.Stamp) error {
+	// Check that the algorithm of the solution is still accepted
+	if !s.isAlgorithmAccepted(stamp.Algorithm()) {
+		return fmt.Errorf("%w: %s", ErrAlgorithmNotAccepted, stamp.Algorithm())
+	}
+
+	// Get the validity window of the stamp
+	validity, err := s.validityOf(stamp)
+	if err != nil {
+		return err
+	}
+
+	// Check if the solution is correct and not expired under the validity window
+	return stamp.WithValidity(validity).Verify()
+}
+
+// Metadata returns the node and the route carried in the hashcash extension,
+// as well as the ID, i.e. the salt, the expiry time, the difficulty and the algorithm of the hashcash.
+func (s *HashcashScheme) Metadata(challenge string) (Metadata, error) {
+	// Parse the hashcash in place
+	stamp, err := hashcash.ParseStamp(challenge)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("parsing hashcash: %w", err)
+	}
+
+	// Parse the extension fields
+	ext, err := hashcash.ParseExtension(stamp.Extension())
+	if err != nil {
+		return Metadata{}, fmt.Errorf("parsing hashcash extension: %w", err)
+	}
+
+	// Get the validity window of the hashcash
+	validity, err := s.validityOf(stamp)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	// Get the expiry time under the validity window, if the hashcash expires
+	expires, _, err := stamp.WithValidity(validity).Expires()
+	if err != nil {
+		return Metadata{}, fmt.Errorf("getting hashcash expiry time: %w", err)
+	}
+
+	return Metadata{
+		ID:         stamp.Salt(),
+		Node:       ext.Node(),
+		Route:      ext.Route(),
+		Expires:    expires,
+		Difficulty: stamp.Difficulty(),
+		Algorithm:  stamp.Algorithm(),
+	}, nil
+}
+
+// Solve solves the hashcash challenge, splitting the work across the given number of workers.
+func (s *HashcashScheme) Solve(ctx context.Context, challenge string, workers int) (*SolveResult, error) {
+	// Parse the hashcash challenge
+	hc, err := hashcash.ParseStr(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("parsing hashcash challenge: %w", err)
+	}
+
+	// Solve the hashcash challenge
+	result, err := hc.SolveContext(ctx, workers)
+	if err != nil {
+		return nil, fmt.Errorf("solving hashcash challenge: %w", err)
+	}
+
+	// Return the result
+	return &SolveResult{Solution: result.Solution, Attempts: result.Attempts, Duration: result.Duration}, nil
+}
+
+// dateFormatFor returns the date format of the new challenges, precise enough for their validity period.
+func dateFormatFor(validFor time.Duration) hashcash.DateFormat {
+	// Fall back to the day precision, if the challenges do not have a validity period
+	if validFor <= 0 {
+		return hashcash.DateFormatYYMMDD
+	}
+
+	return hashcash.DateFormatFor(validFor)
+}
+
+// validity returns the validity window, that the solutions are verified against.
+func (s *HashcashScheme) validity() hashcash.Validity {
+	return hashcash.Validity{TTL: s.cfg.ValidFor, Skew: s.cfg.ClockSkew}
+}
+
+// validityOf returns the validity window of the stamp.
+// The stamps carrying the expiry time are only checked against it, rather than against the configured validity period,
+// as their validity period could be set per challenge. The expiry time can be trusted, as it is covered
+// by the match against the issued challenge or by the signature.
+func (s *HashcashScheme) validityOf(stamp hashcash.Stamp) (hashcash.Validity, error) {
+	// Parse the extension fields
+	ext, err := hashcash.ParseExtension(stamp.Extension())
+	if err != nil {
+		return hashcash.Validity{}, fmt.Errorf("parsing hashcash extension: %w", err)
+	}
+
+	// Check if the stamp carries the expiry time
+	_, ok, err := ext.Expires()
+	if err != nil {
+		return hashcash.Validity{}, fmt.Errorf("parsing hashcash expiry time: %w", err)
+	}
+
+	// Fall back to the configured validity period
+	if !ok {
+		return s.validity(), nil
+	}
+
+	return hashcash.Validity{Skew: s.cfg.ClockSkew}, nil
+}
+
+// algorithm returns the hash algorithm for the new challenges.
+func (s *HashcashScheme) algorithm() hashcash.Algorithm {
+	// Fall back to the default algorithm, if none is configured
+	if s.cfg.Algorithm == "" {
+		return hashcash.DefaultAlgorithm
+	}
+
+	return s.cfg.Algorithm
+}
+
+// isAlgorithmOffered checks if the new challenges could use the given algorithm,
+// i.e. it is the algorithm used for the new challenges or one of the extra algorithms.
+func (s *HashcashScheme) isAlgorithmOffered(algorithm hashcash.Algorithm) bool {
+	// Current algorithm is always offered
+	if algorithm == s.algorithm() {
+		return true
+	}
+
+	// Check if the algorithm is one of the extra algorithms
+	for _, extra := range s.cfg.ExtraAlgorithms {
+		if algorithm == extra {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAlgorithmAccepted checks if the solutions using the given algorithm are accepted.
+// The algorithms offered for the new challenges are always accepted,
+// while the legacy algorithms are only accepted until the end of the migration.
+func (s *HashcashScheme) isAlgorithmAccepted(algorithm hashcash.Algorithm) bool {
+	// Offered algorithms are always accepted
+	if s.isAlgorithmOffered(algorithm) {
+		return true
+	}
+
+	// Legacy algorithms are not accepted after the end of the migration
+	if !s.cfg.Clock().Before(s.cfg.LegacyAlgorithmsUntil) {
+		return false
+	}
+
+	// Check if the algorithm is one of the legacy algorithms
+	for _, legacy := range s.cfg.LegacyAlgorithms {
+		if algorithm == legacy {
+			return true
+		}
+	}
+
+	return false
+}
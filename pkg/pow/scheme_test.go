@@ -0,0 +1,248 @@
+package pow_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/mocks"
+)
+
+// testArgon2idConfig is a cheap argon2id configuration, so that the tests run fast.
+var testArgon2idConfig = pow.Argon2idConfig{
+	Params:   argon2id.Params{Memory: 64, Iterations: 1, Parallelism: 1},
+	ValidFor: time.Minute,
+}
+
+func TestDetectScheme(t *testing.T) {
+	schemes := []pow.Scheme{
+		pow.NewHashcashScheme(pow.HashcashConfig{}),
+		pow.NewArgon2idScheme(testArgon2idConfig),
+	}
+
+	tests := []struct {
+		name      string
+		challenge string
+		scheme    string
+	}{
+		{name: "Hashcash", challenge: "1:20:230520:resource::salt:0", scheme: pow.SchemeHashcash},
+		{name: "Argon2id", challenge: "argon2id:1:4:64:1:1:1684540800:resource:salt:0", scheme: pow.SchemeArgon2id},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Detect the scheme
+			scheme, err := pow.DetectScheme(tt.challenge, schemes...)
+
+			// Expect no error
+			require.NoError(t, err, "expected no error")
+
+			// Expect the right scheme
+			assert.Equal(t, tt.scheme, scheme.Name())
+		})
+	}
+
+	t.Run("Unknown scheme", func(t *testing.T) {
+		// Detect the scheme
+		_, err := pow.DetectScheme("scrypt:1:2:3", schemes...)
+
+		// Expect an error - ErrUnknownScheme
+		assert.ErrorIs(t, err, pow.ErrUnknownScheme, "expected ErrUnknownScheme")
+	})
+}
+
+func TestService_Metadata(t *testing.T) {
+	t.Run("Hashcash challenge carries the metadata", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(map[string]string{}, nil)
+
+		// Create a new service
+		service := pow.NewService(zap.NewNop(), &pow.Config{
+			NodeID:   "node-1",
+			Hashcash: pow.HashcashConfig{ValidFor: time.Minute},
+		}, store, mocks.NewMockSpentStorage(nil))
+
+		// Issue a new challenge
+		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "GET:/v1/quotes/random"), 8, 8)
+		require.NoError(t, err, "expected no error")
+
+		// Read the metadata back from the challenge
+		metadata, err := pow.NewHashcashScheme(pow.HashcashConfig{}).Metadata(challenge)
+		require.NoError(t, err, "expected no error")
+
+		// Expect the metadata to be filled in
+		assert.NotEmpty(t, metadata.ID)
+		assert.Equal(t, "node-1", metadata.Node)
+		assert.Equal(t, "GET:/v1/quotes/random", metadata.Route)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), metadata.Expires, 2*time.Second)
+
+		// Expect the solution of the challenge to be correct
+		result, err := pow.Solve(context.TODO(), challenge, 1)
+		require.NoError(t, err, "expected no error")
+
+		isCorrect, err := service.CheckSolution(context.TODO(), result.Solution, pow.NewChallengeKey("clientID", "GET:/v1/quotes/random"))
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, isCorrect, "expected true")
+	})
+
+	t.Run("Argon2id challenge carries the expiry time", func(t *testing.T) {
+		// Create a new puzzle
+		challenge, err := pow.NewArgon2idScheme(testArgon2idConfig).NewChallenge(pow.ChallengeParams{Resource: "clientID", Difficulty: 4, SaltLength: 8})
+		require.NoError(t, err, "expected no error")
+
+		// Read the metadata back from the challenge
+		metadata, err := pow.NewArgon2idScheme(testArgon2idConfig).Metadata(challenge)
+		require.NoError(t, err, "expected no error")
+
+		// Expect only the ID, the expiry time and the difficulty to be set
+		assert.NotEmpty(t, metadata.ID)
+		assert.Empty(t, metadata.Node)
+		assert.Equal(t, 4, metadata.Difficulty)
+		assert.WithinDuration(t, time.Now().Add(testArgon2idConfig.ValidFor), metadata.Expires, 2*time.Second)
+	})
+
+	t.Run("Service detects the scheme of the challenge", func(t *testing.T) {
+		// Create a new service
+		service := pow.NewService(zap.NewNop(), &pow.Config{
+			NodeID:   "node-1",
+			Hashcash: pow.HashcashConfig{ValidFor: time.Minute},
+			Argon2id: testArgon2idConfig,
+		}, mocks.NewMockChallengeStorage(map[string]string{}, nil), mocks.NewMockSpentStorage(nil))
+
+		// Issue a new hashcash challenge
+		challenge, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "GET:/v1/quotes/random"), 8, 8)
+		require.NoError(t, err, "expected no error")
+
+		// Read the metadata of the hashcash challenge
+		metadata, err := service.Metadata(challenge)
+		require.NoError(t, err, "expected no error")
+
+		// Expect the scheme, the difficulty and the algorithm to be filled in
+		assert.Equal(t, pow.SchemeHashcash, metadata.Scheme)
+		assert.Equal(t, 8, metadata.Difficulty)
+		assert.NotEmpty(t, metadata.Algorithm)
+		assert.Equal(t, "GET:/v1/quotes/random", metadata.Route)
+
+		// Read the metadata of an argon2id puzzle
+		puzzle, err := pow.NewArgon2idScheme(testArgon2idConfig).NewChallenge(pow.ChallengeParams{Resource: "clientID", Difficulty: 4, SaltLength: 8})
+		require.NoError(t, err, "expected no error")
+
+		metadata, err = service.Metadata(puzzle)
+		require.NoError(t, err, "expected no error")
+
+		// Expect the argon2id scheme
+		assert.Equal(t, pow.SchemeArgon2id, metadata.Scheme)
+		assert.Equal(t, 4, metadata.Difficulty)
+
+		// Expect an error for an unknown scheme
+		_, err = service.Metadata("scrypt:1:2:3")
+		assert.ErrorIs(t, err, pow.ErrUnknownScheme, "expected ErrUnknownScheme")
+	})
+}
+
+func TestService_Argon2id(t *testing.T) {
+	t.Run("Issue, solve and check", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(map[string]string{}, nil)
+
+		// Create a new service, issuing argon2id puzzles
+		service := pow.NewService(zap.NewNop(), &pow.Config{Scheme: pow.SchemeArgon2id, Argon2id: testArgon2idConfig}, store, mocks.NewMockSpentStorage(nil))
+
+		// Issue a new challenge
+		key := pow.NewChallengeKey("clientID", "resourceID")
+		challenge, err := service.NewChallenge(context.TODO(), key, 4, 8)
+		require.NoError(t, err, "expected no error")
+
+		// Expect an argon2id puzzle
+		assert.True(t, strings.HasPrefix(challenge, argon2id.Prefix+":"), "expected an argon2id puzzle")
+
+		// Solve the challenge the way clients do
+		result, err := pow.Solve(context.TODO(), challenge, 1)
+		require.NoError(t, err, "expected no error")
+
+		// Expect the attempts to be reported
+		assert.Positive(t, result.Attempts, "expected attempts to be reported")
+
+		// Check the solution
+		isCorrect, err := service.CheckSolution(context.TODO(), result.Solution, key)
+
+		// Expect no error
+		assert.NoError(t, err, "expected no error")
+
+		// Expect the solution to be correct
+		assert.True(t, isCorrect, "expected true")
+	})
+
+	t.Run("Solution of a lower difficulty", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(map[string]string{}, nil)
+
+		// Create a new service, issuing argon2id puzzles
+		service := pow.NewService(zap.NewNop(), &pow.Config{Scheme: pow.SchemeArgon2id, Argon2id: testArgon2idConfig}, store, mocks.NewMockSpentStorage(nil))
+
+		// Issue a new challenge and lower its difficulty
+		key := pow.NewChallengeKey("clientID", "resourceID")
+		challenge, err := service.NewChallenge(context.TODO(), key, 4, 8)
+		require.NoError(t, err, "expected no error")
+
+		lowered := strings.Replace(challenge, argon2id.Prefix+":1:4:", argon2id.Prefix+":1:1:", 1)
+		require.NotEqual(t, challenge, lowered, "expected the difficulty to be lowered")
+
+		// Solve the lowered challenge
+		result, err := pow.Solve(context.TODO(), lowered, 1)
+		require.NoError(t, err, "expected no error")
+
+		// Check the solution
+		isCorrect, err := service.CheckSolution(context.TODO(), result.Solution, key)
+
+		// Expect an error - ErrDifficultyMismatch, whichever the scheme is
+		assert.ErrorIs(t, err, pow.ErrDifficultyMismatch, "expected ErrDifficultyMismatch")
+
+		// Expect the solution to be incorrect
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Hashcash solution for an argon2id challenge", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(map[string]string{}, nil)
+
+		// Create a new service, issuing argon2id puzzles
+		service := pow.NewService(zap.NewNop(), &pow.Config{Scheme: pow.SchemeArgon2id, Argon2id: testArgon2idConfig}, store, mocks.NewMockSpentStorage(nil))
+
+		// Issue a new challenge
+		key := pow.NewChallengeKey("clientID", "resourceID")
+		_, err := service.NewChallenge(context.TODO(), key, 4, 8)
+		require.NoError(t, err, "expected no error")
+
+		// Check a hashcash solution
+		isCorrect, err := service.CheckSolution(context.TODO(), "1:20:23:clientID::Kl7oUEQg:4c73d", key)
+
+		// Expect an error
+		assert.Error(t, err, "expected error")
+
+		// Expect the solution to be incorrect
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Unknown scheme configured", func(t *testing.T) {
+		// Create mock storage
+		store := mocks.NewMockChallengeStorage(map[string]string{}, nil)
+
+		// Create a new service with an unknown scheme
+		service := pow.NewService(zap.NewNop(), &pow.Config{Scheme: "scrypt"}, store, mocks.NewMockSpentStorage(nil))
+
+		// Issue a new challenge
+		_, err := service.NewChallenge(context.TODO(), pow.NewChallengeKey("clientID", "resourceID"), 4, 8)
+
+		// Expect an error - ErrUnknownScheme
+		assert.ErrorIs(t, err, pow.ErrUnknownScheme, "expected ErrUnknownScheme")
+	})
+}
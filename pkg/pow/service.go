@@ -1,35 +1,132 @@
 // Package pow contains the PoW service, that handles the logic of proof-of-work for the application.
-// It currently uses hashcash v1, but can be extended to support other PoW systems.
-// It stores the challenges in a store, that can be in memory, Redis, or any other storage.
+// It supports several challenge schemes, such as hashcash v1 and the memory-hard Argon2id puzzle,
+// and can be extended to support other PoW systems by implementing the Scheme interface.
+// It stores the challenges and the spent solutions in stores, that can be in memory, Redis, or any other storage.
 package pow
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 // ChallengeStore is an interface for storing which challenges are currently active.
 type ChallengeStore interface {
-	// Add adds a challenge to the store.
-	Add(ctx context.Context, key, value string) error
-	// Get checks if a challenge is in the store.
-	Get(ctx context.Context, key string) (bool, error)
+	// Add adds a challenge to the store for the ttl, i.e. for as long as it can be solved,
+	// the store may forget the challenge afterwards.
+	Add(ctx context.Context, key, value string, ttl time.Duration) error
+	// Get returns the challenge stored under the key and reports whether it is in the store.
+	Get(ctx context.Context, key string) (string, bool, error)
 	// Delete deletes a challenge from the store.
 	Delete(ctx context.Context, key string) error
+	// Consume returns the challenge stored under the key and deletes it in one atomic step,
+	// reporting whether it was in the store, so that of the concurrent callers only one gets the challenge.
+	Consume(ctx context.Context, key string) (string, bool, error)
+	// AddToGroup adds a challenge to the store for the ttl, as Add does, and makes it a member of the group.
+	// If the group then has more than limit members, the other ones expiring the soonest are deleted,
+	// so that a group never holds more than limit challenges, but the new challenge always stays.
+	// The consumed and deleted challenges leave the group.
+	AddToGroup(ctx context.Context, group, key, value string, ttl time.Duration, limit int) error
 }
 
+// DefaultMaxChallengesPerClient is the maximum number of the outstanding challenges of a client,
+// used when none is configured. It is enough for the parallel requests of a browser.
+const DefaultMaxChallengesPerClient = 16
+
 // Service is a PoW service.
+// In the stateful mode it keeps the issued challenges in the store,
+// while in the stateless mode it signs them instead.
+// In both modes it remembers the accepted solutions as spent until they expire, to prevent reusing them.
 type Service struct {
 	logger *zap.Logger
+	cfg    *Config
 	// ChallengeStore is a store for challenges.
+	// It is not used in the stateless mode.
 	Store ChallengeStore
+	// Spent is a store for the spent solutions.
+	Spent SpentStore
+	// schemes are the supported challenge schemes.
+	schemes []Scheme
+	// hashcash is the hashcash scheme, that is also used for the stateless challenges.
+	hashcash *HashcashScheme
 }
 
 // NewService creates a new PoW service.
-func NewService(logger *zap.Logger, store ChallengeStore) *Service {
+func NewService(logger *zap.Logger, cfg *Config, store ChallengeStore, spent SpentStore) *Service {
 	// Logging the call
-	logger.Debug("creating a new PoW service")
+	logger.Debug("creating a new PoW service", zap.String("scheme", cfg.Scheme), zap.String("mode", cfg.Mode))
+
+	hashcashCfg := cfg.Hashcash
+
+	// Stateless challenges must expire
+	if cfg.Mode == ModeStateless && hashcashCfg.ValidFor <= 0 {
+		hashcashCfg.ValidFor = DefaultStatelessValidFor
+	}
+
+	hashcashScheme := NewHashcashScheme(hashcashCfg)
+
+	return &Service{
+		logger:   logger,
+		cfg:      cfg,
+		Store:    store,
+		Spent:    spent,
+		schemes:  []Scheme{hashcashScheme, NewArgon2idScheme(cfg.Argon2id)},
+		hashcash: hashcashScheme,
+	}
+}
+
+// isStateless reports whether the service runs in the stateless mode.
+// It returns an error, if the mode is unknown.
+func (s *Service) isStateless() (bool, error) {
+	switch s.cfg.Mode {
+	case "", ModeStateful:
+		return false, nil
+	case ModeStateless:
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnknownMode, s.cfg.Mode)
+	}
+}
+
+// maxChallengesPerClient returns the maximum number of the outstanding challenges of a client.
+func (s *Service) maxChallengesPerClient() int {
+	// Fall back to the default, if no maximum is configured
+	if s.cfg.MaxChallengesPerClient <= 0 {
+		return DefaultMaxChallengesPerClient
+	}
+
+	return s.cfg.MaxChallengesPerClient
+}
+
+// challengeStoreKey returns the key, under which the challenge with the ID is stored.
+// Every challenge is stored under its own key, so that a client can have several challenges for the same resource.
+func challengeStoreKey(key Key, id string) string {
+	return key.String() + ":" + id
+}
+
+// challengeResource returns the resource of the challenges of the client, i.e. the query-escaped client ID,
+// as it may contain the colons separating the challenge fields, e.g. an IPv6 address or a header identity.
+func challengeResource(clientID string) string {
+	return url.QueryEscape(clientID)
+}
+
+// scheme returns the scheme used for the new challenges.
+func (s *Service) scheme() (Scheme, error) {
+	// Fall back to hashcash, if no scheme is configured
+	name := s.cfg.Scheme
+	if name == "" {
+		name = SchemeHashcash
+	}
+
+	// Find the scheme by its name
+	for _, scheme := range s.schemes {
+		if scheme.Name() == name {
+			return scheme, nil
+		}
+	}
 
-	return &Service{logger: logger, Store: store}
+	return nil, ErrUnknownScheme
 }
@@ -0,0 +1,79 @@
+package pow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+// SpentStore is an interface for remembering the spent solutions, i.e. the double-spend database.
+// A solution is remembered until it expires, after which it is rejected as expired anyway,
+// so the store only has to hold the solutions accepted during their validity window.
+// It can be in memory for a single node, or shared between the nodes, e.g. in Redis.
+type SpentStore interface {
+	// Spend marks the key as spent until the expiry time and reports whether it was not spent before.
+	// The check and the mark must be atomic, as the same solution may be sent concurrently.
+	Spend(ctx context.Context, key string, expires time.Time) (bool, error)
+	// IsSpent reports whether the key is spent and has not expired yet.
+	IsSpent(ctx context.Context, key string) (bool, error)
+}
+
+// spend marks the key as spent until the expiry time.
+// It returns ErrStampAlreadySpent, if the key was spent before.
+func (s *Service) spend(ctx context.Context, key string, expires time.Time) error {
+	// Mark the key as spent
+	fresh, err := s.Spent.Spend(ctx, key, expires)
+	if err != nil {
+		return fmt.Errorf("spending solution: %w", err)
+	}
+
+	// If the key was spent before, the solution is reused
+	if !fresh {
+		return ErrStampAlreadySpent
+	}
+
+	return nil
+}
+
+// MaxSpentRetention is the time the solutions, that never expire, are remembered as spent for.
+// It matches the longest validity period of the expiring hashcash date formats.
+const MaxSpentRetention = hashcash.MaxDurationYYMM
+
+// spendSolution marks the solution as spent until it expires, but no longer than MaxSpentRetention.
+func (s *Service) spendSolution(ctx context.Context, scheme Scheme, solution string) error {
+	// Get the expiry time of the solution
+	metadata, err := scheme.Metadata(solution)
+	if err != nil {
+		return fmt.Errorf("getting solution metadata: %w", err)
+	}
+
+	// Cap the retention of the solutions, that never expire or expire too late
+	expires := metadata.Expires
+	if maxExpires := time.Now().Add(MaxSpentRetention); expires.IsZero() || expires.After(maxExpires) {
+		expires = maxExpires
+	}
+
+	return s.spend(ctx, spentKey(solution), expires)
+}
+
+// isSolutionSpent reports whether the solution was already spent.
+func (s *Service) isSolutionSpent(ctx context.Context, solution string) (bool, error) {
+	spent, err := s.Spent.IsSpent(ctx, spentKey(solution))
+	if err != nil {
+		return false, fmt.Errorf("checking if solution is spent: %w", err)
+	}
+
+	return spent, nil
+}
+
+// spentKey returns the key, under which the solution is remembered as spent.
+// The solution is hashed, so that the keys have the same length regardless of the solution.
+func spentKey(solution string) string {
+	sum := sha256.Sum256([]byte(solution))
+
+	return hex.EncodeToString(sum[:])
+}
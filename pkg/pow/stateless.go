@@ -0,0 +1,213 @@
+package pow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+)
+
+const (
+	// ModeStateful is the mode, in which the issued challenges are kept in the ChallengeStore.
+	ModeStateful = "stateful"
+
+	// ModeStateless is the mode, in which the issued challenges are signed with an HMAC key instead of being stored.
+	// Only the solved challenges are remembered, until they expire, to prevent replaying them.
+	ModeStateless = "stateless"
+
+	// KeyIDExtensionKey is the name of the extension field that carries the ID of the signing key.
+	KeyIDExtensionKey = "kid"
+
+	// SignatureExtensionKey is the name of the extension field that carries the challenge signature.
+	SignatureExtensionKey = "sig"
+
+	// DifficultyExtensionKey is the name of the extension field that carries the signed challenge difficulty,
+	// so that a solution with another difficulty could be told apart from a forged one.
+	DifficultyExtensionKey = "d"
+
+	// DefaultStatelessValidFor is the validity period of the stateless challenges, if none is configured.
+	// Stateless challenges must expire, as they are remembered in the SpentStore until then.
+	DefaultStatelessValidFor = 5 * time.Minute
+)
+
+// newStatelessChallenge generates a new hashcash challenge and signs it, instead of saving it to the store.
+// The client, the route and the expiry time are carried in the challenge, so they are covered by the signature.
+func (s *Service) newStatelessChallenge(params ChallengeParams) (string, error) {
+	// Only hashcash challenges could be signed
+	if s.cfg.Scheme != "" && s.cfg.Scheme != SchemeHashcash {
+		return "", fmt.Errorf("%w: %s", ErrSchemeNotStateless, s.cfg.Scheme)
+	}
+
+	// Generate a new challenge
+	challenge, err := s.hashcash.NewChallenge(params)
+	if err != nil {
+		return "", fmt.Errorf("generating new challenge: %w", err)
+	}
+
+	// Sign the challenge
+	signed, err := signChallenge(s.cfg.Keyring, challenge)
+	if err != nil {
+		return "", fmt.Errorf("signing challenge: %w", err)
+	}
+
+	return signed, nil
+}
+
+// checkStatelessSolution checks that the solution is correct and solves a challenge signed by this service
+// for exactly this client and route, that was not solved before.
+func (s *Service) checkStatelessSolution(ctx context.Context, solution string, key Key) (bool, error) {
+	// Check the signature, it proves that all the fields, except for the counter, were set by the service
+	ext, signature, err := verifySignature(s.cfg.Keyring, solution)
+	if err != nil {
+		return false, fmt.Errorf("verifying signature: %w", err)
+	}
+
+	// Parse the solution in place
+	stamp, err := hashcash.ParseStamp(solution)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrMalformedSolution, err)
+	}
+
+	// Check that the challenge was issued to this client for this route
+	if stamp.Resource() != challengeResource(key.ClientID()) || ext.Route() != key.ResourceID() {
+		return false, ErrChallengeBindingMismatch
+	}
+
+	// Check that the challenge expires, otherwise it would have to be remembered forever
+	expires, ok, err := ext.Expires()
+	if err != nil {
+		return false, fmt.Errorf("parsing expiry time: %w", err)
+	}
+
+	if !ok {
+		return false, ErrChallengeExpiryMissing
+	}
+
+	// Check if the solution is correct and not expired
+	if err = s.hashcash.verifyStamp(stamp); err != nil {
+		return false, fmt.Errorf("checking solution: %w", err)
+	}
+
+	// Spend the challenge, rejecting it if it was solved before.
+	// The signature identifies the challenge rather than the solution, so it could not be solved twice.
+	if err = s.spend(ctx, signature, expires.Add(s.hashcash.validity().Skew)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// signChallenge signs the hashcash challenge with the primary key of the keyring.
+// The key ID and the signature are added to the challenge extension, the signature being the last field.
+func signChallenge(keyring *Keyring, challenge string) (string, error) {
+	// Check that there is a key to sign with
+	if keyring == nil {
+		return "", ErrNoSigningKeys
+	}
+
+	// Split the challenge into the fields
+	fields, ext, err := splitChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	// Add the difficulty, so that a solution with another difficulty could be told apart
+	ext = ext.Set(DifficultyExtensionKey, fields[1])
+
+	// Add the key ID, so that the key could be found when verifying the solution
+	key := keyring.Primary()
+	ext = ext.Set(KeyIDExtensionKey, key.ID)
+
+	// Sign the challenge and add the signature
+	ext = ext.Set(SignatureExtensionKey, sign(key, signingInput(fields, ext)))
+
+	// Put the extension back
+	fields[4] = ext.String()
+
+	return strings.Join(fields, ":"), nil
+}
+
+// verifySignature checks the signature of the hashcash solution.
+// It returns the extension fields and the signature, if it is valid.
+func verifySignature(keyring *Keyring, solution string) (hashcash.Extension, string, error) {
+	// Check that there is a key to verify with
+	if keyring == nil {
+		return nil, "", ErrNoSigningKeys
+	}
+
+	// Split the solution into the fields
+	fields, ext, err := splitChallenge(solution)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Tell the changed difficulty apart, the challenges signed before it was carried in the extension have none
+	if difficulty := ext.Value(DifficultyExtensionKey); difficulty != "" && difficulty != fields[1] {
+		return nil, "", fmt.Errorf("%w: expected %s, got %s", ErrDifficultyMismatch, difficulty, fields[1])
+	}
+
+	// Get the signature
+	signature := ext.Value(SignatureExtensionKey)
+	if signature == "" {
+		return nil, "", ErrInvalidSignature
+	}
+
+	// Find the key the challenge was signed with
+	key, ok := keyring.Get(ext.Value(KeyIDExtensionKey))
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownSigningKey, ext.Value(KeyIDExtensionKey))
+	}
+
+	// Remove the signature and sign the rest of the challenge again
+	unsigned := make(hashcash.Extension, 0, len(ext))
+	for _, field := range ext {
+		if field.Name != SignatureExtensionKey {
+			unsigned = append(unsigned, field)
+		}
+	}
+
+	// Compare the signatures in constant time
+	if !hmac.Equal([]byte(signature), []byte(sign(key, signingInput(fields, unsigned)))) {
+		return nil, "", ErrInvalidSignature
+	}
+
+	return ext, signature, nil
+}
+
+// splitChallenge splits the version 1 hashcash string into the fields and parses its extension.
+func splitChallenge(challenge string) ([]string, hashcash.Extension, error) {
+	// Split the challenge into the fields
+	fields := strings.Split(challenge, ":")
+	if len(fields) != hashcash.ValidPartsNumber {
+		return nil, nil, fmt.Errorf("%w: expected %d, got %d",
+			hashcash.ErrIncorrectNumberOfParts, hashcash.ValidPartsNumber, len(fields))
+	}
+
+	// Parse the extension
+	ext, err := hashcash.ParseExtension(fields[4])
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing extension: %w", err)
+	}
+
+	return fields, ext, nil
+}
+
+// signingInput returns the signed part of the challenge: every field, except for the counter,
+// with the given extension in place of the original one.
+func signingInput(fields []string, ext hashcash.Extension) string {
+	return strings.Join([]string{fields[0], fields[1], fields[2], fields[3], ext.String(), fields[5]}, ":")
+}
+
+// sign returns the HMAC-SHA256 of the input, encoded in URL-safe base64 without padding,
+// so that it does not contain the characters that separate the stamp and extension fields.
+func sign(key SigningKey, input string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(input))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,163 @@
+package pow_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/mocks"
+)
+
+// newStatelessService creates a new stateless service, signing the challenges with the given keys.
+func newStatelessService(t *testing.T, cfg pow.Config, keys ...pow.SigningKey) *pow.Service {
+	t.Helper()
+
+	keyring, err := pow.NewKeyring(keys...)
+	require.NoError(t, err, "creating keyring")
+
+	cfg.Mode = pow.ModeStateless
+	cfg.Keyring = keyring
+
+	// No store is needed in the stateless mode
+	return pow.NewService(zap.NewNop(), &cfg, nil, mocks.NewMockSpentStorage(nil))
+}
+
+// issueAndSolve issues a new challenge for the key and solves it.
+func issueAndSolve(t *testing.T, service *pow.Service, key pow.Key) string {
+	t.Helper()
+
+	challenge, err := service.NewChallenge(context.TODO(), key, 8, 8)
+	require.NoError(t, err, "issuing challenge")
+
+	result, err := pow.Solve(context.TODO(), challenge, 1)
+	require.NoError(t, err, "solving challenge")
+
+	return result.Solution
+}
+
+func TestService_Stateless(t *testing.T) {
+	key := pow.NewChallengeKey("clientID", "GET:/v1/quotes/random")
+	primary := pow.SigningKey{ID: "k1", Secret: []byte("secret-1")}
+
+	t.Run("Solution is correct once", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{}, primary)
+
+		solution := issueAndSolve(t, service, key)
+
+		// The solution is correct
+		isCorrect, err := service.CheckSolution(context.TODO(), solution, key)
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, isCorrect, "expected true")
+
+		// The same solution can not be used again
+		isCorrect, err = service.CheckSolution(context.TODO(), solution, key)
+		assert.ErrorIs(t, err, pow.ErrStampAlreadySpent, "expected ErrStampAlreadySpent")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Solution of a lower difficulty", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{}, primary)
+
+		// Lower the difficulty of the issued challenge
+		challenge, err := service.NewChallenge(context.TODO(), key, 8, 8)
+		require.NoError(t, err)
+
+		lowered := strings.Replace(challenge, "1:8:", "1:1:", 1)
+		result, err := pow.Solve(context.TODO(), lowered, 1)
+		require.NoError(t, err)
+
+		isCorrect, err := service.CheckSolution(context.TODO(), result.Solution, key)
+		assert.ErrorIs(t, err, pow.ErrDifficultyMismatch, "expected ErrDifficultyMismatch")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Tampered challenge", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{}, primary)
+
+		// Lower the difficulty of the issued challenge, along with the signed one
+		challenge, err := service.NewChallenge(context.TODO(), key, 8, 8)
+		require.NoError(t, err)
+
+		tampered := strings.Replace(challenge, "1:8:", "1:1:", 1)
+		tampered = strings.Replace(tampered, pow.DifficultyExtensionKey+"=8", pow.DifficultyExtensionKey+"=1", 1)
+		require.Contains(t, tampered, pow.DifficultyExtensionKey+"=1")
+
+		result, err := pow.Solve(context.TODO(), tampered, 1)
+		require.NoError(t, err)
+
+		isCorrect, err := service.CheckSolution(context.TODO(), result.Solution, key)
+		assert.ErrorIs(t, err, pow.ErrInvalidSignature, "expected ErrInvalidSignature")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Solution of another client", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{}, primary)
+
+		solution := issueAndSolve(t, service, pow.NewChallengeKey("anotherClientID", "GET:/v1/quotes/random"))
+
+		isCorrect, err := service.CheckSolution(context.TODO(), solution, key)
+		assert.ErrorIs(t, err, pow.ErrChallengeBindingMismatch, "expected ErrChallengeBindingMismatch")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Solution for another route", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{}, primary)
+
+		solution := issueAndSolve(t, service, pow.NewChallengeKey("clientID", "GET:/v1/other"))
+
+		isCorrect, err := service.CheckSolution(context.TODO(), solution, key)
+		assert.ErrorIs(t, err, pow.ErrChallengeBindingMismatch, "expected ErrChallengeBindingMismatch")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Key rotation", func(t *testing.T) {
+		// Issue the challenge before the rotation
+		solution := issueAndSolve(t, newStatelessService(t, pow.Config{}, primary), key)
+
+		// Rotate the keys: the new key signs, the old one still verifies
+		rotated := newStatelessService(t, pow.Config{}, pow.SigningKey{ID: "k2", Secret: []byte("secret-2")}, primary)
+
+		isCorrect, err := rotated.CheckSolution(context.TODO(), solution, key)
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, isCorrect, "expected true")
+
+		// Remove the old key
+		removed := newStatelessService(t, pow.Config{}, pow.SigningKey{ID: "k2", Secret: []byte("secret-2")})
+
+		isCorrect, err = removed.CheckSolution(context.TODO(), solution, key)
+		assert.ErrorIs(t, err, pow.ErrUnknownSigningKey, "expected ErrUnknownSigningKey")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Spent store failure", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{}, primary)
+		solution := issueAndSolve(t, service, key)
+
+		// The solution can not be accepted, if it can not be spent
+		service.Spent = mocks.NewMockSpentStorage(errors.New("storage error"))
+
+		isCorrect, err := service.CheckSolution(context.TODO(), solution, key)
+		assert.Error(t, err, "expected storage error")
+		assert.False(t, isCorrect, "expected false")
+	})
+
+	t.Run("Scheme does not support the stateless mode", func(t *testing.T) {
+		service := newStatelessService(t, pow.Config{Scheme: pow.SchemeArgon2id}, primary)
+
+		_, err := service.NewChallenge(context.TODO(), key, 8, 8)
+		assert.ErrorIs(t, err, pow.ErrSchemeNotStateless, "expected ErrSchemeNotStateless")
+	})
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		service := pow.NewService(zap.NewNop(), &pow.Config{Mode: "hybrid"}, nil, mocks.NewMockSpentStorage(nil))
+
+		_, err := service.NewChallenge(context.TODO(), key, 8, 8)
+		assert.ErrorIs(t, err, pow.ErrUnknownMode, "expected ErrUnknownMode")
+	})
+}
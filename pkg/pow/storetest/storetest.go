@@ -0,0 +1,440 @@
+// Package storetest contains the conformance test suite of the pow.ChallengeStore implementations.
+// Every implementation runs the suite from its own tests, to prove it behaves the same as the others:
+//
+//	func TestStorage_Conformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) (pow.ChallengeStore, func(time.Duration)) {
+//			return NewStorage(), time.Sleep
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+const (
+	// ttl is the time to live of the challenges, that must not expire during a test.
+	ttl = time.Minute
+	// shortTTL is the time to live of the challenges, that expire during a test.
+	shortTTL = 50 * time.Millisecond
+	// workers is the number of the goroutines accessing the store at once.
+	workers = 16
+	// opsPerWorker is the number of the challenges added by every goroutine.
+	opsPerWorker = 20
+)
+
+// Factory creates a new empty store for a single test, releasing it with t.Cleanup.
+// It also returns the function moving the clock of the store forward, which is time.Sleep for the stores
+// using the real clock, so that the challenges could be expired.
+type Factory func(t *testing.T) (store pow.ChallengeStore, advance func(time.Duration))
+
+// Run runs the conformance test suite against the stores created by the factory.
+func Run(t *testing.T, factory Factory) {
+	t.Run("Add and get", func(t *testing.T) { testAddGet(t, factory) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory) })
+	t.Run("Consume", func(t *testing.T) { testConsume(t, factory) })
+	t.Run("Add to group", func(t *testing.T) { testAddToGroup(t, factory) })
+	t.Run("Canceled context", func(t *testing.T) { testCanceledContext(t, factory) })
+	t.Run("TTL expiry", func(t *testing.T) { testTTL(t, factory) })
+	t.Run("Concurrent access", func(t *testing.T) { testConcurrentAccess(t, factory) })
+	t.Run("Atomic consumption", func(t *testing.T) { testAtomicConsumption(t, factory) })
+}
+
+// testAddGet checks that the added challenges are returned, and the missing ones are reported.
+func testAddGet(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("Added challenge is returned", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		value, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge in the store")
+		assert.Equal(t, "value", value, "expected the added challenge")
+	})
+
+	t.Run("Missing challenge is not found", func(t *testing.T) {
+		store, _ := factory(t)
+
+		value, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected no challenge in the store")
+		assert.Empty(t, value, "expected no challenge")
+	})
+
+	t.Run("Challenge is replaced", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "old", ttl), "expected no error")
+		require.NoError(t, store.Add(ctx, "key", "new", ttl), "expected no error")
+
+		value, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge in the store")
+		assert.Equal(t, "new", value, "expected the replacing challenge")
+	})
+
+	t.Run("Getting does not remove the challenge", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		for i := 0; i < 2; i++ {
+			_, ok, err := store.Get(ctx, "key")
+			assert.NoError(t, err, "expected no error")
+			assert.True(t, ok, "expected the challenge in the store")
+		}
+	})
+
+	t.Run("Keys are independent", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "client-1:/quotes", "value-1", ttl), "expected no error")
+		require.NoError(t, store.Add(ctx, "client-2:/quotes", "value-2", ttl), "expected no error")
+
+		value, _, err := store.Get(ctx, "client-1:/quotes")
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, "value-1", value, "expected the challenge of the first key")
+
+		value, _, err = store.Get(ctx, "client-2:/quotes")
+		assert.NoError(t, err, "expected no error")
+		assert.Equal(t, "value-2", value, "expected the challenge of the second key")
+	})
+}
+
+// testDelete checks that the deleted challenges are gone, and deleting the missing ones is not an error.
+func testDelete(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("Deleted challenge is gone", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		assert.NoError(t, store.Delete(ctx, "key"), "expected no error")
+
+		_, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected no challenge in the store")
+	})
+
+	t.Run("Deleting a missing challenge", func(t *testing.T) {
+		store, _ := factory(t)
+
+		assert.NoError(t, store.Delete(ctx, "key"), "expected no error")
+	})
+
+	t.Run("Challenge can be added again", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "old", ttl), "expected no error")
+		require.NoError(t, store.Delete(ctx, "key"), "expected no error")
+		require.NoError(t, store.Add(ctx, "key", "new", ttl), "expected no error")
+
+		value, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge in the store")
+		assert.Equal(t, "new", value, "expected the challenge added again")
+	})
+}
+
+// testConsume checks that the consumed challenges are returned once and gone afterwards.
+func testConsume(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("Consumed challenge is returned and gone", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		value, ok, err := store.Consume(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge to be consumed")
+		assert.Equal(t, "value", value, "expected the added challenge")
+
+		_, ok, err = store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected no challenge in the store")
+
+		_, ok, err = store.Consume(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected the challenge to be consumed once")
+	})
+
+	t.Run("Consuming a missing challenge", func(t *testing.T) {
+		store, _ := factory(t)
+
+		value, ok, err := store.Consume(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected no challenge to be consumed")
+		assert.Empty(t, value, "expected no challenge")
+	})
+}
+
+// testCanceledContext checks that the operations with a canceled context fail and change nothing.
+func testCanceledContext(t *testing.T, factory Factory) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ctx := context.Background()
+
+	t.Run("Add", func(t *testing.T) {
+		store, _ := factory(t)
+
+		assert.Error(t, store.Add(canceled, "key", "value", ttl), "expected error")
+
+		_, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected no challenge in the store")
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		_, ok, err := store.Get(canceled, "key")
+		assert.Error(t, err, "expected error")
+		assert.False(t, ok, "expected no challenge to be reported")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		assert.Error(t, store.Delete(canceled, "key"), "expected error")
+
+		_, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge to stay in the store")
+	})
+
+	t.Run("Add to group", func(t *testing.T) {
+		store, _ := factory(t)
+
+		assert.Error(t, store.AddToGroup(canceled, "group", "key", "value", ttl, 1), "expected error")
+
+		_, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected no challenge in the store")
+	})
+
+	t.Run("Consume", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+		_, ok, err := store.Consume(canceled, "key")
+		assert.Error(t, err, "expected error")
+		assert.False(t, ok, "expected no challenge to be consumed")
+
+		_, ok, err = store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge to stay in the store")
+	})
+}
+
+// testAddToGroup checks that the groups hold at most the limit of the challenges, evicting the ones expiring the soonest.
+func testAddToGroup(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	// assertStored checks which of the keys are in the store
+	assertStored := func(t *testing.T, store pow.ChallengeStore, want map[string]bool) {
+		t.Helper()
+
+		for key, stored := range want {
+			_, ok, err := store.Get(ctx, key)
+			assert.NoError(t, err, "expected no error")
+			assert.Equal(t, stored, ok, "expected the challenge %q to be stored: %t", key, stored)
+		}
+	}
+
+	t.Run("Challenges within the limit are kept", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-1", "value", ttl, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl, 2), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-1": true, "key-2": true})
+	})
+
+	t.Run("Challenge expiring the soonest is evicted", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-1", "value", ttl+time.Second, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-3", "value", ttl+2*time.Second, 2), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-1": true, "key-2": false, "key-3": true})
+	})
+
+	t.Run("New challenge is never evicted", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-1", "value", ttl+time.Second, 1), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl, 1), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-1": false, "key-2": true})
+	})
+
+	t.Run("Groups are independent", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group-1", "key-1", "value", ttl, 1), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group-2", "key-2", "value", ttl, 1), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-1": true, "key-2": true})
+	})
+
+	t.Run("Replaced challenge is counted once", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-1", "value", ttl, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl, 2), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-1": true, "key-2": true})
+	})
+
+	t.Run("Consumed challenge leaves the group", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-1", "value", ttl, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl+time.Second, 2), "expected no error")
+
+		_, ok, err := store.Consume(ctx, "key-1")
+		require.NoError(t, err, "expected no error")
+		require.True(t, ok, "expected the challenge to be consumed")
+
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-3", "value", ttl, 2), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-2": true, "key-3": true})
+	})
+
+	t.Run("Expired challenge leaves the group", func(t *testing.T) {
+		store, advance := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-1", "value", shortTTL, 2), "expected no error")
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-2", "value", ttl+time.Second, 2), "expected no error")
+		advance(2 * shortTTL)
+
+		require.NoError(t, store.AddToGroup(ctx, "group", "key-3", "value", ttl, 2), "expected no error")
+
+		assertStored(t, store, map[string]bool{"key-1": false, "key-2": true, "key-3": true})
+	})
+
+	t.Run("Added challenge is consumed", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.AddToGroup(ctx, "group", "key", "value", ttl, 1), "expected no error")
+
+		value, ok, err := store.Consume(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge to be consumed")
+		assert.Equal(t, "value", value, "expected the added challenge")
+	})
+}
+
+// testTTL checks that the challenges are gone once their time to live is over.
+func testTTL(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("Challenge expires", func(t *testing.T) {
+		store, advance := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", shortTTL), "expected no error")
+		advance(2 * shortTTL)
+
+		_, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected the challenge to expire")
+
+		_, ok, err = store.Consume(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected the expired challenge not to be consumed")
+	})
+
+	t.Run("Challenge does not expire early", func(t *testing.T) {
+		store, advance := factory(t)
+		require.NoError(t, store.Add(ctx, "short", "value", shortTTL), "expected no error")
+		require.NoError(t, store.Add(ctx, "long", "value", ttl), "expected no error")
+		advance(2 * shortTTL)
+
+		_, ok, err := store.Get(ctx, "long")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the challenge not to expire yet")
+	})
+
+	t.Run("Expired challenge is not stored", func(t *testing.T) {
+		store, _ := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "value", 0), "expected no error")
+
+		_, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.False(t, ok, "expected the expired challenge not to be returned")
+	})
+
+	t.Run("Replacing renews the challenge", func(t *testing.T) {
+		store, advance := factory(t)
+		require.NoError(t, store.Add(ctx, "key", "old", shortTTL), "expected no error")
+		require.NoError(t, store.Add(ctx, "key", "new", ttl), "expected no error")
+		advance(2 * shortTTL)
+
+		value, ok, err := store.Get(ctx, "key")
+		assert.NoError(t, err, "expected no error")
+		assert.True(t, ok, "expected the replacing challenge not to expire yet")
+		assert.Equal(t, "new", value, "expected the replacing challenge")
+	})
+}
+
+// testConcurrentAccess checks that the store can be used by several goroutines at once.
+func testConcurrentAccess(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	store, _ := factory(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < opsPerWorker; j++ {
+				key, value := fmt.Sprintf("key-%d-%d", i, j), fmt.Sprintf("value-%d-%d", i, j)
+				assert.NoError(t, store.Add(ctx, key, value, ttl), "expected no error")
+
+				// Every goroutine sees its own challenges, regardless of the others
+				stored, ok, err := store.Get(ctx, key)
+				assert.NoError(t, err, "expected no error")
+				assert.True(t, ok, "expected the challenge in the store")
+				assert.Equal(t, value, stored, "expected the added challenge")
+
+				if j%2 == 0 {
+					assert.NoError(t, store.Delete(ctx, key), "expected no error")
+				} else {
+					consumed, ok, err := store.Consume(ctx, key)
+					assert.NoError(t, err, "expected no error")
+					assert.True(t, ok, "expected the challenge to be consumed")
+					assert.Equal(t, value, consumed, "expected the added challenge")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// testAtomicConsumption checks that of the goroutines consuming the same challenge at once only one gets it.
+func testAtomicConsumption(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	store, _ := factory(t)
+	require.NoError(t, store.Add(ctx, "key", "value", ttl), "expected no error")
+
+	var consumed int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := store.Consume(ctx, "key")
+			assert.NoError(t, err, "expected no error")
+			if ok {
+				atomic.AddInt32(&consumed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), consumed, "expected the challenge to be consumed once")
+}
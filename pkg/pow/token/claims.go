@@ -0,0 +1,89 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+// version is the version of the token format.
+const version = "v1"
+
+// Claims are the claims of an access token, covered by its signature.
+type Claims struct {
+	// ID is the unique ID of the token, that its uses are counted and it is revoked by.
+	ID string `json:"jti"`
+	// ClientID is the client the token was issued to.
+	ClientID string `json:"sub"`
+	// Route is the route the token was issued for.
+	Route string `json:"route"`
+	// Expires is the expiry time of the token.
+	Expires time.Time `json:"exp"`
+	// MaxUses is how many requests the token allows, 0 means it is only limited by the expiry time.
+	MaxUses int `json:"uses,omitempty"`
+}
+
+// Sign returns the token string carrying the claims, signed with the key:
+// "v1.<key ID>.<base64 JSON claims>.<base64 HMAC-SHA256>", all the base64 being URL-safe without padding,
+// so that the token could be carried in a header or a cookie as it is.
+func Sign(key pow.SigningKey, claims Claims) (string, error) {
+	// Encode the claims
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("encoding claims: %w", err)
+	}
+
+	// Sign the version, the key ID and the claims
+	signed := strings.Join([]string{version, key.ID, base64.RawURLEncoding.EncodeToString(payload)}, ".")
+
+	return signed + "." + sign(key, signed), nil
+}
+
+// Verify checks the signature of the token against the keyring and returns its claims.
+// It does not check the expiry time and the binding of the claims, see Service.Redeem.
+func Verify(keyring *pow.Keyring, token string) (Claims, error) {
+	// Split the token into the fields
+	fields := strings.Split(token, ".")
+	if len(fields) != 4 || fields[0] != version {
+		return Claims{}, ErrMalformedToken
+	}
+
+	// Find the key the token was signed with
+	key, ok := keyring.Get(fields[1])
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, fields[1])
+	}
+
+	// Compare the signatures in constant time
+	signed := strings.Join(fields[:3], ".")
+	if !hmac.Equal([]byte(fields[3]), []byte(sign(key, signed))) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	// Decode the claims, they can be trusted now
+	payload, err := base64.RawURLEncoding.DecodeString(fields[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrMalformedToken, err)
+	}
+
+	var claims Claims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrMalformedToken, err)
+	}
+
+	return claims, nil
+}
+
+// sign returns the HMAC-SHA256 of the input, encoded in URL-safe base64 without padding.
+func sign(key pow.SigningKey, input string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(input))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,92 @@
+package token_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/token"
+)
+
+// newKeyring creates a keyring of the given keys, the first one being the primary key.
+func newKeyring(t *testing.T, keys ...pow.SigningKey) *pow.Keyring {
+	t.Helper()
+
+	keyring, err := pow.NewKeyring(keys...)
+	require.NoError(t, err)
+
+	return keyring
+}
+
+var (
+	oldKey = pow.SigningKey{ID: "k1", Secret: []byte("old-secret")}
+	newKey = pow.SigningKey{ID: "k2", Secret: []byte("new-secret")}
+)
+
+func TestSignVerify(t *testing.T) {
+	claims := token.Claims{
+		ID:       "id",
+		ClientID: "192.0.2.1",
+		Route:    "GET:/v1/quotes/random",
+		Expires:  time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		MaxUses:  10,
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		signed, err := token.Sign(newKey, claims)
+		require.NoError(t, err)
+
+		// The token could be carried in a header or a cookie as it is
+		assert.NotContains(t, signed, " ")
+		assert.NotContains(t, signed, ";")
+		assert.NotContains(t, signed, "=")
+
+		verified, err := token.Verify(newKeyring(t, newKey, oldKey), signed)
+		require.NoError(t, err)
+		assert.Equal(t, claims, verified)
+	})
+
+	t.Run("Signed with the old key", func(t *testing.T) {
+		signed, err := token.Sign(oldKey, claims)
+		require.NoError(t, err)
+
+		_, err = token.Verify(newKeyring(t, newKey, oldKey), signed)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Signed with an unknown key", func(t *testing.T) {
+		signed, err := token.Sign(oldKey, claims)
+		require.NoError(t, err)
+
+		_, err = token.Verify(newKeyring(t, newKey), signed)
+		assert.ErrorIs(t, err, token.ErrInvalidSignature)
+	})
+
+	t.Run("Tampered claims", func(t *testing.T) {
+		signed, err := token.Sign(newKey, claims)
+		require.NoError(t, err)
+
+		// Replace the claims with the claims of another client
+		other := claims
+		other.ClientID = "192.0.2.2"
+		forged, err := token.Sign(pow.SigningKey{ID: newKey.ID, Secret: []byte("guess")}, other)
+		require.NoError(t, err)
+
+		fields := strings.Split(signed, ".")
+		fields[2] = strings.Split(forged, ".")[2]
+
+		_, err = token.Verify(newKeyring(t, newKey), strings.Join(fields, "."))
+		assert.ErrorIs(t, err, token.ErrInvalidSignature)
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		for _, malformed := range []string{"", "v1.k2.claims", "v2.k2.claims.sig", "v1.k2.claims.sig.extra"} {
+			_, err := token.Verify(newKeyring(t, newKey), malformed)
+			assert.ErrorIs(t, err, token.ErrMalformedToken, malformed)
+		}
+	})
+}
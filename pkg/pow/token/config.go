@@ -0,0 +1,11 @@
+package token
+
+import "time"
+
+// Config is the configuration for the token service.
+type Config struct {
+	// TTL is for how long a token is valid after it was issued.
+	TTL time.Duration
+	// MaxUses is how many requests a token allows, 0 means it is only limited by the TTL.
+	MaxUses int
+}
@@ -0,0 +1,29 @@
+package token
+
+import "errors"
+
+var (
+	// ErrInvalidTTL is returned when the token TTL is not positive.
+	ErrInvalidTTL = errors.New("token TTL must be positive")
+
+	// ErrInvalidMaxUses is returned when the maximum number of the token uses is negative.
+	ErrInvalidMaxUses = errors.New("token maximum number of uses must not be negative")
+
+	// ErrMalformedToken is returned when the token could not be parsed.
+	ErrMalformedToken = errors.New("malformed token")
+
+	// ErrInvalidSignature is returned when the token signature does not match.
+	ErrInvalidSignature = errors.New("invalid token signature")
+
+	// ErrTokenExpired is returned when the token has expired.
+	ErrTokenExpired = errors.New("token has expired")
+
+	// ErrTokenBindingMismatch is returned when the token was issued for another client or route.
+	ErrTokenBindingMismatch = errors.New("token was issued for another client or route")
+
+	// ErrTokenExhausted is returned when all the uses of the token were spent.
+	ErrTokenExhausted = errors.New("token uses are exhausted")
+
+	// ErrTokenRevoked is returned when the token was revoked.
+	ErrTokenRevoked = errors.New("token was revoked")
+)
@@ -0,0 +1,135 @@
+// Package token implements the multi-use access tokens, that a solved proof-of-work challenge is exchanged for.
+// A token is signed, so that it is verified without a lookup, and bound to the client and the route.
+// It allows a number of requests or a time budget, whichever runs out first. Only its uses and the revocations
+// are kept in the Store, and only until the token expires.
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+)
+
+// Store is an interface for counting the uses of the tokens and remembering the revoked ones, until they expire.
+type Store interface {
+	// Use counts a use of the token and returns the number of its uses so far, including this one,
+	// and whether the token was revoked. The count and the check must be atomic.
+	Use(ctx context.Context, id string, expires time.Time) (int, bool, error)
+	// Revoke revokes the token until the expiry time.
+	Revoke(ctx context.Context, id string, expires time.Time) error
+}
+
+// idLength is the length of the token ID in bytes.
+const idLength = 16
+
+// Service is a token service.
+type Service struct {
+	logger  *zap.Logger
+	cfg     *Config
+	keyring *pow.Keyring
+	store   Store
+}
+
+// NewService creates a new token service, signing the tokens with the primary key of the keyring.
+func NewService(logger *zap.Logger, cfg *Config, keyring *pow.Keyring, store Store) (*Service, error) {
+	// Logging the call
+	logger.Debug("creating a new token service")
+
+	// Validate the config
+	if cfg.TTL <= 0 {
+		return nil, ErrInvalidTTL
+	}
+
+	if cfg.MaxUses < 0 {
+		return nil, ErrInvalidMaxUses
+	}
+
+	// Check that there is a key to sign with
+	if keyring == nil {
+		return nil, pow.ErrNoSigningKeys
+	}
+
+	return &Service{logger: logger, cfg: cfg, keyring: keyring, store: store}, nil
+}
+
+// Issue issues a new token for the client and the route of the key.
+// Issuing is stateless, the token is only stored once it is used.
+func (s *Service) Issue(_ context.Context, key pow.Key) (string, Claims, error) {
+	// Generate a random ID
+	id := make([]byte, idLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", Claims{}, fmt.Errorf("generating token ID: %w", err)
+	}
+
+	// Bind the token to the client and the route
+	claims := Claims{
+		ID:       hex.EncodeToString(id),
+		ClientID: key.ClientID(),
+		Route:    key.ResourceID(),
+		Expires:  time.Now().Add(s.cfg.TTL).UTC(),
+		MaxUses:  s.cfg.MaxUses,
+	}
+
+	// Sign the token
+	token, err := Sign(s.keyring.Primary(), claims)
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("signing token: %w", err)
+	}
+
+	return token, claims, nil
+}
+
+// Redeem checks that the token is valid for the client and the route of the key and counts its use.
+func (s *Service) Redeem(ctx context.Context, token string, key pow.Key) (Claims, error) {
+	// Check the signature first, it is the cheapest way to reject a forged token
+	claims, err := Verify(s.keyring, token)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	// Check the expiry time
+	if !time.Now().Before(claims.Expires) {
+		return Claims{}, ErrTokenExpired
+	}
+
+	// Check that the token was issued to this client for this route
+	if claims.ClientID != key.ClientID() || claims.Route != key.ResourceID() {
+		return Claims{}, ErrTokenBindingMismatch
+	}
+
+	// Count the use, it also checks the revocation
+	uses, revoked, err := s.store.Use(ctx, claims.ID, claims.Expires)
+	if err != nil {
+		return Claims{}, fmt.Errorf("using token: %w", err)
+	}
+
+	// Check the revocation
+	if revoked {
+		return Claims{}, ErrTokenRevoked
+	}
+
+	// Check the number of uses
+	if claims.MaxUses > 0 && uses > claims.MaxUses {
+		return Claims{}, ErrTokenExhausted
+	}
+
+	return claims, nil
+}
+
+// Revoke revokes the token with the given ID.
+// The expiry time of the token is not known, so it is revoked for the longest time a token could be valid for.
+func (s *Service) Revoke(ctx context.Context, id string) error {
+	if err := s.store.Revoke(ctx, id, time.Now().Add(s.cfg.TTL)); err != nil {
+		return fmt.Errorf("revoking token: %w", err)
+	}
+
+	s.logger.Info("token revoked", zap.String("token_id", id))
+
+	return nil
+}
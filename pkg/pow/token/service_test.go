@@ -0,0 +1,168 @@
+package token_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/token"
+)
+
+// fakeStore is a token store in a map, that never forgets the tokens.
+type fakeStore struct {
+	uses    map[string]int
+	revoked map[string]bool
+	err     error
+}
+
+// newFakeStore creates a new fake store, failing with the error, if it is not nil.
+func newFakeStore(err error) *fakeStore {
+	return &fakeStore{uses: make(map[string]int), revoked: make(map[string]bool), err: err}
+}
+
+// Use counts a use of the token.
+func (s *fakeStore) Use(_ context.Context, id string, _ time.Time) (int, bool, error) {
+	if s.err != nil {
+		return 0, false, s.err
+	}
+
+	if !s.revoked[id] {
+		s.uses[id]++
+	}
+
+	return s.uses[id], s.revoked[id], nil
+}
+
+// Revoke revokes the token.
+func (s *fakeStore) Revoke(_ context.Context, id string, _ time.Time) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.revoked[id] = true
+
+	return nil
+}
+
+// newService creates a new token service with the store.
+func newService(t *testing.T, cfg *token.Config, store token.Store) *token.Service {
+	t.Helper()
+
+	svc, err := token.NewService(zap.NewNop(), cfg, newKeyring(t, newKey), store)
+	require.NoError(t, err)
+
+	return svc
+}
+
+func TestNewService(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *token.Config
+		keyring *pow.Keyring
+		wantErr error
+	}{
+		{name: "Valid config", cfg: &token.Config{TTL: time.Minute, MaxUses: 10}, keyring: newKeyring(t, newKey)},
+		{name: "Zero TTL", cfg: &token.Config{MaxUses: 10}, keyring: newKeyring(t, newKey), wantErr: token.ErrInvalidTTL},
+		{name: "Negative max uses", cfg: &token.Config{TTL: time.Minute, MaxUses: -1}, keyring: newKeyring(t, newKey), wantErr: token.ErrInvalidMaxUses},
+		{name: "No keyring", cfg: &token.Config{TTL: time.Minute}, wantErr: pow.ErrNoSigningKeys},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := token.NewService(zap.NewNop(), tt.cfg, tt.keyring, newFakeStore(nil))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_Redeem(t *testing.T) {
+	key := pow.NewChallengeKey("192.0.2.1", "GET:/v1/quotes/random")
+
+	t.Run("Token allows MaxUses requests", func(t *testing.T) {
+		svc := newService(t, &token.Config{TTL: time.Minute, MaxUses: 2}, newFakeStore(nil))
+
+		issued, claims, err := svc.Issue(context.Background(), key)
+		require.NoError(t, err)
+		assert.Equal(t, 2, claims.MaxUses)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), claims.Expires, time.Second)
+
+		// The first uses are allowed
+		for i := 0; i < 2; i++ {
+			redeemed, err := svc.Redeem(context.Background(), issued, key)
+			require.NoError(t, err)
+			assert.Equal(t, claims, redeemed)
+		}
+
+		// Then the token is exhausted
+		_, err = svc.Redeem(context.Background(), issued, key)
+		assert.ErrorIs(t, err, token.ErrTokenExhausted)
+	})
+
+	t.Run("Token is only limited by the TTL", func(t *testing.T) {
+		svc := newService(t, &token.Config{TTL: time.Minute}, newFakeStore(nil))
+
+		issued, _, err := svc.Issue(context.Background(), key)
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			_, err = svc.Redeem(context.Background(), issued, key)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Token has expired", func(t *testing.T) {
+		svc := newService(t, &token.Config{TTL: time.Millisecond}, newFakeStore(nil))
+
+		issued, _, err := svc.Issue(context.Background(), key)
+		require.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = svc.Redeem(context.Background(), issued, key)
+		assert.ErrorIs(t, err, token.ErrTokenExpired)
+	})
+
+	t.Run("Token is bound to the client and the route", func(t *testing.T) {
+		svc := newService(t, &token.Config{TTL: time.Minute}, newFakeStore(nil))
+
+		issued, _, err := svc.Issue(context.Background(), key)
+		require.NoError(t, err)
+
+		_, err = svc.Redeem(context.Background(), issued, pow.NewChallengeKey("192.0.2.2", key.ResourceID()))
+		assert.ErrorIs(t, err, token.ErrTokenBindingMismatch)
+
+		_, err = svc.Redeem(context.Background(), issued, pow.NewChallengeKey(key.ClientID(), "POST:/v1/quotes"))
+		assert.ErrorIs(t, err, token.ErrTokenBindingMismatch)
+	})
+
+	t.Run("Token is revoked", func(t *testing.T) {
+		svc := newService(t, &token.Config{TTL: time.Minute}, newFakeStore(nil))
+
+		issued, claims, err := svc.Issue(context.Background(), key)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Revoke(context.Background(), claims.ID))
+
+		_, err = svc.Redeem(context.Background(), issued, key)
+		assert.ErrorIs(t, err, token.ErrTokenRevoked)
+	})
+
+	t.Run("Store failure", func(t *testing.T) {
+		storeErr := errors.New("store failure")
+		svc := newService(t, &token.Config{TTL: time.Minute}, newFakeStore(storeErr))
+
+		issued, claims, err := svc.Issue(context.Background(), key)
+		require.NoError(t, err)
+
+		_, err = svc.Redeem(context.Background(), issued, key)
+		assert.ErrorIs(t, err, storeErr)
+
+		err = svc.Revoke(context.Background(), claims.ID)
+		assert.ErrorIs(t, err, storeErr)
+	})
+}
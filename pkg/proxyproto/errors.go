@@ -0,0 +1,14 @@
+package proxyproto
+
+import "errors"
+
+var (
+	// ErrInvalidHeader is returned when the PROXY protocol header is malformed.
+	ErrInvalidHeader = errors.New("proxy protocol header is invalid")
+
+	// ErrUnsupportedVersion is returned when the binary PROXY protocol header is of an unknown version.
+	ErrUnsupportedVersion = errors.New("proxy protocol version is not supported")
+
+	// ErrUnsupportedCommand is returned when the binary PROXY protocol header carries an unknown command.
+	ErrUnsupportedCommand = errors.New("proxy protocol command is not supported")
+)
@@ -0,0 +1,212 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// v1Prefix is the prefix of the text header of version 1.
+	v1Prefix = "PROXY "
+	// v1MaxLength is the maximum length of the text header of version 1, including the CRLF.
+	v1MaxLength = 107
+
+	// v2HeaderLength is the length of the fixed part of the binary header of version 2.
+	v2HeaderLength = 16
+	// v2Version is the version carried in the high nibble of the 13th byte of the binary header.
+	v2Version = 0x2
+	// v2CommandLocal is the command of the connections made by the proxy itself, e.g. the health checks.
+	v2CommandLocal = 0x0
+	// v2CommandProxy is the command of the connections relayed on behalf of the clients.
+	v2CommandProxy = 0x1
+	// v2FamilyInet is the address family of IPv4, carried in the high nibble of the 14th byte.
+	v2FamilyInet = 0x1
+	// v2FamilyInet6 is the address family of IPv6.
+	v2FamilyInet6 = 0x2
+	// v2InetLength is the length of the IPv4 addresses block: two addresses and two ports.
+	v2InetLength = 2*net.IPv4len + 4
+	// v2Inet6Length is the length of the IPv6 addresses block: two addresses and two ports.
+	v2Inet6Length = 2*net.IPv6len + 4
+)
+
+// v2Signature is the signature, that every binary header of version 2 starts with.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// Header is the PROXY protocol header, sent by the proxy ahead of the relayed connection.
+type Header struct {
+	// Version is the version of the header, 1 for the text one and 2 for the binary one.
+	Version int
+	// Source is the address of the client, it is nil if the header does not tell it,
+	// e.g. for the connections made by the proxy itself or of the unknown protocols.
+	Source *net.TCPAddr
+	// Destination is the address the client connected to, it is nil if the header does not tell it.
+	Destination *net.TCPAddr
+}
+
+// ReadHeader reads the PROXY protocol header of either version from the reader.
+// It returns nil and no error, if the connection does not start with a header, leaving the reader intact.
+func ReadHeader(r *bufio.Reader) (*Header, error) {
+	// Peek at the first byte, which tells the versions apart without blocking on the short requests
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("reading proxy protocol header: %w", err)
+	}
+
+	switch first[0] {
+	case v1Prefix[0]:
+		// The HTTP methods starting with P are not followed by the rest of the prefix
+		prefix, err := r.Peek(len(v1Prefix))
+		if err != nil || string(prefix) != v1Prefix {
+			return nil, nil //nolint:nilerr // no header, the connection is read as is
+		}
+
+		return readV1(r)
+	case v2Signature[0]:
+		signature, err := r.Peek(len(v2Signature))
+		if err != nil || !bytes.Equal(signature, v2Signature) {
+			return nil, nil //nolint:nilerr // no header, the connection is read as is
+		}
+
+		return readV2(r)
+	default:
+		return nil, nil
+	}
+}
+
+// readV1 reads the text header of version 1, e.g. "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readV1(r *bufio.Reader) (*Header, error) {
+	// Read the header line, it must fit into the maximum length
+	var line []byte
+
+	for len(line) < v1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, err)
+		}
+
+		line = append(line, b)
+
+		if b == '\n' {
+			break
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: line is not terminated by CRLF", ErrInvalidHeader)
+	}
+
+	fields := strings.Split(string(line[len(v1Prefix):len(line)-2]), " ")
+
+	// The unknown protocols do not tell the addresses, the rest of the line is ignored
+	if fields[0] == "UNKNOWN" {
+		return &Header{Version: 1}, nil
+	}
+
+	if len(fields) != 5 || (fields[0] != "TCP4" && fields[0] != "TCP6") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+	}
+
+	// Parse the addresses, they must be of the family of the protocol
+	source, err := parseV1Addr(fields[1], fields[3], fields[0] == "TCP4")
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := parseV1Addr(fields[2], fields[4], fields[0] == "TCP4")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Header{Version: 1, Source: source, Destination: destination}, nil
+}
+
+// parseV1Addr parses the address and the port of the text header.
+func parseV1Addr(rawIP, rawPort string, isIPv4 bool) (*net.TCPAddr, error) {
+	ip := net.ParseIP(rawIP)
+	if ip == nil || (ip.To4() != nil) != isIPv4 {
+		return nil, fmt.Errorf("%w: address %q", ErrInvalidHeader, rawIP)
+	}
+
+	port, err := strconv.ParseUint(rawPort, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: port %q", ErrInvalidHeader, rawPort)
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 reads the binary header of version 2.
+func readV2(r *bufio.Reader) (*Header, error) {
+	// Read the fixed part of the header
+	fixed := make([]byte, v2HeaderLength)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, err)
+	}
+
+	if version := fixed[12] >> 4; version != v2Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	command, family := fixed[12]&0x0F, fixed[13]>>4
+
+	// Read the addresses along with the TLVs, that follow them and are ignored
+	block := make([]byte, binary.BigEndian.Uint16(fixed[14:16]))
+	if _, err := io.ReadFull(r, block); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, err)
+	}
+
+	switch command {
+	case v2CommandLocal:
+		// The connection is made by the proxy itself, so the addresses are the real ones
+		return &Header{Version: 2}, nil
+	case v2CommandProxy:
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedCommand, command)
+	}
+
+	// Parse the addresses, the families other than IP, e.g. the unix sockets, do not tell the client address
+	switch family {
+	case v2FamilyInet:
+		if len(block) < v2InetLength {
+			return nil, fmt.Errorf("%w: IPv4 addresses block is too short", ErrInvalidHeader)
+		}
+
+		source, destination := parseV2Addrs(block, net.IPv4len)
+
+		return &Header{Version: 2, Source: source, Destination: destination}, nil
+	case v2FamilyInet6:
+		if len(block) < v2Inet6Length {
+			return nil, fmt.Errorf("%w: IPv6 addresses block is too short", ErrInvalidHeader)
+		}
+
+		source, destination := parseV2Addrs(block, net.IPv6len)
+
+		return &Header{Version: 2, Source: source, Destination: destination}, nil
+	default:
+		return &Header{Version: 2}, nil
+	}
+}
+
+// parseV2Addrs parses the source and the destination addresses of the binary header,
+// which are followed by the source and the destination ports.
+func parseV2Addrs(block []byte, ipLength int) (*net.TCPAddr, *net.TCPAddr) {
+	ports := block[2*ipLength:]
+
+	source := &net.TCPAddr{
+		IP:   append(net.IP(nil), block[:ipLength]...),
+		Port: int(binary.BigEndian.Uint16(ports[0:2])),
+	}
+
+	destination := &net.TCPAddr{
+		IP:   append(net.IP(nil), block[ipLength:2*ipLength]...),
+		Port: int(binary.BigEndian.Uint16(ports[2:4])),
+	}
+
+	return source, destination
+}
@@ -0,0 +1,122 @@
+package proxyproto_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/proxyproto"
+)
+
+// v2Header builds a binary header of version 2 with the given version and command byte, family byte and block.
+func v2Header(versionCommand, family byte, block []byte) string {
+	header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+	header = append(header, versionCommand, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:16], uint16(len(block)))
+
+	return string(append(header, block...))
+}
+
+// v2Block builds the addresses block of a binary header of version 2.
+func v2Block(source, destination net.IP, sourcePort, destinationPort uint16) []byte {
+	block := append(append([]byte(nil), source...), destination...)
+	block = binary.BigEndian.AppendUint16(block, sourcePort)
+
+	return binary.BigEndian.AppendUint16(block, destinationPort)
+}
+
+func TestReadHeader(t *testing.T) {
+	const request = "GET / HTTP/1.1\r\n\r\n"
+
+	testCases := []struct {
+		name       string
+		input      string
+		expected   *proxyproto.Header
+		expectedIs error
+	}{
+		{
+			name:  "Version 1, TCP4",
+			input: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n" + request,
+			expected: &proxyproto.Header{
+				Version:     1,
+				Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443},
+			},
+		},
+		{
+			name:  "Version 1, TCP6",
+			input: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n" + request,
+			expected: &proxyproto.Header{
+				Version:     1,
+				Source:      &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+			},
+		},
+		{
+			name:     "Version 1, unknown protocol",
+			input:    "PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n" + request,
+			expected: &proxyproto.Header{Version: 1},
+		},
+		{name: "Version 1, wrong family", input: "PROXY TCP4 2001:db8::1 198.51.100.1 56324 443\r\n", expectedIs: proxyproto.ErrInvalidHeader},
+		{name: "Version 1, invalid port", input: "PROXY TCP4 192.0.2.1 198.51.100.1 65536 443\r\n", expectedIs: proxyproto.ErrInvalidHeader},
+		{name: "Version 1, missing fields", input: "PROXY TCP4 192.0.2.1\r\n", expectedIs: proxyproto.ErrInvalidHeader},
+		{name: "Version 1, missing CRLF", input: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n", expectedIs: proxyproto.ErrInvalidHeader},
+		{name: "Version 1, too long", input: "PROXY " + strings.Repeat("A", 200) + "\r\n", expectedIs: proxyproto.ErrInvalidHeader},
+		{
+			name:  "Version 2, IPv4",
+			input: v2Header(0x21, 0x11, v2Block(net.IPv4(192, 0, 2, 1).To4(), net.IPv4(198, 51, 100, 1).To4(), 56324, 443)) + request,
+			expected: &proxyproto.Header{
+				Version:     2,
+				Source:      &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1).To4(), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.IPv4(198, 51, 100, 1).To4(), Port: 443},
+			},
+		},
+		{
+			name:  "Version 2, IPv6 with TLVs",
+			input: v2Header(0x21, 0x21, append(v2Block(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), 56324, 443), 0x04, 0x00, 0x01, 0x00)) + request,
+			expected: &proxyproto.Header{
+				Version:     2,
+				Source:      &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+				Destination: &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+			},
+		},
+		{name: "Version 2, local", input: v2Header(0x20, 0x00, nil) + request, expected: &proxyproto.Header{Version: 2}},
+		{name: "Version 2, unix socket", input: v2Header(0x21, 0x31, make([]byte, 216)) + request, expected: &proxyproto.Header{Version: 2}},
+		{name: "Version 2, unknown version", input: v2Header(0x11, 0x11, nil), expectedIs: proxyproto.ErrUnsupportedVersion},
+		{name: "Version 2, unknown command", input: v2Header(0x22, 0x11, nil), expectedIs: proxyproto.ErrUnsupportedCommand},
+		{name: "Version 2, short block", input: v2Header(0x21, 0x11, make([]byte, 4)), expectedIs: proxyproto.ErrInvalidHeader},
+		{name: "Version 2, truncated", input: v2Header(0x21, 0x11, make([]byte, 12))[:20], expectedIs: proxyproto.ErrInvalidHeader},
+		{name: "No header", input: request},
+		{name: "No header, method starting with P", input: "POST / HTTP/1.1\r\n\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tc.input))
+
+			// Read the header
+			header, err := proxyproto.ReadHeader(reader)
+
+			if tc.expectedIs != nil {
+				assert.ErrorIs(t, err, tc.expectedIs)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, header)
+
+			// Expect the request to follow the header intact
+			rest, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.True(t, strings.HasSuffix(tc.input, string(rest)), "expected the rest of the connection to be intact")
+			assert.True(t, strings.HasPrefix(string(rest), "GET") || strings.HasPrefix(string(rest), "POST"))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package proxyproto implements the receiving side of the PROXY protocol, versions 1 and 2,
+// which the load balancers, e.g. HAProxy or AWS NLB, use to tell the address of the client,
+// when they relay the TCP connections rather than the HTTP requests.
+// Read more:
+// - https://www.haproxy.org/download/2.8/doc/proxy-protocol.txt
+package proxyproto
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultHeaderTimeout is for how long the header is waited for, before the connection is given up on.
+const DefaultHeaderTimeout = 5 * time.Second
+
+// Listener is a listener reading the PROXY protocol header of the connections from the trusted proxies.
+// The connections from the other peers are passed through as is, so their headers are not honoured,
+// and can not be used to spoof the address of the client.
+// The header is optional, so that the trusted proxies could still connect without it, e.g. for the health checks.
+type Listener struct {
+	net.Listener
+	// trusted reports whether the peer is a trusted proxy, whose headers are honoured.
+	trusted func(ip net.IP) bool
+	// timeout is for how long the header is waited for.
+	timeout time.Duration
+}
+
+// NewListener wraps the listener, honouring the PROXY protocol headers sent by the peers, that trusted reports.
+// The header is read on the first use of the connection, rather than on Accept, so that a slow peer
+// does not hold the other connections back, and it is waited for no longer than the timeout.
+func NewListener(listener net.Listener, trusted func(ip net.IP) bool, timeout time.Duration) *Listener {
+	return &Listener{Listener: listener, trusted: trusted, timeout: timeout}
+}
+
+// Accept waits for the next connection and wraps it, if it comes from a trusted proxy.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	// Pass the connections from the other peers through
+	if !l.trusted(addrIP(conn.RemoteAddr())) {
+		return conn, nil
+	}
+
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+// Conn is a connection from a trusted proxy, whose addresses are the ones the PROXY protocol header tells.
+type Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	// once guards reading the header, which is done on the first use of the connection.
+	once   sync.Once
+	header *Header
+	err    error
+}
+
+// Read reads from the connection after the header. It returns the error, if the header is malformed.
+func (c *Conn) Read(b []byte) (int, error) {
+	if err := c.readHeader(); err != nil {
+		return 0, err
+	}
+
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the address of the client, if the header tells it, otherwise the address of the proxy.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.readHeader() == nil && c.header != nil && c.header.Source != nil {
+		return c.header.Source
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to, if the header tells it, otherwise the local address.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.readHeader() == nil && c.header != nil && c.header.Destination != nil {
+		return c.header.Destination
+	}
+
+	return c.Conn.LocalAddr()
+}
+
+// Header returns the PROXY protocol header of the connection, or nil, if the proxy did not send one.
+func (c *Conn) Header() (*Header, error) {
+	err := c.readHeader()
+
+	return c.header, err
+}
+
+// readHeader reads the header once, within the timeout, and returns the error of reading it.
+func (c *Conn) readHeader() error {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			if c.err = c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); c.err != nil {
+				return
+			}
+		}
+
+		c.header, c.err = ReadHeader(c.reader)
+
+		// Lift the deadline, the server sets its own ones afterwards
+		if c.timeout > 0 && c.err == nil {
+			c.err = c.Conn.SetReadDeadline(time.Time{})
+		}
+	})
+
+	return c.err
+}
+
+// addrIP returns the IP address of the network address, or nil if it has none.
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
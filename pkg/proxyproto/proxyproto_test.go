@@ -0,0 +1,112 @@
+package proxyproto_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/daniel-orlov/quotes-server/pkg/proxyproto"
+)
+
+// accept listens on the loopback interface, sends the data over a new connection and returns the accepted connection.
+func accept(t *testing.T, trusted bool, timeout time.Duration, data string) net.Conn {
+	t.Helper()
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	listener := proxyproto.NewListener(inner, func(net.IP) bool { return trusted }, timeout)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	// Connect and send the data
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	_, err = client.Write([]byte(data))
+	require.NoError(t, err)
+
+	// Accept the connection
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestListener(t *testing.T) {
+	const request = "GET / HTTP/1.1\r\n"
+
+	t.Run("Header of a trusted proxy is honoured", func(t *testing.T) {
+		conn := accept(t, true, time.Second, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"+request)
+
+		// Expect the addresses from the header
+		assert.Equal(t, "192.0.2.1:56324", conn.RemoteAddr().String())
+		assert.Equal(t, "198.51.100.1:443", conn.LocalAddr().String())
+
+		// Expect the request to follow without the header
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, request, line)
+	})
+
+	t.Run("Trusted proxy may omit the header", func(t *testing.T) {
+		conn := accept(t, true, time.Second, request)
+
+		// Expect the address of the proxy
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", host)
+
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, request, line)
+	})
+
+	t.Run("Header of an untrusted peer is ignored", func(t *testing.T) {
+		conn := accept(t, false, time.Second, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"+request)
+
+		// Expect the address of the peer, and the header to be left for the server to reject
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", host)
+
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n", line)
+	})
+
+	t.Run("Malformed header fails the connection", func(t *testing.T) {
+		conn := accept(t, true, time.Second, "PROXY TCP4 192.0.2.1\r\n"+request)
+
+		// Expect reading to fail, and the address of the proxy
+		_, err := conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, proxyproto.ErrInvalidHeader)
+
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", host)
+	})
+
+	t.Run("Header is waited for within the timeout", func(t *testing.T) {
+		conn := accept(t, true, 50*time.Millisecond, "")
+
+		// Expect reading to time out
+		_, err := conn.Read(make([]byte, 1))
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout(), "expected a timeout")
+	})
+
+	t.Run("Header is exposed", func(t *testing.T) {
+		conn := accept(t, true, time.Second, "PROXY UNKNOWN\r\n")
+
+		header, err := conn.(*proxyproto.Conn).Header()
+		require.NoError(t, err)
+		assert.Equal(t, &proxyproto.Header{Version: 1}, header)
+	})
+}
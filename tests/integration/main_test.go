@@ -12,6 +12,7 @@ import (
 	"github.com/daniel-orlov/quotes-server/internal/domain/model"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
 	"github.com/daniel-orlov/quotes-server/pkg/hashcash"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/httpauth"
 )
 
 // Happy path test.
@@ -137,8 +138,8 @@ func TestIntegration_Server_AttemptsToReuseHashcashSolution_ReturnsError(t *test
 		}
 	}(resp.Body)
 
-	// Assert the response status code
-	assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+	// Assert the response status code, the solution is rejected as spent
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
 
 	// Assert the new challenge is received
 	newHashcashChallengeStr := resp.Header.Get(proofer.ChallengeHeader)
@@ -197,3 +198,141 @@ func TestIntegration_Server_AttemptsToSendWrongChallengeSolution_ReturnsNewChall
 	// Assert the new challenge is different from the previous one
 	assert.NotEqual(t, hashcashChallengeStr, newHashcashChallengeStr, "new hashcash challenge is the same as the previous one")
 }
+
+// Exempt route test.
+func TestIntegration_Server_HealthCheck_ReturnsStatus200WithoutChallenge(t *testing.T) {
+	// Prepare endpoint
+	url := fmt.Sprintf("%s/v1/health", testServer.URL)
+
+	// Make the request to the server
+	resp, err := testClient.Get(url)
+	assert.NoError(t, err, "making request to the server failed")
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			t.Logf("closing response body: %v", err)
+		}
+	}(resp.Body)
+
+	// Assert the response status code is 200, as the health check is exempt from the proof of work
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "response status code is not 200")
+
+	// Assert no challenge is issued
+	assert.Empty(t, resp.Header.Get(proofer.ChallengeHeader), "challenge is issued")
+}
+
+// Challenge endpoint test.
+func TestIntegration_Server_GetChallenge_SolveAndReceiveQuote(t *testing.T) {
+	// Make the request for a challenge for the quotes endpoint
+	resp, err := testClient.Get(fmt.Sprintf("%s/v1/challenge?resource=/v1/quotes/random", testServer.URL))
+	assert.NoError(t, err, "making request to the server failed")
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			t.Logf("closing response body: %v", err)
+		}
+	}(resp.Body)
+
+	// Assert the response status code is 200, as the challenge endpoint is not behind the proof of work
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "response status code is not 200")
+
+	// Unmarshal the challenge document
+	doc := model.Challenge{}
+	err = json.NewDecoder(resp.Body).Decode(&doc)
+	assert.NoError(t, err, "unmarshaling response body failed")
+
+	// Assert the challenge document describes the challenge
+	assert.Equal(t, "/v1/quotes/random", doc.Resource, "challenge resource is wrong")
+	assert.Equal(t, proofer.ChallengeHeader, doc.Header, "challenge header is wrong")
+	assert.Equal(t, testCfg.Server.Middlewares.Proofer.ChallengeDifficulty, doc.Difficulty, "challenge difficulty is wrong")
+
+	// Solve the challenge
+	hashcashChallenge, err := hashcash.ParseStr(doc.Challenge)
+	assert.NoError(t, err, "parsing hashcash challenge failed")
+
+	solution, err := hashcashChallenge.Solve()
+	assert.NoError(t, err, "solving hashcash challenge failed")
+
+	// Make the first request to the quotes endpoint with the solution
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, doc.Resource), nil)
+	assert.NoError(t, err)
+	req.Header.Set(doc.Header, solution)
+
+	resp, err = testClient.Do(req)
+	assert.NoError(t, err)
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			t.Logf("closing response body: %v", err)
+		}
+	}(resp.Body)
+
+	// Assert the response status code is 200, without a 428 first
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "response status code is not 200")
+}
+
+// PoW authentication scheme test.
+func TestIntegration_Server_ReturnsWWWAuthenticateChallenge_SolveWithAuthorizationAndReceiveQuote(t *testing.T) {
+	// Prepare endpoint
+	url := fmt.Sprintf("%s/v1/quotes/random", testServer.URL)
+
+	// Make the request to the server
+	resp, err := testClient.Get(url)
+	assert.NoError(t, err, "making request to the server failed")
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			t.Logf("closing response body: %v", err)
+		}
+	}(resp.Body)
+
+	// Assert the initial response status code is 428
+	assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode, "initial response status code is not 428")
+
+	// Retrieve the challenge from the WWW-Authenticate header
+	challenge, err := httpauth.ParseChallenge(resp.Header.Get(httpauth.ChallengeHeader))
+	assert.NoError(t, err, "parsing WWW-Authenticate header failed")
+	assert.Equal(t, testCfg.Server.Middlewares.Proofer.ChallengeDifficulty, challenge.Difficulty, "challenge difficulty is wrong")
+	assert.NotEmpty(t, challenge.Algorithm, "challenge algorithm is empty")
+
+	// Solve the challenge
+	hashcashChallenge, err := hashcash.ParseStr(challenge.Challenge)
+	assert.NoError(t, err, "parsing hashcash challenge failed")
+
+	solution, err := hashcashChallenge.Solve()
+	assert.NoError(t, err, "solving hashcash challenge failed")
+
+	// Make the request with the solution in the Authorization header
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	req.Header.Set(httpauth.AuthorizationHeader, httpauth.Authorization(solution))
+
+	resp, err = testClient.Do(req)
+	assert.NoError(t, err)
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			t.Logf("closing response body: %v", err)
+		}
+	}(resp.Body)
+
+	// Assert the final response status code
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Make the request with the same solution again
+	resp, err = testClient.Do(req)
+	assert.NoError(t, err)
+	defer func(Body io.ReadCloser) {
+		err = Body.Close()
+		if err != nil {
+			t.Logf("closing response body: %v", err)
+		}
+	}(resp.Body)
+
+	// Assert the reused solution is rejected as replayed
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	challenge, err = httpauth.ParseChallenge(resp.Header.Get(httpauth.ChallengeHeader))
+	assert.NoError(t, err, "parsing WWW-Authenticate header failed")
+	assert.Equal(t, httpauth.ErrorReplayedSolution, challenge.Error, "error code is wrong")
+}
@@ -13,12 +13,15 @@ import (
 	qsvc "github.com/daniel-orlov/quotes-server/internal/domain/service/quotes"
 	cstore "github.com/daniel-orlov/quotes-server/internal/storage/challenges"
 	qstore "github.com/daniel-orlov/quotes-server/internal/storage/quotes"
+	sstore "github.com/daniel-orlov/quotes-server/internal/storage/spent"
 	httptransport "github.com/daniel-orlov/quotes-server/internal/transport/http"
+	"github.com/daniel-orlov/quotes-server/internal/transport/http/challenge"
 	"github.com/daniel-orlov/quotes-server/internal/transport/http/quotes"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/proofer"
 	"github.com/daniel-orlov/quotes-server/internal/transport/middleware/ratelimiter"
 	"github.com/daniel-orlov/quotes-server/pkg/logging"
 	"github.com/daniel-orlov/quotes-server/pkg/pow"
+	"github.com/daniel-orlov/quotes-server/pkg/pow/argon2id"
 )
 
 func TestMain(m *testing.M) {
@@ -58,12 +61,45 @@ func setup() {
 	// Initialize the quote storage.
 	quoteStorage := qstore.NewStorageInMemory(testLogger, qstore.GetQuotes())
 	// Initialize the challenge storage.
-	challengeStorage := cstore.NewStorageInMemory(testLogger)
+	challengeStorage := cstore.NewStorageInMemory(testLogger, testCfg.PoW.ChallengeStoreSize)
+	// Initialize the spent solutions storage.
+	spentStorage := sstore.NewStorageInMemory(testLogger, testCfg.PoW.SpentStoreSize)
 
 	// Initialize the quote service.
 	quoteService := qsvc.NewService(testLogger, quoteStorage)
+	// Parse the route policies of the proof-of-work middleware.
+	policies, err := proofer.ParsePolicies(testCfg.Server.Middlewares.Proofer.Policies)
+	if err != nil {
+		log.Fatalf("parsing route policies: %v", err)
+	}
+
 	// Proof-of-work service.
-	powService := pow.NewService(testLogger, challengeStorage)
+	powService := pow.NewService(testLogger,
+		&pow.Config{
+			NodeID:                 testCfg.PoW.NodeID,
+			Mode:                   testCfg.PoW.Mode,
+			MaxChallengesPerClient: testCfg.PoW.MaxChallengesPerClient,
+			Scheme:                 testCfg.PoW.Scheme,
+			Hashcash: pow.HashcashConfig{
+				Algorithm:             testCfg.PoW.Algorithm,
+				LegacyAlgorithms:      testCfg.PoW.LegacyAlgorithms,
+				LegacyAlgorithmsUntil: testCfg.PoW.LegacyAlgorithmsUntil,
+				ExtraAlgorithms:       proofer.Algorithms(policies),
+				ValidFor:              testCfg.PoW.ValidFor,
+				ClockSkew:             testCfg.PoW.ClockSkew,
+			},
+			Argon2id: pow.Argon2idConfig{
+				Params: argon2id.Params{
+					Memory:      testCfg.PoW.Argon2.Memory,
+					Iterations:  testCfg.PoW.Argon2.Iterations,
+					Parallelism: testCfg.PoW.Argon2.Parallelism,
+				},
+				ValidFor: testCfg.PoW.Argon2.ValidFor,
+			},
+		},
+		challengeStorage,
+		spentStorage,
+	)
 
 	// Initialize the quote handler.
 	quotesHandler := quotes.NewHandler(testLogger, quoteService)
@@ -81,6 +117,7 @@ func setup() {
 		&proofer.Config{
 			ChallengeDifficulty: testCfg.Server.Middlewares.Proofer.ChallengeDifficulty,
 			SaltLength:          testCfg.Server.Middlewares.Proofer.SaltLength,
+			Policies:            policies,
 		},
 		powService,
 	)
@@ -88,6 +125,9 @@ func setup() {
 	// Initialize the Gin router.
 	testRouter := httptransport.NewRouter(quotesHandler, ratelimiterMW.Use(), prooferMW.Use())
 
+	// Register the challenge endpoint.
+	httptransport.RegisterChallengeRoutes(testRouter, challenge.NewHandler(testLogger, prooferMW, powService), ratelimiterMW.Use())
+
 	// Create the test server.
 	testServer = httptest.NewServer(testRouter)
 